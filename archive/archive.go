@@ -0,0 +1,175 @@
+// Package archive export the old rows of the raw tables to compressed NDJSON files and restore them for a reparse
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"casperParser/db"
+	"casperParser/logger"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Database read, delete and restore the raw rows. *db.DB satisfies it.
+type Database interface {
+	SelectRawToArchive(ctx context.Context, table string, retention db.Retention, limit int) ([]db.RawRow, error)
+	DeleteRaw(ctx context.Context, table string, rows []db.RawRow) error
+	RestoreRaw(ctx context.Context, table string, rows []string) (int64, error)
+}
+
+var _ Database = (*db.DB)(nil)
+
+// fileExtension of the archive files, one JSON row per line compressed with gzip
+const fileExtension = ".ndjson.gz"
+
+// restoreBatchSize number of rows inserted at once by Restore
+const restoreBatchSize = 500
+
+// Archiver move the raw rows past their retention from the database to the store
+type Archiver struct {
+	Database Database
+	Store    Store
+	// FileRows maximum number of rows per archive file
+	FileRows int
+}
+
+// Archive the rows of the raw table past the retention. Each file is stored before its rows are deleted.
+// Return the number of rows archived.
+func (a *Archiver) Archive(ctx context.Context, table string, retention db.Retention) (int, error) {
+	archived := 0
+	for {
+		rows, err := a.Database.SelectRawToArchive(ctx, table, retention, a.FileRows)
+		if err != nil || len(rows) == 0 {
+			return archived, err
+		}
+		data, err := encode(rows)
+		if err != nil {
+			return archived, err
+		}
+		key := fileKey(table, rows)
+		err = a.Store.Put(ctx, key, data)
+		if err != nil {
+			return archived, fmt.Errorf("can't store %s: %w", key, err)
+		}
+		err = a.Database.DeleteRaw(ctx, table, rows)
+		if err != nil {
+			return archived, err
+		}
+		archived += len(rows)
+		logger.FromContext(ctx).WithFields(log.Fields{"table": table, "file": key, "rows": len(rows)}).Info("raw rows archived")
+	}
+}
+
+// Restore the rows of the raw table archived from the blocks fromHeight to toHeight, toHeight 0 meaning the last block.
+// The files are restored whole, they can hold some rows out of the range. Return the number of rows restored.
+func (a *Archiver) Restore(ctx context.Context, table string, fromHeight int, toHeight int) (int64, error) {
+	keys, err := a.Store.List(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+	var restored int64
+	for _, key := range keys {
+		first, last, ok := parseFileKey(key)
+		if !ok {
+			logger.FromContext(ctx).WithField("file", key).Warn("not an archive file, skipped")
+			continue
+		}
+		if last < fromHeight || (toHeight > 0 && first > toHeight) {
+			continue
+		}
+		n, err := a.restoreFile(ctx, table, key)
+		restored += n
+		if err != nil {
+			return restored, fmt.Errorf("can't restore %s: %w", key, err)
+		}
+		logger.FromContext(ctx).WithFields(log.Fields{"table": table, "file": key, "rows": n}).Info("raw rows restored")
+	}
+	return restored, nil
+}
+
+// restoreFile insert the rows of the file by batches of restoreBatchSize
+func (a *Archiver) restoreFile(ctx context.Context, table string, key string) (int64, error) {
+	file, err := a.Store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	var restored int64
+	var batch []string
+	lines := bufio.NewReader(reader)
+	for {
+		// A raw block can be far bigger than the bufio.Scanner max token size, the lines are read whole
+		line, readErr := lines.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			batch = append(batch, line)
+		}
+		if len(batch) > 0 && (len(batch) == restoreBatchSize || readErr == io.EOF) {
+			n, err := a.Database.RestoreRaw(ctx, table, batch)
+			restored += n
+			if err != nil {
+				return restored, err
+			}
+			batch = batch[:0]
+		}
+		if readErr == io.EOF {
+			return restored, nil
+		}
+		if readErr != nil {
+			return restored, readErr
+		}
+	}
+}
+
+// encode the rows in NDJSON compressed with gzip
+func encode(rows []db.RawRow) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	for _, row := range rows {
+		_, err := io.WriteString(writer, row.Data+"\n")
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	return buf.Bytes(), err
+}
+
+// fileKey of the rows, ordered by height : table/<first height>-<last height>-<first hash>.ndjson.gz
+// The first hash tell apart the files of the same blocks.
+func fileKey(table string, rows []db.RawRow) string {
+	first, last := rows[0], rows[len(rows)-1]
+	return fmt.Sprintf("%s/%010d-%010d-%s%s", table, first.Height, last.Height, first.Hash, fileExtension)
+}
+
+// parseFileKey return the first and last height of a key made by fileKey
+func parseFileKey(key string) (int, int, bool) {
+	name := path.Base(key)
+	if !strings.HasSuffix(name, fileExtension) {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(name, fileExtension), "-", 3)
+	if len(parts) != 3 {
+		return 0, 0, false
+	}
+	first, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	last, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
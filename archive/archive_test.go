@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"casperParser/db"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// memoryDatabase keep the raw rows of a single table in memory
+type memoryDatabase struct {
+	rows map[string]db.RawRow
+}
+
+func (m *memoryDatabase) SelectRawToArchive(ctx context.Context, table string, retention db.Retention, limit int) ([]db.RawRow, error) {
+	var rows []db.RawRow
+	for _, row := range m.rows {
+		if row.Height <= retention.KeepBlocks {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Hash < rows[j].Hash })
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+func (m *memoryDatabase) DeleteRaw(ctx context.Context, table string, rows []db.RawRow) error {
+	for _, row := range rows {
+		delete(m.rows, row.Hash)
+	}
+	return nil
+}
+
+func (m *memoryDatabase) RestoreRaw(ctx context.Context, table string, rows []string) (int64, error) {
+	for _, data := range rows {
+		var row struct {
+			Hash   string
+			Height int
+		}
+		err := json.Unmarshal([]byte(data), &row)
+		if err != nil {
+			return 0, err
+		}
+		m.rows[row.Hash] = db.RawRow{Hash: row.Hash, Height: row.Height, Data: data}
+	}
+	return int64(len(rows)), nil
+}
+
+// newMemoryDatabase with a row per height from 1 to count, the hashes sort like the heights
+func newMemoryDatabase(count int) *memoryDatabase {
+	m := &memoryDatabase{rows: map[string]db.RawRow{}}
+	for height := 1; height <= count; height++ {
+		hash := fmt.Sprintf("%02d", height)
+		m.rows[hash] = db.RawRow{Hash: hash, Height: height, Data: fmt.Sprintf(`{"hash": "%s", "height": %d}`, hash, height)}
+	}
+	return m
+}
+
+func TestArchiver(t *testing.T) {
+	t.Run("Should archive the rows past the retention by files of FileRows rows", func(t *testing.T) {
+		database := newMemoryDatabase(10)
+		store := Dir(t.TempDir())
+		archiver := Archiver{Database: database, Store: store, FileRows: 3}
+		archived, err := archiver.Archive(context.Background(), "raw_blocks", db.Retention{KeepBlocks: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if archived != 7 || len(database.rows) != 3 {
+			t.Errorf("Bad number of rows archived. Received : %d archived, %d left. Expected : 7 archived, 3 left", archived, len(database.rows))
+		}
+		keys, err := store.List(context.Background(), "raw_blocks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{
+			"raw_blocks/0000000001-0000000003-01.ndjson.gz",
+			"raw_blocks/0000000004-0000000006-04.ndjson.gz",
+			"raw_blocks/0000000007-0000000007-07.ndjson.gz",
+		}
+		if fmt.Sprint(keys) != fmt.Sprint(expected) {
+			t.Errorf("Bad archive files. Received : %v. Expected : %v", keys, expected)
+		}
+	})
+	t.Run("Should restore the files of the height range", func(t *testing.T) {
+		database := newMemoryDatabase(10)
+		archiver := Archiver{Database: database, Store: Dir(t.TempDir()), FileRows: 3}
+		_, err := archiver.Archive(context.Background(), "raw_blocks", db.Retention{KeepBlocks: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := archiver.Restore(context.Background(), "raw_blocks", 5, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored != 3 {
+			t.Errorf("Bad number of rows restored. Received : %d. Expected : 3", restored)
+		}
+		for _, hash := range []string{"04", "05", "06"} {
+			if _, ok := database.rows[hash]; !ok {
+				t.Errorf("Row not restored. Received : %v. Expected : %s", database.rows, hash)
+			}
+		}
+	})
+}
+
+func TestParseFileKey(t *testing.T) {
+	t.Run("Should parse the heights of a file key", func(t *testing.T) {
+		first, last, ok := parseFileKey("raw_deploys/0000000012-0000000034-abcdef.ndjson.gz")
+		if !ok || first != 12 || last != 34 {
+			t.Errorf("Bad heights. Received : %d, %d, %t. Expected : 12, 34, true", first, last, ok)
+		}
+	})
+	t.Run("Should reject a foreign file", func(t *testing.T) {
+		if _, _, ok := parseFileKey("raw_deploys/notes.txt"); ok {
+			t.Errorf("Foreign file accepted. Received : %t. Expected : false", ok)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config the connection to an S3 compatible store, AWS S3 or MinIO for example
+type S3Config struct {
+	// Endpoint host[:port] of the store
+	Endpoint string
+	// AccessKey and SecretKey of the store. The AWS_* and MINIO_* env variables are used when they're empty.
+	AccessKey string
+	SecretKey string
+	Region    string
+	// Insecure use plain http
+	Insecure bool
+}
+
+// S3 a Store in a bucket of an S3 compatible store, the keys are put under prefix
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3 store in bucket under prefix
+func NewS3(config S3Config, bucket string, prefix string) (*S3, error) {
+	creds := credentials.NewStaticV4(config.AccessKey, config.SecretKey, "")
+	if config.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{&credentials.EnvAWS{}, &credentials.EnvMinio{}})
+	}
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !config.Insecure,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3{client: client, bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+// Put upload the file
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, path.Join(s.prefix, key), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: "application/gzip"})
+	return err
+}
+
+// Get download the file
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, s.bucket, path.Join(s.prefix, key), minio.GetObjectOptions{})
+}
+
+// List the files under dir
+func (s *S3) List(ctx context.Context, dir string) ([]string, error) {
+	prefix := path.Join(s.prefix, dir) + "/"
+	var keys []string
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			continue
+		}
+		keys = append(keys, dir+"/"+strings.TrimPrefix(object.Key, prefix))
+	}
+	return keys, nil
+}
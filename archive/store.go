@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Store hold the archive files by key. Keys are slash separated paths.
+type Store interface {
+	// Put write the file, replacing the one with the same key
+	Put(ctx context.Context, key string, data []byte) error
+	// Get open the file for reading
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List the keys of the files under dir, sorted
+	List(ctx context.Context, dir string) ([]string, error)
+}
+
+// Open the store at location, an S3 url like s3://bucket/prefix or a local directory
+func Open(location string, s3 S3Config) (Store, error) {
+	if strings.HasPrefix(location, "s3://") {
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+		return NewS3(s3, bucket, prefix)
+	}
+	return Dir(location), nil
+}
+
+// Dir a Store in a local directory
+type Dir string
+
+// Put write the file in a temporary file first, so an interrupted Put never leaves a truncated file behind
+func (d Dir) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(string(d), filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get open the file for reading
+func (d Dir) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(key)))
+}
+
+// List the files of dir, nothing when dir doesn't exist
+func (d Dir) List(ctx context.Context, dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(string(d), filepath.FromSlash(dir)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		keys = append(keys, dir+"/"+entry.Name())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,81 @@
+// Package cmd define the all-in-one command
+package cmd
+
+import (
+	"casperParser/db"
+	"casperParser/queue"
+	"casperParser/tasks"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var capacity int
+
+// allInOneCmd represents the all-in-one command
+var allInOneCmd = &cobra.Command{
+	Use:   "all-in-one",
+	Short: "Start a client and the workers in a single process without Redis",
+	Long: `Start the client and the workers in a single process. The tasks go through an in-memory queue instead of Redis, so only a Postgres database and a node are needed.
+The pending tasks are lost when the process stops, the missing blocks are added back to the queue on the next start.
+Usage examples :
+casperParser all-in-one -- Will parse the existing blocks then listen to the new blocks with the default values
+casperParser all-in-one --queues blocks,1,deploys,3,deployinfos,3,transfers,2,contracts,2,era,1,auction,1,auctionera,1,accounts,2,scheduled,1 --concurrency 20 -- Will give more priority to the deploys with 20 concurrent workers
+casperParser all-in-one --capacity 1000 -- Will pause the client while 1000 tasks are waiting in the queue
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if onlyFromEvents && onlyUntilCurrentBlock {
+			log.Fatal("you can't have both 'onlyFromEvents' and 'onlyUntilCurrentBlock' flags at the same time that's the default behavior. Remove at least one flag")
+		}
+		queues := getQueues(cmd)
+		for name := range queue.Queues {
+			if _, ok := queues[name]; !ok {
+				log.Fatalf("Missing queue %s. The all-in-one command must serve every queue", name)
+			}
+		}
+		log.WithFields(log.Fields{"concurrency": concurrency, "queues": queues, "capacity": capacity}).Info("starting all-in-one")
+		startAllInOne(queues)
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(allInOneCmd)
+	allInOneCmd.Flags().IntVarP(&concurrency, "concurrency", "k", 100, "Number of concurrent workers to use. The database connection pool will be set to the same number")
+	allInOneCmd.Flags().StringSliceVarP(&queues, "queues", "q", []string{"blocks", "1", "deploys", "1", "deployinfos", "1", "transfers", "1", "contracts", "1", "era", "1", "auction", "1", "auctionera", "1", "accounts", "1", "scheduled", "1"}, "Set queues with priority")
+	allInOneCmd.Flags().IntVar(&capacity, "capacity", 10000, "Number of pending tasks above which the client wait for the workers")
+	allInOneCmd.Flags().StringVarP(&event, "event", "e", "http://127.0.0.1:9999/events/main", "Node main event endpoint")
+	allInOneCmd.Flags().BoolVar(&disableCheckMissingBlocks, "disableCheckMissingBlocks", false, "Disable check on missing blocks")
+	allInOneCmd.Flags().BoolVar(&onlyFromEvents, "onlyFromEvents", false, "Only parse incoming events")
+	allInOneCmd.Flags().BoolVar(&onlyUntilCurrentBlock, "onlyUntilCurrentBlock", false, "Only add the blocks until the current block. The workers keep running until the process is stopped")
+	addBatchFlags(allInOneCmd)
+}
+
+// startAllInOne run the client and the workers over a memory queue until the process receive SIGINT or SIGTERM
+func startAllInOne(queues map[string]int) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pgPool, err := db.NewPGXPool(ctx, getDatabaseConnectionString(), concurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pgPool.Close()
+	store, closeStore := newWorkerStore(pgPool)
+	defer closeStore()
+	tasks.WorkerStore = store
+	tasks.WorkerJobStore = &db.DB{Postgres: pgPool}
+	database = tasks.WorkerStore
+	tasks.WorkerRpcClient = getRpcClient()
+
+	memory := queue.NewMemory(queues, capacity)
+	tasks.WorkerAsyncClient = memory
+	client = memory.Producer()
+
+	go runClient()
+	memory.Run(ctx, newServeMux(), concurrency)
+	log.WithField("pending", memory.Len()).Info("all-in-one stopped")
+}
@@ -0,0 +1,136 @@
+// Package cmd define the archive command
+package cmd
+
+import (
+	"casperParser/archive"
+	"casperParser/db"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var archiveLocation string
+var archiveS3 archive.S3Config
+var archiveFileRows int
+var archiveMaxAge time.Duration
+var archiveKeepBlocks int
+var restoreFromHeight int
+var restoreToHeight int
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive [raw_blocks|raw_deploys|raw_deploy_infos|raw_transfers]...",
+	Short: "Export the raw rows past their retention to compressed NDJSON files and delete them",
+	Long: `Export the rows of the raw tables past their retention to compressed NDJSON files, then delete them from the database
+
+The retention of each raw table is read from the retention key of the config file. The --max-age and --keep-blocks flags
+override it for the tables given as arguments, or every raw table without argument.
+Only the rows of validated blocks are archived.
+
+The files are written in the --archive-location directory, or in an S3 compatible store with an s3://bucket/prefix location.
+Restore them with the archive restore command before reparsing the archived blocks or deploys.
+`,
+	ValidArgs: db.RawTables,
+	Args:      cobra.OnlyValidArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables := args
+		if len(tables) == 0 {
+			tables = db.RawTables
+		}
+		retentions, err := getRetentions(cmd, tables)
+		if err != nil {
+			return err
+		}
+		return withArchiver(func(archiver *archive.Archiver) error {
+			for _, table := range tables {
+				if !retentions[table].Enabled() {
+					log.WithField("table", table).Info("no retention, skipped")
+					continue
+				}
+				archived, err := archiver.Archive(context.Background(), table, retentions[table])
+				if err != nil {
+					return err
+				}
+				log.WithFields(log.Fields{"table": table, "rows": archived}).Info("table archived")
+			}
+			return nil
+		})
+	},
+}
+
+// archiveRestoreCmd represents the archive restore command
+var archiveRestoreCmd = &cobra.Command{
+	Use:   "restore raw_blocks|raw_deploys|raw_deploy_infos|raw_transfers",
+	Short: "Restore the archived raw rows of a table in the database",
+	Long: `Restore the archived raw rows of a table in the database, the rows still in the database are kept
+
+Only the files holding the rows of the blocks from --from-height to --to-height are restored.
+`,
+	ValidArgs: db.RawTables,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withArchiver(func(archiver *archive.Archiver) error {
+			restored, err := archiver.Restore(context.Background(), args[0], restoreFromHeight, restoreToHeight)
+			if err != nil {
+				return err
+			}
+			log.WithFields(log.Fields{"table": args[0], "rows": restored}).Info("table restored")
+			return nil
+		})
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(archiveCmd)
+	archiveCmd.AddCommand(archiveRestoreCmd)
+	archiveCmd.PersistentFlags().StringVar(&archiveLocation, "archive-location", "archive", "Directory of the archive files, or s3://bucket/prefix to use an S3 compatible store")
+	archiveCmd.PersistentFlags().StringVar(&archiveS3.Endpoint, "s3-endpoint", "s3.amazonaws.com", "S3 compatible store endpoint (host:port)")
+	archiveCmd.PersistentFlags().StringVar(&archiveS3.AccessKey, "s3-access-key", "", "S3 access key. The AWS_* or MINIO_* env variables are used when empty")
+	archiveCmd.PersistentFlags().StringVar(&archiveS3.SecretKey, "s3-secret-key", "", "S3 secret key. Prefer ENV variable")
+	archiveCmd.PersistentFlags().StringVar(&archiveS3.Region, "s3-region", "", "S3 region")
+	archiveCmd.PersistentFlags().BoolVar(&archiveS3.Insecure, "s3-insecure", false, "Use plain http to reach the S3 compatible store")
+	archiveCmd.Flags().IntVar(&archiveFileRows, "file-rows", 10000, "Maximum number of rows per archive file")
+	archiveCmd.Flags().DurationVar(&archiveMaxAge, "max-age", 0, "Archive the rows of the blocks older than this duration, 2160h for 90 days")
+	archiveCmd.Flags().IntVar(&archiveKeepBlocks, "keep-blocks", 0, "Archive the rows of the blocks more than this number of blocks below the last one")
+	archiveRestoreCmd.Flags().IntVar(&restoreFromHeight, "from-height", 0, "First block height to restore")
+	archiveRestoreCmd.Flags().IntVar(&restoreToHeight, "to-height", 0, "Last block height to restore, 0 for the last block")
+}
+
+// getRetentions of the tables from the config file, overridden by the max-age and keep-blocks flags
+func getRetentions(cmd *cobra.Command, tables []string) (map[string]db.Retention, error) {
+	retentions := map[string]db.Retention{}
+	err := viper.UnmarshalKey("retention", &retentions)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode the retention config: %w", err)
+	}
+	for _, table := range tables {
+		retention := retentions[table]
+		if cmd.Flags().Changed("max-age") {
+			retention.MaxAge = archiveMaxAge
+		}
+		if cmd.Flags().Changed("keep-blocks") {
+			retention.KeepBlocks = archiveKeepBlocks
+		}
+		retentions[table] = retention
+	}
+	return retentions, nil
+}
+
+// withArchiver open the database and the archive store for the duration of fn
+func withArchiver(fn func(archiver *archive.Archiver) error) error {
+	store, err := archive.Open(archiveLocation, archiveS3)
+	if err != nil {
+		return err
+	}
+	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), 2)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+	return fn(&archive.Archiver{Database: &db.DB{Postgres: pgPool}, Store: store, FileRows: archiveFileRows})
+}
@@ -0,0 +1,89 @@
+// Package cmd define the classify command
+package cmd
+
+import (
+	"casperParser/db"
+	"casperParser/tasks"
+	"casperParser/types/config"
+	"casperParser/types/contract"
+	"context"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+var classifyPool int
+var classifyTop int
+var classifyDryRun bool
+var classifyFilter db.ReparseFilter
+
+// classifyCmd represents the classify command
+var classifyCmd = &cobra.Command{
+	Use:   "classify",
+	Short: "Classify the stored contracts again with the contract types of the config",
+	Long: `Classify the contracts of the database again from their raw data with the contract types of the config, without calling rpc
+
+Run it after changing the contract types of the config. The classification of each contract is stored for the config version,
+with the types considered and the best candidates, and the type and the score of the contract are updated.
+The config version is the version set in the config, or a hash of the contract types when it isn't set.
+The contracts classified as a CEP-47 or CEP-78 collection for the first time are added to the NFT collections.
+
+Use --contract-hash or --account to only classify some contracts, and --dry-run to log the types that would change without storing anything.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		startClassify()
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(classifyCmd)
+	classifyCmd.Flags().IntVarP(&classifyPool, "pool", "p", 10, "Database connection pool max connections")
+	classifyCmd.Flags().IntVar(&classifyTop, "top", contract.DefaultTopCandidates, "Number of candidates stored with each classification, 0 for all")
+	classifyCmd.Flags().BoolVar(&classifyDryRun, "dry-run", false, "Log the types that would change without storing anything")
+	classifyCmd.Flags().StringVar(&classifyFilter.ContractHash, "contract-hash", "", "Only classify this contract")
+	classifyCmd.Flags().StringVar(&classifyFilter.Account, "account", "", "Only classify the contracts sent by this public key")
+}
+
+// startClassify classify every contract matching the filters, logging the ones changing type
+func startClassify() {
+	ctx := context.Background()
+	pgPool, err := db.NewPGXPool(ctx, getDatabaseConnectionString(), classifyPool)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pgPool.Close()
+	database := &db.DB{Postgres: pgPool}
+	classified, changed := 0, 0
+	err = database.ForEachReparse(ctx, db.ReparseContracts, classifyFilter, func(item db.ReparseItem) error {
+		contractParsed, err := database.GetRawContract(ctx, item.Hash)
+		if err != nil {
+			return err
+		}
+		previousType, err := database.GetContractType(ctx, item.Hash)
+		if err != nil {
+			return err
+		}
+		classification := contractParsed.Classify(config.ConfigParsed, classifyTop)
+		classified++
+		if classification.Type != previousType {
+			changed++
+			log.WithFields(log.Fields{"contract_hash": item.Hash, "previous_type": previousType, "contract_type": classification.Type, "accuracy": classification.Score}).Info("contract type changed")
+		}
+		if classifyDryRun {
+			return nil
+		}
+		err = database.InsertContractClassification(ctx, item.Hash, classification)
+		if err != nil {
+			return err
+		}
+		packageHash := strings.ReplaceAll(contractParsed.StoredValue.Contract.ContractPackageHash, "contract-package-wasm", "")
+		return tasks.InsertReclassifiedNFTCollection(ctx, database, item.Hash, packageHash, classification.Type)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.WithFields(log.Fields{"config_version": config.ConfigParsed.ContractTypesVersion(), "count": classified, "changed": changed, "dry_run": classifyDryRun}).Info("contracts classified")
+}
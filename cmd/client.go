@@ -3,24 +3,29 @@ package cmd
 
 import (
 	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/queue"
 	"casperParser/tasks"
+	"casperParser/tracing"
 	"context"
 	"fmt"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/hibiken/asynq"
 	"github.com/r3labs/sse/v2"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"log"
+	"go.opentelemetry.io/otel/attribute"
 	"sync"
 )
 
-var database db.DB
-var client *asynq.Client
+var database db.Store
+var client queue.Client
 var pool int
 var event string
 var disableCheckMissingBlocks bool
 var onlyFromEvents bool
 var onlyUntilCurrentBlock bool
+var disableAuctionOnBlock bool
 var wg sync.WaitGroup
 
 // clientCmd represents the client command
@@ -33,23 +38,16 @@ var clientCmd = &cobra.Command{
 			_ = fmt.Errorf("you can't have both 'onlyFromEvents' and 'onlyUntilCurrentBlock' flags at the same time that's the default behavior. Remove at least one flag")
 			return
 		}
-		client = asynq.NewClient(getRedisConf(cmd))
-		defer client.Close()
+		backend := newQueueBackend(getRedisConf(cmd), queue.Queues, pool)
+		defer backend.Close()
+		client = backend
 		pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
 		defer pgPool.Close()
 		if err != nil {
 			log.Fatal(err)
 		}
-		database = db.DB{Postgres: pgPool}
-		if onlyFromEvents || !onlyFromEvents && !onlyUntilCurrentBlock {
-			go listenEvents()
-			wg.Add(1)
-		}
-		if onlyUntilCurrentBlock || !onlyFromEvents && !onlyUntilCurrentBlock {
-			go startClient()
-			wg.Add(1)
-		}
-		wg.Wait()
+		database = &db.DB{Postgres: pgPool}
+		runClient()
 	},
 }
 
@@ -61,6 +59,20 @@ func init() {
 	clientCmd.Flags().BoolVar(&disableCheckMissingBlocks, "disableCheckMissingBlocks", false, "Disable check on missing blocks")
 	clientCmd.Flags().BoolVar(&onlyFromEvents, "onlyFromEvents", false, "Only parse incoming events")
 	clientCmd.Flags().BoolVar(&onlyUntilCurrentBlock, "onlyUntilCurrentBlock", false, "Only parse until the current block")
+	clientCmd.Flags().BoolVar(&disableAuctionOnBlock, "disableAuctionOnBlock", false, "Don't add an auction task on every new block, when the scheduler runs the auction job")
+}
+
+// runClient listen to the node events and/or add the existing blocks to the queue depending on the flags
+func runClient() {
+	if onlyFromEvents || !onlyFromEvents && !onlyUntilCurrentBlock {
+		wg.Add(1)
+		go listenEvents()
+	}
+	if onlyUntilCurrentBlock || !onlyFromEvents && !onlyUntilCurrentBlock {
+		wg.Add(1)
+		go startClient()
+	}
+	wg.Wait()
 }
 
 // startClient and add all blocks to the queue
@@ -69,8 +81,7 @@ func startClient() {
 	if !disableCheckMissingBlocks {
 		blocks, err := database.GetMissingBlocks(context.Background())
 		if err != nil {
-			log.Println("Unable to verify if there's missing blocks in the db.")
-			log.Println(err)
+			log.WithError(err).Error("unable to verify if there's missing blocks in the db")
 		}
 		for _, block := range blocks {
 			addBlockTask(block)
@@ -78,9 +89,9 @@ func startClient() {
 	}
 	lastBlock := getLastBlockInDatabase()
 	rpcClient := getRpcClient()
-	lastBlockHeight, err := rpcClient.GetLastBlockHeight()
+	lastBlockHeight, err := rpcClient.GetLastBlockHeight(context.Background())
 	if err != nil {
-		log.Println("Unable to determine the last block height on the blockchain.")
+		log.WithError(err).Error("unable to determine the last block height on the blockchain")
 		return
 	}
 	for i := lastBlock; i <= lastBlockHeight; i++ {
@@ -88,36 +99,36 @@ func startClient() {
 	}
 }
 
-// addBlockTask to the queue
+// addBlockTask to the queue. Each block starts a new trace that covers all the tasks spawned from it
 func addBlockTask(height int) {
+	ctx, span := tracing.Start(context.Background(), "client.addBlockTask", attribute.Int("block.height", height))
+	defer span.End()
 	task, err := tasks.NewBlockRawTask(height)
 	if err != nil {
-		log.Printf("could not create task: %v\n", err)
+		log.WithError(err).WithField(logger.FieldBlockHeight, height).Error("could not create task")
 	}
-	_, err = client.Enqueue(task, asynq.Queue("blocks"))
+	_, err = client.Enqueue(tracing.Inject(ctx, task), asynq.Queue("blocks"))
 	if err != nil {
-		log.Printf("could not enqueue task: %v\n", err)
+		log.WithError(err).WithField(logger.FieldBlockHeight, height).Error("could not enqueue task")
 	}
 }
 
 func addAuctionTask() {
 	auction, err := tasks.NewAuctionTask()
 	if err != nil {
-		log.Printf("could not create task: %v\n", err)
+		log.WithError(err).Error("could not create task")
 	}
 	_, err = client.Enqueue(auction, asynq.Queue("auction"))
 	if err != nil {
-		log.Printf("could not enqueue task: %v\n", err)
+		log.WithError(err).Error("could not enqueue task")
 	}
 }
 
 // getLastBlockInDatabase defined by the max height block in the db
 func getLastBlockInDatabase() int {
-	lastBlock := 0
-	err := database.Postgres.QueryRow(context.Background(), `select max(height) from blocks`).Scan(&lastBlock)
+	lastBlock, err := database.GetLastBlockHeight(context.Background())
 	if err != nil {
-		log.Println("Failed to find the last block in the DB. Starting from 0.")
-		log.Println(err)
+		log.WithError(err).Warn("failed to find the last block in the db, starting from 0")
 	}
 	return lastBlock
 }
@@ -132,10 +143,12 @@ func listenEvents() {
 		height, ok := transforms.S("BlockAdded", "block", "header", "height").Data().(float64)
 		if ok {
 			addBlockTask(int(height))
-			addAuctionTask()
+			if !disableAuctionOnBlock {
+				addAuctionTask()
+			}
 		}
 	})
 	if err != nil {
-		log.Println(err)
+		log.WithError(err).WithField("event", event).Error("can't subscribe to the node events")
 	}
 }
@@ -0,0 +1,44 @@
+// Package cmd define the maintenance command
+package cmd
+
+import (
+	"casperParser/db"
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceMonthsAhead int
+
+// maintenanceCmd represents the maintenance command
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Create the monthly partitions of the deploys, transfers and raw tables ahead of time",
+	Long: `Create the missing monthly partitions of the deploys, transfers, raw_deploys and raw_transfers tables
+from the current month to --months-ahead months later.
+
+A row whose month has no partition can't be inserted, run it at least once a month, by cron for example.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), 1)
+		if err != nil {
+			return err
+		}
+		defer pgPool.Close()
+		database := &db.DB{Postgres: pgPool}
+		created, err := database.CreatePartitions(context.Background(), maintenanceMonthsAhead)
+		if err != nil {
+			return err
+		}
+		log.Infof("%d partitions created", created)
+		return nil
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(maintenanceCmd)
+	maintenanceCmd.Flags().IntVar(&maintenanceMonthsAhead, "months-ahead", 3, "Number of months after the current one to create the partitions for")
+}
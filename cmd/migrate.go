@@ -0,0 +1,135 @@
+// Package cmd define the migrate command
+package cmd
+
+import (
+	"casperParser/db"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database migrations",
+	Long: `Manage the database migrations embedded in the binary
+
+The other commands apply the pending migrations on start unless --auto-migrate=false is set.
+Disable it when several instances start together, and run the migrations once with :
+
+casperParser migrate up
+`,
+}
+
+// migrateStatusCmd represents the migrate status command
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the database version and the applied and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *db.Migrator) error {
+			version, dirty, err := m.Version()
+			if err != nil {
+				return err
+			}
+			status, err := m.Status()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "version: %d", version)
+			if dirty {
+				fmt.Fprint(out, " (dirty, fix the database then run migrate force)")
+			}
+			fmt.Fprintln(out)
+			for _, migration := range status {
+				state := "pending"
+				if migration.Applied {
+					state = "applied"
+				}
+				fmt.Fprintf(out, "%06d %-8s %s\n", migration.Version, state, migration.Name)
+			}
+			return nil
+		})
+	},
+}
+
+// migrateUpCmd represents the migrate up command
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [N]",
+	Short: "Apply the N next migrations, or all the pending ones",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := migrationsCount(args, 0)
+		if err != nil {
+			return err
+		}
+		return withMigrator(func(m *db.Migrator) error {
+			return m.Up(n)
+		})
+	},
+}
+
+// migrateDownCmd represents the migrate down command
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Revert the N last applied migrations, only the last one by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := migrationsCount(args, 1)
+		if err != nil {
+			return err
+		}
+		return withMigrator(func(m *db.Migrator) error {
+			return m.Down(n)
+		})
+	},
+}
+
+// migrateForceCmd represents the migrate force command
+var migrateForceCmd = &cobra.Command{
+	Use:   "force V",
+	Short: "Set the database version to V and clear the dirty flag without running any migration",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %s: %w", args[0], err)
+		}
+		return withMigrator(func(m *db.Migrator) error {
+			return m.Force(version)
+		})
+	},
+}
+
+// init the subcommands
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateStatusCmd, migrateUpCmd, migrateDownCmd, migrateForceCmd)
+}
+
+// withMigrator open a migrator on the database for the duration of fn
+func withMigrator(fn func(m *db.Migrator) error) error {
+	m, err := db.NewMigrator(getDatabaseConnectionString())
+	if err != nil {
+		return err
+	}
+	err = fn(m)
+	if closeErr := m.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// migrationsCount parsed from the optional N argument
+func migrationsCount(args []string, defaultCount int) (int, error) {
+	if len(args) == 0 {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %s", args[0])
+	}
+	return n, nil
+}
@@ -1,12 +0,0 @@
-// Code generated by pkger; DO NOT EDIT.
-
-// +build !skippkger
-
-package cmd
-
-import (
-	"github.com/markbates/pkger"
-	"github.com/markbates/pkger/pkging/mem"
-)
-
-var _ = pkger.Apply(mem.UnmarshalEmbed([]byte(`1f8b08000000000000ffecbd6993aac8b63ffc554ef8f6d4d9026ad5b6229e17620982a555828072e3440793802443034e75a2bffb1399cc8396eeeebe37fe27ec8eda9ae4ca69e51a7e6b6556f19f8eed6dfda8f3fa9f8ea64481117e2a616484b0fc66879dd74e57888c30ea3a3600b6e2594aa4f946dc7df3b5bd6b7871d44d5a0549aba70ee3067e187f2ab1d579adf6f8d45928aed179edb88aed759e3a6fbed679ed749e3a2b25348df8e250a6df556daf5beb8bf3fdcb4d2ece6eaec49ad579fd9fce8fcebf9f3a7cac00a3f31a877b232d708612f95ee7b513c1d23f7423303cddf0b4f3eb3fcae377e37360445dcdf7b6b6d979ead03e650323821dc3c5fd307dd87dc20af4b4d258737558fd6604a84edd6f6dbff3d451cfb111759e9ab4b547ba5a7f12065afd51ac444ea3b364d6ca5e8b6ddf6baf5481af39ed55f96adbebe250d1e2ebb59f8ae628a6d14ea41b01f0cfed75a17154c20623f6b10dd01a7d37088d28ea6e81121be507e6971da0b2172bb667845d604771fac038a16fe13988fdfc4b5749b6202968766021b149cb7ab9528f94a26068d5a24e0c06f8b0f400003b886dad7862b94aa9647bb1117a0ae8aa7e687be6c58aaeaada576aa3d64a4387dc8b6a536aadee6e6d03e82d449e1dc546db84938aeed656e296da50f174b84d2d55d15e8d815154b8faa028c076a592d62f15ca8c8e2c05af9488c173a53cc08952b936640c4afb791a60c36aa998abab687e8db41b38f6a9f3d4d195585195c8e846bf835ab1ab87f601c98fe1aa065c8fe169be9eec6ff6b5ab441e5e2ec3d6cffdca13db53c273f989161dca45cb38958bbb0869775e0e0c1716c3d00fe122b6408113d8ba70c34c3bb6f6ea0fcd77bbacb1dd2a36e89a8a1a750f44b55233a240098deee9642991d5a8d6cdf01c3976d7f4ff15428bf975f0f75195641b797e6c6fcff9976a356aa9db50d7e1bf879fdf54e79b732b5d174e3c464bbf913ed214ef66eac0f76f9f4a10fac8e8dc469d2ac36dc4a9b65588d5fd76ab00bf6b19a151af038a67fecbb5cd50898d6ef679e8df48d6cde4fd5efaaebb07b11dc57521bca165e047b1191ad1ad0d0bd6840dce5c6a13f9fb50bb79510975d78ae3607bf3b4d24681632213d168930889badfb64b4b8d04f9c95b68ba8ad7d0bc563a7d1f2a294ef89e38b65d238a1537a853fb2630bafbbd5d1360a88bb6e68741d708c363a80497aa4dff5f485290f5ba446469e04a15fa51a2f83b921c245ea70a913c7d47066d877703591c6abe77f88e2cf61dc3bb46044dfe0d0b4064374c0dd1b50d6aabb663785d253a7bbf5fa92a0193866db8405840b2ef697357f63d29f0cd9be85a74ec026588f0f7f77450259ac678a7688ed6d5acbde78486a21b61c395261481a9f99e77b9a6c4b8f8744451cd655a68663545b38c761a230c355fbf5069fbedcf03258ab636b8d00ab1b377716d91111e6ccdb8dc1e5a95f69a53c339956b4af052f1ecd8febad20bfc687aed9462afebf5b9015bed06bfb73debfa75fb963e8fb45071ab35ae62da9aef297608652e30c2d8ae1b6e57091d55898da8cd39d42acb1835b8de4f493415c7f0bd6e2ba96504ca5fd44d776b83d8a82b6abdbbc0311344fc2d4d3783d1df53ba4683edad7451acfbf5f9d9b16619005888a371b8d7e27d1d3605060006744ad03fc5be0b1a92de465170aee18dae93eb8ad79083eb2d60b8edd49b843d00a17d14198de986bebab5cdae16fa5ef7d0abd645c116ef7595ad11fa172b9a0c4f2ab5c64ad3e7be1a2a6d15bba30265e66828b165846e1d802744411ac2342a0e76505f74a9a2e4408a38ec56d2aeeec746dd535f6fd18025d7c96dcfbe877ca71c944b16e49b96511dd55da787d2750ffd5969d0ef553fb263bf6be64cf47fec5523fce1876657897d17a5464cbf001d0867a2ea53370dbb55a03806a15ea80d5447df12f54ae314406811c5a1627b71d4561d015b331a359e11973149b92a3a47a5a0c28b94ad61215fde46b8f7509aa0f21c760a55a3392aaa2947b6172bbbb0629f64d36ea0ea6abe1b2409ba2bd46978dc20b83a56b8f72e2c2432b47d6874555bb7c33d686f9dd204a1a1d9edbdc4a1e2455b3f745b6bf79e0dc10b1ae42a8177a987a3adc756a3c6768d6e98a4139328e647a93e0f7f8a1c0b7c920bcb8df4473bfca6ffc205189196666c6ea4f68354dc6f218fb31cc30dd4854534b687544aef68e51a51a4984664dcba98c20ea5d2794f936f77a425a8b881182257c8e43bc833487b0b3950cc5b67621a9e7debced96e70eb76f96166cfbea70d42c574951b896fdff728be793b0e4618a5a98acbd4798877992434b6c0d0e2e4c1f7fb5b214f0b77b530ed284699dd6b4df61e3246e881bd55b46fa4a84affed8e67872f9116da41ec87817a0bbde3f947afab78e77bc8b38cd23d6df2c452da2870cc1fb6d7d50ccf51406c77554573fcedf6c7012f57db9e5d7b0231c90f846c2d25b2d28fae166a3d222b6c1130b162045ee0473736dc203d522aa0bb6a978bea39361460961f69c1be5cdcba71e48771f991e92b210ad84b4f7443dd9bd547c6293042db35bc5a6314b1144a8dbe549ec4be522e7b460ce381ca32fc28cba89532d80094cbf526a9ec02bbca90c8f64c606c816d5a957946e7485300e81a27434b305fa32ac54605fc30a23849d85c8257281b61fb5d9466b5fdaeeda72996a499ab204f0e3fbac94159fa15b942f43d7574aeed1ae947928a0e14b447e8c1ef7b3f367494e95354845b3c64b52026b4e238287d45ff64dccd1f96105ce359578934db4e6b3c23b6b3eea0afca6c142c27e96ab4d97e84d8987c8b6c33e9da8fbafb24e11724eb861fc8d7a4e5c2248586699c82fc4b373a7bb102799f5a8be25b5733fd522913cbac5cac2203ed8d9a74131acfa3335c4aaa0b45e2338a43db43ee2e3a7b5afa51749fca4ae7a9938041453d867692804d1e143a5a2927d950f834595e0a038b6fdd7dbcc59fabe59fa80825aef3d439189eee87ddb65843b314cd5208ec36aac00767bc870dbea1461fd09edc4a971d1d5e23de8707233bf8bd89ae0c5a92a3e02bcd2c47df5ea7285b971b98d03c1d6e2386daa17b11fc49d1e415c25c4bcd3db202dfd205a17f3a7f434874ad404117252e51d9baa75ca90efdfdc58dbe103b5d242d074817896a71d2b774285cfaf753676544717ebdc5db03903ccaefb6248fe6be0e27f9fa9f4efbcd9fb9627bd9459b5fba5644fb735fbfb359d7f47fb8be8e5a8b29427cede03ff09f9d3ffef8e3a9b34d1655bdf8f48a0ef25f936b51f0533762c50688ce4bee2f2527fd91fd65745e71e2e753c78586e595c0fb2ffd9f7dbc8fa327bf21c3f3da2130a2f72f8cf817f6734560af44ff15effd2088e160f0f2820ffe89e1af18342376f49b0ef992b0089acad7ff74de8c43e7157f7e7979217a3dc46438e2f3cf9797a7ce02d89ed379ed3f7518cfefbce23f9f07cf2ff85347b0f5ceeb00c39f3a34fc46604f1d4e87fd604f9d4f45ff4d33fddfb0ceebff604fe8ff7f3f7546086305860687e4e107fefc32f8f9b34f0c7a4f9d45049f10cffd67ec19effffce3a933bf853e5be01f4f9df19df4a41dc6564b9be71762d8472b4bda0c7bf86030787e19fef1d4e18bcd2081af39115a2f099c6497fad8f0f9a943a1b00a56d086873edfd1a506f475997e858cf937120febbfff165ca5b7eb17e2d4bd0df47f306fff70edc84dcf9bbeb9f6969886e49a5b6139fe1b8d498ac0e08c1df3e28e23cbf2477247285b2b143a2f2e5a16d4a8db8665ea62f03ffc37dbb3e3df74f587ee1fbd1fdf5aac4b8d722bd6c3b1cc8cf509ec16fbf54cbc6043bc61bfb60a88be3160bd1efef3676ec0f0d480f5f11e3e7819fcc506ac9f198be767fcf905ffd97fbedd80252bbcdd80e5f4970c584b9b5eff7980f50b0386f621b3603fffcf2c587a99ad62c50ac94c2a9b86abb047858d49a43cb52ba970540d4bd98e24d40f0372c5805c3400b961e97014fb298c496129b0738e2759ce262969025622353719978d5462116a67c6dc10d4d786a01c7915cdaeb6f1584bc787b64a0f774a6f6e33f4c2d2888525d3c2cbd8e1f8153e32c509355e4d0493173626275aac80732b66cc3af29a8cd41ef8626c72b7592f761b89031fc7602238c3254371e4d23951cc04d0cc44dcf0e24210ce235b73a9de9ae080469f0ec6faaef1762a31c03497dae9d4f0b89116e146d2c15d6b751707d5e3ce1b6981ddd76e60a992e8ac8918fcf29ca78badea52b12c715b85100777ee11d009ea2c4fef1adb5624eeaebd9169319225ddd2e9e1f93efe50bb0d31c455efbef1b4a978d608f1421b99e544adbe2657974ed1bab7c036eb4b63b5b6fb5224fca8d2e256eb71967e898f0ea0c4739df7dc41a7a968ddfb660f5adb2eb08d1403cdbba38d8b1ff4b50c5449dcdf374f36d810d656a5c1973e6edd3b8917c4757d0f36d229527bbaa5ba0b70073fab727d794c5a14066fe2047caeecfa3ac5f3461c9ee5b56e69ee85bd9cc8e2ca5908bc30fca8cf5ba787d8463a6d75978a14894332fbdd1e7140fc58e21cbbc2faf5f5606a8fb3546a686d88c5419706d89fe82bb1a5f8d091d79ca548a7bbd7b621867b7dca5a9b1eb7956911db4820baa08f57f89bebe4565eb33bed8b39de3b0fd86ebe23b70a3d8c645abc64133e456c407163722cf1644f96d8ed461a1cd45d5ecfafb0baee57e8febfce9f87cafbe06ea09c362982fdfee05e9cfc32c487cf2f7f354efef9b7e1e417ece7f30b8e0d6ec7c9c90a6fc7c939fd1d387930c0fb44af14e8a38dc880728f7820e50752fe0e29a7ca5ce0e425ce524b91a372ec91d9e5dedc1edbe68c198f4c66ccfa9bf5c2676cf87d6432944c0ace82598a8cbfd82d038622795ec0490138268f89f2fbc84fe8a0efe299e4fb78642e1d30e58581909557ce5060260371353946e3a46f5ba7412caf211ec0a11dc6140907108ba86b12336cf263852fcd1510e7abf1319f9b2c014f992e6d549eb02c87810f311d83990c3ec531f9210aa779655ee210a86ed266e30e0f6a467f695eb9ff141dc6266d9518464cdea634af911f7c1cfd590b5fcf9bb5beddb8a7c38688bf18d3f7b3be159afa52c68c293a14bfc42c52b04d6231764c8162d99540f1124fbe73a293cf45a6290cf255f11607d565caf39dcd56d16cec2c784ea0048ecf7028c2ac47951e423c3d9be57cb02c8d306da6b4f6dbf7563eab0476a5ad19b4f37e71d07acb4bed2eef99c702ad2746103fe5ed68f6a01227b091fa57da71812a895f3a4dc55ad6765a7a36e59edbe799c488b9ccb7f1a67dbc81460bf7afcfc5814e53ce66cd59eb5edac74ded8a586d4da4fb599b673b2d8cd52a6314f2250d30cd03479500fb8cdf856ce67a1743dc96c962aa07bf6bc470bf296880eea6f8bcd09584c6bcae2757e5f5661985fa3ab1193af85289815de6dfd8765ec666f0263822294e843c36acf0a8475a1b22b664187bb5e82b3326a5a5c8bef1138a9fbd6db0194f2e05072c96222b33939892f89c0f690ec0b9b237853e95edc0c7b8a9f7059f6fb23ba518e4924dbf791d793e81b11b6dda6c73b1eec618398dabb9c3b8651efd120d66ac4950a769d8de62dcaf0d313ccb09afe8d564488a6db6ba344fa87769ff296fefb2edfb8d8403991a86f21a5464455fb340b64b36abbabfbdc5f9b29d477910fe52dbd675041a3e3cea6bf6ab68478e57d870ce09d447bbfd007b65cd05598ca8439b90eacb2d3aa2e1c30b6b27cb79a844d69a727696251968aeb8df401da3cb79a7919fe859c60fb23cce36e16bb93fadd7ecaf928f326753f12c4b9b5ce7b4291ba8127536783294d78e39a31bb634aaf4210e71cd15ddd9329183363fabb9626fb3669daadd6af125853cdea24740732776b3ededb8aa9cb35af7487ce39e820d0e7959b7496661933cd952690075e3a081e15197d8489116282f57d3fffe85795baa0463eb65ebdc0b596eccfb228ed9b8a0ca5bafb696a68e411b5aeefba2be6ddce15ea6c15eaee8d06df646a7a95096b8ed46c2515e04ed351c4f10795e985ff1df1c5069d1dbacb94025065bcda530386e793f2feda9220dac4d8f0b74b78c37127dbf139b3ab274023241616a8fadd82f6d2ada2a0d7648fef93a1668f0b645de4490c9fd2ded20d65708a86f54acf644843f56405c7022cb2ecf9779d1b677bf245fd26287c6adfaca22df75016395f7f6221e6991d53a4f4461c00aa2489570a1a548836d920316ea6d7af37355b6b3f6176db747e29ab700751d82cfd03e95d7f535bf6d5d2e6ba934b5dfc0f915329ff2989a8b1391bf226b890d13b80933597caec042101c8a5d81a5a90b140ee55b25faf9ba98b155b143ef6332ef4f968408c5042e17a8ae9ef12c9179e124702253e24391b74cfd0bc94d9626e70c794e00d3958d6c8339cbe2e1331330144b718ec873c2e08d13abe748b31c4f21be932b8aa304bbdd2f24b6809a7063f26d850d5622c59248dfa7229fe47997e5f5a6e31f4d79cd5ae5f5aa3d765bd5cfa359cdf356e4ad9d0f6e71c6f4bfc18752fc5bda77f953704496c3fa48ae98b19961ece658f573b812ed9f5c5f366f5370449a13596ae52ca8d6b3b86fc7ac9c9f95f79b5ee12cc50bfa4782ab473ee365318660cf7892e70499121cf163898957cff2bee573911bff96d759acd5b6f6521c76cf985b8da6760a4179f2f77ccf638496f597ce4e477e16ffd56c37bb72384a1a939f2bbb31df5c663f8e852de485c184134d7385f52bf28178e1b10795bf9dbebce642266e6babb9e2972e9d30c4cfdbd653ca878d101f725f3e011fdc44dc3093e147c3c622dee53803c83c53c301c9d995ec89914a0d2196c1b433490ac760c509276a892fcde48c64e9cf6c2762a6283f113134ca876df5298babeedc6709ce32782daae457c68eb959cf4d99a606e324c635f3351d83353f11798e2fe7f946e6e70e833e6d3d1b3be63be407e02801c8e46a3cf2e6aba5c94d28993d0653c1198a42729ee26cd6f9bcaa7e2f3903ac9f6b9a4b713e133071ce090b01c61dd0b7cfc67e00ed2be4d1bac79d7569b09b8d75d8b7f73eaecc316068ea0baedf580649fc4ae77b3b13318b121cc1ace432dffa43e44bcf66c08c3184ef38919596c2d164bf261833e14889d7661cce7e8ae2c85c0207f1ac6d9fb233bdc2a754f74aeb89f18c4ef1d39bff5ce0a7642f1aed8f012d38c30533cd7d621b0fb3b33f84751569e031136a35060b6a3511dfc4aa5ffa7aaf629d0ca7413bf0a54b985f3d431f34f0f38c2749e18c68671c603f577cb5ffb1a3f32b5c5c32135686bc4f65fba079f37dc527566d4572b63bcdcf9b911c881810f83199e18a7714c7c2fd1d5b394fb909588913f081f85bee7fe9b7c66489cd97f91586e4bdb456a790bbc9e25314060294bb4c37b2be79421c685f2d73a72be7de55391eeb0516e2b5cbf3bae987b4b43389f22e39ce1b8f4cce397dae70c6177071311b5ba51ce028fedcf54d36c7ed5af0b10bf63ad41917ec66fce8c0bc4d8ef3b751f693ea50a27be531607fb238c4344fdc6fcea607f5803d1f2bfa34e36f5cc7dfa4a7903faabb345549c436086f515b7d0a8e323f1ad6f601d2922b6769e6f69e065fcc14da17a1a1ef63207ef0c2f0a3d0ab91a74b83bf6c5f91fea37966b12b92d3f90a1bf2336a21ae78d32fd63431df57fd7f32633d8be3bdd9ca7f563d3196d76cb0393be6fb79742aede971c623b94962a871698c1b7dc51db209edf22cf1d7648e13c64067207ea9f63bfae727ca4f68fe8c1fc5d057c2984474a83933d64e8b3139598a8e076dd4461a380cddc8c59b9f7c631ffefcbe4e594bf516aebc66bc3fa7ab8f7dbd695fa7a5bd2cf11ed62f85c104fabfe48c890ca0edd33c11d4fc8cd5ee4fca778a902f5ef322c730d3ec4e14f23781ea6a689f6654ee33ebb1ec4a1430bf990329fbeda399f3a68cf168fdacf6c42343b303665a8dfb4a3e3acf6368e74175ec92fdcd732069deb652470c2d9516bf3684e0a7f1358cb903793adf377330b7eefbcdb291f65b92bfb1f5b6c2a839272ede38deb237d209ddd17a1f43991d85d0c72e851325600b6a96ceb3c9dfa3391f3be6ec4c96fb6ae09df7f48e5bd277654f32794de6b79ecfca73e750ae909cad30f0c1d0a53b8010ab4fb37ccc205ddb6858a679afe67faef48bd686e28c520eab8a8550df290f2e62189246b99e49f0c90bfd022b229e81bd7c260faa7d598e9837aca54d95660c63c089c8239d4a7504f965a215775a2a7d3ae804ca2f55f1ce8463059c63570ec4f085cd66c618b46d5f1a2dee655a3cb3e751fc698f3c1ec567890e6a3d12a8301eb38ff9d9db7bcb99c5d8c9315c16b3f4145a3ccb3c59601f71989c4fbd6110ff1cb5297bd05da1647732bb321a66fea1891f904d36d9dee228433f837c025a0703e7c19e914ddaabbda5a9c2386d14882b077caecca015a3ded02f99ed217b46ba95f3eb7d3ccae2e088699e8914783cb3cd9eeecb6b165cf29b9b3579d4dc2121977053ee33a1bc4c595c863248537b794c5631a53df28abdd292b560997e2c3de473e9c1411f937b5d3a456df1523917b2264e81d65b36e2bf225f3348721c0837a66740d33c4713d568937d87b4594c3c85bac7021847be97ef1e8c32fc596a7face81dbb7206946067fa9ce354531e239d2b9fe3eef39ccd1b66cae364cead6b5f8b3b9d0607d55b94fd5339aecf79f39ee457907e24b98ca399e771f8639536b9e750c61da6462c0e9a0b79b4dc97317516db94db8fcb676113f0b172a03d486c4e91f3201d861eee6b3ccff245e6274f3ae99c732cdfb61e45eae776b3e47b9bba5ec4a8cdbdaa9d739668337bd0763e19a866333e2ccd019d278e5bce05537f7261cce247272ca0b982999c15a3d823deacd9505e734795a676b258dcd5658fedb16a82791be7c0e6e7b5d876acd9f32fd29d4bcbd366c79ce4d5c29abbf3af85cbe00b4906f2cec1e66fcc694e8b8ebc5b7e7dbc6d0879270ce66ff3bebc62ce9b1d83c93b935810942bbf995ff26ad4ff90386bce33de8caf9e5d94ef10cb6bd62add398676c752a4a5a948034c9674a025394c4114d90fe14c7e88024e090e7843b90a7be994b010ca4364f9868f5d90d3ce968d7cc82ce3331c0fe95c32ee0bec93999cc895a38b4b41a7186ac1adde2a18b17cf738c314c4463a05d0c6a11c7e9ec7c9cf36d07974fd1e0833198ae298bcd0b6586b19afddf3b3c245a1c0acf9dacd72fe08da4d76bc2c6ce77481abbc95c511cf1f4e7efe135cc171191ff27bede53997f4e3ca1e95d698e6598a7c56a96e9afaa6e995b3ecb722ef82f0895d9d2ff45757cecf4d653d4f7c7286d3dbefda677e335f73ee37ef98dbc7d177d831395f0a83a92850538e27578270bca82f19162b61fa7cfc591627206c583fcfff9bf5e80a3f3f79b2ac07f7ea58e3f70a6674723e95f8b4c63da48adc97ce2f337dfbd2a5c54e5e2fbe5aeadc8d0422595a9674e438fb257d23d0bdc8ebba56ca53ff757155ed27c132499cd3ae93690ec0fc7dc693f59c5aee8f9273be08ee65b21f448a2d2a78b888b367fc28c5e5e26e43885f5a858f56db398459cf11e4ba9eee37d49df4bc31c374105f9ee5f50257a74ba4b7b24b451a21a4b94819ca0b90cbf98dc4beedd4dea22adbb7e61fefe4792a07573076fbdaeeb50bedbf3b63159828c1bef53bc999dc27f67b5cf23963f2531497a5fd6bd6c92e15a8b4e894f5a80dd35cfc298f4d0dd19e94fa0a5a6c534956ff267df9bbf4a771ae0863f7028fa6b6ac1a4b15f9c720f5b3b9edfa3f990b2d5bea7401d2dc61662b1b67411b62f8553fa7fadfd89fef75ad11fb427b56d7917bfd53e92e9178def4e6e7f9f812ee3b16b99c863e3a898da2584aa4447e05e6d9fd8386ff995dc083cd7dadf9c626fe0fc64e43c726bcb81078b8bedbf227d9996ff37ee8047c946d7d3aa7662c76ce63ae4a4c5c3a7f019a0bf9aa996a8f698d5bd37c7ea0f5744ba3c5f3e68b39b26727cf4fe4f7b15bb0e1251fd6ccf1ff77f266d6ccb59d656911683400282733c6fef9698fbc6526bb630dea4d2ef768eea94cc239b49c4b54fb83beaeaa07c1b57db8d717367f7ff39a1ffc0bf42ee74ba16f4d3fe8cc1af8af9cefccefcd23db9af3f62e997aabe464f21cc82fca77336fe78a3b654d0632ca31a6e7c395dc1dc45c79b96987b3339fdbecf0959c2a0b3604382a52228ba98c95f29f230bca3cca8fb460c4243f7957beb529c37fd75a69712ff738bfae934dfec3b86a442c4657d6b88aa0bc99ab09f5c1e122c9618229e0d4fce3e8dffa3bcaa5fc23fbb99a08a64e88f69aa0f62ad13757ce70bec27476b58b6663573f6fa401c64cc52ff4fb24f470cf4c173b8516e34d1effcd4d9dfe59eee3a568b7002a2deef424379ad1eff3fb8553eec04c75b001434b7587fb8f63e0692e0575ee4b964e60d35b9a704e996d7fafde2bbc7bdc52ce12536b3c917baca57a4b532360ccbbc092b5a631ef7950fb7da7bbdb5fb823f827fa99debffeeadfecf83363d7fffec7fdfcccff56c2bd32e0266768bfb0f7a5fb3d77cfb774967677dbecbcf1fe3957effadd3b6ee33eddbde3b7dc61bb770ecdfb6877eb7c710fec6e799786b8ee8ab1fc6bfb7656a4859f9d45dd6de35aceb3eeeea3792e74b7dc96cf652f8e4f8b7d595ae03a2d20decb9eb8cfeef4d773f9e51cfeaff4f7cddf3af16bf9f4e08abc0003f26ccd257e899671d55d608a04d79fc58ec5df0d29e5ef1b39d45f5947eac3ca79995acef217fb6dcff9fc357d17f965a0b98baff96ee497e3d8127efe5559a963f462de7c5376bffb5b29c9dfbb30fedbffb8e5e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fc78c5f3e315cf8f573c3f5ef1fcfff0ab62fef8ff010000ffff010000ffff692182d5dc9e0000`)))
@@ -3,71 +3,108 @@ package cmd
 
 import (
 	"casperParser/db"
+	"casperParser/queue"
 	"casperParser/tasks"
 	"context"
-	"log"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/hibiken/asynq"
 
 	"github.com/spf13/cobra"
 )
 
-var reparseDatabase db.DB
-var reparseClient *asynq.Client
+var reparseDatabase *db.DB
+var reparseClient queue.Backend
 var reparsePool int
+var reparseFromRpc bool
+var reparseDryRun bool
+var reparseRate float64
+var reparseTicker *time.Ticker
+var reparseFilter db.ReparseFilter
+var reparseFromDate string
+var reparseToDate string
+
+// reparseShorthands the former reparse modes, as an item and a filter
+var reparseShorthands = map[string]struct {
+	item   string
+	filter db.ReparseFilter
+}{
+	"all":             {item: db.ReparseBlocks},
+	"era":             {item: db.ReparseBlocks, filter: db.ReparseFilter{EraEnd: true}},
+	"moduleBytes":     {item: db.ReparseDeploys, filter: db.ReparseFilter{Type: "moduleBytes"}},
+	"exceptTransfers": {item: db.ReparseDeploys, filter: db.ReparseFilter{ExceptType: "transfer"}},
+	"auctionEra":      {item: db.ReparseAuctions},
+}
 
 // reparseCmd represents the reparse command
 var reparseCmd = &cobra.Command{
-	Use:   "reparse [all|era|deploys|moduleBytes|exceptTransfers|accountPurses]",
-	Short: "Reparse all unknown deploys from the database without calling rpc",
-	Long: `Reparse specifics items from the database
+	Use:   "reparse [blocks|deploys|rewards|contracts|auctions|accountPurses|systemPackageContracts]",
+	Short: "Reparse the items of the database from their raw data without calling rpc",
+	Long: `Reparse the items of the database matching the filters from the raw tables, without calling rpc
+
+You must add one argument from those :
+
+blocks: reparse the blocks with their deploys, deploy infos, transfers and rewards
+deploys: only reparse deploys
+rewards: only reparse the rewards of the switch blocks
+contracts: only reparse the contracts, the named keys are kept
+auctions: fetch the auction of the switch blocks from rpc
+systemPackageContracts: add system Packages Contracts from rpc. You must add the network type right after. Ex : reparse systemPackageContracts testnet
+accountPurses: Parses Account, purses from rpc
+
+The filters select the items to reparse, they're combined :
+height, era and date ranges apply to every item, a contract is in the block of the deploy that wrote it.
+--type, --except-type, --metadata-type and --result only apply to the deploys, --contract-hash and --account to the deploys and the contracts.
 
-You must add at least one argument from those :
+Shorthands : all (blocks), era (blocks --era-end), moduleBytes (deploys --type moduleBytes), exceptTransfers (deploys --except-type transfer), auctionEra (auctions)
 
-all: reparse every blocks from rpc, will ignore any other args
-era: only reparse switch blocks
-deploys: only reparse deploys, will ignore any other deploy args
-moduleBytes: only reparse moduleBytes deploys
-exceptTransfers: only reparse deploys except transfers deploys
-systemPackageContracts: add system Packages Contracts. You must add the network type right after. Ex : reparse systemPackageContracts testnet
-accountPurses: Parses Account, purses
+Use --dry-run to print the number of items matching the filters without enqueuing anything, and --rate to pace the enqueueing.
+The archived raw rows must be restored before reparsing them. Use --from-rpc to fetch the blocks from rpc again.
+Ex : reparse deploys --metadata-type cep47 --from-date 2022-01-01 --rate 100
 `,
-	ValidArgs: []string{"all", "era", "deploys", "moduleBytes", "exceptTransfers", "accountPurses", "systemPackageContracts", "testnet", "mainnet", "auctionEra"},
+	ValidArgs: []string{db.ReparseBlocks, db.ReparseDeploys, db.ReparseRewards, db.ReparseContracts, db.ReparseAuctions, "accountPurses", "systemPackageContracts", "testnet", "mainnet", "all", "era", "moduleBytes", "exceptTransfers", "auctionEra"},
 	Args:      cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
 	Run: func(cmd *cobra.Command, args []string) {
-		arg := args[0]
-		if arg == "all" {
-			reparseAll(getRedisConf(cmd))
-			return
-		}
-		if arg == "era" {
-			reparseEraBlocks(getRedisConf(cmd))
-		}
-		if arg == "auctionEra" {
-			reparseEraAuctions(getRedisConf(cmd))
-		}
-		if arg == "systemPackageContracts" {
+		item := args[0]
+		if item == "systemPackageContracts" {
 			if len(args) > 1 {
 				network := args[1]
 				reparseSystemPackageContracts(getRedisConf(cmd), network)
 			}
-		}
-		if arg == "deploys" {
-			reparseDeploys(getRedisConf(cmd))
 			return
 		}
-		if arg == "moduleBytes" {
-			reparseModuleBytes(getRedisConf(cmd))
-		}
-		if arg == "exceptTransfers" {
-			reparseExceptTransfers(getRedisConf(cmd))
-		}
-		if arg == "accountPurses" {
+		if item == "accountPurses" {
 			startAccountPurses(getRedisConf(cmd))
 			startAccountHashPurses(getRedisConf(cmd))
 			startUrefPurses(getRedisConf(cmd))
 			startPurses(getRedisConf(cmd))
+			return
+		}
+		filter, err := getReparseFilter()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if shorthand, ok := reparseShorthands[item]; ok {
+			item = shorthand.item
+			filter.EraEnd = filter.EraEnd || shorthand.filter.EraEnd
+			if shorthand.filter.Type != "" {
+				filter.Type = shorthand.filter.Type
+			}
+			if shorthand.filter.ExceptType != "" {
+				filter.ExceptType = shorthand.filter.ExceptType
+			}
+		}
+		if reparseFromRpc && item != db.ReparseBlocks {
+			log.Fatalf("--from-rpc only applies to the blocks, not the %s", item)
 		}
+		if reparseDryRun {
+			countReparse(item, filter)
+			return
+		}
+		startReparse(getRedisConf(cmd), item, filter)
 	},
 }
 
@@ -75,390 +112,207 @@ accountPurses: Parses Account, purses
 func init() {
 	RootCmd.AddCommand(reparseCmd)
 	reparseCmd.Flags().IntVarP(&reparsePool, "pool", "p", 10, "Database connection pool max connections")
+	reparseCmd.Flags().BoolVar(&reparseFromRpc, "from-rpc", false, "Fetch the blocks from rpc instead of reading the raw tables")
+	reparseCmd.Flags().BoolVar(&reparseDryRun, "dry-run", false, "Print the number of items matching the filters without enqueuing anything")
+	reparseCmd.Flags().Float64Var(&reparseRate, "rate", 0, "Maximum number of tasks enqueued per second, 0 for no limit")
+	reparseCmd.Flags().IntVar(&reparseFilter.FromHeight, "from-height", 0, "First block height to reparse")
+	reparseCmd.Flags().IntVar(&reparseFilter.ToHeight, "to-height", 0, "Last block height to reparse, 0 for the last block")
+	reparseCmd.Flags().IntVar(&reparseFilter.FromEra, "from-era", 0, "First era to reparse")
+	reparseCmd.Flags().IntVar(&reparseFilter.ToEra, "to-era", 0, "Last era to reparse, 0 for the last era")
+	reparseCmd.Flags().StringVar(&reparseFromDate, "from-date", "", "First date to reparse, 2006-01-02 or RFC 3339")
+	reparseCmd.Flags().StringVar(&reparseToDate, "to-date", "", "Date to reparse up to, excluded, 2006-01-02 or RFC 3339")
+	reparseCmd.Flags().BoolVar(&reparseFilter.EraEnd, "era-end", false, "Only reparse the switch blocks")
+	reparseCmd.Flags().StringVar(&reparseFilter.Type, "type", "", "Only reparse the deploys of this type")
+	reparseCmd.Flags().StringVar(&reparseFilter.ExceptType, "except-type", "", "Only reparse the deploys not of this type")
+	reparseCmd.Flags().StringVar(&reparseFilter.MetadataType, "metadata-type", "", "Only reparse the deploys with this metadata type")
+	reparseCmd.Flags().StringVar(&reparseFilter.ContractHash, "contract-hash", "", "Only reparse the deploys calling this contract, or this contract")
+	reparseCmd.Flags().StringVar(&reparseFilter.Account, "account", "", "Only reparse the deploys or the contracts sent by this public key")
+	reparseCmd.Flags().StringVar(&reparseFilter.Result, "result", "", "Only reparse the deploys with this result, success or failure")
 }
 
-func reparseAll(redis asynq.RedisConnOpt) {
-	const sql = `SELECT height FROM blocks;`
-	startReparseBlocks(redis, sql)
-}
-
-func reparseEraBlocks(redis asynq.RedisConnOpt) {
-	const sql = `SELECT height FROM blocks WHERE era_end is true;`
-	startReparseBlocks(redis, sql)
-}
-
-func reparseEraAuctions(redis asynq.RedisConnOpt) {
-	const sql = `SELECT height FROM blocks WHERE era_end is true;`
-	startReparseAuctions(redis, sql)
-}
-
-func reparseDeploys(redis asynq.RedisConnOpt) {
-	const sql = `SELECT hash FROM deploys;`
-	startReparseDeploys(redis, sql)
-}
-
-func reparseModuleBytes(redis asynq.RedisConnOpt) {
-	const sql = `SELECT hash FROM deploys WHERE type = 'moduleBytes';`
-	startReparseDeploys(redis, sql)
+// getReparseFilter from the flags
+func getReparseFilter() (db.ReparseFilter, error) {
+	filter := reparseFilter
+	var err error
+	filter.FromDate, err = parseReparseDate(reparseFromDate)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --from-date: %w", err)
+	}
+	filter.ToDate, err = parseReparseDate(reparseToDate)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --to-date: %w", err)
+	}
+	if filter.Result != "" && filter.Result != "success" && filter.Result != "failure" {
+		return filter, fmt.Errorf("invalid --result %s, expected success or failure", filter.Result)
+	}
+	return filter, nil
 }
 
-func reparseExceptTransfers(redis asynq.RedisConnOpt) {
-	const sql = `SELECT hash FROM deploys WHERE type != 'transfer';`
-	startReparseDeploys(redis, sql)
+// parseReparseDate a day or a RFC 3339 timestamp, the zero time if empty
+func parseReparseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err == nil {
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, date)
 }
 
 // reparseSystemPackageContracts reparse System Package Contracts
 func reparseSystemPackageContracts(redis asynq.RedisConnOpt, network string) {
 	if network == "testnet" {
-		reparseClient = asynq.NewClient(redis)
+		reparseClient = newQueueBackend(redis, queue.Queues, reparsePool)
 		defer reparseClient.Close()
 		//Handle payment testnet contract
 		task, err := tasks.NewContractPackageRawTask("624dbe2395b9d9503fbee82162f1714ebff6b639f96d2084d26d944c354ec4c5", "", "")
 		if err != nil {
-			log.Fatalf("could not create task: %v", err)
+			log.WithError(err).Fatal("could not create task")
 		}
 		_, err = reparseClient.Enqueue(task, asynq.Queue("contracts"))
 		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
+			log.WithError(err).Fatal("could not enqueue task")
 		}
 	}
 }
 
-// startReparseBlocks reparse blocks for a given sql query
-func startReparseBlocks(redis asynq.RedisConnOpt, sql string) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
+// openReparse the database and the queue backend used by a reparse, the returned func close both
+func openReparse(redis asynq.RedisConnOpt) func() {
+	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), reparsePool)
 	if err != nil {
 		log.Fatal(err)
 	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), sql)
-	if err != nil {
-		log.Fatal(err)
+	reparseDatabase = &db.DB{Postgres: pgPool}
+	reparseClient = newQueueBackend(redis, queue.Queues, reparsePool)
+	if reparseRate > 0 {
+		reparseTicker = time.NewTicker(time.Duration(float64(time.Second) / reparseRate))
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			height int
-		}{}
-		err := rows.Scan(&missing.height)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewBlockRawTask(missing.height)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("blocks"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
+	return func() {
+		if reparseTicker != nil {
+			reparseTicker.Stop()
 		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
+		reparseClient.Close()
+		pgPool.Close()
 	}
 }
 
-// startReparseBlocks reparse blocks for a given sql query
-func startReparseAuctions(redis asynq.RedisConnOpt, sql string) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), sql)
+// enqueueReparse add a task created by a reparse to the queue, waiting for the rate limit
+func enqueueReparse(task *asynq.Task, err error, queueName string) error {
 	if err != nil {
-		log.Fatal(err)
+		log.WithError(err).Fatal("could not create task")
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			height int
-		}{}
-		err := rows.Scan(&missing.height)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewAuctionEraTask(missing.height)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("auctionera"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
-		}
+	if reparseTicker != nil {
+		<-reparseTicker.C
 	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
+	_, err = reparseClient.Enqueue(task, asynq.Queue(queueName))
+	if err != nil {
+		log.WithError(err).Fatal("could not enqueue task")
 	}
+	return nil
 }
 
-// startReparseDeploys reparse deploys for a given sql query
-func startReparseDeploys(redis asynq.RedisConnOpt, sql string) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
+// countReparse print the number of items matching the filter
+func countReparse(item string, filter db.ReparseFilter) {
+	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), 1)
 	if err != nil {
 		log.Fatal(err)
 	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), sql)
+	defer pgPool.Close()
+	database := &db.DB{Postgres: pgPool}
+	count, err := database.CountReparse(context.Background(), item, filter)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash string
-		}{}
-		err := rows.Scan(&missing.hash)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewDeployKnownTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("deploys"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
+	log.WithFields(log.Fields{"item": item, "count": count}).Info("items matching the filters, nothing enqueued")
+}
+
+// newReparseTask of an item and the queue it goes to
+func newReparseTask(item string, reparseItem db.ReparseItem) (*asynq.Task, string, error) {
+	switch item {
+	case db.ReparseBlocks:
+		if reparseFromRpc {
+			task, err := tasks.NewBlockRawTask(reparseItem.Height)
+			return task, "blocks", err
 		}
+		task, err := tasks.NewBlockKnownTask(reparseItem.Hash)
+		return task, "blocks", err
+	case db.ReparseDeploys:
+		// Without its raw block the height is 0, the purse balance history and the token changes of the deploy are kept
+		task, err := tasks.NewDeployKnownTask(reparseItem.Hash, reparseItem.Height, reparseItem.Index)
+		return task, "deploys", err
+	case db.ReparseRewards:
+		task, err := tasks.NewRewardKnownTask(reparseItem.Hash)
+		return task, "era", err
+	case db.ReparseAuctions:
+		task, err := tasks.NewAuctionEraTask(reparseItem.Height)
+		return task, "auctionera", err
+	case db.ReparseContracts:
+		task, err := tasks.NewContractKnownTask(reparseItem.Hash)
+		return task, "contracts", err
 	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
-	}
+	return nil, "", fmt.Errorf("unknown item %s to reparse", item)
 }
 
-// startAccountHashPurses reparse account hash purses
-func startAccountHashPurses(redis asynq.RedisConnOpt) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
+// startReparse enqueue a task for every item matching the filter
+func startReparse(redis asynq.RedisConnOpt, item string, filter db.ReparseFilter) {
+	defer openReparse(redis)()
+	enqueued := 0
+	err := reparseDatabase.ForEachReparse(context.Background(), item, filter, func(reparseItem db.ReparseItem) error {
+		task, queueName, err := newReparseTask(item, reparseItem)
+		enqueued++
+		return enqueueReparse(task, err, queueName)
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	// TODO: Check if target is an accountHash
-	findMissingAccountHashesSql := `WITH accounthashes AS (SELECT LOWER(metadata ->> 'target') as accounthash, MIN(timestamp) as created_at
-FROM deploys
-WHERE length(LOWER(metadata ->> 'target')) < 66 AND type = 'transfer' AND result = 'success'
-GROUP BY accounthash)
-SELECT accountHash, created_at from accounthashes
-LEFT JOIN accounts ON accounthashes.accounthash = accounts.account_hash WHERE accounts.account_hash IS NULL;`
+	log.WithFields(log.Fields{"item": item, "count": enqueued}).Info("reparse enqueued")
+}
 
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), findMissingAccountHashesSql)
+// startAccountHashPurses reparse account hash purses
+func startAccountHashPurses(redis asynq.RedisConnOpt) {
+	defer openReparse(redis)()
+	err := reparseDatabase.ForEachMissingAccountHash(context.Background(), func(hash string) error {
+		task, err := tasks.NewAccountHashTask(hash)
+		return enqueueReparse(task, err, "accounts")
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash             string
-			deploy_timestamp string
-		}{}
-		err := rows.Scan(&missing.hash)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewAccountHashTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("accounts"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
-		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
-	}
 }
 
 // startAccountPurses reparse account purses
 func startAccountPurses(redis asynq.RedisConnOpt) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	// TODO: Check if target is an accountHash
-	findMissingPublicKeysSql := `WITH keys AS (SELECT LOWER("from") as key, MIN(timestamp) as created_at
-FROM deploys
-WHERE ("from" LIKE '01%' AND length("from") = 66) OR ("from" LIKE '02%' AND length("from") = 68)
-GROUP BY key
-UNION
-SELECT LOWER(metadata ->> 'target') as key, MIN(timestamp) as created_at
-FROM deploys
-WHERE length(LOWER(metadata ->> 'target')) >= 66 AND length(LOWER(metadata ->> 'target')) <= 68 AND type = 'transfer' AND result = 'success'
-GROUP BY key
-)
-SELECT key, created_at from keys
-LEFT JOIN accounts ON keys.key = accounts.public_key WHERE accounts.public_key IS NULL;`
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), findMissingPublicKeysSql)
+	defer openReparse(redis)()
+	err := reparseDatabase.ForEachMissingPublicKey(context.Background(), func(publicKey string) error {
+		task, err := tasks.NewAccountTask(publicKey)
+		return enqueueReparse(task, err, "accounts")
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash             string
-			deploy_timestamp string
-		}{}
-		err := rows.Scan(&missing.hash)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewAccountTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("accounts"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
-		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
-	}
 }
 
 // startUrefPurses reparse uref purses
 func startUrefPurses(redis asynq.RedisConnOpt) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	findMissingPursesSql := `WITH urefs as (WITH uref AS (SELECT jsonb_array_elements(data -> 'Contract' -> 'named_keys') as j
-                             from contracts)
-               SELECT DISTINCT j ->> 'key' as uref
-               FROM uref
-               WHERE (j -> 'is_purse')::bool is true
-               UNION
-               SELECT DISTINCT LOWER(metadata ->> 'target') as uref
-               FROM deploys
-               WHERE length(LOWER(metadata ->> 'target')) > 68
-                 AND type = 'transfer'
-                 AND result = 'success'
-               UNION
-               SELECT DISTINCT LOWER(main_purse) as uref
-               FROM accounts)
-SELECT uref
-from urefs
-         LEFT JOIN purses ON urefs.uref = purses.purse
-WHERE purses.purse IS NULL;`
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), findMissingPursesSql)
+	defer openReparse(redis)()
+	err := reparseDatabase.ForEachMissingPurse(context.Background(), func(uref string) error {
+		task, err := tasks.NewPurseTask(uref)
+		return enqueueReparse(task, err, "accounts")
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash string
-		}{}
-		err := rows.Scan(&missing.hash)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewPurseTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("accounts"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
-		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
-	}
 }
 
 // startPurses reparse purses
 func startPurses(redis asynq.RedisConnOpt) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
+	defer openReparse(redis)()
+	err := reparseDatabase.ForEachPurse(context.Background(), func(uref string) error {
+		task, err := tasks.NewFetchPurseTask(uref)
+		return enqueueReparse(task, err, "accounts")
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
-	reparseDatabase = db.DB{Postgres: pgPool}
-	reparseClient = asynq.NewClient(redis)
-	defer reparseClient.Close()
-
-	findPursesSql := `SELECT purse from purses;`
-
-	rows, err := reparseDatabase.Postgres.Query(context.Background(), findPursesSql)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash string
-		}{}
-		err := rows.Scan(&missing.hash)
-		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewFetchPurseTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
-		}
-		_, err = reparseClient.Enqueue(task, asynq.Queue("accounts"))
-		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
-		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
-		log.Fatal(err)
-	}
 }
@@ -0,0 +1,43 @@
+// Package cmd define the rebuild-rollups command
+package cmd
+
+import (
+	"casperParser/db"
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// rebuildRollupsCmd represents the rebuild-rollups command
+var rebuildRollupsCmd = &cobra.Command{
+	Use:   "rebuild-rollups",
+	Short: "Compute the rewards, stake and fee rollup tables again from scratch",
+	Long: `Compute the rewards and stake rollup tables again from the rewards, bids_per_era and delegators_per_era tables,
+and the daily fees per account and per contract from the deploy_fees table
+
+The workers update the rollups when they insert the rewards or the auction of an era, and the fees of a deploy. Rebuild them after a backfill
+made outside the workers, or after reparsing the rewards or the auctions of the past eras.
+The rollups are locked during the rebuild, the workers inserting an era wait for it to finish.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), 1)
+		if err != nil {
+			return err
+		}
+		defer pgPool.Close()
+		database := &db.DB{Postgres: pgPool}
+		err = database.RebuildRollups(context.Background())
+		if err != nil {
+			return err
+		}
+		log.Info("rollups rebuilt")
+		return nil
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(rebuildRollupsCmd)
+}
@@ -0,0 +1,90 @@
+// Package cmd define the scheduler command
+package cmd
+
+import (
+	"casperParser/tasks"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var schedulerUniqueTTL time.Duration
+
+// schedulerCmd represents the scheduler command
+var schedulerCmd = &cobra.Command{
+	Use:   "scheduler",
+	Short: "Enqueue the periodic jobs following the cron specs of the config file",
+	Long: `Enqueue the periodic jobs following the cron specs of the schedule key of the config file, the workers run them
+Jobs :
+auction: snapshot the current auction state, use it with the client --disableAuctionOnBlock flag
+purses: add the missing accounts and purses and refresh every purse balance, like reparse accountPurses
+views: refresh the staking operations materialized views
+verify: verify the blocks not validated yet, like the verify command
+missing-blocks: add the blocks missing between the first and the last block of the database
+partitions: create the next monthly partitions, like the maintenance command
+
+A job is not enqueued again while its previous run is pending. Run a single scheduler, it needs the asynq queue backend.
+Config example :
+schedule:
+  auction: "@every 1m"
+  views: "*/30 * * * *"
+  purses: "0 */6 * * *"
+	`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if queueBackend != "asynq" {
+			return fmt.Errorf("the scheduler needs the asynq queue backend, got %s", queueBackend)
+		}
+		schedule := viper.GetStringMapString("schedule")
+		if len(schedule) == 0 {
+			return errors.New("no job in the schedule key of the config file")
+		}
+		scheduler := asynq.NewScheduler(getRedisConf(cmd), &asynq.SchedulerOpts{
+			EnqueueErrorHandler: func(task *asynq.Task, opts []asynq.Option, err error) {
+				if errors.Is(err, asynq.ErrDuplicateTask) {
+					log.WithField("task", task.Type()).Warn("previous run still pending, job skipped")
+					return
+				}
+				log.WithError(err).WithField("task", task.Type()).Error("could not enqueue task")
+			},
+		})
+		err := registerJobs(scheduler, schedule)
+		if err != nil {
+			return err
+		}
+		return scheduler.Run()
+	},
+}
+
+// init the command flags
+func init() {
+	RootCmd.AddCommand(schedulerCmd)
+	schedulerCmd.Flags().DurationVar(&schedulerUniqueTTL, "unique-ttl", time.Hour, "Maximum time a pending job prevents its next run from being enqueued")
+}
+
+// registerJobs of the schedule, a map of job name to cron spec
+func registerJobs(scheduler *asynq.Scheduler, schedule map[string]string) error {
+	names := make([]string, 0, len(schedule))
+	for name := range schedule {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		job, ok := tasks.ScheduledJobs[name]
+		if !ok {
+			return fmt.Errorf("unknown job %s in the schedule. Supported jobs : auction, purses, views, verify, missing-blocks, partitions", name)
+		}
+		_, err := scheduler.Register(schedule[name], asynq.NewTask(job.Type, nil), asynq.Queue(job.Queue), asynq.Unique(schedulerUniqueTTL))
+		if err != nil {
+			return fmt.Errorf("can't schedule the %s job: %w", name, err)
+		}
+		log.WithFields(log.Fields{"job": name, "cron": schedule[name], "queue": job.Queue}).Info("job scheduled")
+	}
+	return nil
+}
@@ -3,16 +3,17 @@ package cmd
 
 import (
 	"casperParser/db"
+	"casperParser/queue"
 	"casperParser/tasks"
 	"context"
 	"github.com/hibiken/asynq"
-	"log"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
 )
 
-var verifyDatabase db.DB
-var verifyClient *asynq.Client
+var verifyDatabase *db.DB
+var verifyClient queue.Backend
 var verifyPool int
 
 // verifyCmd represents the verify command
@@ -33,43 +34,27 @@ func init() {
 
 // startVerify fetch all blocks not validated and check if all deploys are present in the db
 func startVerify(redis asynq.RedisConnOpt) {
-	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), pool)
-	defer pgPool.Close()
+	pgPool, err := db.NewPGXPool(context.Background(), getDatabaseConnectionString(), verifyPool)
 	if err != nil {
 		log.Fatal(err)
 	}
-	verifyDatabase = db.DB{Postgres: pgPool}
-	verifyClient = asynq.NewClient(redis)
+	defer pgPool.Close()
+	verifyDatabase = &db.DB{Postgres: pgPool}
+	verifyClient = newQueueBackend(redis, queue.Queues, verifyPool)
 	defer verifyClient.Close()
-	const sql = `SELECT hash FROM blocks WHERE validated = false;`
 
-	rows, err := verifyDatabase.Postgres.Query(context.Background(), sql)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		missing := struct {
-			hash string
-		}{}
-		err := rows.Scan(&missing.hash)
+	err = verifyDatabase.ForEachUnvalidatedBlockHash(context.Background(), func(hash string) error {
+		task, err := tasks.NewBlockVerifyTask(hash)
 		if err != nil {
-			log.Fatal(err)
-		}
-		task, err := tasks.NewBlockVerifyTask(missing.hash)
-		if err != nil {
-			log.Fatalf("could not create task: %v", err)
+			log.WithError(err).Fatal("could not create task")
 		}
 		_, err = verifyClient.Enqueue(task, asynq.Queue("blocks"))
 		if err != nil {
-			log.Fatalf("could not enqueue task: %v", err)
+			log.WithError(err).Fatal("could not enqueue task")
 		}
-	}
-	// check rows.Err() after the last rows.Next() :
-	if err := rows.Err(); err != nil {
-		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
-		// there could also be some bad things like a truncated response because
-		// of some network error, etc ...
+		return nil
+	})
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -3,18 +3,28 @@ package cmd
 
 import (
 	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/queue"
 	"casperParser/tasks"
+	"casperParser/tracing"
 	"context"
-	"log"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v4/pgxpool"
 
 	"github.com/spf13/cobra"
 )
 
 var concurrency int
 var queues []string
+var batchSize int
+var batchInterval time.Duration
 
 // workerCmd represents the worker command
 var workerCmd = &cobra.Command{
@@ -25,45 +35,14 @@ Usage examples :
 casperParser worker -- Will start the worker with the default values (Either from your config file or defined in the program)
 casperParser worker --queues blocks,1 --concurrency 20 -- Will start the worker to handle only blocks and 20 concurrent workers
 casperParser worker --redis 127.0.0.1:6379 -- Will start the worker with a single redis server connection (Use ENV variables preferably to setup a secure redis connection)
+casperParser worker --queue-backend postgres -- Will start the worker with the tasks stored in the database instead of Redis
+casperParser worker --batch-size 1000 -- Will write the deploys, transfers, named keys and purses by batches of 1000 rows, useful during the initial sync
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf := asynq.Config{
-			Concurrency: concurrency,
-			Queues: map[string]int{
-				"blocks":      1,
-				"deploys":     1,
-				"deployinfos": 1,
-				"transfers":   1,
-				"contracts":   1,
-				"era":         1,
-				"auction":     1,
-				"auctionera":  1,
-				"accounts":    1,
-			},
-		}
+		workerQueues := getQueues(cmd)
 		tasks.WorkerRpcClient = getRpcClient()
-		if cmd.Flags().Lookup("queues").Changed {
-			queuesMap := make(map[string]int)
-			if len(queues)%2 != 0 {
-				log.Fatalf("Can't parse queues flag. Usage --queues [queueName1],[priority1],[queue2],[priority2]")
-				return
-			}
-			for i := 0; i < len(queues); i += 2 {
-				var err error
-				if queues[i] != "blocks" && queues[i] != "deploys" && queues[i] != "deployinfos" && queues[i] != "transfers" && queues[i] != "contracts" && queues[i] != "era" && queues[i] != "auction" && queues[i] != "auctionera" {
-					log.Fatalf("Unknown queue %s. Supported queues : blocks, deploys, deployinfos, transfers, contracts, era, auction, auctionera, accounts", queues[i])
-				}
-				queuesMap[queues[i]], err = strconv.Atoi(queues[i+1])
-				if err != nil {
-					log.Fatalf("Can't parse queues flag. Usage --queues [queueName1],[priority1],[queue2],[priority2]")
-					return
-				}
-			}
-			conf.Queues = queuesMap
-		}
-		log.Printf("Concurrency : %v\n", conf.Concurrency)
-		log.Printf("Queue config used : %v\n", conf.Queues)
-		startWorkers(getRedisConf(cmd), conf)
+		log.WithFields(log.Fields{"concurrency": concurrency, "queues": workerQueues, "backend": queueBackend}).Info("starting workers")
+		startWorkers(newQueueBackend(getRedisConf(cmd), workerQueues, concurrency), concurrency)
 	},
 }
 
@@ -71,41 +50,96 @@ casperParser worker --redis 127.0.0.1:6379 -- Will start the worker with a singl
 func init() {
 	RootCmd.AddCommand(workerCmd)
 	workerCmd.Flags().IntVarP(&concurrency, "concurrency", "k", 100, "Number of concurrent workers to use. The database connection pool will be set to the same number")
-	workerCmd.Flags().StringSliceVarP(&queues, "queues", "q", []string{"blocks", "1", "deploys", "1", "deployinfos", "1", "transfers", "1", "contracts", "1", "era", "1", "auction", "1", "auctionera", "1", "accounts", "1"}, "Set queues with priority")
+	workerCmd.Flags().StringSliceVarP(&queues, "queues", "q", []string{"blocks", "1", "deploys", "1", "deployinfos", "1", "transfers", "1", "contracts", "1", "era", "1", "auction", "1", "auctionera", "1", "accounts", "1", "scheduled", "1"}, "Set queues with priority")
+	addBatchFlags(workerCmd)
 }
 
-// startWorkers with a redis and asynq config
-func startWorkers(redis asynq.RedisConnOpt, conf asynq.Config) {
-	var err error
-	tasks.WorkerPool, err = db.NewPGXPool(context.Background(), getDatabaseConnectionString(), conf.Concurrency)
-	defer tasks.WorkerPool.Close()
-	srv := asynq.NewServer(
-		redis,
-		conf,
-	)
-	if err != nil {
-		log.Fatalln(err)
+// addBatchFlags of the commands running the workers
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&batchSize, "batch-size", 0, "Write the deploys, transfers, named keys and purses by batches of this many rows. 0 writes each row on its own")
+	cmd.Flags().DurationVar(&batchInterval, "batch-interval", 500*time.Millisecond, "Maximum delay before a batch is written")
+}
+
+// newWorkerStore used by the handlers, batching the writes when the batch-size flag is set. The returned func flush the last batch
+func newWorkerStore(pgPool *pgxpool.Pool) (db.Store, func()) {
+	database := &db.DB{Postgres: pgPool}
+	if batchSize <= 0 {
+		return database, func() {}
+	}
+	writer := db.NewBatchWriter(database, batchSize, batchInterval)
+	return writer, func() { writer.Close() }
+}
+
+// getQueues and their priority from the queues flag, or the default queues if the flag is not set
+func getQueues(cmd *cobra.Command) map[string]int {
+	if !cmd.Flags().Lookup("queues").Changed {
+		return queue.Queues
+	}
+	if len(queues)%2 != 0 {
+		log.Fatalf("Can't parse queues flag. Usage --queues [queueName1],[priority1],[queue2],[priority2]")
 	}
+	queuesMap := make(map[string]int)
+	for i := 0; i < len(queues); i += 2 {
+		if _, ok := queue.Queues[queues[i]]; !ok {
+			log.Fatalf("Unknown queue %s. Supported queues : blocks, deploys, deployinfos, transfers, contracts, era, auction, auctionera, accounts, scheduled", queues[i])
+		}
+		priority, err := strconv.Atoi(queues[i+1])
+		if err != nil || priority <= 0 {
+			log.Fatalf("Can't parse queues flag. Usage --queues [queueName1],[priority1],[queue2],[priority2]")
+		}
+		queuesMap[queues[i]] = priority
+	}
+	return queuesMap
+}
+
+// newServeMux route every task type to its handler
+func newServeMux() *asynq.ServeMux {
 	mux := asynq.NewServeMux()
+	mux.Use(tracing.Middleware, logger.Middleware, db.Middleware)
 	mux.HandleFunc(tasks.TypeBlockRaw, tasks.HandleBlockRawTask)
+	mux.HandleFunc(tasks.TypeBlockKnown, tasks.HandleBlockKnownTask)
 	mux.HandleFunc(tasks.TypeBlockVerify, tasks.HandleBlockVerifyTask)
 	mux.HandleFunc(tasks.TypeDeployRaw, tasks.HandleDeployRawTask)
 	mux.HandleFunc(tasks.TypeDeployInfoRaw, tasks.HandleDeployInfoRawTask)
+	mux.HandleFunc(tasks.TypeDeployInfoKnown, tasks.HandleDeployInfoKnownTask)
 	mux.HandleFunc(tasks.TypeDeployKnown, tasks.HandleDeployKnownTask)
 	mux.HandleFunc(tasks.TypeTransferRaw, tasks.HandleTransferRawTask)
 	mux.HandleFunc(tasks.TypeTransferKnown, tasks.HandleTransferKnownTask)
 	mux.HandleFunc(tasks.TypeContractPackageRaw, tasks.HandleContractPackageRawTask)
 	mux.HandleFunc(tasks.TypeContractRaw, tasks.HandleContractRawTask)
+	mux.HandleFunc(tasks.TypeContractKnown, tasks.HandleContractKnownTask)
 	mux.HandleFunc(tasks.TypeReward, tasks.HandleRewardTask)
+	mux.HandleFunc(tasks.TypeRewardKnown, tasks.HandleRewardKnownTask)
 	mux.HandleFunc(tasks.TypeAuction, tasks.HandleAuctionTask)
 	mux.HandleFunc(tasks.TypeAuctionEra, tasks.HandleAuctionEraTask)
 	mux.HandleFunc(tasks.TypeAccountHash, tasks.HandleAccountHashTask)
 	mux.HandleFunc(tasks.TypeAccountPublicKey, tasks.HandleAccountTask)
 	mux.HandleFunc(tasks.TypeAccountUref, tasks.HandlePurseTask)
 	mux.HandleFunc(tasks.TypeAccountFetch, tasks.HandleFetchPurseTask)
-	tasks.WorkerAsyncClient = asynq.NewClient(redis)
-	defer tasks.WorkerAsyncClient.Close()
-	if err := srv.Run(mux); err != nil {
-		log.Fatalf("could not run server: %v", err)
+	mux.HandleFunc(tasks.TypeScheduledPurses, tasks.HandleScheduledPursesTask)
+	mux.HandleFunc(tasks.TypeScheduledViews, tasks.HandleScheduledViewsTask)
+	mux.HandleFunc(tasks.TypeScheduledVerify, tasks.HandleScheduledVerifyTask)
+	mux.HandleFunc(tasks.TypeScheduledMissingBlocks, tasks.HandleScheduledMissingBlocksTask)
+	mux.HandleFunc(tasks.TypeScheduledPartitions, tasks.HandleScheduledPartitionsTask)
+	return mux
+}
+
+// startWorkers on a queue backend until the process receive SIGINT or SIGTERM
+func startWorkers(backend queue.Backend, concurrency int) {
+	defer backend.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pgPool, err := db.NewPGXPool(ctx, getDatabaseConnectionString(), concurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pgPool.Close()
+	store, closeStore := newWorkerStore(pgPool)
+	defer closeStore()
+	tasks.WorkerStore = store
+	tasks.WorkerJobStore = &db.DB{Postgres: pgPool}
+	tasks.WorkerAsyncClient = backend
+	if err := backend.Run(ctx, newServeMux(), concurrency); err != nil {
+		log.WithError(err).Fatal("could not run server")
 	}
 }
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RawTables the raw tables that can be archived, see SelectRawToArchive
+var RawTables = []string{"raw_blocks", "raw_deploys", "raw_deploy_infos", "raw_transfers"}
+
+// rawTableSources join each raw table, aliased r, to the block of its rows, aliased b
+var rawTableSources = map[string]string{
+	"raw_blocks":       `raw_blocks r INNER JOIN blocks b ON b.hash = r.hash`,
+	"raw_deploys":      `raw_deploys r INNER JOIN deploys d ON d.hash = r.hash AND d."timestamp" = r."timestamp" INNER JOIN blocks b ON b.hash = d.block`,
+	"raw_deploy_infos": `raw_deploy_infos r INNER JOIN blocks b ON b.hash = r.block`,
+	"raw_transfers":    `raw_transfers r INNER JOIN blocks b ON b.hash = r.block`,
+}
+
+// partitionedRawTables the raw tables partitioned by month on their timestamp
+var partitionedRawTables = map[string]bool{"raw_deploys": true, "raw_transfers": true}
+
+// Retention of the rows of a raw table. A row is archived once its block is older than MaxAge,
+// or more than KeepBlocks blocks below the last block. A zero value disables the criterion.
+type Retention struct {
+	MaxAge     time.Duration `mapstructure:"max-age"`
+	KeepBlocks int           `mapstructure:"keep-blocks"`
+}
+
+// Enabled when at least one criterion is set
+func (r Retention) Enabled() bool {
+	return r.MaxAge > 0 || r.KeepBlocks > 0
+}
+
+// RawRow a row of a raw table. Data is the whole row encoded in JSON, as read and written by RestoreRaw.
+// Timestamp is the partition key of the row in a partitioned table, the timestamp of its block otherwise.
+type RawRow struct {
+	Hash      string
+	Height    int
+	Timestamp time.Time
+	Data      string
+}
+
+// SelectRawToArchive return at most limit rows of the raw table past the retention, by block height.
+// Only the rows of validated blocks are returned, a block is verified with its raw data.
+func (db *DB) SelectRawToArchive(ctx context.Context, table string, retention Retention, limit int) ([]RawRow, error) {
+	ctx, span := startOperation(ctx, "SelectRawToArchive")
+	defer span.End()
+	source, ok := rawTableSources[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown raw table %s", table)
+	}
+	if !retention.Enabled() {
+		return nil, nil
+	}
+	var criteria []string
+	var args []interface{}
+	if retention.MaxAge > 0 {
+		args = append(args, time.Now().Add(-retention.MaxAge))
+		criteria = append(criteria, `b."timestamp" < $`+strconv.Itoa(len(args)))
+	}
+	if retention.KeepBlocks > 0 {
+		args = append(args, retention.KeepBlocks)
+		criteria = append(criteria, `b.height <= (SELECT max(height) FROM blocks) - $`+strconv.Itoa(len(args)))
+	}
+	args = append(args, limit)
+	timestamp := `b."timestamp"`
+	if partitionedRawTables[table] {
+		timestamp = `r."timestamp"`
+	}
+	sql := `SELECT r.hash, b.height, ` + timestamp + `, to_jsonb(r)::text FROM ` + source + `
+WHERE b.validated AND (` + strings.Join(criteria, ` OR `) + `)
+ORDER BY b.height LIMIT $` + strconv.Itoa(len(args)) + `;`
+	rows, err := db.Postgres.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	var raws []RawRow
+	for rows.Next() {
+		var raw RawRow
+		err = rows.Scan(&raw.Hash, &raw.Height, &raw.Timestamp, &raw.Data)
+		if err != nil {
+			return nil, db.checkErr(ctx, err)
+		}
+		raws = append(raws, raw)
+	}
+	return raws, db.checkErr(ctx, rows.Err())
+}
+
+// DeleteRaw delete the rows of the raw table by hash. In a partitioned table the delete is bounded by the timestamps
+// of the rows, so only the partitions of their months are scanned.
+func (db *DB) DeleteRaw(ctx context.Context, table string, rows []RawRow) error {
+	ctx, span := startOperation(ctx, "DeleteRaw")
+	defer span.End()
+	if _, ok := rawTableSources[table]; !ok {
+		return fmt.Errorf("unknown raw table %s", table)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	hashes := make([]string, len(rows))
+	from, to := rows[0].Timestamp, rows[0].Timestamp
+	for i, row := range rows {
+		hashes[i] = row.Hash
+		if row.Timestamp.Before(from) {
+			from = row.Timestamp
+		}
+		if row.Timestamp.After(to) {
+			to = row.Timestamp
+		}
+	}
+	if !partitionedRawTables[table] {
+		sql := `DELETE FROM ` + table + ` WHERE hash = ANY($1);`
+		_, err := db.Postgres.Exec(ctx, sql, hashes)
+		return db.checkErr(ctx, err)
+	}
+	sql := `DELETE FROM ` + table + ` WHERE hash = ANY($1) AND "timestamp" BETWEEN $2 AND $3;`
+	_, err := db.Postgres.Exec(ctx, sql, hashes, from, to)
+	return db.checkErr(ctx, err)
+}
+
+// RestoreRaw insert the rows of the raw table encoded in JSON, like RawRow.Data. The rows already present are kept.
+// Return the number of rows inserted.
+func (db *DB) RestoreRaw(ctx context.Context, table string, rows []string) (int64, error) {
+	ctx, span := startOperation(ctx, "RestoreRaw")
+	defer span.End()
+	if _, ok := rawTableSources[table]; !ok {
+		return 0, fmt.Errorf("unknown raw table %s", table)
+	}
+	sql := `INSERT INTO ` + table + ` SELECT * FROM jsonb_populate_recordset(NULL::` + table + `, $1::jsonb) ON CONFLICT DO NOTHING;`
+	tag, err := db.Postgres.Exec(ctx, sql, "["+strings.Join(rows, ",")+"]")
+	if err != nil {
+		return 0, db.checkErr(ctx, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,400 @@
+package db
+
+import (
+	"casperParser/types/amount"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// batchTable a table written by the BatchWriter, rows are upserted on the conflict columns
+type batchTable struct {
+	name     string
+	columns  []string
+	conflict []string
+}
+
+// batchTables in their flush order, a table is always flushed after the tables its foreign keys reference
+var batchTables = []batchTable{
+	{name: "raw_deploys", columns: []string{"hash", "timestamp", "data"}, conflict: []string{"hash", "timestamp"}},
+	{name: "deploys", columns: []string{"hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type", "contract_hash", "contract_name", "entrypoint", "metadata", "events"}, conflict: []string{"hash", "timestamp"}},
+	{name: "raw_transfers", columns: []string{"hash", "block", "deploy", "timestamp", "data"}, conflict: []string{"hash", "timestamp"}},
+	{name: "transfers", columns: []string{"hash", "block", "deploy", "timestamp", "from", "to", "source", "target", "amount", "gas", "id"}, conflict: []string{"hash", "timestamp"}},
+	{name: "named_keys", columns: []string{"uref", "name", "is_purse", "initial_value"}, conflict: []string{"uref"}},
+	{name: "contracts_named_keys", columns: []string{"contract_hash", "named_key_uref"}, conflict: []string{"contract_hash", "named_key_uref"}},
+	{name: "purses", columns: []string{"purse", "balance"}, conflict: []string{"purse"}},
+}
+
+// batch of rows waiting to be flushed, indexed by table then by primary key so the last write of a row wins
+type batch struct {
+	rows    map[string]map[string][]interface{}
+	count   int
+	flushed chan struct{}
+	err     error
+}
+
+func newBatch() *batch {
+	return &batch{rows: make(map[string]map[string][]interface{}), flushed: make(chan struct{})}
+}
+
+// BatchWriter a Store buffering the deploys, transfers, named keys and purses writes.
+// Rows are flushed with a COPY into staging tables then upserted in one transaction, once size rows are buffered or every interval.
+// The other operations go straight to the database.
+//
+// A write returns once its batch is flushed, unless ctx was given by WithPendingWrites : the write then returns immediately
+// and WaitPendingWrites wait for every batch written with ctx. Middleware do it for each task so a task is only acknowledged once its rows are stored.
+type BatchWriter struct {
+	*DB
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	current *batch
+	sealed  []*batch
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewBatchWriter start a writer flushing size rows at once, or what's buffered every interval. Close flush the remaining rows.
+func NewBatchWriter(db *DB, size int, interval time.Duration) *BatchWriter {
+	w := &BatchWriter{
+		DB:       db,
+		size:     size,
+		interval: interval,
+		current:  newBatch(),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Close flush the buffered rows and stop the writer
+func (w *BatchWriter) Close() error {
+	close(w.stop)
+	<-w.stopped
+	return nil
+}
+
+// InsertDeploy buffer the raw and the parsed deploy
+func (w *BatchWriter) InsertDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, json string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	hash = strings.ToLower(hash)
+	row, err := deployRow(hash, from, cost, result, errorMessage, timestamp, block, deployType, metadataType, contractHash, contractName, entrypoint, metadata, events)
+	if err != nil {
+		return err
+	}
+	// The raw deploy is in the partition of the parsed one, row[5] is its parsed timestamp
+	return w.add(ctx, batchRow{"raw_deploys", hash, []interface{}{hash, row[5], jsonb(json)}}, batchRow{"deploys", hash, row})
+}
+
+// UpdateDeploy buffer the parsed deploy
+func (w *BatchWriter) UpdateDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	hash = strings.ToLower(hash)
+	row, err := deployRow(hash, from, cost, result, errorMessage, timestamp, block, deployType, metadataType, contractHash, contractName, entrypoint, metadata, events)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, batchRow{"deploys", hash, row})
+}
+
+// InsertRawDeploy buffer the raw deploy
+func (w *BatchWriter) InsertRawDeploy(ctx context.Context, hash string, timestamp string, json string) error {
+	hash = strings.ToLower(hash)
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, batchRow{"raw_deploys", hash, []interface{}{hash, t, jsonb(json)}})
+}
+
+// InsertTransfer buffer the raw and the parsed transfer
+func (w *BatchWriter) InsertTransfer(ctx context.Context, hash string, blockHash string, deployHash string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, json string, id string) error {
+	hash = strings.ToLower(hash)
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx,
+		batchRow{"raw_transfers", hash, []interface{}{hash, blockHash, deployHash, t, jsonb(json)}},
+		batchRow{"transfers", hash, []interface{}{hash, blockHash, deployHash, t, from, to, source, target, amount, gas, nullString(id)}})
+}
+
+// UpdateTransfer buffer the parsed transfer
+func (w *BatchWriter) UpdateTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, id string) error {
+	hash = strings.ToLower(hash)
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, batchRow{"transfers", hash, []interface{}{hash, block, deploy, t, from, to, source, target, amount, gas, nullString(id)}})
+}
+
+// InsertRawTransfer buffer the raw transfer
+func (w *BatchWriter) InsertRawTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, json string) error {
+	hash = strings.ToLower(hash)
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return err
+	}
+	return w.add(ctx, batchRow{"raw_transfers", hash, []interface{}{hash, block, deploy, t, jsonb(json)}})
+}
+
+// InsertNamedKey buffer the named key and its link to the contract
+func (w *BatchWriter) InsertNamedKey(ctx context.Context, uref string, name string, isPurse bool, initialValue string, contractHash string) error {
+	contractHash = strings.ToLower(contractHash)
+	return w.add(ctx,
+		batchRow{"named_keys", uref, []interface{}{uref, name, isPurse, jsonb(initialValue)}},
+		batchRow{"contracts_named_keys", contractHash + "/" + uref, []interface{}{contractHash, uref}})
+}
+
+// InsertPurse buffer the purse without balance
+func (w *BatchWriter) InsertPurse(ctx context.Context, hash string) error {
+	hash = strings.ToLower(hash)
+	return w.add(ctx, batchRow{"purses", hash, []interface{}{hash, &pgtype.Numeric{Status: pgtype.Null}}})
+}
+
+// InsertPurseBalance buffer the purse balance
+func (w *BatchWriter) InsertPurseBalance(ctx context.Context, hash string, balance amount.Amount) error {
+	hash = strings.ToLower(hash)
+	return w.add(ctx, batchRow{"purses", hash, []interface{}{hash, balance}})
+}
+
+// deployRow the values of a deploys row, in the batchTables column order
+func deployRow(hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) ([]interface{}, error) {
+	t, err := parseTimestamp(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{hash, from, cost, result, nullString(errorMessage), t, block, deployType, metadataType, nullString(contractHash), nullString(contractName), nullString(entrypoint), jsonb(metadata), jsonb(events)}, nil
+}
+
+// parseTimestamp from the node RFC3339 format or the Postgres text format.
+// COPY use the binary format, the timestamp can't be sent as text like with Exec.
+func parseTimestamp(timestamp string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse("2006-01-02 15:04:05.999999 -07:00", timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s: %w", timestamp, err)
+	}
+	return t, nil
+}
+
+// jsonb encode a json column, NULL for an empty string.
+// COPY send the plain strings as is, the jsonb binary format need a version byte.
+func jsonb(s string) *pgtype.JSONB {
+	if s == "" {
+		return &pgtype.JSONB{Status: pgtype.Null}
+	}
+	return &pgtype.JSONB{Bytes: []byte(s), Status: pgtype.Present}
+}
+
+// nullString nil for an empty string, stored as NULL
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// batchRow the values of a row, in the column order of its table, and its primary key
+type batchRow struct {
+	table  string
+	key    string
+	values []interface{}
+}
+
+// add rows to the current batch and wait for the batch to be flushed
+func (w *BatchWriter) add(ctx context.Context, rows ...batchRow) error {
+	w.mu.Lock()
+	b := w.current
+	for _, row := range rows {
+		if b.rows[row.table] == nil {
+			b.rows[row.table] = make(map[string][]interface{})
+		}
+		if _, ok := b.rows[row.table][row.key]; !ok {
+			b.count++
+		}
+		b.rows[row.table][row.key] = row.values
+	}
+	if b.count >= w.size {
+		w.seal()
+	}
+	w.mu.Unlock()
+
+	if p, ok := ctx.Value(pendingWritesKey{}).(*pendingWrites); ok {
+		p.add(b)
+		return nil
+	}
+	return waitBatch(ctx, b)
+}
+
+// seal queue the current batch for the flush and start a new one. mu must be held
+func (w *BatchWriter) seal() {
+	w.sealed = append(w.sealed, w.current)
+	w.current = newBatch()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run flush the sealed batches in order, sealing the current one every interval
+func (w *BatchWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		stopping := false
+		select {
+		case <-w.wake:
+		case <-ticker.C:
+		case <-w.stop:
+			stopping = true
+		}
+		w.mu.Lock()
+		if w.current.count > 0 {
+			w.seal()
+		}
+		sealed := w.sealed
+		w.sealed = nil
+		w.mu.Unlock()
+		for _, b := range sealed {
+			b.err = w.flush(b)
+			close(b.flushed)
+		}
+		if stopping {
+			return
+		}
+	}
+}
+
+// flush a batch : COPY each table rows in a staging table, then upsert them in the table in the same transaction
+func (w *BatchWriter) flush(b *batch) error {
+	ctx, span := startOperation(context.Background(), "FlushBatch")
+	defer span.End()
+	tx, err := w.Postgres.Begin(ctx)
+	if err != nil {
+		return w.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	for _, table := range batchTables {
+		rows := b.rows[table.name]
+		if len(rows) == 0 {
+			continue
+		}
+		staging := "staging_" + table.name
+		_, err = tx.Exec(ctx, `CREATE TEMP TABLE `+staging+` (LIKE `+table.name+` INCLUDING DEFAULTS) ON COMMIT DROP;`)
+		if err != nil {
+			return w.checkErr(ctx, err)
+		}
+		values := make([][]interface{}, 0, len(rows))
+		for _, row := range rows {
+			values = append(values, row)
+		}
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, table.columns, pgx.CopyFromRows(values))
+		if err != nil {
+			return w.checkErr(ctx, err)
+		}
+		_, err = tx.Exec(ctx, table.upsertSql(staging))
+		if err != nil {
+			return w.checkErr(ctx, err)
+		}
+	}
+	return w.checkErr(ctx, tx.Commit(ctx))
+}
+
+// upsertSql move the rows of the staging table in the table
+func (t batchTable) upsertSql(staging string) string {
+	quoted := func(columns []string) string {
+		q := make([]string, len(columns))
+		for i, column := range columns {
+			q[i] = `"` + column + `"`
+		}
+		return strings.Join(q, ", ")
+	}
+	isConflict := make(map[string]bool, len(t.conflict))
+	for _, column := range t.conflict {
+		isConflict[column] = true
+	}
+	var set []string
+	for _, column := range t.columns {
+		if !isConflict[column] {
+			set = append(set, `"`+column+`" = EXCLUDED."`+column+`"`)
+		}
+	}
+	action := `DO NOTHING`
+	if len(set) > 0 {
+		action = `DO UPDATE SET ` + strings.Join(set, ", ")
+	}
+	return `INSERT INTO ` + t.name + ` (` + quoted(t.columns) + `) SELECT ` + quoted(t.columns) + ` FROM ` + staging + ` ON CONFLICT (` + quoted(t.conflict) + `) ` + action + `;`
+}
+
+// waitBatch block until the batch is flushed and return the flush error
+func waitBatch(ctx context.Context, b *batch) error {
+	select {
+	case <-b.flushed:
+		return b.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type pendingWritesKey struct{}
+
+// pendingWrites the batches written with a ctx
+type pendingWrites struct {
+	mu      sync.Mutex
+	batches map[*batch]bool
+}
+
+func (p *pendingWrites) add(b *batch) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches[b] = true
+}
+
+// WithPendingWrites return a ctx whose batched writes don't wait for the flush, see WaitPendingWrites
+func WithPendingWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pendingWritesKey{}, &pendingWrites{batches: make(map[*batch]bool)})
+}
+
+// WaitPendingWrites block until every batch written with ctx is flushed and return the first flush error.
+// Return nil right away if ctx doesn't come from WithPendingWrites.
+func WaitPendingWrites(ctx context.Context) error {
+	p, ok := ctx.Value(pendingWritesKey{}).(*pendingWrites)
+	if !ok {
+		return nil
+	}
+	p.mu.Lock()
+	batches := p.batches
+	p.batches = make(map[*batch]bool)
+	p.mu.Unlock()
+	for b := range batches {
+		if err := waitBatch(ctx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Middleware acknowledge a task only once the rows it wrote with a BatchWriter are flushed
+func Middleware(h asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		ctx = WithPendingWrites(ctx)
+		if err := h.ProcessTask(ctx, t); err != nil {
+			return err
+		}
+		return WaitPendingWrites(ctx)
+	})
+}
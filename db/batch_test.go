@@ -0,0 +1,96 @@
+package db
+
+import (
+	"casperParser/types/amount"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+func TestBatchTable_UpsertSql(t *testing.T) {
+	t.Run("Should update the non conflict columns", func(t *testing.T) {
+		table := batchTable{name: "purses", columns: []string{"purse", "balance"}, conflict: []string{"purse"}}
+		expected := `INSERT INTO purses ("purse", "balance") SELECT "purse", "balance" FROM staging_purses ON CONFLICT ("purse") DO UPDATE SET "balance" = EXCLUDED."balance";`
+		if sql := table.upsertSql("staging_purses"); sql != expected {
+			t.Errorf("Bad upsert sql. Received : %s. Expected : %s", sql, expected)
+		}
+	})
+	t.Run("Should do nothing when every column is a conflict column", func(t *testing.T) {
+		table := batchTable{name: "contracts_named_keys", columns: []string{"contract_hash", "named_key_uref"}, conflict: []string{"contract_hash", "named_key_uref"}}
+		expected := `INSERT INTO contracts_named_keys ("contract_hash", "named_key_uref") SELECT "contract_hash", "named_key_uref" FROM staging_contracts_named_keys ON CONFLICT ("contract_hash", "named_key_uref") DO NOTHING;`
+		if sql := table.upsertSql("staging_contracts_named_keys"); sql != expected {
+			t.Errorf("Bad upsert sql. Received : %s. Expected : %s", sql, expected)
+		}
+	})
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2021, 4, 8, 18, 10, 32, 115000000, time.UTC)
+	for _, timestamp := range []string{"2021-04-08T18:10:32.115Z", "2021-04-08 18:10:32.115000 +00:00"} {
+		parsed, err := parseTimestamp(timestamp)
+		if err != nil {
+			t.Errorf("Unable to parse %s : %s", timestamp, err)
+		}
+		if !parsed.Equal(expected) {
+			t.Errorf("Bad timestamp. Received : %s. Expected : %s", parsed, expected)
+		}
+	}
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Errorf("Invalid timestamp parsed")
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	dbconstring := os.Getenv("CASPER_PARSER_DATABASE")
+	pool, err := NewPGXPool(context.Background(), dbconstring, 10)
+	if err != nil {
+		t.Fatalf("Unable to init the database pool : %s", err)
+	}
+	defer pool.Close()
+	writer := NewBatchWriter(&DB{Postgres: pool}, 100, 50*time.Millisecond)
+	defer writer.Close()
+	t.Run("Should flush the purses once the task is done", func(t *testing.T) {
+		ctx := WithPendingWrites(context.Background())
+		for _, purse := range []string{"uref-batch-1-007", "uref-batch-2-007"} {
+			if err := writer.InsertPurse(ctx, purse); err != nil {
+				t.Errorf("Unable to InsertPurse : %s", err)
+			}
+		}
+		if err := writer.InsertPurseBalance(ctx, "uref-batch-1-007", amount.FromInt64(42)); err != nil {
+			t.Errorf("Unable to InsertPurseBalance : %s", err)
+		}
+		if err := WaitPendingWrites(ctx); err != nil {
+			t.Fatalf("Unable to flush the batch : %s", err)
+		}
+		var balance string
+		err := pool.QueryRow(context.Background(), `SELECT balance::text FROM purses WHERE purse = $1;`, "uref-batch-1-007").Scan(&balance)
+		if err != nil {
+			t.Fatalf("Unable to read the purse : %s", err)
+		}
+		if balance != "42" {
+			t.Errorf("Bad balance. Received : %s. Expected : %s", balance, "42")
+		}
+	})
+	t.Run("Should wait for the flush without pending writes", func(t *testing.T) {
+		if err := writer.InsertPurse(context.Background(), "uref-batch-3-007"); err != nil {
+			t.Errorf("Unable to InsertPurse : %s", err)
+		}
+	})
+}
+
+func TestJsonb(t *testing.T) {
+	t.Run("Should keep the json", func(t *testing.T) {
+		value := jsonb(`{"a": 1}`)
+		if value.Status != pgtype.Present || string(value.Bytes) != `{"a": 1}` {
+			t.Errorf("Bad jsonb. Received : %s. Expected : %s", value.Bytes, `{"a": 1}`)
+		}
+	})
+	t.Run("Should be NULL for an empty string", func(t *testing.T) {
+		if value := jsonb(""); value.Status != pgtype.Null {
+			t.Errorf("Empty jsonb not NULL")
+		}
+	})
+}
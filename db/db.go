@@ -2,13 +2,21 @@
 package db
 
 import (
+	"casperParser/logger"
+	"casperParser/tracing"
+	"casperParser/types/amount"
 	"casperParser/types/block"
+	"casperParser/types/ces"
+	"casperParser/types/contract"
 	"casperParser/types/deploy"
+	"casperParser/types/deployInfo"
+	"casperParser/types/nft"
+	"casperParser/types/reward"
+	"casperParser/types/token"
 	"casperParser/types/transfer"
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -16,6 +24,8 @@ import (
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DB struct {
@@ -51,6 +61,8 @@ func NewPGXPool(ctx context.Context, connString string, maxConnection int) (*pgx
 
 // InsertBlock in the database
 func (db *DB) InsertBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool, json string) error {
+	ctx, span := startOperation(ctx, "InsertBlock")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	err := db.InsertRawBlock(ctx, hash, json)
 	if err != nil {
@@ -66,11 +78,13 @@ func (db *DB) InsertBlock(ctx context.Context, hash string, era int, timestamp s
 	era_end = $5,
 	validated = $6;`
 	_, err = db.Postgres.Exec(ctx, sql, hash, era, timestamp, height, eraEnd, false)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertRawBlock in the database
 func (db *DB) InsertRawBlock(ctx context.Context, hash string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawBlock")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO raw_blocks ("hash", "data") 
 	VALUES ($1, $2)
@@ -78,33 +92,54 @@ func (db *DB) InsertRawBlock(ctx context.Context, hash string, json string) erro
 	DO UPDATE
 	SET data = $2;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, json)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
+}
+
+// UpdateBlock in the database, the validation of a known block is kept
+func (db *DB) UpdateBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool) error {
+	ctx, span := startOperation(ctx, "UpdateBlock")
+	defer span.End()
+	hash = strings.ToLower(hash)
+	const sql = `INSERT INTO blocks ("hash", "era", "timestamp", "height", "era_end", "validated") 
+	VALUES ($1, $2, $3, $4, $5, false)
+	ON CONFLICT (hash)
+	DO UPDATE
+	SET era = $2,
+	timestamp = $3,
+	height = $4,
+	era_end = $5;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, era, timestamp, height, eraEnd)
+	return db.checkErr(ctx, err)
 }
 
 // InsertDeploy in the database
-func (db *DB) InsertDeploy(ctx context.Context, hash string, from string, cost string, result string, errorMessage string, timestamp string, block string, deployType string, json string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+func (db *DB) InsertDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, json string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	ctx, span := startOperation(ctx, "InsertDeploy")
+	defer span.End()
 	hash = strings.ToLower(hash)
-	err := db.InsertRawDeploy(ctx, hash, json)
+	err := db.InsertRawDeploy(ctx, hash, timestamp, json)
 	if err != nil {
-		println("ERROR InsertRawDeploy")
-		fmt.Printf("%v", err)
 		return err
 	}
 	return db.UpdateDeploy(ctx, hash, from, cost, result, errorMessage, timestamp, block, deployType, metadataType, contractHash, contractName, entrypoint, metadata, events)
 }
 
 // InsertTransfer in the database
-func (db *DB) InsertTransfer(ctx context.Context, hash string, blockHash string, deployHash string, from string, to string, source string, target string, amount int, gas int, json string, id string) error {
+func (db *DB) InsertTransfer(ctx context.Context, hash string, blockHash string, deployHash string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, json string, id string) error {
+	ctx, span := startOperation(ctx, "InsertTransfer")
+	defer span.End()
 	hash = strings.ToLower(hash)
-	err := db.InsertRawTransfer(ctx, hash, blockHash, deployHash, json)
+	err := db.InsertRawTransfer(ctx, hash, blockHash, deployHash, timestamp, json)
 	if err != nil {
 		return err
 	}
-	return db.UpdateTransfer(ctx, hash, blockHash, deployHash, from, to, source, target, amount, gas, id)
+	return db.UpdateTransfer(ctx, hash, blockHash, deployHash, timestamp, from, to, source, target, amount, gas, id)
 }
 
 // InsertTransfer in the database
-func (db *DB) InsertDeployInfo(ctx context.Context, hash string, blockHash string, from string, source string, gas int, json string, transfers string) error {
+func (db *DB) InsertDeployInfo(ctx context.Context, hash string, blockHash string, from string, source string, gas amount.Amount, json string, transfers string) error {
+	ctx, span := startOperation(ctx, "InsertDeployInfo")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	err := db.InsertRawDeployInfo(ctx, hash, blockHash, json)
 	if err != nil {
@@ -113,62 +148,166 @@ func (db *DB) InsertDeployInfo(ctx context.Context, hash string, blockHash strin
 	return db.UpdateDeployInfo(ctx, hash, blockHash, from, source, gas, transfers)
 }
 
-func (db *DB) InsertAuction(ctx context.Context, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
-	const sql = `TRUNCATE bids; TRUNCATE delegators;`
-	_, err := db.Postgres.Exec(ctx, sql)
-	if db.checkErr(err) != nil {
-		return db.checkErr(err)
+// InsertAuction apply the auction state at blockHeight to the bids and delegators, in a transaction.
+// Only the rows that changed are written and each change is logged in auction_changes. A state older than the one applied is ignored.
+func (db *DB) InsertAuction(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
+	ctx, span := startOperation(ctx, "InsertAuction")
+	defer span.End()
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
 	}
+	defer tx.Rollback(ctx)
 
-	_, err = db.Postgres.CopyFrom(
-		ctx,
-		pgx.Identifier{"bids"},
-		[]string{"public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive"},
-		pgx.CopyFromRows(rowsToInsertBids),
-	)
-	if db.checkErr(err) != nil {
-		return db.checkErr(err)
+	var appliedHeight int
+	err = tx.QueryRow(ctx, `SELECT block_height FROM auction_state FOR UPDATE;`).Scan(&appliedHeight)
+	if err != nil {
+		return db.checkErr(ctx, err)
 	}
-	_, err = db.Postgres.CopyFrom(
-		ctx,
-		pgx.Identifier{"delegators"},
-		[]string{"public_key", "delegatee", "staked_amount", "bonding_purse"},
-		pgx.CopyFromRows(rowsToInsertDelegators),
-	)
-	return db.checkErr(err)
+	if blockHeight < appliedHeight {
+		logger.FromContext(ctx).WithFields(log.Fields{"block_height": blockHeight, "applied_block_height": appliedHeight}).Debug("older auction state ignored")
+		return nil
+	}
+
+	const staging = `CREATE TEMP TABLE staging_bids (LIKE bids) ON COMMIT DROP;
+CREATE TEMP TABLE staging_delegators (LIKE delegators) ON COMMIT DROP;`
+	_, err = tx.Exec(ctx, staging)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"staging_bids"}, []string{"public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive"}, pgx.CopyFromRows(rowsToInsertBids))
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"staging_delegators"}, []string{"public_key", "delegatee", "staked_amount", "bonding_purse"}, pgx.CopyFromRows(rowsToInsertDelegators))
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+
+	// A bid changing several ways at once is logged once, with the first matching change. The old and new values are all kept.
+	const logBidChanges = `INSERT INTO auction_changes ("block_height", "change", "validator_public_key", "old_staked_amount", "new_staked_amount", "old_delegation_rate", "new_delegation_rate")
+SELECT $1,
+       CASE
+           WHEN old_bids.public_key IS NULL THEN 'bid_added'
+           WHEN new_bids.public_key IS NULL THEN 'bid_removed'
+           WHEN NOT old_bids.inactive AND new_bids.inactive THEN 'bid_deactivated'
+           WHEN old_bids.inactive AND NOT new_bids.inactive THEN 'bid_activated'
+           WHEN old_bids.staked_amount != new_bids.staked_amount THEN 'bid_stake_changed'
+           ELSE 'bid_delegation_rate_changed'
+           END,
+       COALESCE(new_bids.public_key, old_bids.public_key),
+       old_bids.staked_amount,
+       new_bids.staked_amount,
+       old_bids.delegation_rate,
+       new_bids.delegation_rate
+FROM bids old_bids
+         FULL JOIN staging_bids new_bids ON new_bids.public_key = old_bids.public_key
+WHERE old_bids.public_key IS NULL
+   OR new_bids.public_key IS NULL
+   OR (old_bids.staked_amount, old_bids.delegation_rate, old_bids.inactive) IS DISTINCT FROM
+      (new_bids.staked_amount, new_bids.delegation_rate, new_bids.inactive);`
+	const logDelegatorChanges = `INSERT INTO auction_changes ("block_height", "change", "validator_public_key", "delegator_public_key", "old_staked_amount", "new_staked_amount")
+SELECT $1,
+       CASE
+           WHEN old_delegators.public_key IS NULL THEN 'delegator_added'
+           WHEN new_delegators.public_key IS NULL THEN 'delegator_removed'
+           ELSE 'delegator_stake_changed'
+           END,
+       COALESCE(new_delegators.delegatee, old_delegators.delegatee),
+       COALESCE(new_delegators.public_key, old_delegators.public_key),
+       old_delegators.staked_amount,
+       new_delegators.staked_amount
+FROM delegators old_delegators
+         FULL JOIN staging_delegators new_delegators
+                   ON new_delegators.public_key = old_delegators.public_key
+                       AND new_delegators.delegatee = old_delegators.delegatee
+                       AND new_delegators.bonding_purse = old_delegators.bonding_purse
+WHERE old_delegators.public_key IS NULL
+   OR new_delegators.public_key IS NULL
+   OR old_delegators.staked_amount != new_delegators.staked_amount;`
+	for _, sql := range []string{logBidChanges, logDelegatorChanges} {
+		_, err = tx.Exec(ctx, sql, blockHeight)
+		if err != nil {
+			return db.checkErr(ctx, err)
+		}
+	}
+
+	const applyChanges = `DELETE FROM bids WHERE NOT EXISTS (SELECT FROM staging_bids WHERE staging_bids.public_key = bids.public_key);
+INSERT INTO bids ("public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive")
+SELECT "public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive" FROM staging_bids
+ON CONFLICT (public_key)
+DO UPDATE
+SET bonding_purse = EXCLUDED.bonding_purse, staked_amount = EXCLUDED.staked_amount, delegation_rate = EXCLUDED.delegation_rate, inactive = EXCLUDED.inactive
+WHERE (bids.bonding_purse, bids.staked_amount, bids.delegation_rate, bids.inactive) IS DISTINCT FROM (EXCLUDED.bonding_purse, EXCLUDED.staked_amount, EXCLUDED.delegation_rate, EXCLUDED.inactive);
+DELETE FROM delegators
+WHERE NOT EXISTS (SELECT FROM staging_delegators
+                  WHERE staging_delegators.public_key = delegators.public_key
+                    AND staging_delegators.delegatee = delegators.delegatee
+                    AND staging_delegators.bonding_purse = delegators.bonding_purse);
+INSERT INTO delegators ("public_key", "delegatee", "staked_amount", "bonding_purse")
+SELECT "public_key", "delegatee", "staked_amount", "bonding_purse" FROM staging_delegators
+ON CONFLICT (public_key, delegatee, bonding_purse)
+DO UPDATE
+SET staked_amount = EXCLUDED.staked_amount
+WHERE delegators.staked_amount != EXCLUDED.staked_amount;`
+	_, err = tx.Exec(ctx, applyChanges)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE auction_state SET block_height = $1;`, blockHeight)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
 }
 
-func (db *DB) InsertAuctionEra(ctx context.Context, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
-	_, err := db.Postgres.CopyFrom(
+// InsertAuctionEra the bids and delegators of the switch block at blockHeight and update the stake rollups, in a transaction
+func (db *DB) InsertAuctionEra(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
+	ctx, span := startOperation(ctx, "InsertAuctionEra")
+	defer span.End()
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{"bids_per_era"},
 		[]string{"block_height", "validator_public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive"},
 		pgx.CopyFromRows(rowsToInsertBids),
 	)
-	if db.checkErr(err) != nil {
-		return db.checkErr(err)
+	if err != nil {
+		return db.checkErr(ctx, err)
 	}
-	_, err = db.Postgres.CopyFrom(
+	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{"delegators_per_era"},
 		[]string{"block_height", "delegator_public_key", "validator_public_key", "staked_amount", "bonding_purse"},
 		pgx.CopyFromRows(rowsToInsertDelegators),
 	)
-	return db.checkErr(err)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.Exec(ctx, `SELECT rollup_era_stakes($1);`, blockHeight)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
 }
 
 // UpdateDeploy in the database
-func (db *DB) UpdateDeploy(ctx context.Context, hash string, from string, cost string, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+func (db *DB) UpdateDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	ctx, span := startOperation(ctx, "UpdateDeploy")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO deploys ("hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type", "contract_hash", "contract_name", "entrypoint", "metadata", "events")
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-	ON CONFLICT (hash)
+	ON CONFLICT (hash, "timestamp")
 	DO UPDATE
 	SET "from" = $2,
 	cost = $3,
 	result = $4,
 	error_message = $5,
-	"timestamp" = $6,
 	block = $7,
 	type = $8,
 	metadata_type = $9,
@@ -210,40 +349,42 @@ func (db *DB) UpdateDeploy(ctx context.Context, hash string, from string, cost s
 	_, err := db.Postgres.Exec(ctx, sql, hash, from, cost, result, em, timestamp, block, deployType, metadataType, ch, cn, ep, m, e)
 
 	if err != nil {
-		println("ERROR exec update deploy")
-		fmt.Printf("%v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v, %v", hash, from, cost, result, em, timestamp, block, deployType, metadataType, ch, cn, ep, m, e)
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithFields(log.Fields{logger.FieldDeployHash: hash, logger.FieldBlockHash: block, "deploy_type": deployType, "metadata_type": metadataType}).WithError(err).Error("can't update the deploy")
 	}
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // UpdateTransfer in the database
-func (db *DB) UpdateTransfer(ctx context.Context, hash string, block string, deploy string, from string, to string, source string, target string, amount int, gas int, id string) error {
+func (db *DB) UpdateTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, id string) error {
+	ctx, span := startOperation(ctx, "UpdateTransfer")
+	defer span.End()
 	hash = strings.ToLower(hash)
-	const sql = `INSERT INTO transfers ("hash", "block", "deploy", "from", "to", "source", "target", "amount", "gas", "id")
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	ON CONFLICT (hash)
+	const sql = `INSERT INTO transfers ("hash", "block", "deploy", "timestamp", "from", "to", "source", "target", "amount", "gas", "id")
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (hash, "timestamp")
 	DO UPDATE
 	SET "block" = $2,
 	"deploy" = $3,
-	"from" = $4,
-	"to" = $5,
-	"source" = $6,
-	"target" = $7,
-	"amount" = $8,
-	"gas" = $9,
-	"id" = $10;`
+	"from" = $5,
+	"to" = $6,
+	"source" = $7,
+	"target" = $8,
+	"amount" = $9,
+	"gas" = $10,
+	"id" = $11;`
 	var mId *string
 	mId = nil
 	if id != "" {
 		mId = &id
 	}
-	_, err := db.Postgres.Exec(ctx, sql, hash, block, deploy, from, to, source, target, amount, gas, mId)
-	return db.checkErr(err)
+	_, err := db.Postgres.Exec(ctx, sql, hash, block, deploy, timestamp, from, to, source, target, amount, gas, mId)
+	return db.checkErr(ctx, err)
 }
 
 // UpdateTransfer in the database
-func (db *DB) UpdateDeployInfo(ctx context.Context, hash string, block string, from string, source string, gas int, transfers string) error {
+func (db *DB) UpdateDeployInfo(ctx context.Context, hash string, block string, from string, source string, gas amount.Amount, transfers string) error {
+	ctx, span := startOperation(ctx, "UpdateDeployInfo")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO deploy_infos ("hash", "block", "from", "source", "gas", "transfers")
 	VALUES ($1, $2, $3, $4, $5, $6)
@@ -255,37 +396,43 @@ func (db *DB) UpdateDeployInfo(ctx context.Context, hash string, block string, f
 	"gas" = $5,
 	"transfers" = $6;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, block, from, source, gas, transfers)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertRawDeploy in the database
-func (db *DB) InsertRawDeploy(ctx context.Context, hash string, json string) error {
+func (db *DB) InsertRawDeploy(ctx context.Context, hash string, timestamp string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawDeploy")
+	defer span.End()
 	hash = strings.ToLower(hash)
-	const sql = `INSERT INTO raw_deploys ("hash", "data")
-	VALUES ($1, $2)
-	ON CONFLICT (hash)
+	const sql = `INSERT INTO raw_deploys ("hash", "timestamp", "data")
+	VALUES ($1, $2, $3)
+	ON CONFLICT (hash, "timestamp")
 	DO UPDATE
-	SET data = $2;`
-	_, err := db.Postgres.Exec(ctx, sql, hash, json)
-	return db.checkErr(err)
+	SET data = $3;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, timestamp, json)
+	return db.checkErr(ctx, err)
 }
 
 // InsertRawTransfer in the database
-func (db *DB) InsertRawTransfer(ctx context.Context, hash string, block string, deploy string, json string) error {
+func (db *DB) InsertRawTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawTransfer")
+	defer span.End()
 	hash = strings.ToLower(hash)
-	const sql = `INSERT INTO raw_transfers ("hash", "block", "deploy", "data")
-	VALUES ($1, $2, $3, $4)
-	ON CONFLICT (hash)
+	const sql = `INSERT INTO raw_transfers ("hash", "block", "deploy", "timestamp", "data")
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (hash, "timestamp")
 	DO UPDATE
 	SET "block" = $2,
 	"deploy" = $3,
-	data = $4;`
-	_, err := db.Postgres.Exec(ctx, sql, hash, block, deploy, json)
-	return db.checkErr(err)
+	data = $5;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, block, deploy, timestamp, json)
+	return db.checkErr(ctx, err)
 }
 
 // InsertRawTransfer in the database
 func (db *DB) InsertRawDeployInfo(ctx context.Context, hash string, block string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawDeployInfo")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO raw_deploy_infos ("hash", "block", "data")
 	VALUES ($1, $2, $3)
@@ -294,11 +441,13 @@ func (db *DB) InsertRawDeployInfo(ctx context.Context, hash string, block string
 	SET "block" = $2,
 	data = $3;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, block, json)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertContractPackage in the database
 func (db *DB) InsertContractPackage(ctx context.Context, hash string, deploy string, from string, data string) error {
+	ctx, span := startOperation(ctx, "InsertContractPackage")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	var deployValue *string
 	deployValue = nil
@@ -313,13 +462,19 @@ func (db *DB) InsertContractPackage(ctx context.Context, hash string, deploy str
 	"from" = $3,
 	data = $4;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, deployValue, from, data)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertContract in the database
-func (db *DB) InsertContract(ctx context.Context, hash string, packageHash string, deploy string, from string, contractType string, score float64, data string) error {
+func (db *DB) InsertContract(ctx context.Context, hash string, packageHash string, deploy string, from string, contractType string, score float64, data string, json string) error {
+	ctx, span := startOperation(ctx, "InsertContract")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	packageHash = strings.ToLower(packageHash)
+	err := db.InsertRawContract(ctx, hash, json)
+	if err != nil {
+		return err
+	}
 	const sql = `INSERT INTO contracts ("hash", "package", "deploy", "from", "type", "score", "data")
 	VALUES ($1, $2, $3, $4, $5, $6, $7)
 	ON CONFLICT (hash)
@@ -330,12 +485,75 @@ func (db *DB) InsertContract(ctx context.Context, hash string, packageHash strin
 	type = $5,
 	score = $6,
 	data = $7;`
-	_, err := db.Postgres.Exec(ctx, sql, hash, packageHash, deploy, from, contractType, score, data)
-	return db.checkErr(err)
+	_, err = db.Postgres.Exec(ctx, sql, hash, packageHash, deploy, from, contractType, score, data)
+	return db.checkErr(ctx, err)
+}
+
+// InsertRawContract in the database
+func (db *DB) InsertRawContract(ctx context.Context, hash string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawContract")
+	defer span.End()
+	hash = strings.ToLower(hash)
+	const sql = `INSERT INTO raw_contracts ("hash", "data")
+	VALUES ($1, $2)
+	ON CONFLICT (hash)
+	DO UPDATE
+	SET data = $2;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, json)
+	return db.checkErr(ctx, err)
+}
+
+// UpdateContract in the database, the deploy and the sender of the contract are kept
+func (db *DB) UpdateContract(ctx context.Context, hash string, packageHash string, contractType string, score float64, data string) error {
+	ctx, span := startOperation(ctx, "UpdateContract")
+	defer span.End()
+	hash = strings.ToLower(hash)
+	packageHash = strings.ToLower(packageHash)
+	const sql = `UPDATE contracts
+	SET package = $2,
+	type = $3,
+	score = $4,
+	data = $5
+	WHERE hash = $1;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, packageHash, contractType, score, data)
+	return db.checkErr(ctx, err)
+}
+
+// InsertContractClassification of the contract for the config version, replacing the one already stored, and set the
+// type and the score of the contract, in a transaction
+func (db *DB) InsertContractClassification(ctx context.Context, hash string, classification contract.Classification) error {
+	ctx, span := startOperation(ctx, "InsertContractClassification")
+	defer span.End()
+	hash = strings.ToLower(hash)
+	const sql = `INSERT INTO contract_classifications ("contract_hash", "config_version", "type", "score", "considered", "candidates")
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (contract_hash, config_version)
+	DO UPDATE
+	SET type = $3,
+	score = $4,
+	considered = $5,
+	candidates = $6,
+	classified_at = now();`
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, sql, hash, classification.ConfigVersion, classification.Type, classification.Score, classification.Considered, classification.Candidates)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE contracts SET type = $2, score = $3 WHERE hash = $1;`, hash, classification.Type, classification.Score)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
 }
 
 // InsertNamedKey in the database
 func (db *DB) InsertNamedKey(ctx context.Context, uref string, name string, isPurse bool, initialValue string, contractHash string) error {
+	ctx, span := startOperation(ctx, "InsertNamedKey")
+	defer span.End()
 	contractHash = strings.ToLower(contractHash)
 	const sql = `INSERT INTO named_keys ("uref", "name", "is_purse", "initial_value")
 	VALUES ($1, $2, $3, $4)
@@ -346,15 +564,17 @@ func (db *DB) InsertNamedKey(ctx context.Context, uref string, name string, isPu
 	initial_value = $4;`
 	_, err := db.Postgres.Exec(ctx, sql, uref, name, isPurse, initialValue)
 	if err != nil {
-		return db.checkErr(err)
+		return db.checkErr(ctx, err)
 	}
 	const joinsql = `INSERT INTO contracts_named_keys (contract_hash, named_key_uref) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
 	_, err = db.Postgres.Exec(ctx, joinsql, contractHash, uref)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertAccountHash in the database
 func (db *DB) InsertAccountHash(ctx context.Context, hash string, purse string) error {
+	ctx, span := startOperation(ctx, "InsertAccountHash")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO accounts ("public_key", "account_hash", "main_purse")
 	VALUES ($1, $2, $3)
@@ -363,11 +583,13 @@ func (db *DB) InsertAccountHash(ctx context.Context, hash string, purse string)
 	SET public_key = $1,
 	main_purse = $3;`
 	_, err := db.Postgres.Exec(ctx, sql, nil, hash, purse)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertAccount in the database
 func (db *DB) InsertAccount(ctx context.Context, publicKey string, hash string, purse string) error {
+	ctx, span := startOperation(ctx, "InsertAccount")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO accounts ("public_key", "account_hash", "main_purse")
 	VALUES ($1, $2, $3)
@@ -376,11 +598,13 @@ func (db *DB) InsertAccount(ctx context.Context, publicKey string, hash string,
 	SET public_key = $1,
 	main_purse = $3;`
 	_, err := db.Postgres.Exec(ctx, sql, publicKey, hash, purse)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertPurse in the database
 func (db *DB) InsertPurse(ctx context.Context, hash string) error {
+	ctx, span := startOperation(ctx, "InsertPurse")
+	defer span.End()
 	hash = strings.ToLower(hash)
 	const sql = `INSERT INTO purses ("purse", "balance")
 	VALUES ($1, $2)
@@ -388,11 +612,13 @@ func (db *DB) InsertPurse(ctx context.Context, hash string) error {
 	DO UPDATE
 	SET balance = $2;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, nil)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
 // InsertPurseBalance in the database
-func (db *DB) InsertPurseBalance(ctx context.Context, hash string, balance string) error {
+func (db *DB) InsertPurseBalance(ctx context.Context, hash string, balance amount.Amount) error {
+	ctx, span := startOperation(ctx, "InsertPurseBalance")
+	defer span.End()
 	hash = strings.ToLower(hash)
 
 	const sql = `INSERT INTO purses ("purse", "balance")
@@ -401,35 +627,375 @@ func (db *DB) InsertPurseBalance(ctx context.Context, hash string, balance strin
 	DO UPDATE
 	SET balance = $2;`
 	_, err := db.Postgres.Exec(ctx, sql, hash, balance)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
 }
 
-// InsertRewards in the database
-func (db *DB) InsertRewards(ctx context.Context, rowsToInsert [][]interface{}) error {
-	count, err := db.Postgres.CopyFrom(
+// InsertPurseBalanceHistory the balance changes of the deploy at deployIndex in the block, keyed by purse, block height and deploy index
+func (db *DB) InsertPurseBalanceHistory(ctx context.Context, blockHeight int, deployIndex int, deployHash string, changes []deploy.PurseBalanceChange) error {
+	ctx, span := startOperation(ctx, "InsertPurseBalanceHistory")
+	defer span.End()
+	const sql = `INSERT INTO purse_balance_history ("purse", "block_height", "deploy_index", "deploy", "balance", "delta")
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (purse, block_height, deploy_index)
+	DO UPDATE
+	SET deploy = $4, balance = $5, delta = $6;`
+	batch := &pgx.Batch{}
+	for _, change := range changes {
+		batch.Queue(sql, strings.ToLower(change.Purse), blockHeight, deployIndex, strings.ToLower(deployHash), change.Balance, change.Delta)
+	}
+	err := db.Postgres.SendBatch(ctx, batch).Close()
+	return db.checkErr(ctx, err)
+}
+
+// InsertDeployFee the payment and the fee of a deploy, and add them to the day of its account and of its contract, in a transaction.
+// The fees previously stored for the deploy are removed from their days first.
+func (db *DB) InsertDeployFee(ctx context.Context, deployHash string, from string, timestamp string, contractHash string, fee deploy.Fee) error {
+	ctx, span := startOperation(ctx, "InsertDeployFee")
+	defer span.End()
+	deployHash = strings.ToLower(deployHash)
+	const sql = `INSERT INTO deploy_fees ("deploy", "from", "contract_hash", "date", "payment_amount", "gas_price", "cost", "unused")
+	VALUES ($1, $2, $3, date($4::timestamptz), $5, $6, $7, $8)
+	ON CONFLICT (deploy)
+	DO UPDATE
+	SET "from" = $2,
+	contract_hash = $3,
+	"date" = date($4::timestamptz),
+	payment_amount = $5,
+	gas_price = $6,
+	cost = $7,
+	unused = $8;`
+	var ch *string
+	if contractHash != "" {
+		contractHash = strings.ToLower(contractHash)
+		ch = &contractHash
+	}
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	// The fees stored for the deploy are removed from their days, which may not be the new ones when it's reparsed,
+	// before adding the new fees. The deploy is locked so a concurrent parse doesn't add it twice.
+	batch := &pgx.Batch{}
+	batch.Queue(`SELECT pg_advisory_xact_lock(hashtext('deploy_fees'), hashtext($1));`, deployHash)
+	batch.Queue(`SELECT add_deploy_fees("date", "from", contract_hash, -1, -payment_amount, -cost, -unused) FROM deploy_fees WHERE deploy = $1;`, deployHash)
+	batch.Queue(sql, deployHash, from, ch, timestamp, fee.Payment, fee.GasPrice, fee.Cost, fee.Unused)
+	batch.Queue(`SELECT add_deploy_fees("date", "from", contract_hash, 1, payment_amount, cost, unused) FROM deploy_fees WHERE deploy = $1;`, deployHash)
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertDeployApprovals the approvals of a deploy, replacing the ones already stored, and its verification, in a transaction
+func (db *DB) InsertDeployApprovals(ctx context.Context, deployHash string, approvals []deploy.Approval, verification deploy.Verification) error {
+	ctx, span := startOperation(ctx, "InsertDeployApprovals")
+	defer span.End()
+	deployHash = strings.ToLower(deployHash)
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM deploy_approvals WHERE deploy = $1;`, deployHash)
+	for i, approval := range approvals {
+		valid := i < len(verification.Approvals) && verification.Approvals[i]
+		batch.Queue(`INSERT INTO deploy_approvals ("deploy", "approval_index", "signer", "signature", "valid") VALUES ($1, $2, $3, $4, $5);`,
+			deployHash, i, strings.ToLower(approval.Signer), strings.ToLower(approval.Signature), valid)
+	}
+	// The hashes are unknown when the deploy can't be serialized
+	var e *string
+	bodyHashValid, hashValid := &verification.BodyHashValid, &verification.HashValid
+	if verification.Error != "" {
+		e = &verification.Error
+		bodyHashValid, hashValid = nil, nil
+	}
+	batch.Queue(`INSERT INTO deploy_verifications ("deploy", "body_hash_valid", "hash_valid", "approvals_valid", "error")
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (deploy)
+	DO UPDATE
+	SET body_hash_valid = $2,
+	hash_valid = $3,
+	approvals_valid = $4,
+	error = $5,
+	verified_at = now();`, deployHash, bodyHashValid, hashValid, verification.ApprovalsValid(), e)
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertTransforms of the execution effect of a deploy, replacing the ones already stored, in a transaction
+func (db *DB) InsertTransforms(ctx context.Context, deployHash string, transforms []deploy.Transform) error {
+	ctx, span := startOperation(ctx, "InsertTransforms")
+	defer span.End()
+	deployHash = strings.ToLower(deployHash)
+	rows := make([][]interface{}, 0, len(transforms))
+	for i, transform := range transforms {
+		value, err := transform.StoredValue()
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []interface{}{deployHash, i, transform.Key, transform.Kind, value})
+	}
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `DELETE FROM transforms WHERE deploy = $1;`, deployHash)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"transforms"},
+		[]string{"deploy", "index", "key", "kind", "value"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertCESContract the events dictionary and the schemas of a CES contract
+func (db *DB) InsertCESContract(ctx context.Context, contractHash string, eventsURef string, schemas ces.Schemas) error {
+	ctx, span := startOperation(ctx, "InsertCESContract")
+	defer span.End()
+	const sql = `INSERT INTO ces_contracts ("contract_hash", "events_uref", "schemas")
+	VALUES ($1, $2, $3)
+	ON CONFLICT (contract_hash)
+	DO UPDATE
+	SET events_uref = $2, schemas = $3, updated_at = now();`
+	_, err := db.Postgres.Exec(ctx, sql, strings.ToLower(contractHash), strings.ToLower(eventsURef), schemas)
+	return db.checkErr(ctx, err)
+}
+
+// InsertContractEvents written by a deploy in the events dictionaries of the CES contracts
+func (db *DB) InsertContractEvents(ctx context.Context, deployHash string, events []ces.Event) error {
+	ctx, span := startOperation(ctx, "InsertContractEvents")
+	defer span.End()
+	const sql = `INSERT INTO contract_events ("events_uref", "event_id", "contract_hash", "deploy", "name", "data")
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (events_uref, event_id)
+	DO UPDATE
+	SET contract_hash = $3, deploy = $4, name = $5, data = $6;`
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		batch.Queue(sql, strings.ToLower(event.EventsURef), event.ID, strings.ToLower(event.ContractHash), strings.ToLower(deployHash), event.Name, event.Data)
+	}
+	err := db.Postgres.SendBatch(ctx, batch).Close()
+	return db.checkErr(ctx, err)
+}
+
+// InsertToken a CEP-18 token by contract package. The supply read from the contract is only kept until a deploy writes one.
+func (db *DB) InsertToken(ctx context.Context, t token.Token) error {
+	ctx, span := startOperation(ctx, "InsertToken")
+	defer span.End()
+	const sql = `INSERT INTO tokens ("package_hash", "contract_hash", "name", "symbol", "decimals", "total_supply", "balances_uref", "total_supply_uref")
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (package_hash)
+	DO UPDATE
+	SET contract_hash = $2,
+	name = $3,
+	symbol = $4,
+	decimals = $5,
+	total_supply = CASE WHEN tokens.supply_block_height = 0 THEN $6 ELSE tokens.total_supply END,
+	balances_uref = $7,
+	total_supply_uref = $8;`
+	_, err := db.Postgres.Exec(ctx, sql, strings.ToLower(t.PackageHash), strings.ToLower(t.ContractHash), t.Name, t.Symbol, t.Decimals, t.TotalSupply, strings.ToLower(t.BalancesURef), strings.ToLower(t.TotalSupplyURef))
+	return db.checkErr(ctx, err)
+}
+
+// InsertTokenBalances written by the deploy at deployIndex in the block in the history, and update the balances of the
+// holders unless a later deploy already wrote them
+func (db *DB) InsertTokenBalances(ctx context.Context, blockHeight int, deployIndex int, deployHash string, packageHash string, balances []token.Balance) error {
+	ctx, span := startOperation(ctx, "InsertTokenBalances")
+	defer span.End()
+	const historySql = `INSERT INTO token_balance_history ("package_hash", "holder", "block_height", "deploy_index", "deploy", "balance")
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (package_hash, holder, block_height, deploy_index)
+	DO UPDATE
+	SET deploy = $5, balance = $6;`
+	const balanceSql = `INSERT INTO token_balances ("package_hash", "holder", "balance", "block_height", "deploy_index")
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (package_hash, holder)
+	DO UPDATE
+	SET balance = $3, block_height = $4, deploy_index = $5
+	WHERE (token_balances.block_height, token_balances.deploy_index) <= ($4, $5);`
+	packageHash = strings.ToLower(packageHash)
+	batch := &pgx.Batch{}
+	for _, balance := range balances {
+		batch.Queue(historySql, packageHash, balance.Holder, blockHeight, deployIndex, strings.ToLower(deployHash), balance.Balance)
+		batch.Queue(balanceSql, packageHash, balance.Holder, balance.Balance, blockHeight, deployIndex)
+	}
+	err := db.Postgres.SendBatch(ctx, batch).Close()
+	return db.checkErr(ctx, err)
+}
+
+// UpdateTokenSupply written by the deploy at deployIndex in the block, unless a later deploy already wrote it
+func (db *DB) UpdateTokenSupply(ctx context.Context, blockHeight int, deployIndex int, packageHash string, supply amount.Amount) error {
+	ctx, span := startOperation(ctx, "UpdateTokenSupply")
+	defer span.End()
+	const sql = `UPDATE tokens
+	SET total_supply = $3, supply_block_height = $1, supply_deploy_index = $2
+	WHERE package_hash = $4
+	AND (supply_block_height, supply_deploy_index) <= ($1, $2);`
+	_, err := db.Postgres.Exec(ctx, sql, blockHeight, deployIndex, supply, strings.ToLower(packageHash))
+	return db.checkErr(ctx, err)
+}
+
+// InsertTokenTransfers of the deploy at deployIndex in the block, replacing the ones of the deploy already stored, in a transaction
+func (db *DB) InsertTokenTransfers(ctx context.Context, blockHeight int, deployIndex int, deployHash string, transfers []token.Transfer) error {
+	ctx, span := startOperation(ctx, "InsertTokenTransfers")
+	defer span.End()
+	const sql = `INSERT INTO token_transfers ("deploy", "transfer_index", "package_hash", "kind", "from", "to", "amount", "block_height", "deploy_index")
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9);`
+	deployHash = strings.ToLower(deployHash)
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `DELETE FROM token_transfers WHERE deploy = $1;`, deployHash)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	batch := &pgx.Batch{}
+	for i, transfer := range transfers {
+		batch.Queue(sql, deployHash, i, strings.ToLower(transfer.PackageHash), transfer.Kind, transfer.From, transfer.To, transfer.Amount, blockHeight, deployIndex)
+	}
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertNFTCollection a CEP-47 or CEP-78 collection by contract package
+func (db *DB) InsertNFTCollection(ctx context.Context, c nft.Collection) error {
+	ctx, span := startOperation(ctx, "InsertNFTCollection")
+	defer span.End()
+	const sql = `INSERT INTO nft_collections ("package_hash", "contract_hash", "standard", "name", "symbol")
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (package_hash)
+	DO UPDATE
+	SET contract_hash = $2,
+	standard = $3,
+	name = $4,
+	symbol = $5;`
+	_, err := db.Postgres.Exec(ctx, sql, strings.ToLower(c.PackageHash), strings.ToLower(c.ContractHash), c.Standard, c.Name, c.Symbol)
+	return db.checkErr(ctx, err)
+}
+
+// InsertNFTActions of the deploy at deployIndex in the block, in a transaction. The transfers of the deploy already
+// stored are replaced, the tokens are updated unless a later deploy already acted on them.
+func (db *DB) InsertNFTActions(ctx context.Context, blockHeight int, deployIndex int, deployHash string, actions []nft.Action) error {
+	ctx, span := startOperation(ctx, "InsertNFTActions")
+	defer span.End()
+	const transferSql = `INSERT INTO nft_transfers ("deploy", "action_index", "package_hash", "token_id", "kind", "from", "to", "block_height", "deploy_index")
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9);`
+	const tokenSql = `INSERT INTO nft_tokens ("package_hash", "token_id", "owner", "metadata", "burnt", "approved", "block_height", "deploy_index")
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8)
+	ON CONFLICT (package_hash, token_id)
+	DO UPDATE
+	SET owner = COALESCE(excluded.owner, nft_tokens.owner),
+	metadata = COALESCE(excluded.metadata, nft_tokens.metadata),
+	burnt = excluded.burnt,
+	approved = CASE WHEN $9 THEN excluded.approved ELSE nft_tokens.approved END,
+	block_height = $7,
+	deploy_index = $8
+	WHERE (nft_tokens.block_height, nft_tokens.deploy_index) <= ($7, $8);`
+	deployHash = strings.ToLower(deployHash)
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `DELETE FROM nft_transfers WHERE deploy = $1;`, deployHash)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	batch := &pgx.Batch{}
+	for i, action := range actions {
+		packageHash := strings.ToLower(action.PackageHash)
+		if action.ChangesOwner() {
+			batch.Queue(transferSql, deployHash, i, packageHash, action.TokenID, action.Kind, action.From, action.To, blockHeight, deployIndex)
+		}
+		batch.Queue(tokenSql, packageHash, action.TokenID, action.To, action.Metadata, action.Kind == nft.ActionBurn, action.Spender, blockHeight, deployIndex, action.SetsApproved())
+	}
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertRewards of an era, replacing the ones already stored, and update the rewards rollups, in a transaction
+func (db *DB) InsertRewards(ctx context.Context, era int, rowsToInsert [][]interface{}) error {
+	ctx, span := startOperation(ctx, "InsertRewards")
+	defer span.End()
+	tx, err := db.Postgres.Begin(ctx)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer tx.Rollback(ctx)
+	// The validator rewards have no delegator, the unique constraint doesn't prevent them from being inserted twice
+	_, err = tx.Exec(ctx, `DELETE FROM rewards WHERE era = $1;`, era)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{"rewards"},
 		[]string{"block", "era", "delegator_public_key", "validator_public_key", "amount"},
 		pgx.CopyFromRows(rowsToInsert),
 	)
-	// TODO: Causes troubles when table is too big, find why we delete ?
-	if err != nil || count < int64(len(rowsToInsert)) {
-		//const sql = `DELETE FROM rewards WHERE block = $1;`
-		//_, errD := db.Postgres.Query(ctx, sql, rowsToInsert[0][0])
-		//if db.checkErr(errD) != nil {
-		//	return db.checkErr(errD)
-		//}
-		return db.checkErr(err)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	_, err = tx.Exec(ctx, `SELECT rollup_era_rewards($1);`, era)
+	if err != nil {
+		return db.checkErr(ctx, err)
 	}
-	return db.checkErr(err)
+	return db.checkErr(ctx, tx.Commit(ctx))
+}
+
+// InsertRawEraInfo of a switch block in the database
+func (db *DB) InsertRawEraInfo(ctx context.Context, hash string, json string) error {
+	ctx, span := startOperation(ctx, "InsertRawEraInfo")
+	defer span.End()
+	hash = strings.ToLower(hash)
+	const sql = `INSERT INTO raw_era_infos ("hash", "data")
+	VALUES ($1, $2)
+	ON CONFLICT (hash)
+	DO UPDATE
+	SET data = $2;`
+	_, err := db.Postgres.Exec(ctx, sql, hash, json)
+	return db.checkErr(ctx, err)
+}
+
+// RebuildRollups compute the rewards and stake rollups again from the rewards, bids_per_era and delegators_per_era tables,
+// and the daily fees from the deploy fees
+func (db *DB) RebuildRollups(ctx context.Context) error {
+	ctx, span := startOperation(ctx, "RebuildRollups")
+	defer span.End()
+	_, err := db.Postgres.Exec(ctx, `SELECT rebuild_rollups(); SELECT rebuild_fee_rollups();`)
+	return db.checkErr(ctx, err)
 }
 
 // GetMissingBlocks from the database
 func (db *DB) GetMissingBlocks(ctx context.Context) ([]int, error) {
+	ctx, span := startOperation(ctx, "GetMissingBlocks")
+	defer span.End()
 	const sql = `SELECT all_ids AS missing_ids FROM generate_series((SELECT MIN(height) FROM blocks), (SELECT MAX(height) FROM blocks)) all_ids EXCEPT SELECT height FROM blocks;`
 	rows, err := db.Postgres.Query(ctx, sql)
-	if db.checkErr(err) != nil {
-		return []int{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return []int{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var missingDeploys []int
@@ -438,16 +1004,16 @@ func (db *DB) GetMissingBlocks(ctx context.Context) ([]int, error) {
 			id int
 		}{}
 		err = rows.Scan(&missing.id)
-		if db.checkErr(err) != nil {
-			return []int{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return []int{}, db.checkErr(ctx, err)
 		}
 
-		log.Printf("Missing block found: id=%d ", missing.id)
+		logger.FromContext(ctx).WithField(logger.FieldBlockHeight, missing.id).Info("missing block found")
 		missingDeploys = append(missingDeploys, missing.id)
 	}
 	// check rows.Err() after the last rows.Next() :
-	if err = rows.Err(); db.checkErr(err) != nil {
-		return []int{}, db.checkErr(err)
+	if err = rows.Err(); db.checkErr(ctx, err) != nil {
+		return []int{}, db.checkErr(ctx, err)
 		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
 		// there could also be some bad things like a truncated response because
 		// of some network error, etc ...
@@ -457,10 +1023,12 @@ func (db *DB) GetMissingBlocks(ctx context.Context) ([]int, error) {
 
 // GetMissingBlocks from the database
 func (db *DB) GetMissingBlocksFromHeight(ctx context.Context, startHeight int) ([]int, error) {
+	ctx, span := startOperation(ctx, "GetMissingBlocksFromHeight")
+	defer span.End()
 	const sql = `SELECT all_ids AS missing_ids FROM generate_series($1 , (SELECT MAX(height) FROM blocks)) all_ids EXCEPT SELECT height FROM blocks;`
 	rows, err := db.Postgres.Query(ctx, sql, startHeight)
-	if db.checkErr(err) != nil {
-		return []int{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return []int{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var missingDeploys []int
@@ -469,16 +1037,16 @@ func (db *DB) GetMissingBlocksFromHeight(ctx context.Context, startHeight int) (
 			id int
 		}{}
 		err = rows.Scan(&missing.id)
-		if db.checkErr(err) != nil {
-			return []int{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return []int{}, db.checkErr(ctx, err)
 		}
 
-		log.Printf("Missing block found: id=%d ", missing.id)
+		logger.FromContext(ctx).WithField(logger.FieldBlockHeight, missing.id).Info("missing block found")
 		missingDeploys = append(missingDeploys, missing.id)
 	}
 	// check rows.Err() after the last rows.Next() :
-	if err = rows.Err(); db.checkErr(err) != nil {
-		return []int{}, db.checkErr(err)
+	if err = rows.Err(); db.checkErr(ctx, err) != nil {
+		return []int{}, db.checkErr(ctx, err)
 		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
 		// there could also be some bad things like a truncated response because
 		// of some network error, etc ...
@@ -488,11 +1056,13 @@ func (db *DB) GetMissingBlocksFromHeight(ctx context.Context, startHeight int) (
 
 // GetMissingMetadataDeploysHash from the database
 func (db *DB) GetMissingMetadataDeploysHash(ctx context.Context) ([]string, error) {
+	ctx, span := startOperation(ctx, "GetMissingMetadataDeploysHash")
+	defer span.End()
 	const sql = `SELECT hash FROM deploys WHERE metadata IS NULL;`
 
 	rows, err := db.Postgres.Query(ctx, sql)
-	if db.checkErr(err) != nil {
-		return []string{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return []string{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var missingDeploys []string
@@ -501,14 +1071,14 @@ func (db *DB) GetMissingMetadataDeploysHash(ctx context.Context) ([]string, erro
 			hash string
 		}{}
 		err = rows.Scan(&missing.hash)
-		if db.checkErr(err) != nil {
-			return []string{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return []string{}, db.checkErr(ctx, err)
 		}
 		missingDeploys = append(missingDeploys, missing.hash)
 	}
 	// check rows.Err() after the last rows.Next() :
-	if err = rows.Err(); db.checkErr(err) != nil {
-		return []string{}, db.checkErr(err)
+	if err = rows.Err(); db.checkErr(ctx, err) != nil {
+		return []string{}, db.checkErr(ctx, err)
 		// on top of errors triggered by bad conditions on the 'rows.Scan()' call,
 		// there could also be some bad things like a truncated response because
 		// of some network error, etc ...
@@ -518,17 +1088,19 @@ func (db *DB) GetMissingMetadataDeploysHash(ctx context.Context) ([]string, erro
 
 // GetDeploy from the database
 func (db *DB) GetDeploy(ctx context.Context, hash string) (deploy.Result, error) {
+	ctx, span := startOperation(ctx, "GetDeploy")
+	defer span.End()
 	const sql = `SELECT data FROM raw_deploys WHERE hash = $1;`
 	rows, err := db.Postgres.Query(ctx, sql, hash)
-	if db.checkErr(err) != nil {
-		return deploy.Result{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return deploy.Result{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var d deploy.Result
 	for rows.Next() {
 		err = rows.Scan(&d)
-		if db.checkErr(err) != nil {
-			return deploy.Result{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return deploy.Result{}, db.checkErr(ctx, err)
 		}
 	}
 	return d, nil
@@ -536,17 +1108,19 @@ func (db *DB) GetDeploy(ctx context.Context, hash string) (deploy.Result, error)
 
 // GetTransfer from the database
 func (db *DB) GetTransfer(ctx context.Context, hash string) (transfer.Result, error) {
+	ctx, span := startOperation(ctx, "GetTransfer")
+	defer span.End()
 	const sql = `SELECT data FROM raw_transfers WHERE hash = $1;`
 	rows, err := db.Postgres.Query(ctx, sql, hash)
-	if db.checkErr(err) != nil {
-		return transfer.Result{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return transfer.Result{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var d transfer.Result
 	for rows.Next() {
 		err = rows.Scan(&d)
-		if db.checkErr(err) != nil {
-			return transfer.Result{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return transfer.Result{}, db.checkErr(ctx, err)
 		}
 	}
 	return d, nil
@@ -554,24 +1128,190 @@ func (db *DB) GetTransfer(ctx context.Context, hash string) (transfer.Result, er
 
 // GetRawBlock from the database
 func (db *DB) GetRawBlock(ctx context.Context, hash string) (block.Result, error) {
+	ctx, span := startOperation(ctx, "GetRawBlock")
+	defer span.End()
 	const sql = `SELECT data FROM raw_blocks WHERE hash = $1;`
 	rows, err := db.Postgres.Query(ctx, sql, hash)
-	if db.checkErr(err) != nil {
-		return block.Result{}, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return block.Result{}, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var d block.Result
 	for rows.Next() {
 		err = rows.Scan(&d)
-		if db.checkErr(err) != nil {
-			return block.Result{}, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return block.Result{}, db.checkErr(ctx, err)
+		}
+	}
+	return d, nil
+}
+
+// GetDeployInfo from the database
+func (db *DB) GetDeployInfo(ctx context.Context, hash string) (deployInfo.Result, error) {
+	ctx, span := startOperation(ctx, "GetDeployInfo")
+	defer span.End()
+	// The deploy infos the rpc failed to return are stored as "ERROR"
+	const sql = `SELECT data FROM raw_deploy_infos WHERE hash = $1 AND jsonb_typeof(data) = 'object';`
+	rows, err := db.Postgres.Query(ctx, sql, hash)
+	if db.checkErr(ctx, err) != nil {
+		return deployInfo.Result{}, db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	var d deployInfo.Result
+	for rows.Next() {
+		err = rows.Scan(&d)
+		if db.checkErr(ctx, err) != nil {
+			return deployInfo.Result{}, db.checkErr(ctx, err)
+		}
+	}
+	return d, nil
+}
+
+// GetRawEraInfo of a switch block from the database
+func (db *DB) GetRawEraInfo(ctx context.Context, hash string) (reward.Result, error) {
+	ctx, span := startOperation(ctx, "GetRawEraInfo")
+	defer span.End()
+	const sql = `SELECT data FROM raw_era_infos WHERE hash = $1;`
+	rows, err := db.Postgres.Query(ctx, sql, hash)
+	if db.checkErr(ctx, err) != nil {
+		return reward.Result{}, db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	var d reward.Result
+	for rows.Next() {
+		err = rows.Scan(&d)
+		if db.checkErr(ctx, err) != nil {
+			return reward.Result{}, db.checkErr(ctx, err)
+		}
+	}
+	return d, nil
+}
+
+// GetRawContract from the database
+func (db *DB) GetRawContract(ctx context.Context, hash string) (contract.Result, error) {
+	ctx, span := startOperation(ctx, "GetRawContract")
+	defer span.End()
+	const sql = `SELECT data FROM raw_contracts WHERE hash = $1;`
+	rows, err := db.Postgres.Query(ctx, sql, hash)
+	if db.checkErr(ctx, err) != nil {
+		return contract.Result{}, db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	var d contract.Result
+	for rows.Next() {
+		err = rows.Scan(&d)
+		if db.checkErr(ctx, err) != nil {
+			return contract.Result{}, db.checkErr(ctx, err)
 		}
 	}
 	return d, nil
 }
 
+// GetCESContract using the events dictionary from the database, the last one stored when the dictionary is shared.
+// The contract hash is empty if there is none.
+func (db *DB) GetCESContract(ctx context.Context, eventsURef string) (string, ces.Schemas, error) {
+	ctx, span := startOperation(ctx, "GetCESContract")
+	defer span.End()
+	const sql = `SELECT contract_hash, schemas FROM ces_contracts WHERE events_uref = $1 ORDER BY updated_at DESC LIMIT 1;`
+	var contractHash string
+	var schemas ces.Schemas
+	err := db.Postgres.QueryRow(ctx, sql, strings.ToLower(eventsURef)).Scan(&contractHash, &schemas)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, nil
+	}
+	return contractHash, schemas, db.checkErr(ctx, err)
+}
+
+// GetToken by package hash, or by the hash of one of the contracts of its package, from the database.
+// The package hash is empty if there is none.
+func (db *DB) GetToken(ctx context.Context, hash string) (token.Token, error) {
+	ctx, span := startOperation(ctx, "GetToken")
+	defer span.End()
+	const sql = `SELECT package_hash, contract_hash, COALESCE(name, ''), COALESCE(symbol, ''), COALESCE(decimals, 0), total_supply, balances_uref, total_supply_uref
+	FROM tokens
+	WHERE package_hash = $1 OR package_hash = (SELECT package FROM contracts WHERE contracts.hash = $1)
+	LIMIT 1;`
+	var t token.Token
+	err := db.Postgres.QueryRow(ctx, sql, strings.ToLower(hash)).Scan(&t.PackageHash, &t.ContractHash, &t.Name, &t.Symbol, &t.Decimals, &t.TotalSupply, &t.BalancesURef, &t.TotalSupplyURef)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return token.Token{}, nil
+	}
+	return t, db.checkErr(ctx, err)
+}
+
+// GetTokenByURef the token with the balances dictionary or the total_supply uref at the address from the database.
+// The package hash is empty if there is none.
+func (db *DB) GetTokenByURef(ctx context.Context, uref string) (token.Token, error) {
+	ctx, span := startOperation(ctx, "GetTokenByURef")
+	defer span.End()
+	const sql = `SELECT package_hash, contract_hash, COALESCE(name, ''), COALESCE(symbol, ''), COALESCE(decimals, 0), total_supply, balances_uref, total_supply_uref
+	FROM tokens WHERE balances_uref = $1 OR total_supply_uref = $1 LIMIT 1;`
+	var t token.Token
+	err := db.Postgres.QueryRow(ctx, sql, strings.ToLower(uref)).Scan(&t.PackageHash, &t.ContractHash, &t.Name, &t.Symbol, &t.Decimals, &t.TotalSupply, &t.BalancesURef, &t.TotalSupplyURef)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return token.Token{}, nil
+	}
+	return t, db.checkErr(ctx, err)
+}
+
+// GetNFTCollection by package hash, or by the hash of one of the contracts of its package, from the database.
+// The package hash is empty if there is none.
+func (db *DB) GetNFTCollection(ctx context.Context, hash string) (nft.Collection, error) {
+	ctx, span := startOperation(ctx, "GetNFTCollection")
+	defer span.End()
+	const sql = `SELECT package_hash, contract_hash, standard, COALESCE(name, ''), COALESCE(symbol, '')
+	FROM nft_collections
+	WHERE package_hash = $1 OR package_hash = (SELECT package FROM contracts WHERE contracts.hash = $1)
+	LIMIT 1;`
+	var c nft.Collection
+	err := db.Postgres.QueryRow(ctx, sql, strings.ToLower(hash)).Scan(&c.PackageHash, &c.ContractHash, &c.Standard, &c.Name, &c.Symbol)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nft.Collection{}, nil
+	}
+	return c, db.checkErr(ctx, err)
+}
+
+// GetNamedKeyValues of a contract from the database, the json initial value of each named key by name
+func (db *DB) GetNamedKeyValues(ctx context.Context, contractHash string) (map[string]string, error) {
+	ctx, span := startOperation(ctx, "GetNamedKeyValues")
+	defer span.End()
+	const sql = `SELECT named_keys.name, COALESCE(named_keys.initial_value::TEXT, 'null')
+	FROM contracts_named_keys
+	INNER JOIN named_keys ON named_keys.uref = contracts_named_keys.named_key_uref
+	WHERE contracts_named_keys.contract_hash = $1;`
+	rows, err := db.Postgres.Query(ctx, sql, strings.ToLower(contractHash))
+	if err != nil {
+		return nil, db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	values := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		err = rows.Scan(&name, &value)
+		if err != nil {
+			return nil, db.checkErr(ctx, err)
+		}
+		values[name] = value
+	}
+	return values, db.checkErr(ctx, rows.Err())
+}
+
+// GetContractType from the database, empty if the contract isn't stored
+func (db *DB) GetContractType(ctx context.Context, hash string) (string, error) {
+	ctx, span := startOperation(ctx, "GetContractType")
+	defer span.End()
+	var contractType string
+	err := db.Postgres.QueryRow(ctx, `SELECT type FROM contracts WHERE hash = $1;`, strings.ToLower(hash)).Scan(&contractType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return contractType, db.checkErr(ctx, err)
+}
+
 // CountDeploys from the database
 func (db *DB) CountDeploys(ctx context.Context, hashes []string) (int, error) {
+	ctx, span := startOperation(ctx, "CountDeploys")
+	defer span.End()
 	var paramrefs string
 	for i := range hashes {
 		paramrefs += `$` + strconv.Itoa(i+1) + `,`
@@ -583,15 +1323,15 @@ func (db *DB) CountDeploys(ctx context.Context, hashes []string) (int, error) {
 		genericHashes[i] = v
 	}
 	rows, err := db.Postgres.Query(ctx, sql, genericHashes...)
-	if db.checkErr(err) != nil {
-		return 0, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return 0, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var d int
 	for rows.Next() {
 		err = rows.Scan(&d)
-		if db.checkErr(err) != nil {
-			return 0, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return 0, db.checkErr(ctx, err)
 		}
 	}
 	return d, nil
@@ -599,6 +1339,8 @@ func (db *DB) CountDeploys(ctx context.Context, hashes []string) (int, error) {
 
 // CountTransfers from the database
 func (db *DB) CountTransfers(ctx context.Context, hashes []string) (int, error) {
+	ctx, span := startOperation(ctx, "CountTransfers")
+	defer span.End()
 	var paramrefs string
 	for i := range hashes {
 		paramrefs += `$` + strconv.Itoa(i+1) + `,`
@@ -610,15 +1352,15 @@ func (db *DB) CountTransfers(ctx context.Context, hashes []string) (int, error)
 		genericHashes[i] = v
 	}
 	rows, err := db.Postgres.Query(ctx, sql, genericHashes...)
-	if db.checkErr(err) != nil {
-		return 0, db.checkErr(err)
+	if db.checkErr(ctx, err) != nil {
+		return 0, db.checkErr(ctx, err)
 	}
 	defer rows.Close()
 	var d int
 	for rows.Next() {
 		err = rows.Scan(&d)
-		if db.checkErr(err) != nil {
-			return 0, db.checkErr(err)
+		if db.checkErr(ctx, err) != nil {
+			return 0, db.checkErr(ctx, err)
 		}
 	}
 	return d, nil
@@ -626,9 +1368,174 @@ func (db *DB) CountTransfers(ctx context.Context, hashes []string) (int, error)
 
 // ValidateBlock from the database
 func (db *DB) ValidateBlock(ctx context.Context, hash string) error {
+	ctx, span := startOperation(ctx, "ValidateBlock")
+	defer span.End()
 	const sql = `UPDATE blocks SET validated = true WHERE hash = $1;`
 	_, err := db.Postgres.Exec(ctx, sql, hash)
-	return db.checkErr(err)
+	return db.checkErr(ctx, err)
+}
+
+// GetLastBlockHeight from the database, 0 if there's no block yet
+func (db *DB) GetLastBlockHeight(ctx context.Context) (int, error) {
+	ctx, span := startOperation(ctx, "GetLastBlockHeight")
+	defer span.End()
+	const sql = `SELECT COALESCE(MAX(height), 0) FROM blocks;`
+	var height int
+	err := db.Postgres.QueryRow(ctx, sql).Scan(&height)
+	return height, db.checkErr(ctx, err)
+}
+
+// PartitionedTables partitioned by month on their timestamp, see CreatePartitions
+var PartitionedTables = []string{"raw_deploys", "deploys", "raw_transfers", "transfers"}
+
+// CreatePartitions create the missing monthly partitions of the PartitionedTables from the current month to monthsAhead months later.
+// Rows whose month has no partition are rejected, it must run before the chain reaches the last created month.
+// Return the number of partitions created.
+func (db *DB) CreatePartitions(ctx context.Context, monthsAhead int) (int, error) {
+	ctx, span := startOperation(ctx, "CreatePartitions")
+	defer span.End()
+	const sql = `SELECT create_monthly_partitions($1, now(), now() + make_interval(months => $2));`
+	total := 0
+	for _, table := range PartitionedTables {
+		var created int
+		err := db.Postgres.QueryRow(ctx, sql, table, monthsAhead).Scan(&created)
+		if err != nil {
+			return total, db.checkErr(ctx, err)
+		}
+		total += created
+	}
+	return total, nil
+}
+
+// MaterializedViews refreshed by RefreshMaterializedViews, in dependency order. The rewards and stake aggregates are rollup tables, see RebuildRollups
+var MaterializedViews = []string{
+	"staking_operation_per_era_per_validator",
+	"staking_operation_daily_per_validator",
+}
+
+// RefreshMaterializedViews recompute the MaterializedViews. A view is locked for reading during its refresh.
+func (db *DB) RefreshMaterializedViews(ctx context.Context) error {
+	ctx, span := startOperation(ctx, "RefreshMaterializedViews")
+	defer span.End()
+	for _, view := range MaterializedViews {
+		_, err := db.Postgres.Exec(ctx, `REFRESH MATERIALIZED VIEW `+pgx.Identifier{view}.Sanitize()+`;`)
+		if err != nil {
+			return db.checkErr(ctx, err)
+		}
+	}
+	return nil
+}
+
+// ForEachUnvalidatedBlockHash call fn with the hash of every block not validated yet
+func (db *DB) ForEachUnvalidatedBlockHash(ctx context.Context, fn func(hash string) error) error {
+	ctx, span := startOperation(ctx, "ForEachUnvalidatedBlockHash")
+	defer span.End()
+	const sql = `SELECT hash FROM blocks WHERE validated = false;`
+	return db.forEachString(ctx, sql, fn)
+}
+
+// ForEachMissingAccountHash call fn with the account hashes targeted by a successful transfer but not in the accounts table
+func (db *DB) ForEachMissingAccountHash(ctx context.Context, fn func(hash string) error) error {
+	ctx, span := startOperation(ctx, "ForEachMissingAccountHash")
+	defer span.End()
+	// TODO: Check if target is an accountHash
+	const sql = `WITH accounthashes AS (SELECT LOWER(metadata ->> 'target') as accounthash, MIN(timestamp) as created_at
+FROM deploys
+WHERE length(LOWER(metadata ->> 'target')) < 66 AND type = 'transfer' AND result = 'success'
+GROUP BY accounthash)
+SELECT accountHash from accounthashes
+LEFT JOIN accounts ON accounthashes.accounthash = accounts.account_hash WHERE accounts.account_hash IS NULL;`
+	return db.forEachString(ctx, sql, fn)
+}
+
+// ForEachMissingPublicKey call fn with the public keys that sent a deploy or received a transfer but are not in the accounts table
+func (db *DB) ForEachMissingPublicKey(ctx context.Context, fn func(publicKey string) error) error {
+	ctx, span := startOperation(ctx, "ForEachMissingPublicKey")
+	defer span.End()
+	const sql = `WITH keys AS (SELECT LOWER("from") as key, MIN(timestamp) as created_at
+FROM deploys
+WHERE ("from" LIKE '01%' AND length("from") = 66) OR ("from" LIKE '02%' AND length("from") = 68)
+GROUP BY key
+UNION
+SELECT LOWER(metadata ->> 'target') as key, MIN(timestamp) as created_at
+FROM deploys
+WHERE length(LOWER(metadata ->> 'target')) >= 66 AND length(LOWER(metadata ->> 'target')) <= 68 AND type = 'transfer' AND result = 'success'
+GROUP BY key
+)
+SELECT key from keys
+LEFT JOIN accounts ON keys.key = accounts.public_key WHERE accounts.public_key IS NULL;`
+	return db.forEachString(ctx, sql, fn)
+}
+
+// ForEachMissingPurse call fn with the urefs of the contract purses, transfer targets and main purses not in the purses table
+func (db *DB) ForEachMissingPurse(ctx context.Context, fn func(uref string) error) error {
+	ctx, span := startOperation(ctx, "ForEachMissingPurse")
+	defer span.End()
+	const sql = `WITH urefs as (WITH uref AS (SELECT jsonb_array_elements(data -> 'Contract' -> 'named_keys') as j
+                             from contracts)
+               SELECT DISTINCT j ->> 'key' as uref
+               FROM uref
+               WHERE (j -> 'is_purse')::bool is true
+               UNION
+               SELECT DISTINCT LOWER(metadata ->> 'target') as uref
+               FROM deploys
+               WHERE length(LOWER(metadata ->> 'target')) > 68
+                 AND type = 'transfer'
+                 AND result = 'success'
+               UNION
+               SELECT DISTINCT LOWER(main_purse) as uref
+               FROM accounts)
+SELECT uref
+from urefs
+         LEFT JOIN purses ON urefs.uref = purses.purse
+WHERE purses.purse IS NULL;`
+	return db.forEachString(ctx, sql, fn)
+}
+
+// ForEachPurse call fn with every purse of the purses table
+func (db *DB) ForEachPurse(ctx context.Context, fn func(uref string) error) error {
+	ctx, span := startOperation(ctx, "ForEachPurse")
+	defer span.End()
+	const sql = `SELECT purse from purses;`
+	return db.forEachString(ctx, sql, fn)
+}
+
+// forEachInt call fn with the single int column of each row returned by the query, stopping at the first error
+func (db *DB) forEachInt(ctx context.Context, sql string, fn func(int) error, args ...interface{}) error {
+	rows, err := db.Postgres.Query(ctx, sql, args...)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value int
+		if err := rows.Scan(&value); err != nil {
+			return db.checkErr(ctx, err)
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return db.checkErr(ctx, rows.Err())
+}
+
+// forEachString call fn with the single string column of each row returned by the query, stopping at the first error
+func (db *DB) forEachString(ctx context.Context, sql string, fn func(string) error, args ...interface{}) error {
+	rows, err := db.Postgres.Query(ctx, sql, args...)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return db.checkErr(ctx, err)
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return db.checkErr(ctx, rows.Err())
 }
 
 func (db *DB) blockPgError(err error) error {
@@ -652,18 +1559,32 @@ func (db *DB) blockPgError(err error) error {
 	return nil
 }
 
+// PGXStdLogger forward the pgx logs to the structured logger
 type PGXStdLogger struct{}
 
 func (l *PGXStdLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
-	args := make([]interface{}, 0, len(data)+2) // making space for arguments + level + msg
-	args = append(args, level, msg)
-	for k, v := range data {
-		args = append(args, fmt.Sprintf("%s=%v", k, v))
+	entry := logger.FromContext(ctx).WithFields(data)
+	switch level {
+	case pgx.LogLevelTrace:
+		entry.Trace(msg)
+	case pgx.LogLevelDebug:
+		entry.Debug(msg)
+	case pgx.LogLevelInfo:
+		entry.Info(msg)
+	case pgx.LogLevelWarn:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
 	}
-	log.Println(args...)
 }
 
-func (db *DB) checkErr(err error) error {
+// startOperation start the span of a database operation and attach the operation to the context logger
+func startOperation(ctx context.Context, operation string) (context.Context, trace.Span) {
+	ctx, span := tracing.StartDB(ctx, operation)
+	return logger.WithFields(ctx, log.Fields{logger.FieldDBOperation: operation}), span
+}
+
+func (db *DB) checkErr(ctx context.Context, err error) error {
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -671,7 +1592,7 @@ func (db *DB) checkErr(err error) error {
 		if sqlErr := db.blockPgError(err); sqlErr != nil {
 			return sqlErr
 		}
-		log.Printf("db error : %v\n", err)
+		logger.FromContext(ctx).WithError(err).Error("db error")
 		return errors.New("db error")
 	}
 	return nil
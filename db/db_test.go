@@ -1,6 +1,8 @@
 package db
 
 import (
+	"casperParser/types/amount"
+	"casperParser/types/deploy"
 	"context"
 	"os"
 	"testing"
@@ -38,7 +40,7 @@ func TestDB(t *testing.T) {
 		}
 	})
 	t.Run("Should InsertDeploy", func(t *testing.T) {
-		err = db.InsertDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "232824230", "failure", "error_message", "2021-04-08 18:10:32.115000 +00:00", "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", "moduleBytes", `{"deploy": {"hash": "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "header": {"ttl": "1h", "account": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "body_hash": "a14865e1d6016f4c83a240a7df64a538798b2bb392d86674a4e33a59901a354b", "gas_price": 1, "timestamp": "2021-04-08T18:10:32.115Z", "chain_name": "casper-test", "dependencies": []}, "payment": {"ModuleBytes": {"args": [["amount", {"bytes": "05003ad0b814", "parsed": "89000000000", "cl_type": "U512"}]], "module_bytes": ""}}, "session": {"ModuleBytes": {"args": [["public_key", {"bytes": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "parsed": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "cl_type": "PublicKey"}], ["amount", {"bytes": "0500282e8cd1", "parsed": "900000000000", "cl_type": "U512"}], ["delegation_rate", {"bytes": "0a", "parsed": 10, "cl_type": "U8"}]], "module_bytes": ""}}, "approvals": [{"signer": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "signature": "01d911dea193709debe86db026bb2b9a43e9d0985a3a6f4cde749b55f08b83c12ac9ec7a47b8ae6e7a12cf5dbd96a0314846febf1c9fa95f5aea5a480a170bcc0e"}]}, "api_version": "1.4.7", "execution_results": [{"result": {"Failure": {"cost": "232824230", "effect": {"operations": [{"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "kind": "Read"}, {"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "kind": "Write"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "kind": "Write"}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "kind": "Read"}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "kind": "Read"}], "transforms": [{"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "transform": {"WriteCLValue": {"bytes": "04808f9b95", "parsed": "2510000000", "cl_type": "U512"}}}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "transform": "Identity"}, {"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "transform": "Identity"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "transform": {"AddUInt512": "89000000000"}}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "transform": "Identity"}]}, "transfers": [], "error_message": "ApiError::AuctionError(4) [64516]"}}, "block_hash": "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731"}]}`, "moduleBytes", "", "", "", "", "")
+		err = db.InsertDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", amount.FromInt64(232824230), "failure", "error_message", "2021-04-08 18:10:32.115000 +00:00", "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", "moduleBytes", `{"deploy": {"hash": "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "header": {"ttl": "1h", "account": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "body_hash": "a14865e1d6016f4c83a240a7df64a538798b2bb392d86674a4e33a59901a354b", "gas_price": 1, "timestamp": "2021-04-08T18:10:32.115Z", "chain_name": "casper-test", "dependencies": []}, "payment": {"ModuleBytes": {"args": [["amount", {"bytes": "05003ad0b814", "parsed": "89000000000", "cl_type": "U512"}]], "module_bytes": ""}}, "session": {"ModuleBytes": {"args": [["public_key", {"bytes": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "parsed": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "cl_type": "PublicKey"}], ["amount", {"bytes": "0500282e8cd1", "parsed": "900000000000", "cl_type": "U512"}], ["delegation_rate", {"bytes": "0a", "parsed": 10, "cl_type": "U8"}]], "module_bytes": ""}}, "approvals": [{"signer": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "signature": "01d911dea193709debe86db026bb2b9a43e9d0985a3a6f4cde749b55f08b83c12ac9ec7a47b8ae6e7a12cf5dbd96a0314846febf1c9fa95f5aea5a480a170bcc0e"}]}, "api_version": "1.4.7", "execution_results": [{"result": {"Failure": {"cost": "232824230", "effect": {"operations": [{"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "kind": "Read"}, {"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "kind": "Write"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "kind": "Write"}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "kind": "Read"}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "kind": "Read"}], "transforms": [{"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "transform": {"WriteCLValue": {"bytes": "04808f9b95", "parsed": "2510000000", "cl_type": "U512"}}}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "transform": "Identity"}, {"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "transform": "Identity"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "transform": {"AddUInt512": "89000000000"}}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "transform": "Identity"}]}, "transfers": [], "error_message": "ApiError::AuctionError(4) [64516]"}}, "block_hash": "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731"}]}`, "moduleBytes", "", "", "", "", "")
 		if err != nil {
 			t.Errorf("Unable to InsertDeploy : %s", err)
 		}
@@ -53,13 +55,13 @@ func TestDB(t *testing.T) {
 		}
 	})
 	t.Run("Should UpdateDeploy", func(t *testing.T) {
-		err = db.UpdateDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "232824230", "failure", "error_message", "2021-04-08 18:10:32.115000 +00:00", "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", "moduleBytes", "moduleBytes", "", "", "", "", "")
+		err = db.UpdateDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", amount.FromInt64(232824230), "failure", "error_message", "2021-04-08 18:10:32.115000 +00:00", "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", "moduleBytes", "moduleBytes", "", "", "", "", "")
 		if err != nil {
 			t.Errorf("Unable to UpdateDeploy : %s", err)
 		}
 	})
 	t.Run("Should InsertRawDeploy", func(t *testing.T) {
-		err = db.InsertRawDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", `{"deploy": {"hash": "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "header": {"ttl": "1h", "account": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "body_hash": "a14865e1d6016f4c83a240a7df64a538798b2bb392d86674a4e33a59901a354b", "gas_price": 1, "timestamp": "2021-04-08T18:10:32.115Z", "chain_name": "casper-test", "dependencies": []}, "payment": {"ModuleBytes": {"args": [["amount", {"bytes": "05003ad0b814", "parsed": "89000000000", "cl_type": "U512"}]], "module_bytes": ""}}, "session": {"ModuleBytes": {"args": [["public_key", {"bytes": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "parsed": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "cl_type": "PublicKey"}], ["amount", {"bytes": "0500282e8cd1", "parsed": "900000000000", "cl_type": "U512"}], ["delegation_rate", {"bytes": "0a", "parsed": 10, "cl_type": "U8"}]], "module_bytes": ""}}, "approvals": [{"signer": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "signature": "01d911dea193709debe86db026bb2b9a43e9d0985a3a6f4cde749b55f08b83c12ac9ec7a47b8ae6e7a12cf5dbd96a0314846febf1c9fa95f5aea5a480a170bcc0e"}]}, "api_version": "1.4.7", "execution_results": [{"result": {"Failure": {"cost": "232824230", "effect": {"operations": [{"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "kind": "Read"}, {"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "kind": "Write"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "kind": "Write"}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "kind": "Read"}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "kind": "Read"}], "transforms": [{"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "transform": {"WriteCLValue": {"bytes": "04808f9b95", "parsed": "2510000000", "cl_type": "U512"}}}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "transform": "Identity"}, {"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "transform": "Identity"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "transform": {"AddUInt512": "89000000000"}}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "transform": "Identity"}]}, "transfers": [], "error_message": "ApiError::AuctionError(4) [64516]"}}, "block_hash": "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731"}]}`)
+		err = db.InsertRawDeploy(context.Background(), "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "2021-04-08T18:10:32.115Z", `{"deploy": {"hash": "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "header": {"ttl": "1h", "account": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "body_hash": "a14865e1d6016f4c83a240a7df64a538798b2bb392d86674a4e33a59901a354b", "gas_price": 1, "timestamp": "2021-04-08T18:10:32.115Z", "chain_name": "casper-test", "dependencies": []}, "payment": {"ModuleBytes": {"args": [["amount", {"bytes": "05003ad0b814", "parsed": "89000000000", "cl_type": "U512"}]], "module_bytes": ""}}, "session": {"ModuleBytes": {"args": [["public_key", {"bytes": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "parsed": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "cl_type": "PublicKey"}], ["amount", {"bytes": "0500282e8cd1", "parsed": "900000000000", "cl_type": "U512"}], ["delegation_rate", {"bytes": "0a", "parsed": 10, "cl_type": "U8"}]], "module_bytes": ""}}, "approvals": [{"signer": "01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231", "signature": "01d911dea193709debe86db026bb2b9a43e9d0985a3a6f4cde749b55f08b83c12ac9ec7a47b8ae6e7a12cf5dbd96a0314846febf1c9fa95f5aea5a480a170bcc0e"}]}, "api_version": "1.4.7", "execution_results": [{"result": {"Failure": {"cost": "232824230", "effect": {"operations": [{"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "kind": "Read"}, {"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "kind": "Write"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "kind": "Write"}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "kind": "Read"}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "kind": "Read"}], "transforms": [{"key": "balance-2c4bac63bc01ddc6f76e2bc2bcc6af61d6efa9cd65b22785135adea57f98b24c", "transform": {"WriteCLValue": {"bytes": "04808f9b95", "parsed": "2510000000", "cl_type": "U512"}}}, {"key": "balance-98d945f5324f865243b7c02c0417ab6eac361c5c56602fd42ced834a1ba201b6", "transform": "Identity"}, {"key": "hash-8cf5e4acf51f54eb59291599187838dc3bc234089c46fc6ca8ad17e762ae4401", "transform": "Identity"}, {"key": "balance-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1", "transform": {"AddUInt512": "89000000000"}}, {"key": "hash-010c3fe81b7b862e50c77ef9a958a05bfa98444f26f96f23d37a13c96244cfb7", "transform": "Identity"}]}, "transfers": [], "error_message": "ApiError::AuctionError(4) [64516]"}}, "block_hash": "96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731"}]}`)
 		if err != nil {
 			t.Errorf("Unable to InsertRawDeploy : %s", err)
 		}
@@ -85,11 +87,44 @@ func TestDB(t *testing.T) {
 	t.Run("Should Insert Auction", func(t *testing.T) {
 		bidRow := [][]interface{}{{"publickey", "purse", 1, 1, false}}
 		delegatorRow := [][]interface{}{{"publickey", "validator", 1, "purse"}}
-		err = db.InsertAuction(context.Background(), bidRow, delegatorRow)
+		err = db.InsertAuction(context.Background(), 1, bidRow, delegatorRow)
 		if err != nil {
 			t.Errorf("Unable to Insert Auction : %s", err)
 		}
 	})
+	t.Run("Should apply the same Auction again", func(t *testing.T) {
+		bidRow := [][]interface{}{{"publickey", "purse", 1, 1, false}}
+		delegatorRow := [][]interface{}{{"publickey", "validator", 2, "purse"}}
+		err = db.InsertAuction(context.Background(), 2, bidRow, delegatorRow)
+		if err != nil {
+			t.Errorf("Unable to Insert Auction : %s", err)
+		}
+		count := 0
+		err = pool.QueryRow(context.Background(), `SELECT count(*) FROM delegators WHERE public_key = 'publickey' AND delegatee = 'validator' AND bonding_purse = 'purse';`).Scan(&count)
+		if err != nil || count != 1 {
+			t.Errorf("Bad number of delegators. Received : %d. Expected : %d", count, 1)
+		}
+	})
+	t.Run("Should move the fees of a deploy reparsed with another date", func(t *testing.T) {
+		fee := deploy.Fee{Payment: amount.FromInt64(3000000000), GasPrice: 1, Cost: amount.FromInt64(1000000000), Unused: amount.FromInt64(2000000000)}
+		err = db.InsertDeployFee(context.Background(), "feedeploy", "feefrom", "2022-01-01T00:00:00.000Z", "feecontract", fee)
+		if err != nil {
+			t.Errorf("Unable to InsertDeployFee : %s", err)
+		}
+		err = db.InsertDeployFee(context.Background(), "feedeploy", "feefrom", "2022-01-02T00:00:00.000Z", "feecontract", fee)
+		if err != nil {
+			t.Errorf("Unable to InsertDeployFee : %s", err)
+		}
+		var days, count int
+		err = pool.QueryRow(context.Background(), `SELECT count(*), sum(deploy_count) FROM fees_daily_per_account WHERE "from" = 'feefrom';`).Scan(&days, &count)
+		if err != nil || days != 1 || count != 1 {
+			t.Errorf("Bad fees of the account. Received : %d days %d deploys. Expected : %d days %d deploys", days, count, 1, 1)
+		}
+		err = pool.QueryRow(context.Background(), `SELECT count(*), sum(deploy_count) FROM fees_daily_per_contract WHERE contract_hash = 'feecontract' AND "date" = '2022-01-02';`).Scan(&days, &count)
+		if err != nil || days != 1 || count != 1 {
+			t.Errorf("Bad fees of the contract. Received : %d days %d deploys. Expected : %d days %d deploys", days, count, 1, 1)
+		}
+	})
 	t.Run("Should Insert Contract package", func(t *testing.T) {
 		err = db.InsertContractPackage(context.Background(), "packageHash", "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "from", "{}")
 		if err != nil {
@@ -97,7 +132,7 @@ func TestDB(t *testing.T) {
 		}
 	})
 	t.Run("Should Insert Contract", func(t *testing.T) {
-		err = db.InsertContract(context.Background(), "hash", "packageHash", "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "from", "contractType", 1.0, "{}")
+		err = db.InsertContract(context.Background(), "hash", "packageHash", "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", "from", "contractType", 1.0, "{}", "{}")
 		if err != nil {
 			t.Errorf("Unable to InsertContract : %s", err)
 		}
@@ -127,18 +162,18 @@ func TestDB(t *testing.T) {
 		}
 	})
 	t.Run("Should InsertPurseBalance", func(t *testing.T) {
-		err = db.InsertPurseBalance(context.Background(), "hash", "1")
+		err = db.InsertPurseBalance(context.Background(), "hash", amount.FromInt64(1))
 		if err != nil {
 			t.Errorf("Unable to InsertContract : %s", err)
 		}
 	})
 	t.Run("Should InsertRewards", func(t *testing.T) {
-		row := [][]interface{}{{"96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", 1, "dpk", "vpk", "amount"}}
-		err = db.InsertRewards(context.Background(), row)
+		row := [][]interface{}{{"96b82d76f04b36ba1a83e004e03d862568dec5618620155ca8b53177d415f731", 1, "dpk", "vpk", amount.FromInt64(1)}}
+		err = db.InsertRewards(context.Background(), 1, row)
 		if err != nil {
 			t.Errorf("Unable to InsertContract : %s", err)
 		}
-		_, err = db.Postgres.Exec(context.Background(), `DELETE FROM rewards`)
+		_, err = db.Postgres.Exec(context.Background(), `DELETE FROM rewards; DELETE FROM rewards_era_cumulative_per_validator; DELETE FROM rewards_daily_cumulative_per_validator;`)
 		if err != nil {
 			t.Errorf("%s", err)
 		}
@@ -0,0 +1,725 @@
+package db
+
+import (
+	"casperParser/types/amount"
+	"casperParser/types/block"
+	"casperParser/types/ces"
+	"casperParser/types/contract"
+	"casperParser/types/deploy"
+	"casperParser/types/deployInfo"
+	"casperParser/types/nft"
+	"casperParser/types/reward"
+	"casperParser/types/token"
+	"casperParser/types/transfer"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Row of a Memory table, indexed by column name
+type Row map[string]interface{}
+
+// Memory an in-memory Store used to unit test the task handlers without a database.
+// Each table is a map of rows indexed by the primary key of the matching Postgres table.
+type Memory struct {
+	mu     sync.Mutex
+	tables map[string]map[string]Row
+}
+
+// NewMemory return an empty memory store
+func NewMemory() *Memory {
+	return &Memory{tables: make(map[string]map[string]Row)}
+}
+
+// Row return a copy of the row of table with the given key, nil if it doesn't exist
+func (m *Memory) Row(table string, key string) Row {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.tables[table][key]
+	if !ok {
+		return nil
+	}
+	cp := make(Row, len(row))
+	for column, value := range row {
+		cp[column] = value
+	}
+	return cp
+}
+
+// Count the rows of table
+func (m *Memory) Count(table string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.tables[table])
+}
+
+// upsert the columns of a row, keeping the columns not given
+func (m *Memory) upsert(table string, key string, columns Row) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tables[table] == nil {
+		m.tables[table] = make(map[string]Row)
+	}
+	row, ok := m.tables[table][key]
+	if !ok {
+		row = make(Row)
+		m.tables[table][key] = row
+	}
+	for column, value := range columns {
+		row[column] = value
+	}
+}
+
+// InsertBlock in memory
+func (m *Memory) InsertBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool, json string) error {
+	hash = strings.ToLower(hash)
+	if err := m.InsertRawBlock(ctx, hash, json); err != nil {
+		return err
+	}
+	m.upsert("blocks", hash, Row{"era": era, "timestamp": timestamp, "height": height, "era_end": eraEnd, "validated": false})
+	return nil
+}
+
+// InsertRawBlock in memory
+func (m *Memory) InsertRawBlock(ctx context.Context, hash string, json string) error {
+	m.upsert("raw_blocks", strings.ToLower(hash), Row{"data": json})
+	return nil
+}
+
+// UpdateBlock in memory, the validation of a known block is kept
+func (m *Memory) UpdateBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool) error {
+	hash = strings.ToLower(hash)
+	row := Row{"era": era, "timestamp": timestamp, "height": height, "era_end": eraEnd}
+	if m.Row("blocks", hash) == nil {
+		row["validated"] = false
+	}
+	m.upsert("blocks", hash, row)
+	return nil
+}
+
+// InsertDeploy in memory
+func (m *Memory) InsertDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, json string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	hash = strings.ToLower(hash)
+	if err := m.InsertRawDeploy(ctx, hash, timestamp, json); err != nil {
+		return err
+	}
+	return m.UpdateDeploy(ctx, hash, from, cost, result, errorMessage, timestamp, block, deployType, metadataType, contractHash, contractName, entrypoint, metadata, events)
+}
+
+// InsertTransfer in memory
+func (m *Memory) InsertTransfer(ctx context.Context, hash string, blockHash string, deployHash string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, json string, id string) error {
+	hash = strings.ToLower(hash)
+	if err := m.InsertRawTransfer(ctx, hash, blockHash, deployHash, timestamp, json); err != nil {
+		return err
+	}
+	return m.UpdateTransfer(ctx, hash, blockHash, deployHash, timestamp, from, to, source, target, amount, gas, id)
+}
+
+// InsertDeployInfo in memory
+func (m *Memory) InsertDeployInfo(ctx context.Context, hash string, blockHash string, from string, source string, gas amount.Amount, json string, transfers string) error {
+	hash = strings.ToLower(hash)
+	if err := m.InsertRawDeployInfo(ctx, hash, blockHash, json); err != nil {
+		return err
+	}
+	return m.UpdateDeployInfo(ctx, hash, blockHash, from, source, gas, transfers)
+}
+
+// InsertAuction replace the bids and delegators in memory, the changes aren't logged
+func (m *Memory) InsertAuction(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
+	m.mu.Lock()
+	if height, ok := m.tables["auction_state"]["auction_state"]["block_height"].(int); ok && blockHeight < height {
+		m.mu.Unlock()
+		return nil
+	}
+	delete(m.tables, "bids")
+	delete(m.tables, "delegators")
+	m.mu.Unlock()
+	m.upsert("auction_state", "auction_state", Row{"block_height": blockHeight})
+	m.insertRows("bids", []string{"public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive"}, rowsToInsertBids)
+	m.insertRows("delegators", []string{"public_key", "delegatee", "staked_amount", "bonding_purse"}, rowsToInsertDelegators)
+	return nil
+}
+
+// InsertAuctionEra bids and delegators in memory, without the rollups
+func (m *Memory) InsertAuctionEra(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error {
+	m.insertRows("bids_per_era", []string{"block_height", "validator_public_key", "bonding_purse", "staked_amount", "delegation_rate", "inactive"}, rowsToInsertBids)
+	m.insertRows("delegators_per_era", []string{"block_height", "delegator_public_key", "validator_public_key", "staked_amount", "bonding_purse"}, rowsToInsertDelegators)
+	return nil
+}
+
+// UpdateDeploy in memory
+func (m *Memory) UpdateDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error {
+	m.upsert("deploys", strings.ToLower(hash), Row{"from": from, "cost": cost, "result": result, "error_message": errorMessage, "timestamp": timestamp, "block": block, "type": deployType, "metadata_type": metadataType, "contract_hash": contractHash, "contract_name": contractName, "entrypoint": entrypoint, "metadata": metadata, "events": events})
+	return nil
+}
+
+// UpdateTransfer in memory
+func (m *Memory) UpdateTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, id string) error {
+	m.upsert("transfers", strings.ToLower(hash), Row{"block": block, "deploy": deploy, "timestamp": timestamp, "from": from, "to": to, "source": source, "target": target, "amount": amount, "gas": gas, "id": id})
+	return nil
+}
+
+// UpdateDeployInfo in memory
+func (m *Memory) UpdateDeployInfo(ctx context.Context, hash string, block string, from string, source string, gas amount.Amount, transfers string) error {
+	m.upsert("deploy_infos", strings.ToLower(hash), Row{"block": block, "from": from, "source": source, "gas": gas, "transfers": transfers})
+	return nil
+}
+
+// InsertRawDeploy in memory
+func (m *Memory) InsertRawDeploy(ctx context.Context, hash string, timestamp string, json string) error {
+	m.upsert("raw_deploys", strings.ToLower(hash), Row{"timestamp": timestamp, "data": json})
+	return nil
+}
+
+// InsertRawTransfer in memory
+func (m *Memory) InsertRawTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, json string) error {
+	m.upsert("raw_transfers", strings.ToLower(hash), Row{"block": block, "deploy": deploy, "timestamp": timestamp, "data": json})
+	return nil
+}
+
+// InsertRawDeployInfo in memory
+func (m *Memory) InsertRawDeployInfo(ctx context.Context, hash string, block string, json string) error {
+	m.upsert("raw_deploy_infos", strings.ToLower(hash), Row{"block": block, "data": json})
+	return nil
+}
+
+// InsertContractPackage in memory
+func (m *Memory) InsertContractPackage(ctx context.Context, hash string, deploy string, from string, data string) error {
+	m.upsert("contract_packages", strings.ToLower(hash), Row{"deploy": deploy, "from": from, "data": data})
+	return nil
+}
+
+// InsertContract in memory
+func (m *Memory) InsertContract(ctx context.Context, hash string, packageHash string, deploy string, from string, contractType string, score float64, data string, json string) error {
+	if err := m.InsertRawContract(ctx, hash, json); err != nil {
+		return err
+	}
+	m.upsert("contracts", strings.ToLower(hash), Row{"package": packageHash, "deploy": deploy, "from": from, "type": contractType, "score": score, "data": data})
+	return nil
+}
+
+// InsertRawContract in memory
+func (m *Memory) InsertRawContract(ctx context.Context, hash string, json string) error {
+	m.upsert("raw_contracts", strings.ToLower(hash), Row{"data": json})
+	return nil
+}
+
+// UpdateContract in memory, the deploy and the sender of the contract are kept
+func (m *Memory) UpdateContract(ctx context.Context, hash string, packageHash string, contractType string, score float64, data string) error {
+	hash = strings.ToLower(hash)
+	if m.Row("contracts", hash) == nil {
+		return nil
+	}
+	m.upsert("contracts", hash, Row{"package": packageHash, "type": contractType, "score": score, "data": data})
+	return nil
+}
+
+// InsertContractClassification in memory, the rows are indexed by contract_hash:config_version
+func (m *Memory) InsertContractClassification(ctx context.Context, hash string, classification contract.Classification) error {
+	hash = strings.ToLower(hash)
+	m.upsert("contract_classifications", hash+":"+classification.ConfigVersion, Row{"type": classification.Type, "score": classification.Score, "considered": classification.Considered, "candidates": classification.Candidates})
+	if m.Row("contracts", hash) != nil {
+		m.upsert("contracts", hash, Row{"type": classification.Type, "score": classification.Score})
+	}
+	return nil
+}
+
+// InsertNamedKey in memory
+func (m *Memory) InsertNamedKey(ctx context.Context, uref string, name string, isPurse bool, initialValue string, contractHash string) error {
+	m.upsert("named_keys", uref, Row{"name": name, "is_purse": isPurse, "initial_value": initialValue})
+	m.upsert("contracts_named_keys", strings.ToLower(contractHash)+"_"+uref, Row{"contract_hash": strings.ToLower(contractHash), "named_key_uref": uref})
+	return nil
+}
+
+// InsertAccountHash in memory
+func (m *Memory) InsertAccountHash(ctx context.Context, hash string, purse string) error {
+	m.upsert("accounts", strings.ToLower(hash), Row{"account_hash": hash, "main_purse": purse})
+	return nil
+}
+
+// InsertAccount in memory
+func (m *Memory) InsertAccount(ctx context.Context, publicKey string, hash string, purse string) error {
+	m.upsert("accounts", strings.ToLower(hash), Row{"public_key": publicKey, "account_hash": hash, "main_purse": purse})
+	return nil
+}
+
+// InsertPurse in memory
+func (m *Memory) InsertPurse(ctx context.Context, hash string) error {
+	m.upsert("purses", strings.ToLower(hash), Row{})
+	return nil
+}
+
+// InsertPurseBalance in memory
+func (m *Memory) InsertPurseBalance(ctx context.Context, hash string, balance amount.Amount) error {
+	m.upsert("purses", strings.ToLower(hash), Row{"balance": balance})
+	return nil
+}
+
+// InsertPurseBalanceHistory in memory, the rows are indexed by purse:block_height:deploy_index
+func (m *Memory) InsertPurseBalanceHistory(ctx context.Context, blockHeight int, deployIndex int, deployHash string, changes []deploy.PurseBalanceChange) error {
+	for _, change := range changes {
+		key := strings.ToLower(change.Purse) + ":" + strconv.Itoa(blockHeight) + ":" + strconv.Itoa(deployIndex)
+		m.upsert("purse_balance_history", key, Row{"deploy": strings.ToLower(deployHash), "balance": change.Balance, "delta": change.Delta})
+	}
+	return nil
+}
+
+// InsertDeployFee in memory, without the daily rollups
+func (m *Memory) InsertDeployFee(ctx context.Context, deployHash string, from string, timestamp string, contractHash string, fee deploy.Fee) error {
+	m.upsert("deploy_fees", strings.ToLower(deployHash), Row{"from": from, "contract_hash": strings.ToLower(contractHash), "timestamp": timestamp, "payment_amount": fee.Payment, "gas_price": fee.GasPrice, "cost": fee.Cost, "unused": fee.Unused})
+	return nil
+}
+
+// InsertDeployApprovals in memory, replacing the ones of the deploy already stored. The rows are indexed by deploy:index
+func (m *Memory) InsertDeployApprovals(ctx context.Context, deployHash string, approvals []deploy.Approval, verification deploy.Verification) error {
+	deployHash = strings.ToLower(deployHash)
+	m.mu.Lock()
+	for key := range m.tables["deploy_approvals"] {
+		if strings.HasPrefix(key, deployHash+":") {
+			delete(m.tables["deploy_approvals"], key)
+		}
+	}
+	m.mu.Unlock()
+	for i, approval := range approvals {
+		valid := i < len(verification.Approvals) && verification.Approvals[i]
+		m.upsert("deploy_approvals", deployHash+":"+strconv.Itoa(i), Row{"signer": strings.ToLower(approval.Signer), "signature": strings.ToLower(approval.Signature), "valid": valid})
+	}
+	row := Row{"body_hash_valid": verification.BodyHashValid, "hash_valid": verification.HashValid, "approvals_valid": verification.ApprovalsValid(), "error": verification.Error}
+	if verification.Error != "" {
+		row["body_hash_valid"], row["hash_valid"] = nil, nil
+	}
+	m.upsert("deploy_verifications", deployHash, row)
+	return nil
+}
+
+// InsertTransforms in memory, replacing the ones of the deploy already stored. The rows are indexed by deploy:index
+func (m *Memory) InsertTransforms(ctx context.Context, deployHash string, transforms []deploy.Transform) error {
+	deployHash = strings.ToLower(deployHash)
+	m.mu.Lock()
+	for key := range m.tables["transforms"] {
+		if strings.HasPrefix(key, deployHash+":") {
+			delete(m.tables["transforms"], key)
+		}
+	}
+	m.mu.Unlock()
+	for i, transform := range transforms {
+		value, err := transform.StoredValue()
+		if err != nil {
+			return err
+		}
+		m.upsert("transforms", deployHash+":"+strconv.Itoa(i), Row{"key": transform.Key, "kind": transform.Kind, "value": value})
+	}
+	return nil
+}
+
+// InsertCESContract in memory
+func (m *Memory) InsertCESContract(ctx context.Context, contractHash string, eventsURef string, schemas ces.Schemas) error {
+	m.upsert("ces_contracts", strings.ToLower(contractHash), Row{"events_uref": strings.ToLower(eventsURef), "schemas": schemas})
+	return nil
+}
+
+// InsertContractEvents in memory, the rows are indexed by events_uref:event_id
+func (m *Memory) InsertContractEvents(ctx context.Context, deployHash string, events []ces.Event) error {
+	for _, event := range events {
+		key := strings.ToLower(event.EventsURef) + ":" + strconv.FormatInt(event.ID, 10)
+		m.upsert("contract_events", key, Row{"contract_hash": strings.ToLower(event.ContractHash), "deploy": strings.ToLower(deployHash), "name": event.Name, "data": event.Data})
+	}
+	return nil
+}
+
+// InsertToken in memory, the rows are indexed by package hash
+func (m *Memory) InsertToken(ctx context.Context, t token.Token) error {
+	key := strings.ToLower(t.PackageHash)
+	columns := Row{"contract_hash": strings.ToLower(t.ContractHash), "name": t.Name, "symbol": t.Symbol, "decimals": t.Decimals, "balances_uref": strings.ToLower(t.BalancesURef), "total_supply_uref": strings.ToLower(t.TotalSupplyURef)}
+	if row := m.Row("tokens", key); row == nil || row["supply_block_height"] == nil {
+		columns["total_supply"] = t.TotalSupply
+	}
+	m.upsert("tokens", key, columns)
+	return nil
+}
+
+// InsertTokenBalances in memory, the history rows are indexed by package_hash:holder:block_height:deploy_index
+// and the balances by package_hash:holder
+func (m *Memory) InsertTokenBalances(ctx context.Context, blockHeight int, deployIndex int, deployHash string, packageHash string, balances []token.Balance) error {
+	packageHash = strings.ToLower(packageHash)
+	for _, balance := range balances {
+		key := packageHash + ":" + balance.Holder
+		m.upsert("token_balance_history", key+":"+strconv.Itoa(blockHeight)+":"+strconv.Itoa(deployIndex), Row{"deploy": strings.ToLower(deployHash), "balance": balance.Balance})
+		if row := m.Row("token_balances", key); row != nil && isAfter(row["block_height"].(int), row["deploy_index"].(int), blockHeight, deployIndex) {
+			continue
+		}
+		m.upsert("token_balances", key, Row{"balance": balance.Balance, "block_height": blockHeight, "deploy_index": deployIndex})
+	}
+	return nil
+}
+
+// UpdateTokenSupply in memory
+func (m *Memory) UpdateTokenSupply(ctx context.Context, blockHeight int, deployIndex int, packageHash string, supply amount.Amount) error {
+	key := strings.ToLower(packageHash)
+	row := m.Row("tokens", key)
+	if row == nil {
+		return nil
+	}
+	if height, ok := row["supply_block_height"].(int); ok && isAfter(height, row["supply_deploy_index"].(int), blockHeight, deployIndex) {
+		return nil
+	}
+	m.upsert("tokens", key, Row{"total_supply": supply, "supply_block_height": blockHeight, "supply_deploy_index": deployIndex})
+	return nil
+}
+
+// InsertTokenTransfers in memory, replacing the ones of the deploy already stored. The rows are indexed by deploy:transfer_index
+func (m *Memory) InsertTokenTransfers(ctx context.Context, blockHeight int, deployIndex int, deployHash string, transfers []token.Transfer) error {
+	deployHash = strings.ToLower(deployHash)
+	m.mu.Lock()
+	for key := range m.tables["token_transfers"] {
+		if strings.HasPrefix(key, deployHash+":") {
+			delete(m.tables["token_transfers"], key)
+		}
+	}
+	m.mu.Unlock()
+	for i, transfer := range transfers {
+		m.upsert("token_transfers", deployHash+":"+strconv.Itoa(i), Row{"package_hash": strings.ToLower(transfer.PackageHash), "kind": transfer.Kind, "from": transfer.From, "to": transfer.To, "amount": transfer.Amount, "block_height": blockHeight, "deploy_index": deployIndex})
+	}
+	return nil
+}
+
+// InsertNFTCollection in memory, the rows are indexed by package hash
+func (m *Memory) InsertNFTCollection(ctx context.Context, c nft.Collection) error {
+	m.upsert("nft_collections", strings.ToLower(c.PackageHash), Row{"contract_hash": strings.ToLower(c.ContractHash), "standard": c.Standard, "name": c.Name, "symbol": c.Symbol})
+	return nil
+}
+
+// InsertNFTActions in memory, replacing the transfers of the deploy already stored. The transfers are indexed by
+// deploy:action_index and the tokens by package_hash:token_id
+func (m *Memory) InsertNFTActions(ctx context.Context, blockHeight int, deployIndex int, deployHash string, actions []nft.Action) error {
+	deployHash = strings.ToLower(deployHash)
+	m.mu.Lock()
+	for key := range m.tables["nft_transfers"] {
+		if strings.HasPrefix(key, deployHash+":") {
+			delete(m.tables["nft_transfers"], key)
+		}
+	}
+	m.mu.Unlock()
+	for i, action := range actions {
+		packageHash := strings.ToLower(action.PackageHash)
+		if action.ChangesOwner() {
+			m.upsert("nft_transfers", deployHash+":"+strconv.Itoa(i), Row{"package_hash": packageHash, "token_id": action.TokenID, "kind": action.Kind, "from": action.From, "to": action.To, "block_height": blockHeight, "deploy_index": deployIndex})
+		}
+		key := packageHash + ":" + action.TokenID
+		if row := m.Row("nft_tokens", key); row != nil && isAfter(row["block_height"].(int), row["deploy_index"].(int), blockHeight, deployIndex) {
+			continue
+		}
+		columns := Row{"burnt": action.Kind == nft.ActionBurn, "block_height": blockHeight, "deploy_index": deployIndex}
+		if action.To != "" {
+			columns["owner"] = action.To
+		}
+		if action.Metadata != "" {
+			columns["metadata"] = action.Metadata
+		}
+		if action.SetsApproved() {
+			columns["approved"] = action.Spender
+		}
+		m.upsert("nft_tokens", key, columns)
+	}
+	return nil
+}
+
+// isAfter check if the deploy at the first height and index comes after the second one
+func isAfter(blockHeight int, deployIndex int, otherBlockHeight int, otherDeployIndex int) bool {
+	return blockHeight > otherBlockHeight || blockHeight == otherBlockHeight && deployIndex > otherDeployIndex
+}
+
+// InsertRewards in memory, replacing the ones of the era already stored, without the rollups
+func (m *Memory) InsertRewards(ctx context.Context, era int, rowsToInsert [][]interface{}) error {
+	m.mu.Lock()
+	for key, row := range m.tables["rewards"] {
+		if row["era"] == era {
+			delete(m.tables["rewards"], key)
+		}
+	}
+	m.mu.Unlock()
+	m.insertRows("rewards", []string{"block", "era", "delegator_public_key", "validator_public_key", "amount"}, rowsToInsert)
+	return nil
+}
+
+// InsertRawEraInfo in memory
+func (m *Memory) InsertRawEraInfo(ctx context.Context, hash string, json string) error {
+	m.upsert("raw_era_infos", strings.ToLower(hash), Row{"data": json})
+	return nil
+}
+
+// insertRows like a COPY FROM, the rows are indexed by their position in the table
+func (m *Memory) insertRows(table string, columns []string, rows [][]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tables[table] == nil {
+		m.tables[table] = make(map[string]Row)
+	}
+	for _, values := range rows {
+		row := make(Row, len(columns))
+		for i, column := range columns {
+			if i < len(values) {
+				row[column] = values[i]
+			}
+		}
+		key := len(m.tables[table])
+		for m.tables[table][strconv.Itoa(key)] != nil {
+			key++
+		}
+		m.tables[table][strconv.Itoa(key)] = row
+	}
+}
+
+// GetLastBlockHeight in memory, 0 if there's no block yet
+func (m *Memory) GetLastBlockHeight(ctx context.Context) (int, error) {
+	last := 0
+	for _, height := range m.heights() {
+		if height > last {
+			last = height
+		}
+	}
+	return last, nil
+}
+
+// GetMissingBlocks between the lowest and the highest block in memory
+func (m *Memory) GetMissingBlocks(ctx context.Context) ([]int, error) {
+	heights := m.heights()
+	if len(heights) == 0 {
+		return nil, nil
+	}
+	return m.GetMissingBlocksFromHeight(ctx, heights[0])
+}
+
+// GetMissingBlocksFromHeight up to the highest block in memory
+func (m *Memory) GetMissingBlocksFromHeight(ctx context.Context, startHeight int) ([]int, error) {
+	heights := m.heights()
+	known := make(map[int]bool, len(heights))
+	for _, height := range heights {
+		known[height] = true
+	}
+	var missing []int
+	if len(heights) == 0 {
+		return missing, nil
+	}
+	for height := startHeight; height <= heights[len(heights)-1]; height++ {
+		if !known[height] {
+			missing = append(missing, height)
+		}
+	}
+	return missing, nil
+}
+
+// heights of the blocks in memory, sorted
+func (m *Memory) heights() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	heights := make([]int, 0, len(m.tables["blocks"]))
+	for _, row := range m.tables["blocks"] {
+		heights = append(heights, row["height"].(int))
+	}
+	sort.Ints(heights)
+	return heights
+}
+
+// GetMissingMetadataDeploysHash in memory
+func (m *Memory) GetMissingMetadataDeploysHash(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var missing []string
+	for hash, row := range m.tables["deploys"] {
+		if row["metadata"] == nil || row["metadata"] == "" {
+			missing = append(missing, hash)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// GetDeploy from the raw deploys in memory
+func (m *Memory) GetDeploy(ctx context.Context, hash string) (deploy.Result, error) {
+	var d deploy.Result
+	return d, m.decodeRaw("raw_deploys", hash, &d)
+}
+
+// GetTransfer from the raw transfers in memory
+func (m *Memory) GetTransfer(ctx context.Context, hash string) (transfer.Result, error) {
+	var t transfer.Result
+	return t, m.decodeRaw("raw_transfers", hash, &t)
+}
+
+// GetRawBlock from the raw blocks in memory
+func (m *Memory) GetRawBlock(ctx context.Context, hash string) (block.Result, error) {
+	var b block.Result
+	return b, m.decodeRaw("raw_blocks", hash, &b)
+}
+
+// GetDeployInfo from the raw deploy infos in memory, left empty if the rpc failed to return it
+func (m *Memory) GetDeployInfo(ctx context.Context, hash string) (deployInfo.Result, error) {
+	var d deployInfo.Result
+	row := m.Row("raw_deploy_infos", strings.ToLower(hash))
+	if row == nil || !strings.HasPrefix(row["data"].(string), "{") {
+		return d, nil
+	}
+	return d, m.decodeRaw("raw_deploy_infos", hash, &d)
+}
+
+// GetRawEraInfo from the raw era infos in memory
+func (m *Memory) GetRawEraInfo(ctx context.Context, hash string) (reward.Result, error) {
+	var r reward.Result
+	return r, m.decodeRaw("raw_era_infos", hash, &r)
+}
+
+// GetRawContract from the raw contracts in memory
+func (m *Memory) GetRawContract(ctx context.Context, hash string) (contract.Result, error) {
+	var c contract.Result
+	return c, m.decodeRaw("raw_contracts", hash, &c)
+}
+
+// GetCESContract in memory, the first contract by hash when the dictionary is shared
+func (m *Memory) GetCESContract(ctx context.Context, eventsURef string) (string, ces.Schemas, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var hashes []string
+	for hash, row := range m.tables["ces_contracts"] {
+		if row["events_uref"] == strings.ToLower(eventsURef) {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return "", nil, nil
+	}
+	sort.Strings(hashes)
+	return hashes[0], m.tables["ces_contracts"][hashes[0]]["schemas"].(ces.Schemas), nil
+}
+
+// GetToken in memory, by package hash or by the hash of one of the contracts of its package
+func (m *Memory) GetToken(ctx context.Context, hash string) (token.Token, error) {
+	hash = strings.ToLower(hash)
+	row := m.Row("tokens", hash)
+	if row == nil {
+		if contract := m.Row("contracts", hash); contract != nil {
+			hash = strings.ToLower(contract["package"].(string))
+			row = m.Row("tokens", hash)
+		}
+	}
+	if row == nil {
+		return token.Token{}, nil
+	}
+	return tokenFromRow(hash, row), nil
+}
+
+// GetTokenByURef in memory, the first token by package hash when the uref is shared
+func (m *Memory) GetTokenByURef(ctx context.Context, uref string) (token.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	uref = strings.ToLower(uref)
+	var hashes []string
+	for hash, row := range m.tables["tokens"] {
+		if row["balances_uref"] == uref || row["total_supply_uref"] == uref {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return token.Token{}, nil
+	}
+	sort.Strings(hashes)
+	return tokenFromRow(hashes[0], m.tables["tokens"][hashes[0]]), nil
+}
+
+// tokenFromRow the token of a row of the tokens table
+func tokenFromRow(packageHash string, row Row) token.Token {
+	return token.Token{
+		PackageHash:     packageHash,
+		ContractHash:    row["contract_hash"].(string),
+		Name:            row["name"].(string),
+		Symbol:          row["symbol"].(string),
+		Decimals:        row["decimals"].(int),
+		TotalSupply:     row["total_supply"].(amount.Amount),
+		BalancesURef:    row["balances_uref"].(string),
+		TotalSupplyURef: row["total_supply_uref"].(string),
+	}
+}
+
+// GetNFTCollection in memory, by package hash or by the hash of one of the contracts of its package
+func (m *Memory) GetNFTCollection(ctx context.Context, hash string) (nft.Collection, error) {
+	hash = strings.ToLower(hash)
+	row := m.Row("nft_collections", hash)
+	if row == nil {
+		if contract := m.Row("contracts", hash); contract != nil {
+			hash = strings.ToLower(contract["package"].(string))
+			row = m.Row("nft_collections", hash)
+		}
+	}
+	if row == nil {
+		return nft.Collection{}, nil
+	}
+	return nft.Collection{
+		PackageHash:  hash,
+		ContractHash: row["contract_hash"].(string),
+		Standard:     row["standard"].(string),
+		Name:         row["name"].(string),
+		Symbol:       row["symbol"].(string),
+	}, nil
+}
+
+// GetNamedKeyValues in memory, the json initial value of each named key of the contract by name
+func (m *Memory) GetNamedKeyValues(ctx context.Context, contractHash string) (map[string]string, error) {
+	contractHash = strings.ToLower(contractHash)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make(map[string]string)
+	for _, join := range m.tables["contracts_named_keys"] {
+		if join["contract_hash"] != contractHash {
+			continue
+		}
+		if namedKey, ok := m.tables["named_keys"][join["named_key_uref"].(string)]; ok {
+			values[namedKey["name"].(string)] = namedKey["initial_value"].(string)
+		}
+	}
+	return values, nil
+}
+
+// decodeRaw the json data of a raw table row, leaving v empty if the row doesn't exist like the Postgres implementation
+func (m *Memory) decodeRaw(table string, hash string, v interface{}) error {
+	row := m.Row(table, strings.ToLower(hash))
+	if row == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(row["data"].(string)), v)
+}
+
+// CountDeploys in memory
+func (m *Memory) CountDeploys(ctx context.Context, hashes []string) (int, error) {
+	return m.count("deploys", hashes), nil
+}
+
+// CountTransfers in memory
+func (m *Memory) CountTransfers(ctx context.Context, hashes []string) (int, error) {
+	return m.count("transfers", hashes), nil
+}
+
+// count the rows of table whose key is in keys
+func (m *Memory) count(table string, keys []string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, key := range keys {
+		if _, ok := m.tables[table][key]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// ValidateBlock in memory
+func (m *Memory) ValidateBlock(ctx context.Context, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if row, ok := m.tables["blocks"][hash]; ok {
+		row["validated"] = true
+	}
+	return nil
+}
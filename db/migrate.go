@@ -0,0 +1,121 @@
+package db
+
+import (
+	migrations "casperParser/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migration a migration embedded in the binary
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator apply the migrations embedded in the binary to the database
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+}
+
+// NewMigrator for the database of the connection string
+func NewMigrator(connString string) (*Migrator, error) {
+	src, err := iofs.New(migrations.Migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, connString)
+	if err != nil {
+		return nil, err
+	}
+	// The source is closed by the migrate instance, a second one is opened to list the migrations
+	list, err := iofs.New(migrations.Migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{migrate: m, source: list}, nil
+}
+
+// Up apply the n next migrations, or all the pending ones when n is 0
+func (m *Migrator) Up(n int) error {
+	var err error
+	if n > 0 {
+		err = m.migrate.Steps(n)
+	} else {
+		err = m.migrate.Up()
+	}
+	return ignoreNoChange(err)
+}
+
+// Down revert the n last applied migrations
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("the number of migrations to revert must be positive, got %d", n)
+	}
+	return ignoreNoChange(m.migrate.Steps(-n))
+}
+
+// Force set the version without running any migration, used to clean a dirty database after a failed migration
+func (m *Migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+// Version of the database. The version is 0 when no migration was applied, dirty when the last one failed.
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Status of each embedded migration, in the order they're applied
+func (m *Migrator) Status() ([]Migration, error) {
+	current, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	var status []Migration
+	version, err := m.source.First()
+	for err == nil {
+		var r io.ReadCloser
+		var name string
+		r, name, err = m.source.ReadUp(version)
+		if err != nil {
+			return nil, err
+		}
+		r.Close()
+		status = append(status, Migration{Version: version, Name: name, Applied: version <= current})
+		version, err = m.source.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Close the source and the database connection
+func (m *Migrator) Close() error {
+	m.source.Close()
+	srcErr, dbErr := m.migrate.Close()
+	if srcErr != nil {
+		return srcErr
+	}
+	return dbErr
+}
+
+// ignoreNoChange the error returned when there's no migration to apply or revert
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
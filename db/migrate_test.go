@@ -0,0 +1,23 @@
+package db
+
+import (
+	migrations "casperParser/sql"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Run("Should embed a down script for each up script", func(t *testing.T) {
+		ups, err := fs.Glob(migrations.Migrations, "*.up.sql")
+		if err != nil || len(ups) == 0 {
+			t.Fatalf("No migration embedded : %s", err)
+		}
+		for _, up := range ups {
+			down := strings.TrimSuffix(up, ".up.sql") + ".down.sql"
+			if _, err := fs.Stat(migrations.Migrations, down); err != nil {
+				t.Errorf("Missing down migration. Received : %s. Expected : %s", err, down)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The items that can be selected with a ReparseFilter
+const (
+	ReparseBlocks    = "blocks"
+	ReparseDeploys   = "deploys"
+	ReparseRewards   = "rewards"
+	ReparseAuctions  = "auctions"
+	ReparseContracts = "contracts"
+)
+
+// reparseSources select the hash, the block height and the index in the block of the items, the blocks are aliased b,
+// the deploys d and the contracts c. The contracts of the system packages have no deploy, their height is 0.
+// The index of a deploy is its position in the deploy hashes then the transfer hashes of its raw block. When the raw block
+// is missing, archived, the position isn't known and the height is 0.
+var reparseSources = map[string]string{
+	ReparseBlocks: `SELECT b.hash, b.height, 0 FROM blocks b`,
+	ReparseDeploys: `SELECT d.hash, CASE WHEN p.index IS NULL THEN 0 ELSE b.height END, COALESCE(p.index - 1, 0)
+FROM deploys d INNER JOIN blocks b ON b.hash = d.block LEFT JOIN raw_blocks rb ON rb.hash = d.block
+LEFT JOIN LATERAL (SELECT block_deploys.index FROM jsonb_array_elements_text(COALESCE(rb.data -> 'block' -> 'body' -> 'deploy_hashes', '[]') || COALESCE(rb.data -> 'block' -> 'body' -> 'transfer_hashes', '[]'))
+WITH ORDINALITY AS block_deploys(hash, index) WHERE block_deploys.hash = d.hash LIMIT 1) p ON true`,
+	ReparseRewards:   `SELECT b.hash, b.height, 0 FROM blocks b`,
+	ReparseAuctions:  `SELECT b.hash, b.height, 0 FROM blocks b`,
+	ReparseContracts: `SELECT c.hash, COALESCE(b.height, 0), 0 FROM contracts c INNER JOIN raw_contracts r ON r.hash = c.hash LEFT JOIN deploys d ON d.hash = c.deploy LEFT JOIN blocks b ON b.hash = d.block`,
+}
+
+// ReparseFilter select the items to reparse. The zero value match every item, a zero bound is open.
+// The block filters apply to every item, a contract is in the block of the deploy that wrote it.
+type ReparseFilter struct {
+	FromHeight int
+	ToHeight   int
+	FromEra    int
+	ToEra      int
+	// FromDate included, ToDate excluded. The date of a deploy is its own timestamp, the one of its block for the others
+	FromDate time.Time
+	ToDate   time.Time
+	// EraEnd only match the switch blocks, always set for the rewards and the auctions
+	EraEnd bool
+	// Type only match the deploys of this type
+	Type string
+	// ExceptType only match the deploys not of this type
+	ExceptType string
+	// MetadataType only match the deploys with this metadata type
+	MetadataType string
+	// ContractHash only match the deploys calling this contract, or this contract
+	ContractHash string
+	// Account only match the deploys or the contracts sent by this public key
+	Account string
+	// Result only match the deploys with this result, success or failure
+	Result string
+}
+
+// ReparseItem an item selected by a ReparseFilter
+type ReparseItem struct {
+	Hash   string
+	Height int
+	// Index of a deploy in its block
+	Index int
+}
+
+// reparseCondition of a filter, with the column it applies to for each item. A filter can't be set on the other items.
+type reparseCondition struct {
+	name    string
+	set     bool
+	op      string
+	value   interface{}
+	columns map[string]string
+}
+
+// blockColumn the column of the blocks for every item
+func blockColumn(column string) map[string]string {
+	columns := make(map[string]string, len(reparseSources))
+	for item := range reparseSources {
+		columns[item] = `b.` + column
+	}
+	return columns
+}
+
+// where clause of the filter for the item
+func (f ReparseFilter) where(item string) (string, []interface{}, error) {
+	if _, ok := reparseSources[item]; !ok {
+		return "", nil, fmt.Errorf("unknown item %s to reparse", item)
+	}
+	dateColumns := blockColumn(`"timestamp"`)
+	dateColumns[ReparseDeploys] = `d."timestamp"`
+	conditions := []reparseCondition{
+		{"from-height", f.FromHeight > 0, `>=`, f.FromHeight, blockColumn(`height`)},
+		{"to-height", f.ToHeight > 0, `<=`, f.ToHeight, blockColumn(`height`)},
+		{"from-era", f.FromEra > 0, `>=`, f.FromEra, blockColumn(`era`)},
+		{"to-era", f.ToEra > 0, `<=`, f.ToEra, blockColumn(`era`)},
+		{"from-date", !f.FromDate.IsZero(), `>=`, f.FromDate, dateColumns},
+		{"to-date", !f.ToDate.IsZero(), `<`, f.ToDate, dateColumns},
+		{"era-end", f.EraEnd || item == ReparseRewards || item == ReparseAuctions, `=`, true, blockColumn(`era_end`)},
+		{"type", f.Type != "", `=`, f.Type, map[string]string{ReparseDeploys: `d.type`}},
+		{"except-type", f.ExceptType != "", `!=`, f.ExceptType, map[string]string{ReparseDeploys: `d.type`}},
+		{"metadata-type", f.MetadataType != "", `=`, f.MetadataType, map[string]string{ReparseDeploys: `d.metadata_type`}},
+		{"contract-hash", f.ContractHash != "", `=`, strings.ToLower(f.ContractHash), map[string]string{ReparseDeploys: `d.contract_hash`, ReparseContracts: `c.hash`}},
+		{"account", f.Account != "", `=`, strings.ToLower(f.Account), map[string]string{ReparseDeploys: `LOWER(d."from")`, ReparseContracts: `LOWER(c."from")`}},
+		{"result", f.Result != "", `=`, f.Result, map[string]string{ReparseDeploys: `d.result`}},
+	}
+	var clauses []string
+	var args []interface{}
+	for _, condition := range conditions {
+		if !condition.set {
+			continue
+		}
+		column, ok := condition.columns[item]
+		if !ok {
+			return "", nil, fmt.Errorf("the %s filter doesn't apply to the %s", condition.name, item)
+		}
+		args = append(args, condition.value)
+		clauses = append(clauses, column+` `+condition.op+` $`+strconv.Itoa(len(args)))
+	}
+	if len(clauses) == 0 {
+		return "", nil, nil
+	}
+	return ` WHERE ` + strings.Join(clauses, ` AND `), args, nil
+}
+
+// ForEachReparse call fn with every item matching the filter
+func (db *DB) ForEachReparse(ctx context.Context, item string, filter ReparseFilter, fn func(ReparseItem) error) error {
+	ctx, span := startOperation(ctx, "ForEachReparse")
+	defer span.End()
+	where, args, err := filter.where(item)
+	if err != nil {
+		return err
+	}
+	rows, err := db.Postgres.Query(ctx, reparseSources[item]+where+`;`, args...)
+	if err != nil {
+		return db.checkErr(ctx, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reparseItem ReparseItem
+		if err := rows.Scan(&reparseItem.Hash, &reparseItem.Height, &reparseItem.Index); err != nil {
+			return db.checkErr(ctx, err)
+		}
+		if err := fn(reparseItem); err != nil {
+			return err
+		}
+	}
+	return db.checkErr(ctx, rows.Err())
+}
+
+// CountReparse the items matching the filter
+func (db *DB) CountReparse(ctx context.Context, item string, filter ReparseFilter) (int, error) {
+	ctx, span := startOperation(ctx, "CountReparse")
+	defer span.End()
+	where, args, err := filter.where(item)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = db.Postgres.QueryRow(ctx, `SELECT count(*) FROM (`+reparseSources[item]+where+`) items;`, args...).Scan(&count)
+	return count, db.checkErr(ctx, err)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReparseFilter(t *testing.T) {
+	t.Run("Should match every item without filter", func(t *testing.T) {
+		where, args, err := ReparseFilter{}.where(ReparseBlocks)
+		if err != nil || where != "" || len(args) != 0 {
+			t.Errorf("Bad where clause. Received : %s %v %v. Expected : an empty clause", where, args, err)
+		}
+	})
+
+	t.Run("Should combine the filters", func(t *testing.T) {
+		filter := ReparseFilter{FromHeight: 10, ToHeight: 20, FromDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), MetadataType: "cep47", Result: "success"}
+		where, args, err := filter.where(ReparseDeploys)
+		if err != nil {
+			t.Fatalf("Unable to build the where clause : %s", err)
+		}
+		expected := ` WHERE b.height >= $1 AND b.height <= $2 AND d."timestamp" >= $3 AND d.metadata_type = $4 AND d.result = $5`
+		if where != expected {
+			t.Errorf("Bad where clause. Received : %s. Expected : %s", where, expected)
+		}
+		if len(args) != 5 {
+			t.Errorf("Bad number of arguments. Received : %d. Expected : %d", len(args), 5)
+		}
+	})
+
+	t.Run("Should only select the switch blocks for the rewards", func(t *testing.T) {
+		where, _, err := ReparseFilter{}.where(ReparseRewards)
+		expected := ` WHERE b.era_end = $1`
+		if err != nil || where != expected {
+			t.Errorf("Bad where clause. Received : %s. Expected : %s", where, expected)
+		}
+	})
+
+	t.Run("Should lower the contract hash and the account", func(t *testing.T) {
+		_, args, err := ReparseFilter{ContractHash: "ABC", Account: "01DEF"}.where(ReparseContracts)
+		if err != nil || args[0] != "abc" || args[1] != "01def" {
+			t.Errorf("Bad arguments. Received : %v %v. Expected : [abc 01def]", args, err)
+		}
+	})
+
+	t.Run("Should reject a deploy filter on the blocks", func(t *testing.T) {
+		_, _, err := ReparseFilter{Type: "transfer"}.where(ReparseBlocks)
+		if err == nil {
+			t.Errorf("Should have thrown an error")
+		}
+	})
+}
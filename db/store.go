@@ -0,0 +1,92 @@
+package db
+
+import (
+	"casperParser/types/amount"
+	"casperParser/types/block"
+	"casperParser/types/ces"
+	"casperParser/types/contract"
+	"casperParser/types/deploy"
+	"casperParser/types/deployInfo"
+	"casperParser/types/nft"
+	"casperParser/types/reward"
+	"casperParser/types/token"
+	"casperParser/types/transfer"
+	"context"
+)
+
+// Store every read and write done by the task handlers. DB is the Postgres implementation, Memory an in-memory one for the unit tests.
+type Store interface {
+	InsertBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool, json string) error
+	InsertRawBlock(ctx context.Context, hash string, json string) error
+	UpdateBlock(ctx context.Context, hash string, era int, timestamp string, height int, eraEnd bool) error
+	InsertDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, json string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error
+	InsertTransfer(ctx context.Context, hash string, blockHash string, deployHash string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, json string, id string) error
+	InsertDeployInfo(ctx context.Context, hash string, blockHash string, from string, source string, gas amount.Amount, json string, transfers string) error
+	InsertAuction(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error
+	InsertAuctionEra(ctx context.Context, blockHeight int, rowsToInsertBids [][]interface{}, rowsToInsertDelegators [][]interface{}) error
+	UpdateDeploy(ctx context.Context, hash string, from string, cost amount.Amount, result string, errorMessage string, timestamp string, block string, deployType string, metadataType string, contractHash string, contractName string, entrypoint string, metadata string, events string) error
+	UpdateTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, from string, to string, source string, target string, amount amount.Amount, gas amount.Amount, id string) error
+	UpdateDeployInfo(ctx context.Context, hash string, block string, from string, source string, gas amount.Amount, transfers string) error
+	InsertRawDeploy(ctx context.Context, hash string, timestamp string, json string) error
+	InsertRawTransfer(ctx context.Context, hash string, block string, deploy string, timestamp string, json string) error
+	InsertRawDeployInfo(ctx context.Context, hash string, block string, json string) error
+	InsertContractPackage(ctx context.Context, hash string, deploy string, from string, data string) error
+	InsertContract(ctx context.Context, hash string, packageHash string, deploy string, from string, contractType string, score float64, data string, json string) error
+	InsertRawContract(ctx context.Context, hash string, json string) error
+	UpdateContract(ctx context.Context, hash string, packageHash string, contractType string, score float64, data string) error
+	InsertContractClassification(ctx context.Context, hash string, classification contract.Classification) error
+	InsertNamedKey(ctx context.Context, uref string, name string, isPurse bool, initialValue string, contractHash string) error
+	InsertAccountHash(ctx context.Context, hash string, purse string) error
+	InsertAccount(ctx context.Context, publicKey string, hash string, purse string) error
+	InsertPurse(ctx context.Context, hash string) error
+	InsertPurseBalance(ctx context.Context, hash string, balance amount.Amount) error
+	InsertRewards(ctx context.Context, era int, rowsToInsert [][]interface{}) error
+	InsertRawEraInfo(ctx context.Context, hash string, json string) error
+	InsertPurseBalanceHistory(ctx context.Context, blockHeight int, deployIndex int, deployHash string, changes []deploy.PurseBalanceChange) error
+	InsertDeployFee(ctx context.Context, deployHash string, from string, timestamp string, contractHash string, fee deploy.Fee) error
+	InsertDeployApprovals(ctx context.Context, deployHash string, approvals []deploy.Approval, verification deploy.Verification) error
+	InsertTransforms(ctx context.Context, deployHash string, transforms []deploy.Transform) error
+	InsertCESContract(ctx context.Context, contractHash string, eventsURef string, schemas ces.Schemas) error
+	InsertContractEvents(ctx context.Context, deployHash string, events []ces.Event) error
+	InsertToken(ctx context.Context, t token.Token) error
+	InsertTokenBalances(ctx context.Context, blockHeight int, deployIndex int, deployHash string, packageHash string, balances []token.Balance) error
+	UpdateTokenSupply(ctx context.Context, blockHeight int, deployIndex int, packageHash string, supply amount.Amount) error
+	InsertTokenTransfers(ctx context.Context, blockHeight int, deployIndex int, deployHash string, transfers []token.Transfer) error
+	InsertNFTCollection(ctx context.Context, c nft.Collection) error
+	InsertNFTActions(ctx context.Context, blockHeight int, deployIndex int, deployHash string, actions []nft.Action) error
+	GetLastBlockHeight(ctx context.Context) (int, error)
+	GetMissingBlocks(ctx context.Context) ([]int, error)
+	GetMissingBlocksFromHeight(ctx context.Context, startHeight int) ([]int, error)
+	GetMissingMetadataDeploysHash(ctx context.Context) ([]string, error)
+	GetDeploy(ctx context.Context, hash string) (deploy.Result, error)
+	GetTransfer(ctx context.Context, hash string) (transfer.Result, error)
+	GetRawBlock(ctx context.Context, hash string) (block.Result, error)
+	GetDeployInfo(ctx context.Context, hash string) (deployInfo.Result, error)
+	GetRawEraInfo(ctx context.Context, hash string) (reward.Result, error)
+	GetRawContract(ctx context.Context, hash string) (contract.Result, error)
+	GetCESContract(ctx context.Context, eventsURef string) (string, ces.Schemas, error)
+	GetToken(ctx context.Context, hash string) (token.Token, error)
+	GetTokenByURef(ctx context.Context, uref string) (token.Token, error)
+	GetNFTCollection(ctx context.Context, hash string) (nft.Collection, error)
+	GetNamedKeyValues(ctx context.Context, contractHash string) (map[string]string, error)
+	CountDeploys(ctx context.Context, hashes []string) (int, error)
+	CountTransfers(ctx context.Context, hashes []string) (int, error)
+	ValidateBlock(ctx context.Context, hash string) error
+}
+
+var _ Store = (*DB)(nil)
+var _ Store = (*Memory)(nil)
+
+// JobStore the scans and the maintenance run by the scheduled jobs. Only DB implements it.
+type JobStore interface {
+	GetMissingBlocks(ctx context.Context) ([]int, error)
+	ForEachUnvalidatedBlockHash(ctx context.Context, fn func(hash string) error) error
+	ForEachMissingPublicKey(ctx context.Context, fn func(publicKey string) error) error
+	ForEachMissingAccountHash(ctx context.Context, fn func(hash string) error) error
+	ForEachMissingPurse(ctx context.Context, fn func(uref string) error) error
+	ForEachPurse(ctx context.Context, fn func(uref string) error) error
+	RefreshMaterializedViews(ctx context.Context) error
+	CreatePartitions(ctx context.Context, monthsAhead int) (int, error)
+}
+
+var _ JobStore = (*DB)(nil)
@@ -0,0 +1,99 @@
+// Package logger setup the structured logger shared by every package and carry the task fields through the context
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names attached to the log entries. Always use those constants so the log pipeline can filter on them.
+const (
+	FieldTask        = "task"
+	FieldTaskID      = "task_id"
+	FieldQueue       = "queue"
+	FieldBlockHeight = "block_height"
+	FieldBlockHash   = "block_hash"
+	FieldDeployHash  = "deploy_hash"
+	FieldRpcMethod   = "rpc_method"
+	FieldDBOperation = "db_operation"
+	FieldTraceID     = "trace_id"
+	FieldDuration    = "duration"
+)
+
+type contextKey struct{}
+
+// Init the standard logger with a level (trace, debug, info, warn, error, fatal, panic) and a format (text or json)
+func Init(level string, format string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(lvl)
+	log.SetOutput(os.Stderr)
+	switch format {
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	case "text":
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("unknown log format %s. Supported formats : text, json", format)
+	}
+	return nil
+}
+
+// WithEntry return a copy of ctx carrying the entry
+func WithEntry(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, entry)
+}
+
+// WithFields return a copy of ctx whose entry carries the fields
+func WithFields(ctx context.Context, fields log.Fields) context.Context {
+	return WithEntry(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext return the entry carried by ctx, or a fresh entry of the standard logger.
+// The trace id of the current span is added when there's one.
+func FromContext(ctx context.Context) *log.Entry {
+	entry, ok := ctx.Value(contextKey{}).(*log.Entry)
+	if !ok {
+		entry = log.NewEntry(log.StandardLogger())
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		entry = entry.WithField(FieldTraceID, spanContext.TraceID().String())
+	}
+	return entry.WithContext(ctx)
+}
+
+// Middleware attach the task type and id to the context logger and log the outcome of every task.
+// A task failing once its context is done stopped with the worker, it isn't logged as an error.
+func Middleware(h asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		fields := log.Fields{FieldTask: t.Type()}
+		if id, ok := asynq.GetTaskID(ctx); ok {
+			fields[FieldTaskID] = id
+		}
+		if queue, ok := asynq.GetQueueName(ctx); ok {
+			fields[FieldQueue] = queue
+		}
+		ctx = WithEntry(ctx, log.WithFields(fields))
+		start := time.Now()
+		err := h.ProcessTask(ctx, t)
+		entry := FromContext(ctx).WithField(FieldDuration, time.Since(start).String())
+		if err != nil && ctx.Err() != nil {
+			entry.WithError(err).Info("task stopped")
+			return err
+		}
+		if err != nil {
+			entry.WithError(err).Error("task failed")
+			return err
+		}
+		entry.Debug("task processed")
+		return nil
+	})
+}
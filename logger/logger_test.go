@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("Should refuse an unknown level", func(t *testing.T) {
+		if err := Init("verbose", "text"); err == nil {
+			t.Errorf("Init should fail with an unknown level")
+		}
+	})
+	t.Run("Should refuse an unknown format", func(t *testing.T) {
+		if err := Init("info", "xml"); err == nil {
+			t.Errorf("Init should fail with an unknown format")
+		}
+	})
+	t.Run("Should set the level", func(t *testing.T) {
+		if err := Init("debug", "json"); err != nil || log.GetLevel() != log.DebugLevel {
+			t.Errorf("Bad level set. Received : %s. Expected : %s", log.GetLevel(), log.DebugLevel)
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Init("info", "json"); err != nil {
+		t.Fatal(err)
+	}
+	log.SetOutput(&buf)
+	handler := Middleware(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		FromContext(WithFields(ctx, log.Fields{FieldDeployHash: "abc"})).Info("inside")
+		return errors.New("boom")
+	}))
+	_ = handler.ProcessTask(context.Background(), asynq.NewTask("deploy:raw", nil))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Bad number of log lines. Received : %d. Expected : %d", len(lines), 2)
+	}
+	var inside, failed map[string]interface{}
+	_ = json.Unmarshal(lines[0], &inside)
+	_ = json.Unmarshal(lines[1], &failed)
+	t.Run("Should attach the task type and the handler fields", func(t *testing.T) {
+		if inside[FieldTask] != "deploy:raw" || inside[FieldDeployHash] != "abc" {
+			t.Errorf("Bad fields. Received : %s", lines[0])
+		}
+	})
+	t.Run("Should log the task failure", func(t *testing.T) {
+		if failed["level"] != "error" || failed["error"] != "boom" || failed[FieldTask] != "deploy:raw" {
+			t.Errorf("Bad failure entry. Received : %s", lines[1])
+		}
+	})
+	t.Run("Should not log a task stopped with the worker as failed", func(t *testing.T) {
+		buf.Reset()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = handler.ProcessTask(ctx, asynq.NewTask("deploy:raw", nil))
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		var stopped map[string]interface{}
+		_ = json.Unmarshal(lines[len(lines)-1], &stopped)
+		if stopped["level"] != "info" || stopped["msg"] != "task stopped" {
+			t.Errorf("Bad stop entry. Received : %s", lines[len(lines)-1])
+		}
+	})
+}
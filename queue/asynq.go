@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// Asynq backend, the tasks go through Redis. This is the default backend.
+type Asynq struct {
+	redis  asynq.RedisConnOpt
+	queues map[string]int
+	client *asynq.Client
+}
+
+// NewAsynq return an asynq backend serving the queues with their priority
+func NewAsynq(redis asynq.RedisConnOpt, queues map[string]int) *Asynq {
+	return &Asynq{
+		redis:  redis,
+		queues: queues,
+		client: asynq.NewClient(redis),
+	}
+}
+
+// Enqueue a task in Redis
+func (a *Asynq) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return a.client.Enqueue(task, opts...)
+}
+
+// Run an asynq server until ctx is done
+func (a *Asynq) Run(ctx context.Context, h asynq.Handler, concurrency int) error {
+	srv := asynq.NewServer(a.redis, asynq.Config{
+		Concurrency: concurrency,
+		Queues:      a.queues,
+	})
+	if err := srv.Start(h); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	srv.Shutdown()
+	return nil
+}
+
+// Close the Redis client
+func (a *Asynq) Close() error {
+	return a.client.Close()
+}
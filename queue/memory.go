@@ -0,0 +1,195 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrClosed returned when a task is enqueued after the memory queue stopped
+var ErrClosed = errors.New("queue closed")
+
+// Memory an in-process queue with asynq like weighted priorities and retries. Used to run the client and the workers in a single process without Redis.
+type Memory struct {
+	// RetryDelayFunc delay before retrying a failed task, asynq.DefaultRetryDelayFunc by default
+	RetryDelayFunc asynq.RetryDelayFunc
+
+	mu         sync.Mutex
+	notEmpty   *sync.Cond
+	notFull    *sync.Cond
+	priorities map[string]int
+	queues     map[string][]*message
+	pending    int
+	capacity   int
+	closed     bool
+	nextID     uint64
+}
+
+type message struct {
+	id       string
+	queue    string
+	task     *asynq.Task
+	maxRetry int
+	retried  int
+}
+
+// NewMemory return a memory queue serving the queues with their priority. Producers are blocked above capacity pending tasks.
+func NewMemory(priorities map[string]int, capacity int) *Memory {
+	m := &Memory{
+		RetryDelayFunc: asynq.DefaultRetryDelayFunc,
+		priorities:     priorities,
+		queues:         make(map[string][]*message),
+		capacity:       capacity,
+	}
+	m.notEmpty = sync.NewCond(&m.mu)
+	m.notFull = sync.NewCond(&m.mu)
+	return m
+}
+
+// Enqueue a task without blocking. Used by the handlers so a full queue can never block the workers that drain it.
+func (m *Memory) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return m.enqueue(task, false, opts)
+}
+
+// Producer return a client whose Enqueue block while the queue hold capacity pending tasks
+func (m *Memory) Producer() Client {
+	return producer{m}
+}
+
+// Len number of pending tasks
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pending
+}
+
+// Run process the tasks with concurrency workers until ctx is done
+func (m *Memory) Run(ctx context.Context, h asynq.Handler, concurrency int) error {
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		m.closed = true
+		m.mu.Unlock()
+		m.notEmpty.Broadcast()
+		m.notFull.Broadcast()
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, ok := m.pop()
+				if !ok {
+					return
+				}
+				m.process(ctx, h, msg)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close nothing to release, the queue stop with the ctx given to Run
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) enqueue(task *asynq.Task, wait bool, opts []asynq.Option) (*asynq.TaskInfo, error) {
+	o := parseOptions(opts)
+	if priority, ok := m.priorities[o.queue]; !ok || priority <= 0 {
+		return nil, fmt.Errorf("queue %s is not served", o.queue)
+	}
+	m.mu.Lock()
+	for wait && !m.closed && m.pending >= m.capacity {
+		m.notFull.Wait()
+	}
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	m.nextID++
+	msg := &message{id: strconv.FormatUint(m.nextID, 10), queue: o.queue, task: task, maxRetry: o.maxRetry}
+	m.mu.Unlock()
+
+	info := &asynq.TaskInfo{ID: msg.id, Queue: msg.queue, Type: task.Type(), Payload: task.Payload(), MaxRetry: msg.maxRetry, State: asynq.TaskStatePending}
+	if o.processIn > 0 {
+		info.State = asynq.TaskStateScheduled
+		info.NextProcessAt = time.Now().Add(o.processIn)
+		time.AfterFunc(o.processIn, func() { m.push(msg) })
+		return info, nil
+	}
+	m.push(msg)
+	return info, nil
+}
+
+// push a message at the end of its queue
+func (m *Memory) push(msg *message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.queues[msg.queue] = append(m.queues[msg.queue], msg)
+	m.pending++
+	m.notEmpty.Signal()
+}
+
+// pop the next message, picking a non-empty queue at random weighted by its priority like the asynq server does
+func (m *Memory) pop() (*message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for !m.closed && m.pending == 0 {
+		m.notEmpty.Wait()
+	}
+	if m.closed {
+		return nil, false
+	}
+	total := 0
+	for name, queue := range m.queues {
+		if len(queue) > 0 {
+			total += m.priorities[name]
+		}
+	}
+	pick := rand.Intn(total)
+	for name, queue := range m.queues {
+		if len(queue) == 0 {
+			continue
+		}
+		pick -= m.priorities[name]
+		if pick < 0 {
+			m.queues[name] = queue[1:]
+			m.pending--
+			m.notFull.Signal()
+			return queue[0], true
+		}
+	}
+	return nil, false
+}
+
+// process a message and schedule a retry when the handler fails
+func (m *Memory) process(ctx context.Context, h asynq.Handler, msg *message) {
+	err := h.ProcessTask(ctx, msg.task)
+	if err == nil || errors.Is(err, asynq.SkipRetry) || msg.retried >= msg.maxRetry || ctx.Err() != nil {
+		return
+	}
+	msg.retried++
+	time.AfterFunc(m.RetryDelayFunc(msg.retried, err, msg.task), func() { m.push(msg) })
+}
+
+// producer block the enqueue above the memory queue capacity
+type producer struct {
+	m *Memory
+}
+
+// Enqueue a task, waiting for the queue to have room
+func (p producer) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return p.m.enqueue(task, true, opts)
+}
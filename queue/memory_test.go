@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestMemory_Run(t *testing.T) {
+	t.Run("Should process every task enqueued", func(t *testing.T) {
+		m := NewMemory(map[string]int{"blocks": 1, "deploys": 3}, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		var mu sync.Mutex
+		processed := map[string]int{}
+		handler := asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed[task.Type()]++
+			if task.Type() == "block:raw" {
+				_, err := m.Enqueue(asynq.NewTask("deploy:raw", nil), asynq.Queue("deploys"))
+				return err
+			}
+			if processed["deploy:raw"] == 5 {
+				cancel()
+			}
+			return nil
+		})
+		for i := 0; i < 5; i++ {
+			_, err := m.Producer().Enqueue(asynq.NewTask("block:raw", nil), asynq.Queue("blocks"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		m.Run(ctx, handler, 2)
+		if processed["block:raw"] != 5 || processed["deploy:raw"] != 5 {
+			t.Errorf("Bad number of tasks processed. Received : %v. Expected : 5 blocks and 5 deploys", processed)
+		}
+	})
+	t.Run("Should retry a failed task", func(t *testing.T) {
+		m := NewMemory(map[string]int{"blocks": 1}, 10)
+		m.RetryDelayFunc = func(int, error, *asynq.Task) time.Duration { return 0 }
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		attempts := 0
+		handler := asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("rpc unavailable")
+			}
+			cancel()
+			return nil
+		})
+		_, _ = m.Enqueue(asynq.NewTask("block:raw", nil), asynq.Queue("blocks"), asynq.MaxRetry(1))
+		m.Run(ctx, handler, 1)
+		if attempts != 2 {
+			t.Errorf("Bad number of attempts. Received : %d. Expected : %d", attempts, 2)
+		}
+	})
+}
+
+func TestMemory_Enqueue(t *testing.T) {
+	t.Run("Should refuse a queue not served", func(t *testing.T) {
+		m := NewMemory(map[string]int{"blocks": 1}, 10)
+		if _, err := m.Enqueue(asynq.NewTask("deploy:raw", nil), asynq.Queue("deploys")); err == nil {
+			t.Errorf("Enqueue should fail on a queue not served")
+		}
+	})
+	t.Run("Should block the producer when the queue is full", func(t *testing.T) {
+		m := NewMemory(map[string]int{"blocks": 1}, 1)
+		_, _ = m.Producer().Enqueue(asynq.NewTask("block:raw", nil), asynq.Queue("blocks"))
+		done := make(chan struct{})
+		go func() {
+			_, _ = m.Producer().Enqueue(asynq.NewTask("block:raw", nil), asynq.Queue("blocks"))
+			close(done)
+		}()
+		select {
+		case <-done:
+			t.Errorf("Producer should wait for room in the queue")
+		case <-time.After(50 * time.Millisecond):
+		}
+		if _, ok := m.pop(); !ok {
+			t.Fatal("Queue should not be empty")
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("Producer should be released once a task is dequeued")
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"casperParser/logger"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Postgres backend, the tasks are stored in the task_queue table and claimed with SELECT ... FOR UPDATE SKIP LOCKED.
+// A claimed task is hidden for the lease duration, so the task of a crashed worker is processed again once its lease expire.
+// The asynq.TaskID option is used as a dedup key : a task can't be enqueued while another one with the same id is waiting or running,
+// the key of an archived task can be used again.
+type Postgres struct {
+	// PollInterval delay between two claims when the queues are empty, 1 second by default
+	PollInterval time.Duration
+	// Lease time given to a worker to process a task before it's claimed again, 30 minutes by default like the asynq timeout
+	Lease time.Duration
+	// RetryDelayFunc delay before retrying a failed task, asynq.DefaultRetryDelayFunc by default
+	RetryDelayFunc asynq.RetryDelayFunc
+
+	pool   *pgxpool.Pool
+	queues map[string]int
+}
+
+// NewPostgres return a postgres backend serving the queues with their priority. The backend close the pool when closed.
+func NewPostgres(pool *pgxpool.Pool, queues map[string]int) *Postgres {
+	return &Postgres{
+		PollInterval:   time.Second,
+		Lease:          30 * time.Minute,
+		RetryDelayFunc: asynq.DefaultRetryDelayFunc,
+		pool:           pool,
+		queues:         queues,
+	}
+}
+
+// Enqueue a task in the task_queue table. Return asynq.ErrTaskIDConflict if the dedup key is used by a task not archived.
+func (p *Postgres) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	const sql = `INSERT INTO task_queue (queue, type, payload, dedup_key, max_retry, process_at)
+VALUES ($1, $2, $3, $4, $5, now() + make_interval(secs => $6))
+ON CONFLICT (dedup_key) WHERE NOT archived DO NOTHING
+RETURNING id, process_at;`
+	o := parseOptions(opts)
+	var dedupKey *string
+	if o.taskID != "" {
+		dedupKey = &o.taskID
+	}
+	var id int64
+	var processAt time.Time
+	err := p.pool.QueryRow(context.Background(), sql, o.queue, task.Type(), task.Payload(), dedupKey, o.maxRetry, o.processIn.Seconds()).Scan(&id, &processAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, asynq.ErrTaskIDConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &asynq.TaskInfo{ID: strconv.FormatInt(id, 10), Queue: o.queue, Type: task.Type(), Payload: task.Payload(), MaxRetry: o.maxRetry, State: asynq.TaskStatePending}
+	if dedupKey != nil {
+		info.ID = o.taskID
+	}
+	if o.processIn > 0 {
+		info.State = asynq.TaskStateScheduled
+		info.NextProcessAt = processAt
+	}
+	return info, nil
+}
+
+// Run claim and process the tasks of the served queues until ctx is done
+func (p *Postgres) Run(ctx context.Context, h asynq.Handler, concurrency int) error {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				claimed, err := p.claim(ctx)
+				if err != nil && ctx.Err() == nil {
+					logger.FromContext(ctx).WithError(err).Error("can't claim a task")
+				}
+				if err != nil || claimed == nil {
+					select {
+					case <-ctx.Done():
+					case <-time.After(p.PollInterval):
+					}
+					continue
+				}
+				p.process(ctx, h, claimed)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close the pool of the backend
+func (p *Postgres) Close() error {
+	p.pool.Close()
+	return nil
+}
+
+type claimedTask struct {
+	id       int64
+	task     *asynq.Task
+	retried  int
+	maxRetry int
+}
+
+// claim the next task, trying the queues in a random order weighted by their priority like the asynq server does
+func (p *Postgres) claim(ctx context.Context) (*claimedTask, error) {
+	const sql = `UPDATE task_queue SET process_at = now() + make_interval(secs => $2)
+WHERE id = (
+    SELECT id FROM task_queue
+    WHERE queue = $1 AND NOT archived AND process_at <= now()
+    ORDER BY process_at, id
+    FOR UPDATE SKIP LOCKED
+    LIMIT 1
+)
+RETURNING id, type, payload, retried, max_retry;`
+	for _, queue := range weightedOrder(p.queues) {
+		var c claimedTask
+		var taskType string
+		var payload []byte
+		err := p.pool.QueryRow(ctx, sql, queue, p.Lease.Seconds()).Scan(&c.id, &taskType, &payload, &c.retried, &c.maxRetry)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.task = asynq.NewTask(taskType, payload)
+		return &c, nil
+	}
+	return nil, nil
+}
+
+// process a claimed task. The task is deleted on success, delayed on failure and archived once it has no retry left.
+func (p *Postgres) process(ctx context.Context, h asynq.Handler, c *claimedTask) {
+	const deleteSql = `DELETE FROM task_queue WHERE id = $1;`
+	const retrySql = `UPDATE task_queue SET retried = retried + 1, process_at = now() + make_interval(secs => $2), last_error = $3 WHERE id = $1;`
+	const archiveSql = `UPDATE task_queue SET archived = true, last_error = $2 WHERE id = $1;`
+	err := h.ProcessTask(ctx, c.task)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by the shutdown, the task will be claimed again once its lease expire
+		return
+	}
+	// The handler ctx may be done, the outcome is saved with a fresh one
+	saveCtx := context.Background()
+	var saveErr error
+	switch {
+	case err == nil:
+		_, saveErr = p.pool.Exec(saveCtx, deleteSql, c.id)
+	case errors.Is(err, asynq.SkipRetry) || c.retried >= c.maxRetry:
+		_, saveErr = p.pool.Exec(saveCtx, archiveSql, c.id, err.Error())
+	default:
+		_, saveErr = p.pool.Exec(saveCtx, retrySql, c.id, p.RetryDelayFunc(c.retried+1, err, c.task).Seconds(), err.Error())
+	}
+	if saveErr != nil {
+		logger.FromContext(ctx).WithError(saveErr).WithField(logger.FieldTask, c.task.Type()).Error("can't save the task outcome")
+	}
+}
@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"casperParser/db"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestPostgres(t *testing.T) {
+	dbconstring := os.Getenv("CASPER_PARSER_DATABASE")
+	pool, err := db.NewPGXPool(context.Background(), dbconstring, 10)
+	if err != nil {
+		t.Fatalf("Unable to init the database pool : %s", err)
+	}
+	p := NewPostgres(pool, map[string]int{"test": 1})
+	defer p.Close()
+	p.PollInterval = 10 * time.Millisecond
+	p.RetryDelayFunc = func(int, error, *asynq.Task) time.Duration { return 0 }
+	_, _ = pool.Exec(context.Background(), `DELETE FROM task_queue WHERE queue = 'test';`)
+
+	t.Run("Should refuse a duplicated dedup key", func(t *testing.T) {
+		_, err := p.Enqueue(asynq.NewTask("block:raw", []byte(`{"BlockHeight":64}`)), asynq.Queue("test"), asynq.TaskID("block-64"))
+		if err != nil {
+			t.Errorf("Unable to enqueue task : %s", err)
+		}
+		_, err = p.Enqueue(asynq.NewTask("block:raw", []byte(`{"BlockHeight":64}`)), asynq.Queue("test"), asynq.TaskID("block-64"))
+		if !errors.Is(err, asynq.ErrTaskIDConflict) {
+			t.Errorf("Bad error. Received : %v. Expected : %s", err, asynq.ErrTaskIDConflict)
+		}
+	})
+	t.Run("Should accept the dedup key of an archived task", func(t *testing.T) {
+		_, _ = pool.Exec(context.Background(), `UPDATE task_queue SET archived = true WHERE queue = 'test' AND dedup_key = 'block-64';`)
+		_, err := p.Enqueue(asynq.NewTask("block:raw", []byte(`{"BlockHeight":64}`)), asynq.Queue("test"), asynq.TaskID("block-64"))
+		if err != nil {
+			t.Errorf("Unable to enqueue task : %s", err)
+		}
+		_, _ = pool.Exec(context.Background(), `DELETE FROM task_queue WHERE queue = 'test' AND archived;`)
+	})
+	t.Run("Should process, retry and delete the task", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		attempts := 0
+		_ = p.Run(ctx, asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("rpc unavailable")
+			}
+			cancel()
+			return nil
+		}), 1)
+		if attempts != 2 {
+			t.Errorf("Bad number of attempts. Received : %d. Expected : %d", attempts, 2)
+		}
+		count := 0
+		_ = pool.QueryRow(context.Background(), `SELECT count(*) FROM task_queue WHERE queue = 'test';`).Scan(&count)
+		if count != 0 {
+			t.Errorf("Task should be deleted once processed. Received : %d rows", count)
+		}
+	})
+}
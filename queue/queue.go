@@ -0,0 +1,94 @@
+// Package queue define the queue client used by the tasks and the backends that can carry them
+package queue
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Client enqueue tasks. *asynq.Client satisfies it, so does every backend.
+type Client interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
+// Backend carry the tasks from the clients to the workers
+type Backend interface {
+	Client
+	// Run process the tasks of the served queues with concurrency workers until ctx is done
+	Run(ctx context.Context, h asynq.Handler, concurrency int) error
+	// Close release the connections of the backend
+	Close() error
+}
+
+// DefaultQueue name of the queue used when no asynq.Queue option is given, same as asynq
+const DefaultQueue = "default"
+
+// DefaultMaxRetry number of retries of a failed task when no asynq.MaxRetry option is given, same as asynq
+const DefaultMaxRetry = 25
+
+// Queues the queues used by the parser and their default priority
+var Queues = map[string]int{
+	"blocks":      1,
+	"deploys":     1,
+	"deployinfos": 1,
+	"transfers":   1,
+	"contracts":   1,
+	"era":         1,
+	"auction":     1,
+	"auctionera":  1,
+	"accounts":    1,
+	"scheduled":   1,
+}
+
+// options the asynq options supported by the queue backends
+type options struct {
+	queue     string
+	maxRetry  int
+	processIn time.Duration
+	taskID    string
+}
+
+// parseOptions keep the queue, max retry, process in and task id options. The others are ignored.
+func parseOptions(opts []asynq.Option) options {
+	o := options{queue: DefaultQueue, maxRetry: DefaultMaxRetry}
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.QueueOpt:
+			o.queue = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			o.maxRetry = opt.Value().(int)
+		case asynq.ProcessInOpt:
+			o.processIn = opt.Value().(time.Duration)
+		case asynq.TaskIDOpt:
+			o.taskID = opt.Value().(string)
+		}
+	}
+	return o
+}
+
+// weightedOrder return the queues in a random order where a queue come first with a probability proportional to its priority
+func weightedOrder(priorities map[string]int) []string {
+	remaining := make(map[string]int, len(priorities))
+	total := 0
+	for name, priority := range priorities {
+		remaining[name] = priority
+		total += priority
+	}
+	order := make([]string, 0, len(priorities))
+	for total > 0 {
+		pick := rand.Intn(total)
+		for name, priority := range remaining {
+			pick -= priority
+			if pick < 0 {
+				order = append(order, name)
+				total -= priority
+				delete(remaining, name)
+				break
+			}
+		}
+	}
+	return order
+}
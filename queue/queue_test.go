@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestParseOptions(t *testing.T) {
+	t.Run("Should use the asynq defaults", func(t *testing.T) {
+		o := parseOptions(nil)
+		if o.queue != DefaultQueue || o.maxRetry != DefaultMaxRetry {
+			t.Errorf("Bad default options. Received : %s %d. Expected : %s %d", o.queue, o.maxRetry, DefaultQueue, DefaultMaxRetry)
+		}
+	})
+	t.Run("Should keep the supported options", func(t *testing.T) {
+		o := parseOptions([]asynq.Option{asynq.Queue("deploys"), asynq.MaxRetry(3), asynq.ProcessIn(time.Minute), asynq.TaskID("deploy-1")})
+		if o.queue != "deploys" || o.maxRetry != 3 || o.processIn != time.Minute || o.taskID != "deploy-1" {
+			t.Errorf("Bad options. Received : %+v", o)
+		}
+	})
+}
+
+func TestWeightedOrder(t *testing.T) {
+	t.Run("Should return every queue once", func(t *testing.T) {
+		order := weightedOrder(map[string]int{"blocks": 1, "deploys": 6, "accounts": 3})
+		if len(order) != 3 {
+			t.Errorf("Bad number of queues. Received : %v", order)
+		}
+	})
+	t.Run("Should favor the queues with a higher priority", func(t *testing.T) {
+		first := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			first[weightedOrder(map[string]int{"blocks": 1, "deploys": 9})[0]]++
+		}
+		if first["deploys"] < first["blocks"]*3 {
+			t.Errorf("Deploys should come first more often. Received : %v", first)
+		}
+	})
+}
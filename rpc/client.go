@@ -3,23 +3,28 @@ package rpc
 
 import (
 	"bytes"
+	"casperParser/logger"
+	"casperParser/tracing"
+	"casperParser/types/amount"
 	"casperParser/types/auction"
 	"casperParser/types/block"
+	"casperParser/types/clvalue"
 	"casperParser/types/contract"
 	"casperParser/types/contractPackage"
 	"casperParser/types/deploy"
 	"casperParser/types/deployInfo"
 	"casperParser/types/reward"
 	"casperParser/types/transfer"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-
-	//"log"
 	"net/http"
 	"strings"
 	"time"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var cachedStateRootHash = ""
@@ -37,7 +42,19 @@ func NewRpcClient(endpoint string) *Client {
 }
 
 // RpcCall make a rpc call
-func (c *Client) RpcCall(method string, params interface{}) (Response, error) {
+func (c *Client) RpcCall(ctx context.Context, method string, params interface{}) (rpcResponse Response, err error) {
+	ctx, span := tracing.StartRpc(ctx, method)
+	start := time.Now()
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+		entry := logger.FromContext(ctx).WithFields(log.Fields{logger.FieldRpcMethod: method, logger.FieldDuration: time.Since(start).String()})
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+		entry.Debug("rpc call")
+	}()
+
 	body, err := json.Marshal(Request{
 		Version: "2.0",
 		Method:  method,
@@ -48,7 +65,12 @@ func (c *Client) RpcCall(method string, params interface{}) (Response, error) {
 		return Response{}, fmt.Errorf("failed to marshal json %w", err)
 	}
 
-	resp, err := http.Post(c.endpoint, "application/json", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
@@ -65,7 +87,6 @@ func (c *Client) RpcCall(method string, params interface{}) (Response, error) {
 		return Response{}, fmt.Errorf("request failed, status code - %d, response - %s", resp.StatusCode, string(b))
 	}
 
-	var rpcResponse Response
 	err = json.Unmarshal(b, &rpcResponse)
 	_, err = io.Copy(ioutil.Discard, resp.Body)
 	if err != nil {
@@ -80,8 +101,8 @@ func (c *Client) RpcCall(method string, params interface{}) (Response, error) {
 }
 
 // GetBlock from the casper blockchain
-func (c *Client) GetBlock(height int) (block.Result, json.RawMessage, error) {
-	resp, err := c.RpcCall("chain_get_block",
+func (c *Client) GetBlock(ctx context.Context, height int) (block.Result, json.RawMessage, error) {
+	resp, err := c.RpcCall(ctx, "chain_get_block",
 		blockParams{blockIdentifier{
 			Height: uint64(height),
 		}})
@@ -98,8 +119,8 @@ func (c *Client) GetBlock(height int) (block.Result, json.RawMessage, error) {
 }
 
 // GetLastBlockHeight from the casper blockchain
-func (c *Client) GetLastBlockHeight() (int, error) {
-	resp, err := c.RpcCall("chain_get_block", nil)
+func (c *Client) GetLastBlockHeight(ctx context.Context) (int, error) {
+	resp, err := c.RpcCall(ctx, "chain_get_block", nil)
 
 	if err != nil {
 		return -1, err
@@ -114,8 +135,8 @@ func (c *Client) GetLastBlockHeight() (int, error) {
 }
 
 // GetAuction from the casper blockchain
-func (c *Client) GetAuction() (auction.Result, error) {
-	resp, err := c.RpcCall("state_get_auction_info", nil)
+func (c *Client) GetAuction(ctx context.Context) (auction.Result, error) {
+	resp, err := c.RpcCall(ctx, "state_get_auction_info", nil)
 
 	if err != nil {
 		return auction.Result{}, err
@@ -129,8 +150,8 @@ func (c *Client) GetAuction() (auction.Result, error) {
 	return result, nil
 }
 
-func (c *Client) GetAuctionEra(blockHeight int) (auction.Result, error) {
-	resp, err := c.RpcCall("state_get_auction_info", map[string]interface{}{
+func (c *Client) GetAuctionEra(ctx context.Context, blockHeight int) (auction.Result, error) {
+	resp, err := c.RpcCall(ctx, "state_get_auction_info", map[string]interface{}{
 		"block_identifier": map[string]int{"Height": blockHeight}})
 
 	if err != nil {
@@ -146,21 +167,18 @@ func (c *Client) GetAuctionEra(blockHeight int) (auction.Result, error) {
 }
 
 // GetDeploy from the casper blockchain
-func (c *Client) GetDeploy(hash string) (deploy.Result, json.RawMessage, error) {
-	resp, err := c.RpcCall("info_get_deploy", map[string]string{
+func (c *Client) GetDeploy(ctx context.Context, hash string) (deploy.Result, json.RawMessage, error) {
+	resp, err := c.RpcCall(ctx, "info_get_deploy", map[string]string{
 		"deploy_hash": hash,
 	})
 
 	if err != nil {
-		println("ERROR RPC call GetDeploy ", hash)
-		fmt.Printf("%v", err)
 		return deploy.Result{}, json.RawMessage{}, err
 	}
 	var result deploy.Result
 	err = json.Unmarshal(resp.Result, &result)
 	if err != nil {
-		println("ERROR unmarshalling GetDeploy")
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithFields(log.Fields{logger.FieldRpcMethod: "info_get_deploy", logger.FieldDeployHash: hash}).WithError(err).Error("can't unmarshal the rpc result")
 		return deploy.Result{}, json.RawMessage{}, fmt.Errorf("failed to get result: %w", err)
 	}
 
@@ -172,12 +190,10 @@ func addDeployHashPrefix(deployHash string) string {
 }
 
 // GetDeploy from the casper blockchain
-func (c *Client) GetDeployInfo(srh string, hash string) (deployInfo.Result, json.RawMessage, error) {
+func (c *Client) GetDeployInfo(ctx context.Context, srh string, hash string) (deployInfo.Result, json.RawMessage, error) {
 	prefixed_hash := addDeployHashPrefix(hash)
-	resp, err := c.RpcCall("query_global_state", map[string]interface{}{"state_identifier": map[string]string{"StateRootHash": srh}, "key": prefixed_hash})
+	resp, err := c.RpcCall(ctx, "query_global_state", map[string]interface{}{"state_identifier": map[string]string{"StateRootHash": srh}, "key": prefixed_hash})
 	if err != nil {
-		//println("Fails to get DeployInfo for hash ", hash, " on state ", srh, ". Probably errored Deploy. To handled")
-		//fmt.Printf("%v", err)
 		return deployInfo.Result{}, json.RawMessage{}, err
 	}
 
@@ -202,35 +218,30 @@ func addTransferHashPrefix(transferHash string) string {
 }
 
 // GetTransfer from the casper blockchain TODO
-func (c *Client) GetTransfer(srh string, hash string) (transfer.Result, json.RawMessage, error) {
+func (c *Client) GetTransfer(ctx context.Context, srh string, hash string) (transfer.Result, json.RawMessage, error) {
 	prefixed_hash := addTransferHashPrefix(hash)
-	resp, err := c.RpcCall("query_global_state", map[string]interface{}{"state_identifier": map[string]string{"StateRootHash": srh}, "key": prefixed_hash})
+	resp, err := c.RpcCall(ctx, "query_global_state", map[string]interface{}{"state_identifier": map[string]string{"StateRootHash": srh}, "key": prefixed_hash})
 	if err != nil {
-		println("ERROR on RPC Call Get Transfer")
-		println(srh, prefixed_hash)
-		fmt.Printf("%v", err)
 		return transfer.Result{}, json.RawMessage{}, err
 	}
 
 	var result transfer.Result
 	err = json.Unmarshal(resp.Result, &result)
 	if err != nil {
-		println("ERROR on Unmarshalling")
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithFields(log.Fields{logger.FieldRpcMethod: "query_global_state", "transfer_hash": prefixed_hash}).WithError(err).Error("can't unmarshal the rpc result")
 		return transfer.Result{}, json.RawMessage{}, fmt.Errorf("failed to get result: %w", err)
 	}
-	//log.Println(result)
 	return result, resp.Result, nil
 }
 
 // GetContractPackage from the casper blockchain
-func (c *Client) GetContractPackage(hash string) (string, error) {
-	srh, err := c.GetStateRootHash(false)
+func (c *Client) GetContractPackage(ctx context.Context, hash string) (string, error) {
+	srh, err := c.GetStateRootHash(ctx, false)
 	if err != nil {
 		return "", fmt.Errorf("failed to get result: %w", err)
 	}
 
-	resp, err := c.RpcCall("state_get_item", []string{srh, "hash-" + hash})
+	resp, err := c.RpcCall(ctx, "state_get_item", []string{srh, "hash-" + hash})
 	if err != nil {
 		return "", err
 	}
@@ -247,10 +258,10 @@ func (c *Client) GetContractPackage(hash string) (string, error) {
 }
 
 // GetStateRootHash from the casper blockchain
-func (c *Client) GetStateRootHash(cache bool) (string, error) {
+func (c *Client) GetStateRootHash(ctx context.Context, cache bool) (string, error) {
 	now := time.Now()
 	if cachedStateRootHash == "" || !cache || now.Sub(cachedStateRootHashTime).Seconds() > 32 {
-		resp, err := c.RpcCall("chain_get_state_root_hash", nil)
+		resp, err := c.RpcCall(ctx, "chain_get_state_root_hash", nil)
 		if err != nil {
 			return "", err
 		}
@@ -267,12 +278,12 @@ func (c *Client) GetStateRootHash(cache bool) (string, error) {
 }
 
 // GetMainPurse from the casper blockchain
-func (c *Client) GetMainPurse(hash string) (string, error) {
-	srh, err := c.GetStateRootHash(false)
+func (c *Client) GetMainPurse(ctx context.Context, hash string) (string, error) {
+	srh, err := c.GetStateRootHash(ctx, false)
 	if err != nil {
 		return "", fmt.Errorf("failed to get result: %w", err)
 	}
-	resp, err := c.RpcCall("state_get_item", []string{srh, hash})
+	resp, err := c.RpcCall(ctx, "state_get_item", []string{srh, hash})
 	if err != nil {
 		return "", err
 	}
@@ -282,86 +293,95 @@ func (c *Client) GetMainPurse(hash string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get result: %w", err)
 	}
-	//log.Println(result)
 	return result.StoredValue.Account.MainPurse, nil
 }
 
 // GetPurseBalance from the casper blockchain
-func (c *Client) GetPurseBalance(hash string) (string, error) {
-	srh, err := c.GetStateRootHash(false)
+func (c *Client) GetPurseBalance(ctx context.Context, hash string) (amount.Amount, error) {
+	srh, err := c.GetStateRootHash(ctx, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to get result: %w", err)
+		return amount.Amount{}, fmt.Errorf("failed to get result: %w", err)
 	}
-	resp, err := c.RpcCall("state_get_balance", []string{srh, hash})
+	resp, err := c.RpcCall(ctx, "state_get_balance", []string{srh, hash})
 	if err != nil {
-		return "", err
+		return amount.Amount{}, err
 	}
 	var result purseBalance
 	err = json.Unmarshal(resp.Result, &result)
 	if err != nil {
-		return "", fmt.Errorf("failed to get result: %w", err)
+		return amount.Amount{}, fmt.Errorf("failed to get result: %w", err)
 	}
 	return result.BalanceValue, nil
 }
 
 // GetContract from the casper blockchain
-func (c *Client) GetContract(hash string) (contract.Result, error) {
-	srh, err := c.GetStateRootHash(false)
+func (c *Client) GetContract(ctx context.Context, hash string) (contract.Result, json.RawMessage, error) {
+	srh, err := c.GetStateRootHash(ctx, false)
 	if err != nil {
-		return contract.Result{}, fmt.Errorf("failed to get result: %w", err)
+		return contract.Result{}, json.RawMessage{}, fmt.Errorf("failed to get result: %w", err)
 	}
 
-	resp, err := c.RpcCall("state_get_item", []string{srh, "hash-" + hash})
+	resp, err := c.RpcCall(ctx, "state_get_item", []string{srh, "hash-" + hash})
 	if err != nil {
-		return contract.Result{}, err
+		return contract.Result{}, json.RawMessage{}, err
 	}
 	var contractParsed contract.Result
 	err = json.Unmarshal(resp.Result, &contractParsed)
 	if err != nil {
-		return contract.Result{}, err
+		return contract.Result{}, json.RawMessage{}, err
 	}
-	return contractParsed, nil
+	return contractParsed, resp.Result, nil
 }
 
 // GetEraInfo from the casper blockchain
-func (c *Client) GetEraInfo(hash string) (reward.Result, error) {
-	resp, err := c.RpcCall("chain_get_era_info_by_switch_block", []map[string]string{{
+func (c *Client) GetEraInfo(ctx context.Context, hash string) (reward.Result, json.RawMessage, error) {
+	resp, err := c.RpcCall(ctx, "chain_get_era_info_by_switch_block", []map[string]string{{
 		"Hash": hash,
 	}})
 	if err != nil {
-		return reward.Result{}, err
+		return reward.Result{}, json.RawMessage{}, err
 	}
 	var rewardParsed reward.Result
 	err = json.Unmarshal(resp.Result, &rewardParsed)
 	if err != nil {
-		return reward.Result{}, err
+		return reward.Result{}, json.RawMessage{}, err
 	}
-	return rewardParsed, nil
+	return rewardParsed, resp.Result, nil
 }
 
-// GetUrefValue from the casper blockchain
-func (c *Client) GetUrefValue(hash string) (string, bool, error) {
-	srh, err := c.GetStateRootHash(true)
+// GetCLValue retrieve the CLValue stored at a key from the rpc endpoint, with its bytes and cl_type
+func (c *Client) GetCLValue(ctx context.Context, key string) (clvalue.CLValue, error) {
+	srh, err := c.GetStateRootHash(ctx, true)
 	if err != nil {
-		return "null", false, fmt.Errorf("failed to get result: %w", err)
+		return clvalue.CLValue{}, fmt.Errorf("failed to get result: %w", err)
 	}
 
-	resp, err := c.RpcCall("state_get_item", []string{srh, hash})
+	resp, err := c.RpcCall(ctx, "state_get_item", []string{srh, key})
 	if err != nil {
-		return "null", false, err
+		return clvalue.CLValue{}, err
 	}
 	var parsedUref uref
 	err = json.Unmarshal(resp.Result, &parsedUref)
+	if err != nil {
+		return clvalue.CLValue{}, err
+	}
+	return parsedUref.StoredValue.CLValue, nil
+}
+
+// GetUrefValue from the casper blockchain
+func (c *Client) GetUrefValue(ctx context.Context, hash string) (string, bool, error) {
+	clValue, err := c.GetCLValue(ctx, hash)
 	if err != nil {
 		return "null", false, err
 	}
-	if parsedUref.StoredValue.CLValue.Parsed == nil {
-		balance, errB := c.GetPurseBalance(hash)
+	value := clValue.Value()
+	if value == nil {
+		balance, errB := c.GetPurseBalance(ctx, hash)
 		if errB == nil {
-			return balance, true, nil
+			return balance.String(), true, nil
 		}
 	}
-	b, err := json.Marshal(parsedUref.StoredValue.CLValue.Parsed)
+	b, err := json.Marshal(value)
 	if err != nil {
 		return "null", false, err
 	}
@@ -408,14 +428,12 @@ type mainPurse struct {
 
 type uref struct {
 	StoredValue struct {
-		CLValue struct {
-			Parsed interface{} `json:"parsed"`
-		} `json:"CLValue"`
+		CLValue clvalue.CLValue `json:"CLValue"`
 	} `json:"stored_value"`
 }
 
 type purseBalance struct {
-	BalanceValue string `json:"balance_value"`
+	BalanceValue amount.Amount `json:"balance_value"`
 }
 
 type urefValue struct {
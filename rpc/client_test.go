@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"math"
 	"testing"
 )
@@ -8,102 +9,112 @@ import (
 var rpcClient = NewRpcClient("http://rpc.testnet.casperholders.com/rpc")
 
 func TestClient_GetBlock(t *testing.T) {
-	_, _, err := rpcClient.GetBlock(1)
+	_, _, err := rpcClient.GetBlock(context.Background(), 1)
 	if err != nil {
 		t.Errorf("Unable to retrieve block : %s", err)
 	}
-	_, _, err = rpcClient.GetBlock(math.MaxInt)
+	_, _, err = rpcClient.GetBlock(context.Background(), math.MaxInt)
 	if err == nil {
 		t.Errorf("Should have thrown an error")
 	}
 }
 
 func TestClient_GetLastBlockHeight(t *testing.T) {
-	_, err := rpcClient.GetLastBlockHeight()
+	_, err := rpcClient.GetLastBlockHeight(context.Background())
 	if err != nil {
 		t.Errorf("Unable to retrieve last block height %s", err)
 	}
 }
 
 func TestClient_GetDeploy(t *testing.T) {
-	_, _, err := rpcClient.GetDeploy("999eebd07739ca44945a102cbfa31e97a486979b83111e1af8ac050c73cc4872")
+	_, _, err := rpcClient.GetDeploy(context.Background(), "999eebd07739ca44945a102cbfa31e97a486979b83111e1af8ac050c73cc4872")
 	if err != nil {
 		t.Errorf("Unable to retrieve deploy %s", err)
 	}
-	_, _, err = rpcClient.GetDeploy("wrongdeploy")
+	_, _, err = rpcClient.GetDeploy(context.Background(), "wrongdeploy")
 	if err == nil {
 		t.Errorf("Should have thrown an error")
 	}
 }
 
 func TestClient_GetContractPackageHash(t *testing.T) {
-	_, err := rpcClient.GetContractPackage("394d973ba79e37a455f85fd2e4d7c138c4a6c1a4145fa087d25de59b4a088c6b")
+	_, err := rpcClient.GetContractPackage(context.Background(), "394d973ba79e37a455f85fd2e4d7c138c4a6c1a4145fa087d25de59b4a088c6b")
 	if err != nil {
 		t.Errorf("Unable to retrieve contract package %s", err)
 	}
-	_, err = rpcClient.GetContractPackage("wronghash")
+	_, err = rpcClient.GetContractPackage(context.Background(), "wronghash")
 	if err == nil {
 		t.Errorf("Should have thrown an error")
 	}
 }
 
 func TestClient_GetContract(t *testing.T) {
-	_, err := rpcClient.GetContract("db3a41adea55e5ae65c8cba29d8e8527a16ac5fa998a76dfed553215e3254090")
+	_, _, err := rpcClient.GetContract(context.Background(), "db3a41adea55e5ae65c8cba29d8e8527a16ac5fa998a76dfed553215e3254090")
 	if err != nil {
 		t.Errorf("Unable to retrieve contract package %s", err)
 	}
-	_, err = rpcClient.GetContract("wronghash")
+	_, _, err = rpcClient.GetContract(context.Background(), "wronghash")
 	if err == nil {
 		t.Errorf("Should have thrown an error")
 	}
 }
 
 func TestClient_GetEraInfo(t *testing.T) {
-	r, err := rpcClient.GetEraInfo("3293b31319a97a6451614f57bdd7f65225d4cb2add24fd78af373b4188413a10")
+	r, _, err := rpcClient.GetEraInfo(context.Background(), "3293b31319a97a6451614f57bdd7f65225d4cb2add24fd78af373b4188413a10")
 	if err != nil {
 		t.Errorf("Unable to retrieve era info %s", err)
 	}
-	r, err = rpcClient.GetEraInfo("wronghash")
+	r, _, err = rpcClient.GetEraInfo(context.Background(), "wronghash")
 	if r.EraSummary != nil {
 		t.Errorf("Should be nil")
 	}
 }
 
 func TestClient_GetAuction(t *testing.T) {
-	_, err := rpcClient.GetAuction()
+	_, err := rpcClient.GetAuction(context.Background())
 	if err != nil {
 		t.Errorf("Unable to retrieve auction info %s", err)
 	}
 }
 
 func TestClient_GetMainPurse(t *testing.T) {
-	_, err := rpcClient.GetMainPurse("account-hash-fa12d2dd5547714f8c2754d418aa8c9d59dc88780350cb4254d622e2d4ef7e69")
+	_, err := rpcClient.GetMainPurse(context.Background(), "account-hash-fa12d2dd5547714f8c2754d418aa8c9d59dc88780350cb4254d622e2d4ef7e69")
 	if err != nil {
 		t.Errorf("Unable to retrieve main purse info %s", err)
 	}
 }
 
 func TestClient_GetPurseBalance(t *testing.T) {
-	_, err := rpcClient.GetPurseBalance("uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
+	_, err := rpcClient.GetPurseBalance(context.Background(), "uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
 	if err != nil {
 		t.Errorf("Unable to retrieve balance %s", err)
 	}
 }
 
+func TestClient_GetCLValue(t *testing.T) {
+	clValue, err := rpcClient.GetCLValue(context.Background(), "uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
+	if err != nil {
+		t.Errorf("Unable to retrieve the CLValue %s", err)
+	}
+	if clValue.ClType == nil {
+		t.Errorf("CLValue without cl_type")
+	}
+}
+
 func TestClient_GetUrefValue(t *testing.T) {
-	_, _, err := rpcClient.GetUrefValue("uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
+	_, _, err := rpcClient.GetUrefValue(context.Background(), "uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
 	if err != nil {
 		t.Errorf("Unable to retrieve uref %s", err)
 	}
-	_, _, err = rpcClient.GetUrefValue("hash-d204aaea638a26d580fc0b40af97c468469f3c11c7aa60f2866adc46f03b5033")
+	_, _, err = rpcClient.GetUrefValue(context.Background(), "hash-d204aaea638a26d580fc0b40af97c468469f3c11c7aa60f2866adc46f03b5033")
 	if err != nil {
 		t.Errorf("Unable to retrieve uref %s", err)
 	}
-	initValue, _, err := rpcClient.GetUrefValue("hash-0000000000000000000000000000000000000000000000000000000000000000")
+	initValue, _, err := rpcClient.GetUrefValue(context.Background(), "hash-0000000000000000000000000000000000000000000000000000000000000000")
 	if err == nil || initValue != "null" {
 		t.Errorf("Init value should be null. Received : %s", initValue)
 	}
-	_, _, err = rpcClient.GetUrefValue("uref-d4a9e949503f14a524ee5a163386aec4ff231b87e4e856f68d8840432ecd693e-007")
+	_, _, err = rpcClient.GetUrefValue(context.Background(), "uref-d4a9e949503f14a524ee5a163386aec4ff231b87e4e856f68d8840432ecd693e-007")
 	if err != nil {
 		t.Errorf("Unable to retrieve uref %s", err)
 	}
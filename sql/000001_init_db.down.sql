@@ -18,11 +18,13 @@ DROP TABLE IF EXISTS "purses" cascade;
 DROP VIEW IF EXISTS "full_stats" cascade;
 DROP VIEW IF EXISTS "simple_stats" cascade;
 DROP VIEW IF EXISTS "total_rewards" cascade;
+DROP VIEW IF EXISTS "total_staking" cascade;
 DROP VIEW IF EXISTS "stakers" cascade;
 DROP VIEW IF EXISTS "mouvements" cascade;
 DROP VIEW IF EXISTS "rich_list" cascade;
 DROP VIEW IF EXISTS "allowance" cascade;
 DROP VIEW IF EXISTS "contracts_list" cascade;
+DROP VIEW IF EXISTS "auctions_list" cascade;
 DROP FUNCTION IF EXISTS "era_rewards" cascade;
 DROP FUNCTION IF EXISTS "total_validator_rewards" cascade;
 DROP FUNCTION IF EXISTS "total_account_rewards" cascade;
@@ -0,0 +1,16 @@
+CREATE TABLE "task_queue"
+(
+    "id"         BIGSERIAL    PRIMARY KEY,
+    "queue"      VARCHAR(64)  NOT NULL,
+    "type"       VARCHAR(64)  NOT NULL,
+    "payload"    bytea,
+    "dedup_key"  VARCHAR(256) UNIQUE,
+    "retried"    INT          NOT NULL DEFAULT 0,
+    "max_retry"  INT          NOT NULL,
+    "process_at" timestamptz  NOT NULL DEFAULT now(),
+    "archived"   bool         NOT NULL DEFAULT false,
+    "last_error" TEXT,
+    "created_at" timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ON "task_queue" ("queue", "process_at") WHERE NOT "archived";
@@ -0,0 +1,91 @@
+-- The U128, U256 and U512 values overflow a BIGINT, they're stored as NUMERIC.
+-- The views reading rewards.amount must be dropped to change its type, they're created again below.
+DROP MATERIALIZED VIEW IF EXISTS "rewards_daily_cumulative_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "rewards_era_cumulative_per_validator";
+DROP VIEW IF EXISTS "total_rewards";
+
+ALTER TABLE "deploys" ALTER COLUMN "cost" TYPE NUMERIC USING "cost"::NUMERIC;
+ALTER TABLE "deploy_infos" ALTER COLUMN "gas" TYPE NUMERIC;
+ALTER TABLE "transfers" ALTER COLUMN "amount" TYPE NUMERIC;
+ALTER TABLE "transfers" ALTER COLUMN "gas" TYPE NUMERIC;
+ALTER TABLE "rewards" ALTER COLUMN "amount" TYPE NUMERIC USING "amount"::NUMERIC;
+
+CREATE VIEW total_rewards AS
+SELECT sum(amount::NUMERIC) as total_rewards
+FROM rewards;
+
+grant select on public.total_rewards to web_anon;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS "rewards_era_cumulative_per_validator" AS
+WITH tmp_rewards_validator AS (
+    SELECT 
+        rewards.era,
+        rewards.validator_public_key,
+        sum(rewards.amount::numeric) AS amount
+    FROM rewards
+    WHERE rewards.delegator_public_key IS NULL
+    GROUP BY 
+        rewards.era, 
+        rewards.validator_public_key
+), 
+        
+tmp_rewards_delegator AS (
+    SELECT 
+        rewards.era,
+        rewards.validator_public_key,
+        sum(rewards.amount::numeric) AS amount
+    FROM rewards
+    WHERE rewards.delegator_public_key IS NOT NULL
+    GROUP BY 
+        rewards.era, 
+        rewards.validator_public_key
+)
+
+SELECT 
+    rew.era,
+    rew.validator_public_key,
+    vrew.era_cumulative_validator_rewards,
+    rew.era_cumulative_delegators_rewards
+FROM ( 
+    SELECT 
+        tmp_rewards_delegator.era,
+        tmp_rewards_delegator.validator_public_key,
+        sum(tmp_rewards_delegator.amount) OVER (PARTITION BY tmp_rewards_delegator.validator_public_key ORDER BY tmp_rewards_delegator.era) AS era_cumulative_delegators_rewards
+    FROM tmp_rewards_delegator
+) rew
+LEFT JOIN ( 
+    SELECT 
+        tmp_rewards_validator.era,
+        tmp_rewards_validator.validator_public_key,
+        sum(tmp_rewards_validator.amount) OVER (PARTITION BY tmp_rewards_validator.validator_public_key ORDER BY tmp_rewards_validator.era) AS era_cumulative_validator_rewards
+    FROM tmp_rewards_validator
+) vrew 
+ON vrew.validator_public_key::text = rew.validator_public_key::text 
+AND vrew.era = rew.era
+WITH DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS "rewards_daily_cumulative_per_validator" AS
+SELECT 
+    bl.date_era_end,
+    per_era.validator_public_key,
+    max(per_era.era_cumulative_validator_rewards) AS daily_cumulative_validator_rewards,
+    max(per_era.era_cumulative_delegators_rewards) AS daily_cumulative_delegators_rewards
+FROM (
+    SELECT 
+        era,
+        validator_public_key,
+        sum(era_cumulative_validator_rewards) OVER (PARTITION BY validator_public_key ORDER BY era) AS era_cumulative_delegators_rewards,
+        sum(era_cumulative_delegators_rewards) OVER (PARTITION BY validator_public_key ORDER BY era) AS era_cumulative_validator_rewards
+    FROM rewards_era_cumulative_per_validator
+) per_era
+LEFT JOIN (
+    SELECT 
+        era,
+        date(max("timestamp")) AS date_era_end
+    FROM blocks
+    GROUP BY era
+) bl ON per_era.era = bl.era
+GROUP BY 
+    bl.date_era_end, 
+    per_era.validator_public_key
+WITH DATA;
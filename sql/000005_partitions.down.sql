@@ -0,0 +1,252 @@
+-- The partitioned tables are copied back to heap tables, the created partitions are dropped with them.
+DROP MATERIALIZED VIEW IF EXISTS "staking_operation_daily_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "staking_operation_per_era_per_validator";
+DROP VIEW IF EXISTS "full_stats";
+DROP VIEW IF EXISTS "simple_stats";
+DROP VIEW IF EXISTS "mouvements";
+DROP VIEW IF EXISTS "allowance";
+DROP VIEW IF EXISTS "contracts_list";
+DROP VIEW IF EXISTS "auctions_list";
+
+ALTER TABLE "deploys" DROP CONSTRAINT IF EXISTS "deploys_hash_timestamp_fkey";
+
+ALTER TABLE "raw_deploys" RENAME TO "raw_deploys_old";
+ALTER TABLE "raw_deploys_old" DROP CONSTRAINT "raw_deploys_pkey";
+ALTER TABLE "deploys" RENAME TO "deploys_old";
+ALTER TABLE "deploys_old" DROP CONSTRAINT "deploys_pkey";
+ALTER TABLE "raw_transfers" RENAME TO "raw_transfers_old";
+ALTER TABLE "raw_transfers_old" DROP CONSTRAINT "raw_transfers_pkey";
+ALTER TABLE "transfers" RENAME TO "transfers_old";
+ALTER TABLE "transfers_old" DROP CONSTRAINT "transfers_pkey";
+
+CREATE TABLE "raw_deploys"
+(
+    "hash" VARCHAR(64) PRIMARY KEY,
+    "data" jsonb NOT NULL
+);
+
+CREATE TABLE "deploys"
+(
+    "hash"          VARCHAR(64) PRIMARY KEY,
+    "from"          VARCHAR(68) NOT NULL,
+    "cost"          NUMERIC     NOT NULL,
+    "result"        VARCHAR     NOT NULL,
+    "error_message" VARCHAR,
+    "timestamp"     timestamptz NOT NULL,
+    "block"         VARCHAR(64) NOT NULL,
+    "type"          VARCHAR     NOT NULL,
+    "metadata_type" VARCHAR     NOT NULL,
+    "contract_hash" VARCHAR(64),
+    "contract_name" VARCHAR,
+    "entrypoint"    VARCHAR,
+    "metadata"      jsonb,
+    "events"        jsonb
+);
+
+CREATE TABLE "raw_transfers"
+(
+    "hash"   VARCHAR(80) PRIMARY KEY,
+    "block"  VARCHAR(80) NOT NULL,
+    "deploy" VARCHAR(80) NOT NULL,
+    "data"   jsonb NOT NULL
+);
+
+CREATE TABLE "transfers"
+(
+    "hash"   VARCHAR(80) PRIMARY KEY,
+    "block"  VARCHAR(80) NOT NULL,
+    "deploy" VARCHAR(80) NOT NULL,
+    "from"   VARCHAR(80) NOT NULL,
+    "to"     VARCHAR(80) NOT NULL,
+    "source" VARCHAR(80) NOT NULL,
+    "target" VARCHAR(80) NOT NULL,
+    "amount" NUMERIC     NOT NULL,
+    "gas"    NUMERIC     NOT NULL,
+    "id"     VARCHAR(80) NOT NULL
+);
+
+INSERT INTO "raw_deploys" ("hash", "data")
+SELECT "hash", "data"
+FROM "raw_deploys_old";
+
+INSERT INTO "deploys" ("hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type",
+                       "contract_hash", "contract_name", "entrypoint", "metadata", "events")
+SELECT "hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type",
+       "contract_hash", "contract_name", "entrypoint", "metadata", "events"
+FROM "deploys_old";
+
+INSERT INTO "raw_transfers" ("hash", "block", "deploy", "data")
+SELECT "hash", "block", "deploy", "data"
+FROM "raw_transfers_old";
+
+INSERT INTO "transfers" ("hash", "block", "deploy", "from", "to", "source", "target", "amount", "gas", "id")
+SELECT "hash", "block", "deploy", "from", "to", "source", "target", "amount", "gas", "id"
+FROM "transfers_old";
+
+DROP TABLE "raw_deploys_old";
+DROP TABLE "deploys_old";
+DROP TABLE "raw_transfers_old";
+DROP TABLE "transfers_old";
+DROP FUNCTION IF EXISTS "create_monthly_partitions";
+
+ALTER TABLE "deploys"
+    ADD FOREIGN KEY ("block") REFERENCES "blocks" ("hash");
+
+ALTER TABLE "deploys"
+    ADD FOREIGN KEY ("hash") REFERENCES "raw_deploys" ("hash");
+
+ALTER TABLE "transfers"
+    ADD FOREIGN KEY ("block") REFERENCES "blocks" ("hash");
+
+ALTER TABLE "contracts"
+    ADD FOREIGN KEY ("deploy") REFERENCES "deploys" ("hash");
+
+ALTER TABLE "contract_packages"
+    ADD FOREIGN KEY ("deploy") REFERENCES "deploys" ("hash");
+
+CREATE INDEX ON "deploys" ("block");
+CREATE INDEX ON "deploys" ("from");
+CREATE INDEX ON "deploys" ("contract_hash");
+CREATE INDEX ON "deploys" ("result");
+CREATE INDEX ON "deploys" ("timestamp");
+
+CREATE VIEW full_stats AS
+SELECT count(*), type, date_trunc('day', timestamp) as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+GROUP BY day, type;
+
+CREATE VIEW simple_stats AS
+SELECT count(*), date_trunc('day', timestamp) as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+GROUP BY day;
+
+CREATE VIEW mouvements AS
+SELECT 'delegate'                                                as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and metadata_type = 'delegate'
+  AND result = 'success'
+GROUP BY day
+UNION
+SELECT 'undelegate'                                              as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and metadata_type = 'undelegate'
+  AND result = 'success'
+GROUP BY day
+UNION
+SELECT 'transfer'                                                as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and type = 'transfer'
+  AND result = 'success'
+GROUP BY day;
+
+CREATE VIEW allowance AS
+SELECT DISTINCT metadata -> 'spender' -> 'Hash' as spender, "from", contract_hash
+FROM deploys
+where metadata_type = 'approve'
+  and result = 'success'
+  and metadata -> 'spender' -> 'Hash' is not null
+UNION
+SELECT DISTINCT metadata -> 'spender' -> 'Account' as spender, "from", contract_hash
+FROM deploys
+where metadata_type = 'approve'
+  and result = 'success'
+  and metadata -> 'spender' -> 'Account' is not null;
+
+CREATE VIEW contracts_list AS
+SELECT contracts.hash as hash, package, contracts.type as type, score, d.timestamp
+from contracts
+         INNER JOIN deploys d on contracts.deploy = d.hash;
+
+CREATE VIEW auctions_list AS
+SELECT contracts.hash as hash, package, contracts.type as type, score, d.timestamp
+from contracts
+         INNER JOIN deploys d on contracts.deploy = d.hash
+WHERE contracts.hash in
+      (SELECT contract_hash
+       from contracts_named_keys
+       where named_key_uref in
+             (SELECT uref
+              from named_keys
+              where name = 'marketplace_account'
+                and initial_value =
+                    '"30f1d1b21e3a2c36b55fef940210edf43866f59038e22b24f867afd83e089da1"'));
+
+-- Staking operations per validator
+CREATE MATERIALIZED VIEW IF NOT EXISTS "staking_operation_per_era_per_validator" AS
+SELECT 
+    rich_blocks.era,
+    rich_blocks.date,
+    stakingop.validator_public_key,
+    stakingop.staking_operation,
+    count(1) AS count_op,
+    sum(stakingop.amount) / 1000000000.0 AS amount_approx
+FROM ( 
+    SELECT 
+        deploys.block,
+        deploys.metadata_type,
+        deploys.metadata,
+        COALESCE(
+            CASE
+                WHEN deploys.metadata_type::text = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text THEN ((deploys.metadata ->> 'amount'::text)::numeric) * '-1'::integer::numeric
+                ELSE (deploys.metadata ->> 'amount'::text)::numeric
+            END, 
+            0::numeric
+        ) AS amount,
+        CASE
+            WHEN deploys.metadata_type::text = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text THEN 'undelegate'::text
+            ELSE 'delegate'::text
+        END AS staking_operation,
+        (deploys.metadata ->> 'validator'::text)::character varying AS validator_public_key
+    FROM deploys
+    WHERE 
+        deploys.result::text = 'success'::text 
+        AND (deploys.metadata_type::text = 'delegate'::text OR deploys.metadata_type::text = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text OR deploys.metadata_type::text = 'enter_staking'::text OR deploys.metadata_type::text = 'moduleBytes'::text AND ((deploys.metadata ->> 'action'::text) = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'delegate'::text))
+) stakingop
+LEFT JOIN (
+    SELECT 
+        blocks.hash,
+        date(blocks."timestamp") AS date,
+        blocks.era
+    FROM blocks
+) rich_blocks 
+ON rich_blocks.hash::text = stakingop.block::text
+GROUP BY 
+    rich_blocks.era, 
+    rich_blocks.date, 
+    stakingop.validator_public_key, 
+    stakingop.staking_operation
+WITH DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS "staking_operation_daily_per_validator" AS
+SELECT 
+    date,
+    validator_public_key,
+    staking_operation,
+    sum(count_op) AS count_op,
+    sum(amount_approx) AS amount_approx
+FROM staking_operation_per_era_per_validator
+GROUP BY 
+    date,
+    validator_public_key,
+    staking_operation
+WITH DATA;
+
+grant select on public.deploys to web_anon;
+grant select on public.raw_deploys to web_anon;
+grant select on public.full_stats to web_anon;
+grant select on public.simple_stats to web_anon;
+grant select on public.mouvements to web_anon;
+grant select on public.allowance to web_anon;
+grant select on public.contracts_list to web_anon;
+grant select on public.auctions_list to web_anon;
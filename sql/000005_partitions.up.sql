@@ -0,0 +1,319 @@
+-- deploys, raw_deploys, transfers and raw_transfers are partitioned by month on their timestamp.
+-- The partition key must be part of the primary key, the upserts conflict on ("hash", "timestamp").
+-- The raw tables and the transfers get the timestamp of their deploy or of their block.
+-- The contracts and the contract packages can't reference a deploy hash alone anymore, their foreign key is dropped.
+
+-- create_monthly_partitions create the missing partitions of parent for each month from from_month to to_month, in UTC.
+-- Used by this migration and the maintenance command, return the number of partitions created.
+CREATE FUNCTION create_monthly_partitions(parent TEXT, from_month TIMESTAMPTZ, to_month TIMESTAMPTZ) RETURNS INTEGER AS
+$$
+DECLARE
+    month_start    TIMESTAMP := date_trunc('month', from_month AT TIME ZONE 'UTC');
+    last_month     TIMESTAMP := date_trunc('month', to_month AT TIME ZONE 'UTC');
+    partition_name TEXT;
+    created        INTEGER   := 0;
+BEGIN
+    WHILE month_start <= last_month
+        LOOP
+            partition_name := parent || '_' || to_char(month_start, 'YYYY_MM');
+            IF to_regclass(partition_name) IS NULL THEN
+                EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)', partition_name, parent,
+                               month_start AT TIME ZONE 'UTC', (month_start + INTERVAL '1 month') AT TIME ZONE 'UTC');
+                created := created + 1;
+            END IF;
+            month_start := month_start + INTERVAL '1 month';
+        END LOOP;
+    RETURN created;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP MATERIALIZED VIEW IF EXISTS "staking_operation_daily_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "staking_operation_per_era_per_validator";
+DROP VIEW IF EXISTS "full_stats";
+DROP VIEW IF EXISTS "simple_stats";
+DROP VIEW IF EXISTS "mouvements";
+DROP VIEW IF EXISTS "allowance";
+DROP VIEW IF EXISTS "contracts_list";
+DROP VIEW IF EXISTS "auctions_list";
+
+ALTER TABLE "contracts" DROP CONSTRAINT IF EXISTS "contracts_deploy_fkey";
+ALTER TABLE "contract_packages" DROP CONSTRAINT IF EXISTS "contract_packages_deploy_fkey";
+ALTER TABLE "deploys" DROP CONSTRAINT IF EXISTS "deploys_hash_fkey";
+
+ALTER TABLE "raw_deploys" RENAME TO "raw_deploys_old";
+ALTER TABLE "raw_deploys_old" DROP CONSTRAINT "raw_deploys_pkey";
+ALTER TABLE "deploys" RENAME TO "deploys_old";
+ALTER TABLE "deploys_old" DROP CONSTRAINT "deploys_pkey";
+ALTER TABLE "raw_transfers" RENAME TO "raw_transfers_old";
+ALTER TABLE "raw_transfers_old" DROP CONSTRAINT "raw_transfers_pkey";
+ALTER TABLE "transfers" RENAME TO "transfers_old";
+ALTER TABLE "transfers_old" DROP CONSTRAINT "transfers_pkey";
+
+CREATE TABLE "raw_deploys"
+(
+    "hash"      VARCHAR(64) NOT NULL,
+    "timestamp" timestamptz NOT NULL,
+    "data"      jsonb       NOT NULL,
+    PRIMARY KEY ("hash", "timestamp")
+) PARTITION BY RANGE ("timestamp");
+
+CREATE TABLE "deploys"
+(
+    "hash"          VARCHAR(64) NOT NULL,
+    "from"          VARCHAR(68) NOT NULL,
+    "cost"          NUMERIC     NOT NULL,
+    "result"        VARCHAR     NOT NULL,
+    "error_message" VARCHAR,
+    "timestamp"     timestamptz NOT NULL,
+    "block"         VARCHAR(64) NOT NULL,
+    "type"          VARCHAR     NOT NULL,
+    "metadata_type" VARCHAR     NOT NULL,
+    "contract_hash" VARCHAR(64),
+    "contract_name" VARCHAR,
+    "entrypoint"    VARCHAR,
+    "metadata"      jsonb,
+    "events"        jsonb,
+    PRIMARY KEY ("hash", "timestamp")
+) PARTITION BY RANGE ("timestamp");
+
+CREATE TABLE "raw_transfers"
+(
+    "hash"      VARCHAR(80) NOT NULL,
+    "block"     VARCHAR(80) NOT NULL,
+    "deploy"    VARCHAR(80) NOT NULL,
+    "timestamp" timestamptz NOT NULL,
+    "data"      jsonb       NOT NULL,
+    PRIMARY KEY ("hash", "timestamp")
+) PARTITION BY RANGE ("timestamp");
+
+CREATE TABLE "transfers"
+(
+    "hash"      VARCHAR(80) NOT NULL,
+    "block"     VARCHAR(80) NOT NULL,
+    "deploy"    VARCHAR(80) NOT NULL,
+    "timestamp" timestamptz NOT NULL,
+    "from"      VARCHAR(80) NOT NULL,
+    "to"        VARCHAR(80) NOT NULL,
+    "source"    VARCHAR(80) NOT NULL,
+    "target"    VARCHAR(80) NOT NULL,
+    "amount"    NUMERIC     NOT NULL,
+    "gas"       NUMERIC     NOT NULL,
+    "id"        VARCHAR(80) NOT NULL,
+    PRIMARY KEY ("hash", "timestamp")
+) PARTITION BY RANGE ("timestamp");
+
+-- The partitions cover the whole chain, a deploy can be a bit older than its block, and the next 3 months
+SELECT create_monthly_partitions(parent,
+                                 LEAST('2021-01-01', (SELECT min("timestamp") FROM "blocks") - INTERVAL '1 month'),
+                                 now() + INTERVAL '3 months')
+FROM unnest(ARRAY ['raw_deploys', 'deploys', 'raw_transfers', 'transfers']) AS parent;
+
+INSERT INTO "raw_deploys" ("hash", "timestamp", "data")
+SELECT "hash", ("data" -> 'deploy' -> 'header' ->> 'timestamp')::timestamptz, "data"
+FROM "raw_deploys_old";
+
+INSERT INTO "deploys" ("hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type",
+                       "contract_hash", "contract_name", "entrypoint", "metadata", "events")
+SELECT "hash", "from", "cost", "result", "error_message", "timestamp", "block", "type", "metadata_type",
+       "contract_hash", "contract_name", "entrypoint", "metadata", "events"
+FROM "deploys_old";
+
+-- The transfers get the timestamp of their block, or of their deploy when their block is missing
+INSERT INTO "raw_transfers" ("hash", "block", "deploy", "timestamp", "data")
+SELECT raw_transfers_old."hash", raw_transfers_old."block", raw_transfers_old."deploy",
+       COALESCE(blocks."timestamp", raw_deploys."timestamp"), raw_transfers_old."data"
+FROM "raw_transfers_old"
+         LEFT JOIN "blocks" ON blocks.hash = raw_transfers_old.block
+         LEFT JOIN "raw_deploys" ON raw_deploys.hash = raw_transfers_old.deploy
+WHERE COALESCE(blocks."timestamp", raw_deploys."timestamp") IS NOT NULL;
+
+INSERT INTO "transfers" ("hash", "block", "deploy", "timestamp", "from", "to", "source", "target", "amount", "gas", "id")
+SELECT transfers_old."hash", transfers_old."block", transfers_old."deploy",
+       COALESCE(blocks."timestamp", raw_deploys."timestamp"), transfers_old."from",
+       transfers_old."to", transfers_old."source", transfers_old."target", transfers_old."amount", transfers_old."gas",
+       transfers_old."id"
+FROM "transfers_old"
+         LEFT JOIN "blocks" ON blocks.hash = transfers_old.block
+         LEFT JOIN "raw_deploys" ON raw_deploys.hash = transfers_old.deploy
+WHERE COALESCE(blocks."timestamp", raw_deploys."timestamp") IS NOT NULL;
+
+-- The old tables are only dropped when every row was copied, the migration is rolled back otherwise
+DO
+$$
+DECLARE
+    old_count BIGINT;
+    new_count BIGINT;
+    table_name TEXT;
+BEGIN
+    FOREACH table_name IN ARRAY ARRAY ['raw_deploys', 'deploys', 'raw_transfers', 'transfers']
+        LOOP
+            EXECUTE format('SELECT count(*) FROM %I', table_name || '_old') INTO old_count;
+            EXECUTE format('SELECT count(*) FROM %I', table_name) INTO new_count;
+            IF old_count <> new_count THEN
+                RAISE EXCEPTION '% rows of % copied in the partitioned table instead of %, the transfers need the timestamp of their block or of their deploy',
+                    new_count, table_name, old_count;
+            END IF;
+        END LOOP;
+END
+$$;
+
+DROP TABLE "raw_deploys_old";
+DROP TABLE "deploys_old";
+DROP TABLE "raw_transfers_old";
+DROP TABLE "transfers_old";
+
+ALTER TABLE "deploys"
+    ADD FOREIGN KEY ("block") REFERENCES "blocks" ("hash");
+
+ALTER TABLE "deploys"
+    ADD FOREIGN KEY ("hash", "timestamp") REFERENCES "raw_deploys" ("hash", "timestamp");
+
+ALTER TABLE "transfers"
+    ADD FOREIGN KEY ("block") REFERENCES "blocks" ("hash");
+
+CREATE INDEX ON "deploys" ("block");
+CREATE INDEX ON "deploys" ("from");
+CREATE INDEX ON "deploys" ("contract_hash");
+CREATE INDEX ON "deploys" ("result");
+CREATE INDEX ON "deploys" ("timestamp");
+
+CREATE VIEW full_stats AS
+SELECT count(*), type, date_trunc('day', timestamp) as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+GROUP BY day, type;
+
+CREATE VIEW simple_stats AS
+SELECT count(*), date_trunc('day', timestamp) as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+GROUP BY day;
+
+CREATE VIEW mouvements AS
+SELECT 'delegate'                                                as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and metadata_type = 'delegate'
+  AND result = 'success'
+GROUP BY day
+UNION
+SELECT 'undelegate'                                              as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and metadata_type = 'undelegate'
+  AND result = 'success'
+GROUP BY day
+UNION
+SELECT 'transfer'                                                as type,
+       FLOOR(SUM((metadata ->> 'amount')::numeric) / 1000000000) as count,
+       date_trunc('day', timestamp)                              as day
+from deploys
+WHERE timestamp >= NOW() - INTERVAL '14 DAY'
+  and type = 'transfer'
+  AND result = 'success'
+GROUP BY day;
+
+CREATE VIEW allowance AS
+SELECT DISTINCT metadata -> 'spender' -> 'Hash' as spender, "from", contract_hash
+FROM deploys
+where metadata_type = 'approve'
+  and result = 'success'
+  and metadata -> 'spender' -> 'Hash' is not null
+UNION
+SELECT DISTINCT metadata -> 'spender' -> 'Account' as spender, "from", contract_hash
+FROM deploys
+where metadata_type = 'approve'
+  and result = 'success'
+  and metadata -> 'spender' -> 'Account' is not null;
+
+CREATE VIEW contracts_list AS
+SELECT contracts.hash as hash, package, contracts.type as type, score, d.timestamp
+from contracts
+         INNER JOIN deploys d on contracts.deploy = d.hash;
+
+CREATE VIEW auctions_list AS
+SELECT contracts.hash as hash, package, contracts.type as type, score, d.timestamp
+from contracts
+         INNER JOIN deploys d on contracts.deploy = d.hash
+WHERE contracts.hash in
+      (SELECT contract_hash
+       from contracts_named_keys
+       where named_key_uref in
+             (SELECT uref
+              from named_keys
+              where name = 'marketplace_account'
+                and initial_value =
+                    '"30f1d1b21e3a2c36b55fef940210edf43866f59038e22b24f867afd83e089da1"'));
+
+-- Staking operations per validator
+CREATE MATERIALIZED VIEW IF NOT EXISTS "staking_operation_per_era_per_validator" AS
+SELECT 
+    rich_blocks.era,
+    rich_blocks.date,
+    stakingop.validator_public_key,
+    stakingop.staking_operation,
+    count(1) AS count_op,
+    sum(stakingop.amount) / 1000000000.0 AS amount_approx
+FROM ( 
+    SELECT 
+        deploys.block,
+        deploys.metadata_type,
+        deploys.metadata,
+        COALESCE(
+            CASE
+                WHEN deploys.metadata_type::text = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text THEN ((deploys.metadata ->> 'amount'::text)::numeric) * '-1'::integer::numeric
+                ELSE (deploys.metadata ->> 'amount'::text)::numeric
+            END, 
+            0::numeric
+        ) AS amount,
+        CASE
+            WHEN deploys.metadata_type::text = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text THEN 'undelegate'::text
+            ELSE 'delegate'::text
+        END AS staking_operation,
+        (deploys.metadata ->> 'validator'::text)::character varying AS validator_public_key
+    FROM deploys
+    WHERE 
+        deploys.result::text = 'success'::text 
+        AND (deploys.metadata_type::text = 'delegate'::text OR deploys.metadata_type::text = 'undelegate'::text OR deploys.metadata_type::text = 'leave_staking'::text OR deploys.metadata_type::text = 'enter_staking'::text OR deploys.metadata_type::text = 'moduleBytes'::text AND ((deploys.metadata ->> 'action'::text) = 'undelegate'::text OR (deploys.metadata ->> 'action'::text) = 'delegate'::text))
+) stakingop
+LEFT JOIN (
+    SELECT 
+        blocks.hash,
+        date(blocks."timestamp") AS date,
+        blocks.era
+    FROM blocks
+) rich_blocks 
+ON rich_blocks.hash::text = stakingop.block::text
+GROUP BY 
+    rich_blocks.era, 
+    rich_blocks.date, 
+    stakingop.validator_public_key, 
+    stakingop.staking_operation
+WITH DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS "staking_operation_daily_per_validator" AS
+SELECT 
+    date,
+    validator_public_key,
+    staking_operation,
+    sum(count_op) AS count_op,
+    sum(amount_approx) AS amount_approx
+FROM staking_operation_per_era_per_validator
+GROUP BY 
+    date,
+    validator_public_key,
+    staking_operation
+WITH DATA;
+
+grant select on public.deploys to web_anon;
+grant select on public.raw_deploys to web_anon;
+grant select on public.full_stats to web_anon;
+grant select on public.simple_stats to web_anon;
+grant select on public.mouvements to web_anon;
+grant select on public.allowance to web_anon;
+grant select on public.contracts_list to web_anon;
+grant select on public.auctions_list to web_anon;
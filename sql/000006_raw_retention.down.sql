@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "raw_deploy_infos_block_idx";
+DROP INDEX IF EXISTS "raw_transfers_block_idx";
+
+-- NOT VALID, the raw rows of the blocks archived meanwhile are missing
+ALTER TABLE "blocks"
+    ADD FOREIGN KEY ("hash") REFERENCES "raw_blocks" ("hash") NOT VALID;
+-- A partitioned table can't have a NOT VALID foreign key, restore the archived raw deploys before reverting
+ALTER TABLE "deploys"
+    ADD FOREIGN KEY ("hash", "timestamp") REFERENCES "raw_deploys" ("hash", "timestamp");
@@ -0,0 +1,6 @@
+-- The raw rows can be archived and deleted by the archive command, the parsed rows don't require them anymore.
+ALTER TABLE "blocks" DROP CONSTRAINT IF EXISTS "blocks_hash_fkey";
+ALTER TABLE "deploys" DROP CONSTRAINT IF EXISTS "deploys_hash_timestamp_fkey";
+
+CREATE INDEX ON "raw_deploy_infos" ("block");
+CREATE INDEX ON "raw_transfers" ("block");
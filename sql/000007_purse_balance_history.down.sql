@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS purse_balance_at(TEXT, BIGINT);
+DROP TABLE IF EXISTS "purse_balance_history";
@@ -0,0 +1,80 @@
+-- Balance changes of the purses made by the deploys, one per deploy and purse. A deploy either writes the balance of a purse,
+-- the last balance written plus the amounts added after it, or only adds amounts to it.
+-- The purse is uref-<address>, without the access rights of the purses table. The deploy index is its position in the block,
+-- deploy hashes first then transfer hashes, the order the deploys are executed in.
+CREATE TABLE "purse_balance_history"
+(
+    "purse"        VARCHAR(69) NOT NULL,
+    "block_height" BIGINT      NOT NULL,
+    "deploy_index" INTEGER     NOT NULL,
+    "deploy"       VARCHAR(64) NOT NULL,
+    "balance"      NUMERIC,
+    "delta"        NUMERIC,
+    PRIMARY KEY ("purse", "block_height", "deploy_index")
+);
+
+-- purse_balance_at the balance of the purse at the end of the block at height : the last balance written, plus the amounts added after.
+-- NULL when no balance was written up to this height.
+CREATE FUNCTION purse_balance_at(purse_uref TEXT, height BIGINT) RETURNS NUMERIC AS
+$$
+WITH last_write AS (SELECT block_height, deploy_index, balance
+                    FROM purse_balance_history
+                    WHERE purse = regexp_replace(lower(purse_uref), '-\d{3}$', '')
+                      AND block_height <= height
+                      AND balance IS NOT NULL
+                    ORDER BY block_height DESC, deploy_index DESC
+                    LIMIT 1)
+SELECT last_write.balance + COALESCE((SELECT sum(delta)
+                                      FROM purse_balance_history
+                                      WHERE purse = regexp_replace(lower(purse_uref), '-\d{3}$', '')
+                                        AND block_height <= height
+                                        AND (block_height, deploy_index) >
+                                            (last_write.block_height, last_write.deploy_index)), 0)
+FROM last_write;
+$$ LANGUAGE sql STABLE;
+
+-- Fill the history from the raw deploys still in the database. The deploy index is 0 when the raw block is missing.
+-- The transforms of a deploy on a purse are folded in their order, like the workers do : the last balance written plus
+-- the amounts added after it, or the sum of the amounts added when no balance is written.
+INSERT INTO "purse_balance_history" ("purse", "block_height", "deploy_index", "deploy", "balance", "delta")
+SELECT purse,
+       block_height,
+       deploy_index,
+       deploy,
+       (array_agg(balance ORDER BY position DESC) FILTER (WHERE balance IS NOT NULL))[1] +
+       COALESCE(sum(delta) FILTER (WHERE position > last_write), 0),
+       CASE WHEN max(last_write) IS NULL THEN sum(delta) END
+FROM (SELECT 'uref-' || substring(transforms.transform ->> 'key' FROM 9)                                   AS purse,
+             blocks.height                                                                              AS block_height,
+             COALESCE(positions.index - 1, 0)                                                           AS deploy_index,
+             deploys.hash                                                                               AS deploy,
+             transforms.position,
+             (transforms.transform -> 'transform' -> 'WriteCLValue' ->> 'parsed')::NUMERIC              AS balance,
+             (transforms.transform -> 'transform' ->> 'AddUInt512')::NUMERIC                            AS delta,
+             max(transforms.position)
+             FILTER (WHERE transforms.transform -> 'transform' -> 'WriteCLValue' ->> 'parsed' IS NOT NULL)
+                 OVER (PARTITION BY deploys.hash, transforms.transform ->> 'key')                       AS last_write
+      FROM "deploys"
+               INNER JOIN "raw_deploys"
+                          ON raw_deploys.hash = deploys.hash AND raw_deploys."timestamp" = deploys."timestamp"
+               INNER JOIN "blocks" ON blocks.hash = deploys.block
+               LEFT JOIN "raw_blocks" ON raw_blocks.hash = deploys.block
+               LEFT JOIN LATERAL (SELECT block_deploys.index
+                                  FROM jsonb_array_elements_text(
+                                                   COALESCE(raw_blocks.data -> 'block' -> 'body' -> 'deploy_hashes', '[]') ||
+                                                   COALESCE(raw_blocks.data -> 'block' -> 'body' -> 'transfer_hashes', '[]'))
+                                           WITH ORDINALITY AS block_deploys(hash, index)
+                                  WHERE block_deploys.hash = deploys.hash
+                                  LIMIT 1) positions ON true
+               CROSS JOIN LATERAL jsonb_array_elements(
+              COALESCE(raw_deploys.data -> 'execution_results' -> 0 -> 'result' -> 'Success' -> 'effect',
+                       raw_deploys.data -> 'execution_results' -> 0 -> 'result' -> 'Failure' -> 'effect') -> 'transforms'
+          ) WITH ORDINALITY AS transforms(transform, position)
+      WHERE transforms.transform ->> 'key' LIKE 'balance-%'
+        AND (transforms.transform -> 'transform' -> 'WriteCLValue' ->> 'parsed' IS NOT NULL OR
+             transforms.transform -> 'transform' ->> 'AddUInt512' IS NOT NULL)) changes
+GROUP BY purse, block_height, deploy_index, deploy
+ON CONFLICT DO NOTHING;
+
+grant select on public.purse_balance_history to web_anon;
+grant execute on function purse_balance_at(TEXT, BIGINT) to web_anon;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "auction_state";
+DROP TABLE IF EXISTS "auction_changes";
@@ -0,0 +1,29 @@
+-- The bids and delegators are updated by diff, each change is logged with the height of the auction state it was observed in
+CREATE TABLE "auction_changes"
+(
+    "id"                   BIGSERIAL PRIMARY KEY,
+    "block_height"         BIGINT      NOT NULL,
+    "change"               VARCHAR(32) NOT NULL,
+    "validator_public_key" VARCHAR(68) NOT NULL,
+    "delegator_public_key" VARCHAR(68),
+    "old_staked_amount"    NUMERIC,
+    "new_staked_amount"    NUMERIC,
+    "old_delegation_rate"  INT,
+    "new_delegation_rate"  INT
+);
+
+CREATE INDEX ON "auction_changes" ("block_height");
+CREATE INDEX ON "auction_changes" ("validator_public_key");
+CREATE INDEX ON "auction_changes" ("delegator_public_key");
+
+-- Height of the auction state in the bids and delegators tables, an older state is never applied over a newer one
+CREATE TABLE "auction_state"
+(
+    "id"           BOOL PRIMARY KEY DEFAULT true CHECK ("id"),
+    "block_height" BIGINT NOT NULL
+);
+
+INSERT INTO "auction_state" ("block_height")
+VALUES (0);
+
+grant select on public.auction_changes to web_anon;
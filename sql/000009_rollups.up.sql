@@ -0,0 +1,281 @@
+-- The rewards and stake materialized views are replaced by tables of the same name, updated by the workers
+-- with rollup_era_rewards and rollup_era_stakes when an era is inserted. rebuild_rollups recompute them from scratch.
+DROP MATERIALIZED VIEW IF EXISTS "rewards_daily_cumulative_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "rewards_era_cumulative_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "staked_amount_daily_candle_per_validator";
+DROP MATERIALIZED VIEW IF EXISTS "delegators_daily_count_per_validator";
+
+CREATE TABLE "rewards_era_cumulative_per_validator"
+(
+    "era"                               BIGINT      NOT NULL,
+    "validator_public_key"              VARCHAR(68) NOT NULL,
+    "date_era_end"                      DATE        NOT NULL,
+    "era_validator_rewards"             NUMERIC     NOT NULL,
+    "era_delegators_rewards"            NUMERIC     NOT NULL,
+    "era_cumulative_validator_rewards"  NUMERIC     NOT NULL,
+    "era_cumulative_delegators_rewards" NUMERIC     NOT NULL,
+    PRIMARY KEY ("validator_public_key", "era")
+);
+
+CREATE INDEX ON "rewards_era_cumulative_per_validator" ("era");
+
+-- The cumulative rewards at the end of the last era of the day
+CREATE TABLE "rewards_daily_cumulative_per_validator"
+(
+    "date_era_end"                        DATE        NOT NULL,
+    "validator_public_key"                VARCHAR(68) NOT NULL,
+    "daily_cumulative_validator_rewards"  NUMERIC     NOT NULL,
+    "daily_cumulative_delegators_rewards" NUMERIC     NOT NULL,
+    PRIMARY KEY ("validator_public_key", "date_era_end")
+);
+
+-- The stake of each validator at each switch block, the bid plus its delegators
+CREATE TABLE "staked_amount_per_era_per_validator"
+(
+    "block_height"         BIGINT      NOT NULL,
+    "validator_public_key" VARCHAR(68) NOT NULL,
+    "timestamp"            timestamptz NOT NULL,
+    "date"                 DATE        NOT NULL,
+    "staked_amount"        NUMERIC     NOT NULL,
+    "delegator_count"      INT         NOT NULL,
+    PRIMARY KEY ("validator_public_key", "block_height")
+);
+
+CREATE INDEX ON "staked_amount_per_era_per_validator" ("validator_public_key", "date");
+CREATE INDEX ON "staked_amount_per_era_per_validator" ("block_height");
+
+CREATE TABLE "staked_amount_daily_candle_per_validator"
+(
+    "date"                 DATE        NOT NULL,
+    "validator_public_key" VARCHAR(68) NOT NULL,
+    "open_staked_amount"   NUMERIC     NOT NULL,
+    "avg_staked_amount"    NUMERIC     NOT NULL,
+    "max_staked_amount"    NUMERIC     NOT NULL,
+    "min_staked_amount"    NUMERIC     NOT NULL,
+    "close_staked_amount"  NUMERIC,
+    PRIMARY KEY ("validator_public_key", "date")
+);
+
+-- Average number of delegators of the validators with delegators, over the switch blocks of the day
+CREATE TABLE "delegators_daily_count_per_validator"
+(
+    "date"                 DATE        NOT NULL,
+    "validator_public_key" VARCHAR(68) NOT NULL,
+    "avg"                  NUMERIC     NOT NULL,
+    PRIMARY KEY ("validator_public_key", "date")
+);
+
+-- era_rewards_per_validator sum the validator and delegators rewards of each validator for an era
+CREATE OR REPLACE FUNCTION era_rewards_per_validator(era_id BIGINT)
+    RETURNS TABLE (validator_public_key VARCHAR, date_era_end DATE, validator_rewards NUMERIC, delegators_rewards NUMERIC)
+AS $$
+SELECT r.validator_public_key,
+       date(max(b."timestamp")),
+       COALESCE(sum(r.amount) FILTER (WHERE r.delegator_public_key IS NULL), 0),
+       COALESCE(sum(r.amount) FILTER (WHERE r.delegator_public_key IS NOT NULL), 0)
+FROM rewards r
+         JOIN blocks b ON b.hash = r.block
+WHERE r.era = era_id
+GROUP BY r.validator_public_key;
+$$ LANGUAGE SQL STABLE;
+
+-- rollup_era_rewards update the rewards rollups with the rewards of an era. The eras can be rolled up in any order and again,
+-- the cumulative sums of the later eras are shifted by the difference with the previous rollup of the era.
+CREATE OR REPLACE FUNCTION rollup_era_rewards(era_id BIGINT) RETURNS VOID AS $$
+BEGIN
+    -- The cumulative sums of an era depend on the previous ones, the eras are rolled up one at a time
+    PERFORM pg_advisory_xact_lock(hashtext('rollup_era_rewards'));
+
+    UPDATE rewards_era_cumulative_per_validator c
+    SET era_cumulative_validator_rewards  = c.era_cumulative_validator_rewards + e.validator_rewards - COALESCE(o.era_validator_rewards, 0),
+        era_cumulative_delegators_rewards = c.era_cumulative_delegators_rewards + e.delegators_rewards - COALESCE(o.era_delegators_rewards, 0)
+    FROM era_rewards_per_validator(era_id) e
+             LEFT JOIN rewards_era_cumulative_per_validator o ON o.validator_public_key = e.validator_public_key AND o.era = era_id
+    WHERE c.validator_public_key = e.validator_public_key
+      AND c.era > era_id;
+
+    INSERT INTO rewards_era_cumulative_per_validator (era, validator_public_key, date_era_end, era_validator_rewards, era_delegators_rewards,
+                                                      era_cumulative_validator_rewards, era_cumulative_delegators_rewards)
+    SELECT era_id,
+           e.validator_public_key,
+           e.date_era_end,
+           e.validator_rewards,
+           e.delegators_rewards,
+           COALESCE(p.era_cumulative_validator_rewards, 0) + e.validator_rewards,
+           COALESCE(p.era_cumulative_delegators_rewards, 0) + e.delegators_rewards
+    FROM era_rewards_per_validator(era_id) e
+             LEFT JOIN LATERAL (SELECT era_cumulative_validator_rewards, era_cumulative_delegators_rewards
+                                FROM rewards_era_cumulative_per_validator
+                                WHERE validator_public_key = e.validator_public_key
+                                  AND era < era_id
+                                ORDER BY era DESC
+                                LIMIT 1) p ON true
+    ON CONFLICT (validator_public_key, era) DO UPDATE
+        SET date_era_end                      = excluded.date_era_end,
+            era_validator_rewards             = excluded.era_validator_rewards,
+            era_delegators_rewards            = excluded.era_delegators_rewards,
+            era_cumulative_validator_rewards  = excluded.era_cumulative_validator_rewards,
+            era_cumulative_delegators_rewards = excluded.era_cumulative_delegators_rewards;
+
+    -- The rewards are positive, the last era of the day has the highest cumulative sums
+    INSERT INTO rewards_daily_cumulative_per_validator (date_era_end, validator_public_key, daily_cumulative_validator_rewards, daily_cumulative_delegators_rewards)
+    SELECT c.date_era_end,
+           c.validator_public_key,
+           max(c.era_cumulative_validator_rewards),
+           max(c.era_cumulative_delegators_rewards)
+    FROM rewards_era_cumulative_per_validator c
+             JOIN rewards_era_cumulative_per_validator e
+                  ON e.validator_public_key = c.validator_public_key AND e.era = era_id AND c.date_era_end >= e.date_era_end
+    GROUP BY c.date_era_end, c.validator_public_key
+    ON CONFLICT (validator_public_key, date_era_end) DO UPDATE
+        SET daily_cumulative_validator_rewards  = excluded.daily_cumulative_validator_rewards,
+            daily_cumulative_delegators_rewards = excluded.daily_cumulative_delegators_rewards;
+END;
+$$ LANGUAGE plpgsql;
+
+-- rollup_era_stakes update the stake rollups with the bids and delegators of a switch block. The switch blocks can be rolled up in any order and again.
+CREATE OR REPLACE FUNCTION rollup_era_stakes(era_block_height BIGINT) RETURNS VOID AS $$
+BEGIN
+    -- The candles of a day are computed from every switch block of the day, they're rolled up one at a time
+    PERFORM pg_advisory_xact_lock(hashtext('rollup_era_stakes'));
+
+    INSERT INTO staked_amount_per_era_per_validator (block_height, validator_public_key, "timestamp", "date", staked_amount, delegator_count)
+    SELECT s.block_height, s.validator_public_key, b."timestamp", date(b."timestamp"), sum(s.staked_amount), sum(s.delegator_count)
+    FROM (SELECT block_height, validator_public_key, max(staked_amount) AS staked_amount, 0 AS delegator_count
+          FROM bids_per_era
+          WHERE block_height = era_block_height
+          GROUP BY block_height, validator_public_key
+          UNION ALL
+          SELECT block_height, validator_public_key, sum(staked_amount), count(DISTINCT delegator_public_key)
+          FROM delegators_per_era
+          WHERE block_height = era_block_height
+          GROUP BY block_height, validator_public_key) s
+             JOIN blocks b ON b.height = s.block_height
+    GROUP BY s.block_height, s.validator_public_key, b."timestamp"
+    ON CONFLICT (validator_public_key, block_height) DO UPDATE
+        SET "timestamp"     = excluded."timestamp",
+            "date"          = excluded."date",
+            staked_amount   = excluded.staked_amount,
+            delegator_count = excluded.delegator_count;
+
+    -- The candles of the day, and the close of the previous day which is the open of this one, are computed again.
+    -- The candles are computed from the previous to the next day with a stake, the next day only gives the close.
+    WITH affected AS (SELECT s.validator_public_key,
+                             s."date",
+                             COALESCE((SELECT max(p."date") FROM staked_amount_per_era_per_validator p
+                                       WHERE p.validator_public_key = s.validator_public_key AND p."date" < s."date"), s."date") AS from_date,
+                             COALESCE((SELECT min(n."date") FROM staked_amount_per_era_per_validator n
+                                       WHERE n.validator_public_key = s.validator_public_key AND n."date" > s."date"), s."date") AS to_date
+                      FROM staked_amount_per_era_per_validator s
+                      WHERE s.block_height = era_block_height),
+         candles AS (SELECT a.validator_public_key,
+                            a."date" AS rolled_up_date,
+                            a.to_date,
+                            s."date",
+                            (array_agg(s.staked_amount ORDER BY s."timestamp"))[1] AS open_staked_amount,
+                            avg(s.staked_amount) AS avg_staked_amount,
+                            max(s.staked_amount) AS max_staked_amount,
+                            min(s.staked_amount) AS min_staked_amount
+                     FROM affected a
+                              JOIN staked_amount_per_era_per_validator s
+                                   ON s.validator_public_key = a.validator_public_key AND s."date" BETWEEN a.from_date AND a.to_date
+                     GROUP BY a.validator_public_key, a."date", a.to_date, s."date")
+    INSERT INTO staked_amount_daily_candle_per_validator ("date", validator_public_key, open_staked_amount, avg_staked_amount, max_staked_amount, min_staked_amount, close_staked_amount)
+    SELECT "date", validator_public_key, open_staked_amount, avg_staked_amount, max_staked_amount, min_staked_amount, close_staked_amount
+    FROM (SELECT *, lead(open_staked_amount) OVER (PARTITION BY validator_public_key ORDER BY "date") AS close_staked_amount
+          FROM candles) c
+    WHERE c."date" < c.to_date OR c.to_date = c.rolled_up_date
+    ON CONFLICT (validator_public_key, "date") DO UPDATE
+        SET open_staked_amount  = excluded.open_staked_amount,
+            avg_staked_amount   = excluded.avg_staked_amount,
+            max_staked_amount   = excluded.max_staked_amount,
+            min_staked_amount   = excluded.min_staked_amount,
+            close_staked_amount = excluded.close_staked_amount;
+
+    INSERT INTO delegators_daily_count_per_validator ("date", validator_public_key, "avg")
+    SELECT s."date", s.validator_public_key, avg(s.delegator_count) FILTER (WHERE s.delegator_count > 0)
+    FROM staked_amount_per_era_per_validator s
+             JOIN staked_amount_per_era_per_validator a
+                  ON a.validator_public_key = s.validator_public_key AND a."date" = s."date" AND a.block_height = era_block_height
+    GROUP BY s."date", s.validator_public_key
+    HAVING count(*) FILTER (WHERE s.delegator_count > 0) > 0
+    ON CONFLICT (validator_public_key, "date") DO UPDATE
+        SET "avg" = excluded."avg";
+END;
+$$ LANGUAGE plpgsql;
+
+-- rebuild_rollups compute every rollup again from the rewards, bids_per_era and delegators_per_era tables
+CREATE OR REPLACE FUNCTION rebuild_rollups() RETURNS VOID AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('rollup_era_rewards'));
+    PERFORM pg_advisory_xact_lock(hashtext('rollup_era_stakes'));
+    TRUNCATE rewards_era_cumulative_per_validator, rewards_daily_cumulative_per_validator, staked_amount_per_era_per_validator,
+        staked_amount_daily_candle_per_validator, delegators_daily_count_per_validator;
+
+    INSERT INTO rewards_era_cumulative_per_validator (era, validator_public_key, date_era_end, era_validator_rewards, era_delegators_rewards,
+                                                      era_cumulative_validator_rewards, era_cumulative_delegators_rewards)
+    SELECT era,
+           validator_public_key,
+           date_era_end,
+           validator_rewards,
+           delegators_rewards,
+           sum(validator_rewards) OVER (PARTITION BY validator_public_key ORDER BY era),
+           sum(delegators_rewards) OVER (PARTITION BY validator_public_key ORDER BY era)
+    FROM (SELECT r.era,
+                 r.validator_public_key,
+                 date(max(b."timestamp")) AS date_era_end,
+                 COALESCE(sum(r.amount) FILTER (WHERE r.delegator_public_key IS NULL), 0) AS validator_rewards,
+                 COALESCE(sum(r.amount) FILTER (WHERE r.delegator_public_key IS NOT NULL), 0) AS delegators_rewards
+          FROM rewards r
+                   JOIN blocks b ON b.hash = r.block
+          GROUP BY r.era, r.validator_public_key) e;
+
+    INSERT INTO rewards_daily_cumulative_per_validator (date_era_end, validator_public_key, daily_cumulative_validator_rewards, daily_cumulative_delegators_rewards)
+    SELECT date_era_end, validator_public_key, max(era_cumulative_validator_rewards), max(era_cumulative_delegators_rewards)
+    FROM rewards_era_cumulative_per_validator
+    GROUP BY date_era_end, validator_public_key;
+
+    INSERT INTO staked_amount_per_era_per_validator (block_height, validator_public_key, "timestamp", "date", staked_amount, delegator_count)
+    SELECT s.block_height, s.validator_public_key, b."timestamp", date(b."timestamp"), sum(s.staked_amount), sum(s.delegator_count)
+    FROM (SELECT block_height, validator_public_key, max(staked_amount) AS staked_amount, 0 AS delegator_count
+          FROM bids_per_era
+          GROUP BY block_height, validator_public_key
+          UNION ALL
+          SELECT block_height, validator_public_key, sum(staked_amount), count(DISTINCT delegator_public_key)
+          FROM delegators_per_era
+          GROUP BY block_height, validator_public_key) s
+             JOIN blocks b ON b.height = s.block_height
+    GROUP BY s.block_height, s.validator_public_key, b."timestamp";
+
+    INSERT INTO staked_amount_daily_candle_per_validator ("date", validator_public_key, open_staked_amount, avg_staked_amount, max_staked_amount, min_staked_amount, close_staked_amount)
+    SELECT "date",
+           validator_public_key,
+           open_staked_amount,
+           avg_staked_amount,
+           max_staked_amount,
+           min_staked_amount,
+           lead(open_staked_amount) OVER (PARTITION BY validator_public_key ORDER BY "date")
+    FROM (SELECT "date",
+                 validator_public_key,
+                 (array_agg(staked_amount ORDER BY "timestamp"))[1] AS open_staked_amount,
+                 avg(staked_amount) AS avg_staked_amount,
+                 max(staked_amount) AS max_staked_amount,
+                 min(staked_amount) AS min_staked_amount
+          FROM staked_amount_per_era_per_validator
+          GROUP BY "date", validator_public_key) d;
+
+    INSERT INTO delegators_daily_count_per_validator ("date", validator_public_key, "avg")
+    SELECT "date", validator_public_key, avg(delegator_count)
+    FROM staked_amount_per_era_per_validator
+    WHERE delegator_count > 0
+    GROUP BY "date", validator_public_key;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT rebuild_rollups();
+
+grant select on public.rewards_era_cumulative_per_validator to web_anon;
+grant select on public.rewards_daily_cumulative_per_validator to web_anon;
+grant select on public.staked_amount_per_era_per_validator to web_anon;
+grant select on public.staked_amount_daily_candle_per_validator to web_anon;
+grant select on public.delegators_daily_count_per_validator to web_anon;
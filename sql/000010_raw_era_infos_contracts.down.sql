@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "raw_contracts";
+DROP TABLE IF EXISTS "raw_era_infos";
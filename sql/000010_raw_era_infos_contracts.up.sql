@@ -0,0 +1,44 @@
+-- The era infos and the contracts are stored raw like the blocks, the deploys, the deploy infos and the transfers,
+-- so the rewards and the contracts can be parsed again from the database alone.
+-- The era info of a switch block, by the hash of the block
+CREATE TABLE "raw_era_infos"
+(
+    "hash" VARCHAR(64) PRIMARY KEY,
+    "data" jsonb NOT NULL
+);
+
+CREATE TABLE "raw_contracts"
+(
+    "hash" VARCHAR(64) PRIMARY KEY,
+    "data" jsonb NOT NULL
+);
+
+-- The era infos parsed before this migration are rebuilt from the rewards, without the state root hash and the merkle proof
+INSERT INTO raw_era_infos ("hash", "data")
+SELECT block,
+       jsonb_build_object('era_summary', jsonb_build_object(
+               'block_hash', block,
+               'era_id', era,
+               'stored_value', jsonb_build_object('EraInfo', jsonb_build_object('seigniorage_allocations', jsonb_agg(
+                       CASE
+                           WHEN delegator_public_key IS NULL THEN jsonb_build_object('Validator', jsonb_build_object(
+                                   'validator_public_key', validator_public_key,
+                                   'amount', amount::TEXT))
+                           ELSE jsonb_build_object('Delegator', jsonb_build_object(
+                                   'delegator_public_key', delegator_public_key,
+                                   'validator_public_key', validator_public_key,
+                                   'amount', amount::TEXT))
+                           END)))))
+FROM rewards
+GROUP BY block, era
+ON CONFLICT DO NOTHING;
+
+-- The contracts parsed before this migration are rebuilt from the contracts and their named keys, without the merkle proof
+INSERT INTO raw_contracts ("hash", "data")
+SELECT c.hash,
+       jsonb_build_object('stored_value', jsonb_set(c.data, '{Contract,named_keys}', COALESCE(
+               (SELECT jsonb_agg(jsonb_build_object('name', nk.name, 'key', nk.uref))
+                FROM contracts_named_keys cnk
+                         INNER JOIN named_keys nk ON nk.uref = cnk.named_key_uref
+                WHERE cnk.contract_hash = c.hash), '[]'::jsonb)))
+FROM contracts c;
@@ -0,0 +1,39 @@
+-- The transforms of the execution effect of the deploys, in the order of the effect, so any change of the global state
+-- can be queried without the raw deploys. The value is the json value of the transform, NULL for the transforms without one.
+-- The value of a WriteCLValue is the value decoded from its bytes, the parsed value of the node for the backfilled rows.
+CREATE TABLE "transforms"
+(
+    "deploy" VARCHAR(64) NOT NULL,
+    "index"  INTEGER     NOT NULL,
+    "key"    TEXT        NOT NULL,
+    "kind"   VARCHAR(32) NOT NULL,
+    "value"  jsonb,
+    PRIMARY KEY ("deploy", "index")
+);
+
+CREATE INDEX "transforms_key_idx" ON "transforms" ("key");
+CREATE INDEX "transforms_kind_idx" ON "transforms" ("kind");
+
+-- Fill the transforms from the raw deploys still in the database
+INSERT INTO "transforms" ("deploy", "index", "key", "kind", "value")
+SELECT raw_deploys.hash,
+       transforms.index - 1,
+       transforms.transform ->> 'key',
+       kinds.kind,
+       CASE WHEN kinds.kind = 'WriteCLValue' THEN kinds.value -> 'parsed' ELSE kinds.value END
+FROM "raw_deploys"
+         CROSS JOIN LATERAL jsonb_array_elements(
+        COALESCE(raw_deploys.data -> 'execution_results' -> 0 -> 'result' -> 'Success' -> 'effect',
+                 raw_deploys.data -> 'execution_results' -> 0 -> 'result' -> 'Failure' -> 'effect') -> 'transforms'
+    ) WITH ORDINALITY AS transforms(transform, index)
+         CROSS JOIN LATERAL (SELECT transforms.transform ->> 'transform' AS kind, NULL::jsonb AS value
+                             WHERE jsonb_typeof(transforms.transform -> 'transform') = 'string'
+                             UNION ALL
+                             SELECT transform_values.key, transform_values.value
+                             FROM jsonb_each(CASE
+                                                 WHEN jsonb_typeof(transforms.transform -> 'transform') = 'object'
+                                                     THEN transforms.transform -> 'transform'
+                                                 ELSE '{}'::jsonb END) AS transform_values) kinds
+ON CONFLICT DO NOTHING;
+
+grant select on public.transforms to web_anon;
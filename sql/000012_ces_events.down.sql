@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "contract_events";
+DROP TABLE IF EXISTS "ces_contracts";
@@ -0,0 +1,30 @@
+-- The contracts following the Casper Event Standard. The events_uref is the address of the uref of their __events
+-- dictionary, without the access rights, the versions of a contract package can share it.
+-- The schemas of their events are decoded from their __events_schema named key, by event name.
+CREATE TABLE "ces_contracts"
+(
+    "contract_hash" VARCHAR(64) PRIMARY KEY,
+    "events_uref"   VARCHAR(64) NOT NULL,
+    "schemas"       jsonb       NOT NULL,
+    "updated_at"    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "ces_contracts_events_uref_idx" ON "ces_contracts" ("events_uref");
+
+-- The events written by the deploys in the events dictionary of the CES contracts, the id is the key of the event in the dictionary
+CREATE TABLE "contract_events"
+(
+    "events_uref"   VARCHAR(64) NOT NULL,
+    "event_id"      BIGINT      NOT NULL,
+    "contract_hash" VARCHAR(64) NOT NULL,
+    "deploy"        VARCHAR(64) NOT NULL,
+    "name"          TEXT        NOT NULL,
+    "data"          jsonb       NOT NULL,
+    PRIMARY KEY ("events_uref", "event_id")
+);
+
+CREATE INDEX "contract_events_contract_hash_name_idx" ON "contract_events" ("contract_hash", "name");
+CREATE INDEX "contract_events_deploy_idx" ON "contract_events" ("deploy");
+
+grant select on public.ces_contracts to web_anon;
+grant select on public.contract_events to web_anon;
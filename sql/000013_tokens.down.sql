@@ -0,0 +1,50 @@
+DROP TABLE IF EXISTS "token_transfers";
+DROP TABLE IF EXISTS "token_balances";
+DROP TABLE IF EXISTS "token_balance_history";
+DROP TABLE IF EXISTS "tokens";
+
+CREATE OR REPLACE FUNCTION account_ercs20(publickey VARCHAR, accounthash VARCHAR)
+    RETURNS TABLE
+            (
+                contract_hash VARCHAR(64)
+            )
+AS
+$$
+SELECT DISTINCT contract_hash
+FROM deploys
+WHERE contract_hash IN (SELECT hash from contracts where contracts.type = 'erc20' or contracts.type = 'uniswaperc20')
+  and "from" = publickey
+  and result = 'success'
+UNION
+SELECT DISTINCT contract_hash
+FROM deploys
+WHERE contract_hash IN (SELECT hash from contracts where contracts.type = 'erc20' or contracts.type = 'uniswaperc20')
+  and (metadata -> 'recipient' ->> 'Account' = accounthash
+    or metadata ->> 'recipient' = accounthash)
+  and result = 'success';
+$$ LANGUAGE SQL;
+
+CREATE OR REPLACE FUNCTION erc20_holders(contracthash VARCHAR)
+    RETURNS TABLE
+            (
+                account VARCHAR
+            )
+AS
+$$
+SELECT DISTINCT "from" as account
+FROM deploys
+WHERE contract_hash = contracthash
+  and result = 'success'
+UNION
+SELECT DISTINCT metadata -> 'recipient' ->> 'Account' as account
+FROM deploys
+WHERE contract_hash = contracthash
+  and metadata -> 'recipient' ->> 'Account' != ''
+  and result = 'success'
+UNION
+SELECT DISTINCT metadata ->> 'recipient' as account
+FROM deploys
+WHERE contract_hash = contracthash
+  and length(metadata ->> 'recipient') = 64
+  and result = 'success';
+$$ LANGUAGE SQL;
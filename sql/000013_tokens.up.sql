@@ -0,0 +1,139 @@
+-- The CEP-18 tokens, by contract package. The contract_hash is the last version of the package stored.
+-- The urefs are the addresses of the balances dictionary and of the total_supply uref, without the access rights.
+-- The supply is the value read when the contract was stored, then the last one written by a deploy, at supply_block_height and supply_deploy_index.
+CREATE TABLE "tokens"
+(
+    "package_hash"        VARCHAR(64) PRIMARY KEY,
+    "contract_hash"       VARCHAR(64) NOT NULL,
+    "name"                TEXT,
+    "symbol"              TEXT,
+    "decimals"            INTEGER,
+    "total_supply"        NUMERIC,
+    "balances_uref"       VARCHAR(64) NOT NULL,
+    "total_supply_uref"   VARCHAR(64) NOT NULL,
+    "supply_block_height" BIGINT      NOT NULL DEFAULT 0,
+    "supply_deploy_index" INTEGER     NOT NULL DEFAULT 0
+);
+
+CREATE INDEX "tokens_balances_uref_idx" ON "tokens" ("balances_uref");
+CREATE INDEX "tokens_total_supply_uref_idx" ON "tokens" ("total_supply_uref");
+
+-- The balances written by the deploys in the balances dictionary of the tokens. The holder is account-hash-<hex> or hash-<hex>,
+-- the deploy index is the position of the deploy in the block like purse_balance_history.
+CREATE TABLE "token_balance_history"
+(
+    "package_hash" VARCHAR(64) NOT NULL,
+    "holder"       TEXT        NOT NULL,
+    "block_height" BIGINT      NOT NULL,
+    "deploy_index" INTEGER     NOT NULL,
+    "deploy"       VARCHAR(64) NOT NULL,
+    "balance"      NUMERIC     NOT NULL,
+    PRIMARY KEY ("package_hash", "holder", "block_height", "deploy_index")
+);
+
+CREATE INDEX "token_balance_history_deploy_idx" ON "token_balance_history" ("deploy");
+
+-- The last balance written of each holder of a token
+CREATE TABLE "token_balances"
+(
+    "package_hash" VARCHAR(64) NOT NULL,
+    "holder"       TEXT        NOT NULL,
+    "balance"      NUMERIC     NOT NULL,
+    "block_height" BIGINT      NOT NULL,
+    "deploy_index" INTEGER     NOT NULL,
+    PRIMARY KEY ("package_hash", "holder")
+);
+
+CREATE INDEX "token_balances_holder_idx" ON "token_balances" ("holder");
+
+-- The mints, transfers and burns of the tokens, from the CES events of the tokens or from the entrypoint called when the
+-- token emitted no event, in the order of each deploy. A mint has no sender, a burn no recipient.
+CREATE TABLE "token_transfers"
+(
+    "deploy"         VARCHAR(64) NOT NULL,
+    "transfer_index" INTEGER     NOT NULL,
+    "package_hash"   VARCHAR(64) NOT NULL,
+    "kind"           VARCHAR     NOT NULL,
+    "from"           TEXT,
+    "to"             TEXT,
+    "amount"         NUMERIC     NOT NULL,
+    "block_height"   BIGINT      NOT NULL,
+    "deploy_index"   INTEGER     NOT NULL,
+    PRIMARY KEY ("deploy", "transfer_index")
+);
+
+CREATE INDEX "token_transfers_package_hash_idx" ON "token_transfers" ("package_hash", "block_height", "deploy_index");
+CREATE INDEX "token_transfers_from_idx" ON "token_transfers" ("from");
+CREATE INDEX "token_transfers_to_idx" ON "token_transfers" ("to");
+
+-- Fill the tokens from the contracts already stored, the balances and the transfers are filled by reparsing the deploys.
+-- The CEP-47 collections have balances and total_supply too, a token needs decimals and isn't classified as a collection.
+-- The contract of a package is the one stored by its last deploy.
+INSERT INTO "tokens" ("package_hash", "contract_hash", "name", "symbol", "decimals", "total_supply", "balances_uref",
+                      "total_supply_uref")
+SELECT DISTINCT ON (package) package,
+                             hash,
+                             name,
+                             symbol,
+                             CASE WHEN decimals ~ '^\d+$' THEN decimals::INTEGER END,
+                             CASE WHEN total_supply ~ '^\d+$' THEN total_supply::NUMERIC END,
+                             balances_uref,
+                             total_supply_uref
+FROM (SELECT contracts.package,
+             contracts.hash,
+             deploys."timestamp",
+             max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name = 'name')         AS name,
+             max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name = 'symbol')       AS symbol,
+             max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name = 'decimals')     AS decimals,
+             max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name = 'total_supply') AS total_supply,
+             max(substring(named_keys.uref FROM 6 FOR 64)) FILTER (WHERE named_keys.name = 'balances')     AS balances_uref,
+             max(substring(named_keys.uref FROM 6 FOR 64)) FILTER (WHERE named_keys.name = 'total_supply') AS total_supply_uref,
+             bool_or(named_keys.name = 'decimals')                                                        AS has_decimals
+      FROM "contracts"
+               INNER JOIN "contracts_named_keys" ON contracts_named_keys.contract_hash = contracts.hash
+               INNER JOIN "named_keys" ON named_keys.uref = contracts_named_keys.named_key_uref
+               LEFT JOIN "deploys" ON deploys.hash = contracts.deploy
+      WHERE named_keys.uref LIKE 'uref-%'
+        AND contracts.type NOT IN ('nftcep47', 'nftcep78')
+      GROUP BY contracts.package, contracts.hash, deploys."timestamp") token_contracts
+WHERE balances_uref IS NOT NULL
+  AND total_supply_uref IS NOT NULL
+  AND has_decimals
+ORDER BY package, "timestamp" DESC NULLS LAST, hash
+ON CONFLICT DO NOTHING;
+
+-- The tokens held by an account, from its balances instead of the deploy args
+CREATE OR REPLACE FUNCTION account_ercs20(publickey VARCHAR, accounthash VARCHAR)
+    RETURNS TABLE
+            (
+                contract_hash VARCHAR(64)
+            )
+AS
+$$
+SELECT DISTINCT tokens.contract_hash
+FROM token_balances
+         INNER JOIN tokens ON tokens.package_hash = token_balances.package_hash
+WHERE token_balances.holder IN (SELECT 'account-hash-' || account_hash FROM accounts WHERE public_key = publickey
+                                UNION
+                                SELECT 'account-hash-' || regexp_replace(accounthash, '^account-hash-', ''))
+  AND token_balances.balance > 0;
+$$ LANGUAGE SQL;
+
+-- The holders of a token with a balance, any version of its package
+CREATE OR REPLACE FUNCTION erc20_holders(contracthash VARCHAR)
+    RETURNS TABLE
+            (
+                account VARCHAR
+            )
+AS
+$$
+SELECT token_balances.holder
+FROM token_balances
+WHERE token_balances.package_hash IN (SELECT package FROM contracts WHERE hash = contracthash)
+  AND token_balances.balance > 0;
+$$ LANGUAGE SQL;
+
+grant select on public.tokens to web_anon;
+grant select on public.token_balance_history to web_anon;
+grant select on public.token_balances to web_anon;
+grant select on public.token_transfers to web_anon;
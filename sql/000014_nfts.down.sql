@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "nft_transfers";
+DROP TABLE IF EXISTS "nft_tokens";
+DROP TABLE IF EXISTS "nft_collections";
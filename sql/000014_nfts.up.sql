@@ -0,0 +1,69 @@
+-- The CEP-47 and CEP-78 NFT collections, by contract package. The contract_hash is the last version of the package stored.
+CREATE TABLE "nft_collections"
+(
+    "package_hash"  VARCHAR(64) PRIMARY KEY,
+    "contract_hash" VARCHAR(64) NOT NULL,
+    "standard"      VARCHAR     NOT NULL,
+    "name"          TEXT,
+    "symbol"        TEXT
+);
+
+-- The tokens of the collections, with their last owner, metadata and spender approved. A burnt token keeps its last owner.
+-- The block height and the deploy index are the ones of the last deploy acting on the token, like purse_balance_history.
+CREATE TABLE "nft_tokens"
+(
+    "package_hash" VARCHAR(64) NOT NULL,
+    "token_id"     TEXT        NOT NULL,
+    "owner"        TEXT,
+    "metadata"     TEXT,
+    "burnt"        BOOLEAN     NOT NULL DEFAULT false,
+    "approved"     TEXT,
+    "block_height" BIGINT      NOT NULL,
+    "deploy_index" INTEGER     NOT NULL,
+    PRIMARY KEY ("package_hash", "token_id")
+);
+
+CREATE INDEX "nft_tokens_owner_idx" ON "nft_tokens" ("owner");
+
+-- The mints, transfers and burns of the tokens, in the order of the actions of each deploy. A mint has no sender,
+-- a burn no recipient. The sender is empty when the deploy doesn't tell it.
+CREATE TABLE "nft_transfers"
+(
+    "deploy"       VARCHAR(64) NOT NULL,
+    "action_index" INTEGER     NOT NULL,
+    "package_hash" VARCHAR(64) NOT NULL,
+    "token_id"     TEXT        NOT NULL,
+    "kind"         VARCHAR     NOT NULL,
+    "from"         TEXT,
+    "to"           TEXT,
+    "block_height" BIGINT      NOT NULL,
+    "deploy_index" INTEGER     NOT NULL,
+    PRIMARY KEY ("deploy", "action_index")
+);
+
+CREATE INDEX "nft_transfers_package_hash_token_id_idx" ON "nft_transfers" ("package_hash", "token_id", "block_height", "deploy_index");
+CREATE INDEX "nft_transfers_from_idx" ON "nft_transfers" ("from");
+CREATE INDEX "nft_transfers_to_idx" ON "nft_transfers" ("to");
+
+-- Fill the collections from the contracts already classified, the tokens are filled by reparsing the deploys.
+-- The contract of a package is the one stored by its last deploy.
+INSERT INTO "nft_collections" ("package_hash", "contract_hash", "standard", "name", "symbol")
+SELECT DISTINCT ON (contracts.package) contracts.package,
+                                       contracts.hash,
+                                       CASE contracts.type WHEN 'nftcep78' THEN 'cep78' ELSE 'cep47' END,
+                                       max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name =
+                                           CASE contracts.type WHEN 'nftcep78' THEN 'collection_name' ELSE 'name' END),
+                                       max(named_keys.initial_value #>> '{}') FILTER (WHERE named_keys.name =
+                                           CASE contracts.type WHEN 'nftcep78' THEN 'collection_symbol' ELSE 'symbol' END)
+FROM "contracts"
+         LEFT JOIN "contracts_named_keys" ON contracts_named_keys.contract_hash = contracts.hash
+         LEFT JOIN "named_keys" ON named_keys.uref = contracts_named_keys.named_key_uref
+         LEFT JOIN "deploys" ON deploys.hash = contracts.deploy
+WHERE contracts.type IN ('nftcep47', 'nftcep78')
+GROUP BY contracts.package, contracts.hash, contracts.type, deploys."timestamp"
+ORDER BY contracts.package, deploys."timestamp" DESC NULLS LAST, contracts.hash
+ON CONFLICT DO NOTHING;
+
+grant select on public.nft_collections to web_anon;
+grant select on public.nft_tokens to web_anon;
+grant select on public.nft_transfers to web_anon;
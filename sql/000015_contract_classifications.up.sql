@@ -0,0 +1,17 @@
+-- The classifications of the contracts, by version of the contract types of the config. The type and the score of the last
+-- classification are also in the contracts table. The candidates are the best definitions with the score of each component.
+CREATE TABLE "contract_classifications"
+(
+    "contract_hash"  VARCHAR(64) NOT NULL,
+    "config_version" VARCHAR     NOT NULL,
+    "type"           VARCHAR     NOT NULL,
+    "score"          FLOAT       NOT NULL,
+    "considered"     jsonb       NOT NULL,
+    "candidates"     jsonb       NOT NULL,
+    "classified_at"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("contract_hash", "config_version")
+);
+
+CREATE INDEX "contract_classifications_config_version_type_idx" ON "contract_classifications" ("config_version", "type");
+
+grant select on public.contract_classifications to web_anon;
@@ -0,0 +1,6 @@
+DROP FUNCTION IF EXISTS rebuild_fee_rollups();
+DROP FUNCTION IF EXISTS add_deploy_fees(DATE, VARCHAR, VARCHAR, INTEGER, NUMERIC, NUMERIC, NUMERIC);
+
+DROP TABLE IF EXISTS "fees_daily_per_contract";
+DROP TABLE IF EXISTS "fees_daily_per_account";
+DROP TABLE IF EXISTS "deploy_fees";
@@ -0,0 +1,111 @@
+-- The payment and the fee of each deploy. The payment is the amount arg of the payment code, zero for a custom payment
+-- without one. The unused amount is the part of the payment above the cost of the execution.
+CREATE TABLE "deploy_fees"
+(
+    "deploy"         VARCHAR(64) PRIMARY KEY,
+    "from"           VARCHAR(68) NOT NULL,
+    "contract_hash"  VARCHAR(64),
+    "date"           DATE        NOT NULL,
+    "payment_amount" NUMERIC     NOT NULL,
+    "gas_price"      INTEGER     NOT NULL,
+    "cost"           NUMERIC     NOT NULL,
+    "unused"         NUMERIC     NOT NULL
+);
+
+CREATE INDEX "deploy_fees_from_date_idx" ON "deploy_fees" ("from", "date");
+CREATE INDEX "deploy_fees_contract_hash_date_idx" ON "deploy_fees" ("contract_hash", "date");
+
+-- The fees paid each day by the accounts sending the deploys, the date is the one of the deploy
+CREATE TABLE "fees_daily_per_account"
+(
+    "date"           DATE        NOT NULL,
+    "from"           VARCHAR(68) NOT NULL,
+    "deploy_count"   INTEGER     NOT NULL,
+    "payment_amount" NUMERIC     NOT NULL,
+    "cost"           NUMERIC     NOT NULL,
+    "unused"         NUMERIC     NOT NULL,
+    PRIMARY KEY ("from", "date")
+);
+
+-- The fees paid each day by the deploys calling a contract by its hash
+CREATE TABLE "fees_daily_per_contract"
+(
+    "date"           DATE        NOT NULL,
+    "contract_hash"  VARCHAR(64) NOT NULL,
+    "deploy_count"   INTEGER     NOT NULL,
+    "payment_amount" NUMERIC     NOT NULL,
+    "cost"           NUMERIC     NOT NULL,
+    "unused"         NUMERIC     NOT NULL,
+    PRIMARY KEY ("contract_hash", "date")
+);
+
+-- add_deploy_fees add the fees of deploys to the day of an account, and of a contract when it's set. A deploy removed
+-- from a day is added with negative amounts, the days left without deploy are deleted.
+CREATE OR REPLACE FUNCTION add_deploy_fees(fee_date DATE, account VARCHAR, contract VARCHAR, deploys INTEGER,
+                                           payment NUMERIC, fee_cost NUMERIC, fee_unused NUMERIC) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO fees_daily_per_account ("date", "from", deploy_count, payment_amount, cost, unused)
+    VALUES (fee_date, account, deploys, payment, fee_cost, fee_unused)
+    ON CONFLICT ("from", "date") DO UPDATE
+        SET deploy_count   = fees_daily_per_account.deploy_count + excluded.deploy_count,
+            payment_amount = fees_daily_per_account.payment_amount + excluded.payment_amount,
+            cost           = fees_daily_per_account.cost + excluded.cost,
+            unused         = fees_daily_per_account.unused + excluded.unused;
+    DELETE FROM fees_daily_per_account WHERE "from" = account AND "date" = fee_date AND deploy_count <= 0;
+
+    IF contract IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO fees_daily_per_contract ("date", contract_hash, deploy_count, payment_amount, cost, unused)
+    VALUES (fee_date, contract, deploys, payment, fee_cost, fee_unused)
+    ON CONFLICT (contract_hash, "date") DO UPDATE
+        SET deploy_count   = fees_daily_per_contract.deploy_count + excluded.deploy_count,
+            payment_amount = fees_daily_per_contract.payment_amount + excluded.payment_amount,
+            cost           = fees_daily_per_contract.cost + excluded.cost,
+            unused         = fees_daily_per_contract.unused + excluded.unused;
+    DELETE FROM fees_daily_per_contract WHERE contract_hash = contract AND "date" = fee_date AND deploy_count <= 0;
+END;
+$$ LANGUAGE plpgsql;
+
+-- rebuild_fee_rollups compute the daily fees again from the deploy fees, used after a backfill of the deploy fees
+CREATE OR REPLACE FUNCTION rebuild_fee_rollups() RETURNS VOID AS $$
+BEGIN
+    TRUNCATE fees_daily_per_account, fees_daily_per_contract;
+
+    INSERT INTO fees_daily_per_account ("date", "from", deploy_count, payment_amount, cost, unused)
+    SELECT "date", "from", count(*), sum(payment_amount), sum(cost), sum(unused)
+    FROM deploy_fees
+    GROUP BY "date", "from";
+
+    INSERT INTO fees_daily_per_contract ("date", contract_hash, deploy_count, payment_amount, cost, unused)
+    SELECT "date", contract_hash, count(*), sum(payment_amount), sum(cost), sum(unused)
+    FROM deploy_fees
+    WHERE contract_hash IS NOT NULL
+    GROUP BY "date", contract_hash;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Fill the fees of the deploys whose raw deploy is still stored, the archived ones are filled when they're restored and reparsed
+INSERT INTO "deploy_fees" ("deploy", "from", "contract_hash", "date", "payment_amount", "gas_price", "cost", "unused")
+SELECT d.hash,
+       d."from",
+       d.contract_hash,
+       date(d."timestamp"),
+       COALESCE(p.amount, 0),
+       COALESCE((r.data -> 'deploy' -> 'header' ->> 'gas_price')::INTEGER, 0),
+       d.cost,
+       GREATEST(COALESCE(p.amount, 0) - d.cost, 0)
+FROM "deploys" d
+         INNER JOIN "raw_deploys" r ON r.hash = d.hash AND r."timestamp" = d."timestamp"
+         LEFT JOIN LATERAL (SELECT (arg -> 1 ->> 'parsed')::NUMERIC AS amount
+                            FROM jsonb_array_elements(r.data -> 'deploy' -> 'payment' -> 'ModuleBytes' -> 'args') arg
+                            WHERE arg ->> 0 = 'amount'
+                              AND arg -> 1 ->> 'parsed' ~ '^[0-9]+$'
+                            LIMIT 1) p ON true
+ON CONFLICT DO NOTHING;
+
+SELECT rebuild_fee_rollups();
+
+grant select on public.deploy_fees to web_anon;
+grant select on public.fees_daily_per_account to web_anon;
+grant select on public.fees_daily_per_contract to web_anon;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "deploy_verifications";
+DROP TABLE IF EXISTS "deploy_approvals";
@@ -0,0 +1,33 @@
+-- The approvals of the deploys, in the order of the deploy. An approval is valid when it's the signature of the deploy
+-- hash by its signer.
+CREATE TABLE "deploy_approvals"
+(
+    "deploy"         VARCHAR(64) NOT NULL,
+    "approval_index" INTEGER     NOT NULL,
+    "signer"         TEXT        NOT NULL,
+    "signature"      TEXT        NOT NULL,
+    "valid"          BOOLEAN     NOT NULL,
+    PRIMARY KEY ("deploy", "approval_index")
+);
+
+CREATE INDEX "deploy_approvals_signer_idx" ON "deploy_approvals" ("signer");
+
+-- The integrity check of the deploys sent by the node, their body hash and hash computed again from their header,
+-- payment and session, and their approvals verified. The error tells why a deploy can't be serialized, its hashes are
+-- then NULL.
+-- The deploys parsed before this table was added are checked when they're reparsed.
+CREATE TABLE "deploy_verifications"
+(
+    "deploy"          VARCHAR(64) PRIMARY KEY,
+    "body_hash_valid" BOOLEAN,
+    "hash_valid"      BOOLEAN,
+    "approvals_valid" BOOLEAN     NOT NULL,
+    "error"           TEXT,
+    "verified_at"     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "deploy_verifications_invalid_idx" ON "deploy_verifications" ("verified_at")
+    WHERE "error" IS NOT NULL OR NOT ("body_hash_valid" AND "hash_valid" AND "approvals_valid");
+
+grant select on public.deploy_approvals to web_anon;
+grant select on public.deploy_verifications to web_anon;
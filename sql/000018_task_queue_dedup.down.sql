@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "task_queue_dedup_key";
+
+UPDATE "task_queue" SET "dedup_key" = NULL
+WHERE "archived" AND "dedup_key" IN (SELECT "dedup_key" FROM "task_queue" WHERE NOT "archived");
+
+ALTER TABLE "task_queue" ADD CONSTRAINT "task_queue_dedup_key_key" UNIQUE ("dedup_key");
@@ -0,0 +1,4 @@
+-- The dedup key only block the tasks waiting or running, an archived task keep its key for inspection
+ALTER TABLE "task_queue" DROP CONSTRAINT IF EXISTS "task_queue_dedup_key_key";
+
+CREATE UNIQUE INDEX IF NOT EXISTS "task_queue_dedup_key" ON "task_queue" ("dedup_key") WHERE NOT "archived";
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "delegators_auction_key";
@@ -0,0 +1,25 @@
+-- The auction upserts the delegators on (public_key, delegatee, bonding_purse), the columns need a unique index.
+-- The init migration adds the uAuction constraint, a database missing it is deduplicated and gets the index.
+DO
+$$
+BEGIN
+    IF NOT EXISTS (SELECT
+                   FROM pg_index
+                   WHERE pg_index.indrelid = 'delegators'::regclass
+                     AND pg_index.indisunique
+                     AND (SELECT array_agg(pg_attribute.attname::TEXT ORDER BY pg_attribute.attname)
+                          FROM pg_attribute
+                          WHERE pg_attribute.attrelid = pg_index.indrelid
+                            AND pg_attribute.attnum = ANY (pg_index.indkey)) =
+                         ARRAY ['bonding_purse', 'delegatee', 'public_key']) THEN
+        DELETE
+        FROM "delegators" duplicate
+            USING "delegators" kept
+        WHERE duplicate.ctid > kept.ctid
+          AND duplicate.public_key = kept.public_key
+          AND duplicate.delegatee = kept.delegatee
+          AND duplicate.bonding_purse = kept.bonding_purse;
+        CREATE UNIQUE INDEX "delegators_auction_key" ON "delegators" ("public_key", "delegatee", "bonding_purse");
+    END IF;
+END
+$$;
@@ -0,0 +1,9 @@
+// Package sql embed the database migrations in the binary
+package sql
+
+import "embed"
+
+// Migrations the up and down scripts, named as expected by golang-migrate
+//
+//go:embed *.sql
+var Migrations embed.FS
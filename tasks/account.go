@@ -1,15 +1,15 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
 	"casperParser/utils"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 )
 
 // TypeAccountHash Task account hash raw insert
@@ -69,20 +69,19 @@ func HandleAccountHashTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
-	purse, err := WorkerRpcClient.GetMainPurse("account-hash-" + p.Hash)
+	purse, err := WorkerRpcClient.GetMainPurse(ctx, "account-hash-"+p.Hash)
 	if err != nil {
 		return err
 	}
-	//log.Printf("Hash: %s Purse: %s \n", p.Hash, purse)
-	var database = db.DB{Postgres: WorkerPool}
+	logger.FromContext(ctx).WithFields(log.Fields{"account_hash": p.Hash, "purse": purse}).Debug("main purse found")
+	var database = WorkerStore
 
 	err = database.InsertAccountHash(ctx, p.Hash, purse)
 	if err != nil {
 		return err
 	}
 
-	addFetchPurseToQueue(purse)
-	return nil
+	return addFetchPurseToQueue(ctx, purse)
 }
 
 // HandleAccountTask fetch main purse from the rpc endpoint, parse it, and insert it in the database
@@ -98,23 +97,22 @@ func HandleAccountTask(ctx context.Context, t *asynq.Task) error {
 		return fmt.Errorf("unable to convert public key : %s into account hash", p.Hash)
 	}
 
-	purse, err := WorkerRpcClient.GetMainPurse("account-hash-" + accountHash)
+	purse, err := WorkerRpcClient.GetMainPurse(ctx, "account-hash-"+accountHash)
 	if err != nil {
 		if strings.Contains(err.Error(), "ValueNotFound(\"Failed to find base key at path") {
 			return nil
 		}
 		return err
 	}
-	//log.Printf("Hash: %s AccountHash: %s Purse: %s \n", p.Hash, accountHash, purse)
-	var database = db.DB{Postgres: WorkerPool}
+	logger.FromContext(ctx).WithFields(log.Fields{"public_key": p.Hash, "account_hash": accountHash, "purse": purse}).Debug("main purse found")
+	var database = WorkerStore
 
 	err = database.InsertAccount(ctx, p.Hash, accountHash, purse)
 	if err != nil {
 		return err
 	}
 
-	addFetchPurseToQueue(purse)
-	return nil
+	return addFetchPurseToQueue(ctx, purse)
 }
 
 // HandlePurseTask insert purse in the database
@@ -124,27 +122,19 @@ func HandlePurseTask(ctx context.Context, t *asynq.Task) error {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 
-	//log.Printf("Purse: %s \n", p.Hash)
 	err := database.InsertPurse(ctx, p.Hash)
 	if err != nil {
 		return err
 	}
-	addFetchPurseToQueue(p.Hash)
-	return nil
+	return addFetchPurseToQueue(ctx, p.Hash)
 }
 
 // addFetchPurseToQueue
-func addFetchPurseToQueue(hash string) {
+func addFetchPurseToQueue(ctx context.Context, hash string) error {
 	task, err := NewFetchPurseTask(hash)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("accounts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "accounts")
 }
 
 // HandleFetchPurseTask fetch purse from the rpc endpoint, parse it, and insert it in the database
@@ -154,11 +144,14 @@ func HandleFetchPurseTask(ctx context.Context, t *asynq.Task) error {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
 
-	balance, err := WorkerRpcClient.GetPurseBalance(p.Hash)
+	balance, err := WorkerRpcClient.GetPurseBalance(ctx, p.Hash)
+	if err != nil {
+		return err
+	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 
-	//log.Printf("Purse: %s Balance: %s \n", p.Hash, balance)
+	logger.FromContext(ctx).WithFields(log.Fields{"purse": p.Hash, "balance": balance.String()}).Debug("purse balance found")
 	err = database.InsertPurseBalance(ctx, p.Hash, balance)
 	if err != nil {
 		return err
@@ -169,4 +162,4 @@ func HandleFetchPurseTask(ctx context.Context, t *asynq.Task) error {
 
 type Hash struct {
 	Hash string
-}
\ No newline at end of file
+}
@@ -4,7 +4,9 @@ import (
 	"casperParser/db"
 	"casperParser/rpc"
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/hibiken/asynq"
@@ -57,11 +59,13 @@ func TestHandleAccountHashTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewAccountHashTask("fa12d2dd5547714f8c2754d418aa8c9d59dc88780350cb4254d622e2d4ef7e69")
 	if err != nil {
 		t.Errorf("Unable to create a NewAccountHashTask : %s", err)
@@ -79,11 +83,13 @@ func TestHandleAccountTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewAccountTask("0106ca7c39cd272dbf21a86eeb3b36b7c26e2e9b94af64292419f7862936bca2ca")
 	if err != nil {
 		t.Errorf("Unable to create a NewAccountTask : %s", err)
@@ -101,11 +107,13 @@ func TestHandlePurseTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewPurseTask("uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
 	if err != nil {
 		t.Errorf("Unable to create a NewPurseTask : %s", err)
@@ -123,11 +131,13 @@ func TestHandleFetchPurseTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewFetchPurseTask("uref-bb9f47c30ddbe192438fad10b7db8200247529d6592af7159d92c5f3aa7716a1-007")
 	if err != nil {
 		t.Errorf("Unable to create a NewFetchPurseTask : %s", err)
@@ -137,3 +147,27 @@ func TestHandleFetchPurseTask(t *testing.T) {
 		t.Errorf("Unable to run HandleFetchPurseTask : %s", err)
 	}
 }
+
+// failingClient a queue whose database is unreachable
+type failingClient struct{}
+
+func (failingClient) Enqueue(*asynq.Task, ...asynq.Option) (*asynq.TaskInfo, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHandlePurseTaskWithMemoryStore(t *testing.T) {
+	store := db.NewMemory()
+	WorkerStore = store
+	WorkerAsyncClient = failingClient{}
+	task, err := NewPurseTask("uref-" + strings.Repeat("ab", 32) + "-007")
+	if err != nil {
+		t.Fatalf("Unable to create a NewPurseTask : %s", err)
+	}
+
+	t.Run("Should fail the task to retry it when the queue can't enqueue", func(t *testing.T) {
+		err := HandlePurseTask(context.Background(), task)
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("Bad error. Received : %v. Expected : %s", err, "could not enqueue task: connection refused")
+		}
+	})
+}
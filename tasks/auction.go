@@ -2,17 +2,17 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 )
 
 // TypeAuction Task auction type
 const (
-	TypeAuction = "auction:raw"
+	TypeAuction    = "auction:raw"
 	TypeAuctionEra = "auctionEra:raw"
 )
 
@@ -29,9 +29,9 @@ func NewAuctionEraTask(blockheight int) (*asynq.Task, error) {
 	return asynq.NewTask(TypeAuctionEra, payload), nil
 }
 
-// HandleAuctionTask fetch auction from the rpc endpoint, parse it, and insert it in the database
+// HandleAuctionTask fetch auction from the rpc endpoint, parse it, and apply it to the bids and delegators in the database
 func HandleAuctionTask(ctx context.Context, t *asynq.Task) error {
-	auctionParsed, err := WorkerRpcClient.GetAuction()
+	auctionParsed, err := WorkerRpcClient.GetAuction(ctx)
 	if err != nil {
 		return err
 	}
@@ -39,27 +39,17 @@ func HandleAuctionTask(ctx context.Context, t *asynq.Task) error {
 	var rowsToInsertDelegators [][]interface{}
 
 	for _, b := range auctionParsed.AuctionState.Bids {
-		bStaked, ok := new(big.Int).SetString(b.Bid.StakedAmount, 10)
-		if ok {
-			bidRow := []interface{}{b.PublicKey, b.Bid.BondingPurse, bStaked.Int64(), b.Bid.DelegationRate, b.Bid.Inactive}
-			rowsToInsertBids = append(rowsToInsertBids, bidRow)
-			for _, d := range b.Bid.Delegators {
-				dStaked, dok := new(big.Int).SetString(d.StakedAmount, 10)
-				if dok {
-					delegatorRow := []interface{}{d.PublicKey, d.Delegatee, dStaked.Int64(), d.BondingPurse}
-					rowsToInsertDelegators = append(rowsToInsertDelegators, delegatorRow)
-				} else {
-					return fmt.Errorf("cannot convert stake to bigint for %s / v: %s", d.PublicKey, d.Delegatee)
-				}
-			}
-		} else {
-			return fmt.Errorf("cannot convert stake to bigint for v: %s", b.PublicKey)
+		bidRow := []interface{}{b.PublicKey, b.Bid.BondingPurse, b.Bid.StakedAmount, b.Bid.DelegationRate, b.Bid.Inactive}
+		rowsToInsertBids = append(rowsToInsertBids, bidRow)
+		for _, d := range b.Bid.Delegators {
+			delegatorRow := []interface{}{d.PublicKey, d.Delegatee, d.StakedAmount, d.BondingPurse}
+			rowsToInsertDelegators = append(rowsToInsertDelegators, delegatorRow)
 		}
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 
-	err = database.InsertAuction(ctx, rowsToInsertBids, rowsToInsertDelegators)
+	err = database.InsertAuction(ctx, auctionParsed.AuctionState.BlockHeight, rowsToInsertBids, rowsToInsertDelegators)
 	if err != nil {
 		return err
 	}
@@ -72,8 +62,9 @@ func HandleAuctionEraTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHeight: p.BlockIdentifier})
 
-	auctionParsed, err := WorkerRpcClient.GetAuctionEra(p.BlockIdentifier)
+	auctionParsed, err := WorkerRpcClient.GetAuctionEra(ctx, p.BlockIdentifier)
 	if err != nil {
 		return err
 	}
@@ -81,27 +72,17 @@ func HandleAuctionEraTask(ctx context.Context, t *asynq.Task) error {
 	var rowsToInsertDelegators [][]interface{}
 
 	for _, b := range auctionParsed.AuctionState.Bids {
-		bStaked, ok := new(big.Int).SetString(b.Bid.StakedAmount, 10)
-		if ok {
-			bidRow := []interface{}{p.BlockIdentifier, b.PublicKey, b.Bid.BondingPurse, bStaked.Int64(), b.Bid.DelegationRate, b.Bid.Inactive}
-			rowsToInsertBids = append(rowsToInsertBids, bidRow)
-			for _, d := range b.Bid.Delegators {
-				dStaked, dok := new(big.Int).SetString(d.StakedAmount, 10)
-				if dok {
-					delegatorRow := []interface{}{p.BlockIdentifier, d.PublicKey, d.Delegatee, dStaked.Int64(), d.BondingPurse}
-					rowsToInsertDelegators = append(rowsToInsertDelegators, delegatorRow)
-				} else {
-					return fmt.Errorf("cannot convert stake to bigint for %s / v: %s", d.PublicKey, d.Delegatee)
-				}
-			}
-		} else {
-			return fmt.Errorf("cannot convert stake to bigint for v: %s", b.PublicKey)
+		bidRow := []interface{}{p.BlockIdentifier, b.PublicKey, b.Bid.BondingPurse, b.Bid.StakedAmount, b.Bid.DelegationRate, b.Bid.Inactive}
+		rowsToInsertBids = append(rowsToInsertBids, bidRow)
+		for _, d := range b.Bid.Delegators {
+			delegatorRow := []interface{}{p.BlockIdentifier, d.PublicKey, d.Delegatee, d.StakedAmount, d.BondingPurse}
+			rowsToInsertDelegators = append(rowsToInsertDelegators, delegatorRow)
 		}
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 
-	err = database.InsertAuctionEra(ctx, rowsToInsertBids, rowsToInsertDelegators)
+	err = database.InsertAuctionEra(ctx, p.BlockIdentifier, rowsToInsertBids, rowsToInsertDelegators)
 	if err != nil {
 		return err
 	}
@@ -109,7 +90,6 @@ func HandleAuctionEraTask(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
-
 type AuctionEraPayload struct {
-	BlockIdentifier	int
-}
\ No newline at end of file
+	BlockIdentifier int
+}
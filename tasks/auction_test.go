@@ -26,11 +26,13 @@ func TestHandleAuctionTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewAuctionTask()
 	if err != nil {
 		t.Errorf("Unable to create a NewAuctionTask : %s", err)
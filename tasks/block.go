@@ -2,19 +2,23 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/tracing"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"strings"
 
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 )
 
 // TypeBlockRaw Task block raw insert
+// TypeBlockKnown Task block known type
 // TypeBlockVerify Task block verify
 const (
 	TypeBlockRaw       = "block:raw"
+	TypeBlockKnown     = "block:known"
 	TypeBlockVerify    = "block:verify"
 	TypeNativeTransfer = "native_transfer"
 )
@@ -28,6 +32,15 @@ func NewBlockRawTask(blockHeight int) (*asynq.Task, error) {
 	return asynq.NewTask(TypeBlockRaw, payload), nil
 }
 
+// NewBlockKnownTask used for already parsed blocks
+func NewBlockKnownTask(blockHash string) (*asynq.Task, error) {
+	payload, err := json.Marshal(BlockKnownPayload{BlockHash: blockHash})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeBlockKnown, payload), nil
+}
+
 // NewBlockVerifyTask used to verify blocks
 func NewBlockVerifyTask(blockHash string) (*asynq.Task, error) {
 	payload, err := json.Marshal(BlockVerifyPayload{BlockHash: blockHash})
@@ -43,13 +56,14 @@ func HandleBlockRawTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHeight: p.BlockHeight})
 
-	result, block, err := WorkerRpcClient.GetBlock(p.BlockHeight)
+	result, block, err := WorkerRpcClient.GetBlock(ctx, p.BlockHeight)
 	if err != nil {
 		return err
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 	eraEnd := result.Block.Header.EraEnd != nil
 	err = database.InsertBlock(ctx, result.Block.Hash, result.Block.Header.EraID, result.Block.Header.Timestamp, result.Block.Header.Height, eraEnd, string(block))
 	if err != nil {
@@ -57,19 +71,76 @@ func HandleBlockRawTask(ctx context.Context, t *asynq.Task) error {
 	}
 
 	if eraEnd {
-		addEraToQueue(result.Block.Hash)
-		addAuctionEraToQueue(result.Block.Header.Height)
+		if err := addEraToQueue(ctx, result.Block.Hash); err != nil {
+			return err
+		}
+		addAuctionEraToQueue(ctx, result.Block.Header.Height)
 	}
 
-	for _, s := range result.Block.Body.TransferHashes {
-		addDeployToQueue(s)
+	// The deploys are executed in this order, deploy hashes first then transfer hashes
+	for i, s := range result.Block.Body.TransferHashes {
+		if err := addDeployToQueue(ctx, s, result.Block.Header.Height, len(result.Block.Body.DeployHashes)+i); err != nil {
+			return err
+		}
 		// TODO: Deploy info does not contain timestamp, so we take Block timestamps which is not accurate, even false sometimes
-		addDeployToDeployInfoQueue(s, result.Block.Hash, result.Block.Header.StateRootHash, result.Block.Header.Timestamp)
+		if err := addDeployToDeployInfoQueue(ctx, s, result.Block.Hash, result.Block.Header.StateRootHash, result.Block.Header.Timestamp); err != nil {
+			return err
+		}
 	}
-	for _, s := range result.Block.Body.DeployHashes {
-		addDeployToQueue(s)
+	for i, s := range result.Block.Body.DeployHashes {
+		if err := addDeployToQueue(ctx, s, result.Block.Header.Height, i); err != nil {
+			return err
+		}
 		// TODO: Deploy info does not contain timestamp, so we take Block timestamps which is not accurate, even false sometimes
-		addDeployToDeployInfoQueue(s, result.Block.Hash, result.Block.Header.StateRootHash, result.Block.Header.Timestamp)
+		if err := addDeployToDeployInfoQueue(ctx, s, result.Block.Hash, result.Block.Header.StateRootHash, result.Block.Header.Timestamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleBlockKnownTask fetch a block from the database, parse it, update it in the database, and add the known tasks
+// of its deploys, deploy infos and rewards to the queue. Nothing is fetched from the rpc, the auction of a switch block isn't parsed again.
+func HandleBlockKnownTask(ctx context.Context, t *asynq.Task) error {
+	var p BlockKnownPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
+	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHash: p.BlockHash})
+
+	var database = WorkerStore
+	result, err := database.GetRawBlock(ctx, strings.ToLower(p.BlockHash))
+	if err != nil {
+		return err
+	}
+	if result.Block.Hash == "" {
+		return fmt.Errorf("block %s not found in raw_blocks", p.BlockHash)
+	}
+
+	eraEnd := result.Block.Header.EraEnd != nil
+	err = database.UpdateBlock(ctx, result.Block.Hash, result.Block.Header.EraID, result.Block.Header.Timestamp, result.Block.Header.Height, eraEnd)
+	if err != nil {
+		return err
+	}
+
+	if eraEnd {
+		task, err := NewRewardKnownTask(result.Block.Hash)
+		if err := enqueueTask(ctx, task, err, "era"); err != nil {
+			return err
+		}
+	}
+
+	// The deploys are executed in this order, deploy hashes first then transfer hashes
+	allDeploys := append(result.Block.Body.DeployHashes, result.Block.Body.TransferHashes...)
+	for i, s := range allDeploys {
+		task, err := NewDeployKnownTask(s, result.Block.Header.Height, i)
+		if err := enqueueTask(ctx, task, err, "deploys"); err != nil {
+			return err
+		}
+		task, err = NewDeployInfoKnownTask(s, result.Block.Hash, result.Block.Header.Timestamp)
+		if err := enqueueTask(ctx, task, err, "deployinfos"); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -80,8 +151,9 @@ func HandleBlockVerifyTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHash: p.BlockHash})
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 	block, err := database.GetRawBlock(ctx, p.BlockHash)
 	if err != nil {
 		return err
@@ -95,8 +167,11 @@ func HandleBlockVerifyTask(ctx context.Context, t *asynq.Task) error {
 		return err
 	}
 	if countDeploys != len(allDeploys) {
-		for _, s := range allDeploys {
-			addDeployToQueue(s)
+		logger.FromContext(ctx).WithFields(log.Fields{"deploys": len(allDeploys), "deploys_found": countDeploys}).Info("missing deploys, adding them back to the queue")
+		for i, s := range allDeploys {
+			if err := addDeployToQueue(ctx, s, block.Block.Header.Height, i); err != nil {
+				return err
+			}
 		}
 	} else {
 		return database.ValidateBlock(ctx, p.BlockHash)
@@ -104,69 +179,49 @@ func HandleBlockVerifyTask(ctx context.Context, t *asynq.Task) error {
 	return nil
 }
 
-func addAuctionEraToQueue(blockheight int) {
+func addAuctionEraToQueue(ctx context.Context, blockheight int) {
 	task, err := NewAuctionEraTask(blockheight)
 	if err != nil {
-		log.Printf("could not create task: %v\n", err)
+		logger.FromContext(ctx).WithError(err).Error("could not create task")
 	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("auctionera"))
+	_, err = WorkerAsyncClient.Enqueue(tracing.Inject(ctx, task), asynq.Queue("auctionera"))
 	if err != nil {
-		log.Printf("could not enqueue task: %v\n", err)
+		logger.FromContext(ctx).WithError(err).Error("could not enqueue task")
 	}
 }
 
 // addDeployToQueue a deploy hash to the queue
-func addDeployToQueue(hash string) {
-	task, err := NewDeployRawTask(hash)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("deploys"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+func addDeployToQueue(ctx context.Context, hash string, blockHeight int, index int) error {
+	task, err := NewDeployRawTask(hash, blockHeight, index)
+	return enqueueTask(ctx, task, err, "deploys")
 }
 
 // addDeployToQueue a deploy hash to the queue
-func addDeployToDeployInfoQueue(hash string, blockHash string, stateRootHash string, deployTimestamp string) {
+func addDeployToDeployInfoQueue(ctx context.Context, hash string, blockHash string, stateRootHash string, deployTimestamp string) error {
 	task, err := NewDeployInfoRawTask(hash, blockHash, stateRootHash, deployTimestamp)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("deployinfos"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "deployinfos")
 }
 
 // addDeployToQueue a deploy hash to the queue
-func addTransferToQueue(hash string, blockHash string, deployHash string, deployTimestamp string, stateRootHash string) {
+func addTransferToQueue(ctx context.Context, hash string, blockHash string, deployHash string, deployTimestamp string, stateRootHash string) error {
 	task, err := NewTransferRawTask(hash, blockHash, deployHash, deployTimestamp, stateRootHash)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("transfers"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "transfers")
 }
 
 // addEraToQueue a era hash to the queue
-func addEraToQueue(hash string) {
+func addEraToQueue(ctx context.Context, hash string) error {
 	task, err := NewRewardTask(hash)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("era"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "era")
 }
 
 type BlockRawPayload struct {
 	BlockHeight int
 }
 
+type BlockKnownPayload struct {
+	BlockHash string
+}
+
 type BlockVerifyPayload struct {
 	BlockHash string
 }
@@ -2,8 +2,11 @@ package tasks
 
 import (
 	"casperParser/db"
+	"casperParser/queue"
 	"casperParser/rpc"
+	"casperParser/types/amount"
 	"context"
+	"errors"
 	"github.com/hibiken/asynq"
 	"os"
 	"testing"
@@ -36,11 +39,13 @@ func TestHandleBlockRawTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewBlockRawTask(84)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
@@ -74,3 +79,119 @@ func TestHandleBlockRawTask(t *testing.T) {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
 }
+
+func TestHandleBlockVerifyTaskWithMemoryStore(t *testing.T) {
+	const blockHash = "d9dd87b06db708800036da57f1acf9302f51dde2a57b548ad4804ceb2377bdff"
+	const rawBlock = `{"block":{"hash":"` + blockHash + `","header":{"height":84},"body":{"deploy_hashes":["aa"],"transfer_hashes":["bb"]}}}`
+	store := db.NewMemory()
+	memoryQueue := queue.NewMemory(queue.Queues, 100)
+	WorkerStore = store
+	WorkerAsyncClient = memoryQueue
+	ctx := context.Background()
+	if err := store.InsertBlock(ctx, blockHash, 1, "2021-03-31T15:00:00.000Z", 84, false, rawBlock); err != nil {
+		t.Fatalf("Unable to insert the block : %s", err)
+	}
+	task, err := NewBlockVerifyTask(blockHash)
+	if err != nil {
+		t.Fatalf("Unable to create a NewBlockVerifyTask : %s", err)
+	}
+
+	t.Run("Should add the missing deploys back to the queue", func(t *testing.T) {
+		if err := store.InsertDeploy(ctx, "aa", "", amount.Amount{}, "success", "", "", blockHash, "transfer", "{}", "", "", "", "", "", ""); err != nil {
+			t.Fatalf("Unable to insert the deploy : %s", err)
+		}
+		if err := HandleBlockVerifyTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleBlockVerifyTask : %s", err)
+		}
+		if memoryQueue.Len() != 2 {
+			t.Errorf("Bad number of tasks enqueued. Received : %d. Expected : %d", memoryQueue.Len(), 2)
+		}
+		if store.Row("blocks", blockHash)["validated"] != false {
+			t.Errorf("Block validated with a missing deploy")
+		}
+	})
+
+	t.Run("Should validate the block once all deploys are stored", func(t *testing.T) {
+		if err := store.InsertDeploy(ctx, "bb", "", amount.Amount{}, "success", "", "", blockHash, "transfer", "{}", "", "", "", "", "", ""); err != nil {
+			t.Fatalf("Unable to insert the deploy : %s", err)
+		}
+		if err := HandleBlockVerifyTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleBlockVerifyTask : %s", err)
+		}
+		if store.Row("blocks", blockHash)["validated"] != true {
+			t.Errorf("Block not validated with all its deploys stored")
+		}
+	})
+
+	t.Run("Should stop when the queue is closed", func(t *testing.T) {
+		const missingBlockHash = "e9dd87b06db708800036da57f1acf9302f51dde2a57b548ad4804ceb2377bdff"
+		if err := store.InsertBlock(ctx, missingBlockHash, 1, "2021-03-31T15:00:00.000Z", 85, false, `{"block":{"hash":"`+missingBlockHash+`","header":{"height":85},"body":{"deploy_hashes":["cc"],"transfer_hashes":[]}}}`); err != nil {
+			t.Fatalf("Unable to insert the block : %s", err)
+		}
+		closedQueue := queue.NewMemory(queue.Queues, 100)
+		stopped, cancel := context.WithCancel(ctx)
+		cancel()
+		_ = closedQueue.Run(stopped, asynq.HandlerFunc(func(context.Context, *asynq.Task) error { return nil }), 1)
+		WorkerAsyncClient = closedQueue
+		defer func() { WorkerAsyncClient = memoryQueue }()
+		task, err := NewBlockVerifyTask(missingBlockHash)
+		if err != nil {
+			t.Fatalf("Unable to create a NewBlockVerifyTask : %s", err)
+		}
+		if err := HandleBlockVerifyTask(ctx, task); !errors.Is(err, queue.ErrClosed) {
+			t.Errorf("Bad error. Received : %v. Expected : %s", err, queue.ErrClosed)
+		}
+	})
+}
+
+func TestHandleBlockKnownTaskWithMemoryStore(t *testing.T) {
+	const blockHash = "d9dd87b06db708800036da57f1acf9302f51dde2a57b548ad4804ceb2377bdff"
+	const rawBlock = `{"block":{"hash":"` + blockHash + `","header":{"era_id":3,"height":84,"timestamp":"2021-03-31T15:00:00.000Z","era_end":{}},"body":{"deploy_hashes":["aa"],"transfer_hashes":["bb"]}}}`
+	store := db.NewMemory()
+	memoryQueue := queue.NewMemory(queue.Queues, 100)
+	WorkerStore = store
+	WorkerAsyncClient = memoryQueue
+	ctx := context.Background()
+	if err := store.InsertBlock(ctx, blockHash, 1, "", 84, false, rawBlock); err != nil {
+		t.Fatalf("Unable to insert the block : %s", err)
+	}
+	if err := store.ValidateBlock(ctx, blockHash); err != nil {
+		t.Fatalf("Unable to validate the block : %s", err)
+	}
+	task, err := NewBlockKnownTask(blockHash)
+	if err != nil {
+		t.Fatalf("Unable to create a NewBlockKnownTask : %s", err)
+	}
+	if task.Type() != TypeBlockKnown {
+		t.Errorf("NewBlockKnownTask has a bad name. Received : %s. Expected : %s", task.Type(), TypeBlockKnown)
+	}
+
+	t.Run("Should update the block from its raw data and keep its validation", func(t *testing.T) {
+		if err := HandleBlockKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleBlockKnownTask : %s", err)
+		}
+		row := store.Row("blocks", blockHash)
+		if row["era"] != 3 || row["era_end"] != true {
+			t.Errorf("Block not updated. Received : %v. Expected : era 3 and era_end true", row)
+		}
+		if row["validated"] != true {
+			t.Errorf("Block validation not kept")
+		}
+	})
+
+	t.Run("Should enqueue the known tasks of the deploys, deploy infos and rewards", func(t *testing.T) {
+		if memoryQueue.Len() != 5 {
+			t.Errorf("Bad number of tasks enqueued. Received : %d. Expected : %d", memoryQueue.Len(), 5)
+		}
+	})
+
+	t.Run("Should fail when the block isn't stored raw", func(t *testing.T) {
+		task, err := NewBlockKnownTask("unknown")
+		if err != nil {
+			t.Fatalf("Unable to create a NewBlockKnownTask : %s", err)
+		}
+		if err := HandleBlockKnownTask(ctx, task); err == nil {
+			t.Errorf("Should have thrown an error")
+		}
+	})
+}
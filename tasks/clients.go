@@ -2,11 +2,30 @@
 package tasks
 
 import (
+	"casperParser/db"
+	"casperParser/queue"
 	"casperParser/rpc"
+	"casperParser/tracing"
+	"context"
+	"fmt"
+
 	"github.com/hibiken/asynq"
-	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-var WorkerPool *pgxpool.Pool
-var WorkerAsyncClient *asynq.Client
+var WorkerStore db.Store
+var WorkerJobStore db.JobStore
+var WorkerAsyncClient queue.Client
 var WorkerRpcClient *rpc.Client
+
+// enqueueTask add a task created by a handler to the queue, the error is returned to retry the handler.
+// The memory queue is closed when the process stops, the handler then stops with queue.ErrClosed.
+func enqueueTask(ctx context.Context, task *asynq.Task, err error, queueName string) error {
+	if err != nil {
+		return fmt.Errorf("could not create task: %w", err)
+	}
+	_, err = WorkerAsyncClient.Enqueue(tracing.Inject(ctx, task), asynq.Queue(queueName))
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	return nil
+}
@@ -3,17 +3,26 @@ package tasks
 
 import (
 	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/types/ces"
+	"casperParser/types/config"
 	"casperParser/types/contract"
+	"casperParser/types/nft"
+	"casperParser/types/token"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 	"strings"
 )
 
 // TypeContractRaw Task contract  raw type
+// TypeContractKnown Task contract known type
 const (
-	TypeContractRaw = "contract:raw"
+	TypeContractRaw   = "contract:raw"
+	TypeContractKnown = "contract:known"
 )
 
 // NewContractRawTask Used for not yet parsed contract
@@ -25,26 +34,41 @@ func NewContractRawTask(hash string, deployHash string, from string) (*asynq.Tas
 	return asynq.NewTask(TypeContractRaw, payload), nil
 }
 
+// NewContractKnownTask used for already parsed contract
+func NewContractKnownTask(hash string) (*asynq.Task, error) {
+	payload, err := json.Marshal(ContractKnownPayload{ContractHash: hash})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeContractKnown, payload), nil
+}
+
 // HandleContractRawTask fetch a contract  from the rpc endpoint, parse it, and insert it in the database
 func HandleContractRawTask(ctx context.Context, t *asynq.Task) error {
 	var p ContractRawPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployHash, "contract_hash": p.ContractHash})
 
-	contractParsed, err := WorkerRpcClient.GetContract(strings.ToLower(p.ContractHash))
+	contractParsed, resp, err := WorkerRpcClient.GetContract(ctx, strings.ToLower(p.ContractHash))
 	if err != nil {
 		return err
 	}
-	var database = db.DB{Postgres: WorkerPool}
-	namedKeys := retrieveNamedKeyValues(contractParsed)
+	var database = WorkerStore
+	namedKeys := retrieveNamedKeyValues(ctx, contractParsed)
+	classification := contractParsed.Classify(config.ConfigParsed, contract.DefaultTopCandidates)
 	contractParsed.StoredValue.Contract.NamedKeys = []contract.NamedKey{}
 	contractJsonString, err := json.Marshal(contractParsed.StoredValue)
 	if err != nil {
 		return err
 	}
-	contractType, score := contractParsed.GetContractTypeAndScore()
-	err = database.InsertContract(ctx, p.ContractHash, strings.ReplaceAll(contractParsed.StoredValue.Contract.ContractPackageHash, "contract-package-wasm", ""), p.DeployHash, p.From, contractType, score, string(contractJsonString))
+	packageHash := strings.ReplaceAll(contractParsed.StoredValue.Contract.ContractPackageHash, "contract-package-wasm", "")
+	err = database.InsertContract(ctx, p.ContractHash, packageHash, p.DeployHash, p.From, classification.Type, classification.Score, string(contractJsonString), string(resp))
+	if err != nil {
+		return err
+	}
+	err = database.InsertContractClassification(ctx, p.ContractHash, classification)
 	if err != nil {
 		return err
 	}
@@ -54,10 +78,144 @@ func HandleContractRawTask(ctx context.Context, t *asynq.Task) error {
 			return err
 		}
 	}
-	return nil
+	err = insertToken(ctx, p.ContractHash, packageHash, classification.Type, namedKeys)
+	if err != nil {
+		return err
+	}
+	err = insertNFTCollection(ctx, p.ContractHash, packageHash, classification.Type, namedKeys)
+	if err != nil {
+		return err
+	}
+	return insertCESContract(ctx, p.ContractHash, namedKeys)
+}
+
+// HandleContractKnownTask fetch a contract from the database, parse it, and update it in the database.
+// The values of the named keys come from the rpc, the named keys already stored are kept.
+func HandleContractKnownTask(ctx context.Context, t *asynq.Task) error {
+	var p ContractKnownPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
+	}
+	ctx = logger.WithFields(ctx, log.Fields{"contract_hash": p.ContractHash})
+
+	var database = WorkerStore
+	contractParsed, err := database.GetRawContract(ctx, strings.ToLower(p.ContractHash))
+	if err != nil {
+		return err
+	}
+	if contractParsed.StoredValue.Contract.ContractPackageHash == "" {
+		return fmt.Errorf("contract %s not found in raw_contracts", p.ContractHash)
+	}
+	classification := contractParsed.Classify(config.ConfigParsed, contract.DefaultTopCandidates)
+	contractParsed.StoredValue.Contract.NamedKeys = []contract.NamedKey{}
+	contractJsonString, err := json.Marshal(contractParsed.StoredValue)
+	if err != nil {
+		return err
+	}
+	packageHash := strings.ReplaceAll(contractParsed.StoredValue.Contract.ContractPackageHash, "contract-package-wasm", "")
+	err = database.UpdateContract(ctx, p.ContractHash, packageHash, classification.Type, classification.Score, string(contractJsonString))
+	if err != nil {
+		return err
+	}
+	err = database.InsertContractClassification(ctx, p.ContractHash, classification)
+	if err != nil {
+		return err
+	}
+	return InsertReclassifiedNFTCollection(ctx, database, p.ContractHash, packageHash, classification.Type)
+}
+
+// insertCESContract store the events dictionary and the schemas of a contract following the Casper Event Standard,
+// the other contracts are ignored
+func insertCESContract(ctx context.Context, contractHash string, namedKeys []NamedKey) error {
+	var eventsURef, schemasURef string
+	for _, namedKey := range namedKeys {
+		switch namedKey.Name {
+		case ces.EventsNamedKey:
+			eventsURef = namedKey.Uref
+		case ces.SchemasNamedKey:
+			schemasURef = namedKey.Uref
+		}
+	}
+	if eventsURef == "" || schemasURef == "" {
+		return nil
+	}
+	clValue, err := WorkerRpcClient.GetCLValue(ctx, schemasURef)
+	if err != nil {
+		return err
+	}
+	data, err := hex.DecodeString(clValue.Bytes)
+	if err != nil {
+		return err
+	}
+	schemas, err := ces.ParseSchemas(data)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Warn("can't decode the CES schemas")
+		return nil
+	}
+	return WorkerStore.InsertCESContract(ctx, contractHash, ces.URefAddress(eventsURef), schemas)
+}
+
+// insertToken store the package of a CEP-18 contract with its metadata, the contracts without balances, total_supply
+// and decimals urefs are ignored. The CEP-47 collections have balances and total_supply too, the contracts classified
+// as a collection are ignored.
+func insertToken(ctx context.Context, contractHash string, packageHash string, contractType string, namedKeys []NamedKey) error {
+	if contractType == nft.ContractTypeCEP47 || contractType == nft.ContractTypeCEP78 {
+		return nil
+	}
+	values := make(map[string]NamedKey, len(namedKeys))
+	for _, namedKey := range namedKeys {
+		values[namedKey.Name] = namedKey
+	}
+	balances, okBalances := values[token.BalancesNamedKey]
+	totalSupply, okTotalSupply := values[token.TotalSupplyNamedKey]
+	decimals, okDecimals := values[token.DecimalsNamedKey]
+	if !okBalances || !okTotalSupply || !okDecimals || !strings.HasPrefix(balances.Uref, "uref-") || !strings.HasPrefix(totalSupply.Uref, "uref-") || !strings.HasPrefix(decimals.Uref, "uref-") {
+		return nil
+	}
+	t := token.Token{
+		PackageHash:     packageHash,
+		ContractHash:    contractHash,
+		BalancesURef:    ces.URefAddress(balances.Uref),
+		TotalSupplyURef: ces.URefAddress(totalSupply.Uref),
+	}
+	t.ParseNamedKeyValues(values[token.NameNamedKey].InitialValue, values[token.SymbolNamedKey].InitialValue, values[token.DecimalsNamedKey].InitialValue, totalSupply.InitialValue)
+	return WorkerStore.InsertToken(ctx, t)
+}
+
+// insertNFTCollection store the package of a contract classified as CEP-47 or CEP-78 with its name and symbol,
+// the other contracts are ignored
+func insertNFTCollection(ctx context.Context, contractHash string, packageHash string, contractType string, namedKeys []NamedKey) error {
+	values := make(map[string]string, len(namedKeys))
+	for _, namedKey := range namedKeys {
+		values[namedKey.Name] = namedKey.InitialValue
+	}
+	c, ok := nft.NewCollection(contractHash, packageHash, contractType, values)
+	if !ok {
+		return nil
+	}
+	return WorkerStore.InsertNFTCollection(ctx, c)
+}
+
+// InsertReclassifiedNFTCollection store the package of a contract classified again as CEP-47 or CEP-78 from its
+// stored named keys. A collection already stored is kept, the contract may not be the last version of its package.
+// Used by the known contract task and the classify command.
+func InsertReclassifiedNFTCollection(ctx context.Context, database db.Store, contractHash string, packageHash string, contractType string) error {
+	if standard, _, _ := nft.StandardOf(contractType); standard == "" {
+		return nil
+	}
+	collection, err := database.GetNFTCollection(ctx, packageHash)
+	if err != nil || collection.PackageHash != "" {
+		return err
+	}
+	values, err := database.GetNamedKeyValues(ctx, contractHash)
+	if err != nil {
+		return err
+	}
+	c, _ := nft.NewCollection(contractHash, packageHash, contractType, values)
+	return database.InsertNFTCollection(ctx, c)
 }
 
-func retrieveNamedKeyValues(c contract.Result) []NamedKey {
+func retrieveNamedKeyValues(ctx context.Context, c contract.Result) []NamedKey {
 	var namedKeys []NamedKey
 	for _, namedKey := range c.StoredValue.Contract.NamedKeys {
 		if strings.Contains(namedKey.Key, "account-hash-") {
@@ -68,7 +226,7 @@ func retrieveNamedKeyValues(c contract.Result) []NamedKey {
 				InitialValue: "null",
 			})
 		} else {
-			value, isPurse, _ := WorkerRpcClient.GetUrefValue(namedKey.Key)
+			value, isPurse, _ := WorkerRpcClient.GetUrefValue(ctx, namedKey.Key)
 			namedKeys = append(namedKeys, NamedKey{
 				Uref:         namedKey.Key,
 				Name:         namedKey.Name,
@@ -92,3 +250,7 @@ type ContractRawPayload struct {
 	DeployHash   string
 	From         string
 }
+
+type ContractKnownPayload struct {
+	ContractHash string
+}
@@ -2,11 +2,12 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 	"strings"
 )
 
@@ -30,13 +31,14 @@ func HandleContractPackageRawTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployHash, "contract_package_hash": p.ContractPackageHash})
 
-	rawContractPackageHash, err := WorkerRpcClient.GetContractPackage(strings.ToLower(p.ContractPackageHash))
+	rawContractPackageHash, err := WorkerRpcClient.GetContractPackage(ctx, strings.ToLower(p.ContractPackageHash))
 	if err != nil {
 		return err
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 	err = database.InsertContractPackage(ctx, p.ContractPackageHash, p.DeployHash, p.From, rawContractPackageHash)
 	if err != nil {
 		return err
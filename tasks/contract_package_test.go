@@ -26,11 +26,13 @@ func TestHandleContractPackageRawTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewBlockRawTask(981072)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
@@ -39,7 +41,7 @@ func TestHandleContractPackageRawTask(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
-	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950")
+	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -3,9 +3,16 @@ package tasks
 import (
 	"casperParser/db"
 	"casperParser/rpc"
+	"casperParser/types/config"
+	"casperParser/types/contract"
+	"casperParser/types/nft"
 	"context"
+	"encoding/json"
 	"github.com/hibiken/asynq"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -26,11 +33,13 @@ func TestHandleContractRawTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewBlockRawTask(981072)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
@@ -39,7 +48,7 @@ func TestHandleContractRawTask(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
-	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950")
+	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -56,3 +65,186 @@ func TestHandleContractRawTask(t *testing.T) {
 		t.Errorf("Unable to run HandleContractRawTask : %s", err)
 	}
 }
+
+func TestHandleContractRawTaskWithMemoryStore(t *testing.T) {
+	const contractHash = "a37f861cad9bb577d6062512b85695083579056bfcb3c5db56650cc7687e7f17"
+	const rawContract = `{"stored_value":{"Contract":{"contract_package_hash":"contract-package-wasmaa","entry_points":[{"name":"transfer","args":[{"name":"recipient"},{"name":"amount"}]}],"named_keys":[{"name":"balances","key":"uref-bb-007"}]}}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string   `json:"method"`
+			Params []string `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		switch {
+		case request.Method == "chain_get_state_root_hash":
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":{"state_root_hash":"` + strings.Repeat("ef", 32) + `"}}`))
+		case len(request.Params) == 2 && request.Params[1] == "hash-"+contractHash:
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","result":` + rawContract + `}`))
+		default:
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32003,"message":"state query failed"}}`))
+		}
+	}))
+	defer server.Close()
+	defer func(client *rpc.Client) { WorkerRpcClient = client }(WorkerRpcClient)
+	WorkerRpcClient = rpc.NewRpcClient(server.URL)
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	defer func(cfg config.Config) { config.ConfigParsed = cfg }(config.ConfigParsed)
+	config.ConfigParsed = config.Config{ContractTypes: map[string]config.ContractType{
+		"token": {Entrypoints: []config.Entrypoint{{Name: "transfer", Args: []string{"recipient", "amount"}}}, NamedKeys: []string{"balances"}},
+	}}
+	task, err := NewContractRawTask(contractHash, strings.Repeat("cd", 32), "01aa")
+	if err != nil {
+		t.Fatalf("Unable to create a NewContractRawTask : %s", err)
+	}
+	if err := HandleContractRawTask(ctx, task); err != nil {
+		t.Fatalf("Unable to run HandleContractRawTask : %s", err)
+	}
+
+	t.Run("Should classify the contract with its named keys like the known task", func(t *testing.T) {
+		row := store.Row("contracts", contractHash)
+		if row["type"] != "token" || row["score"] != 100.0 {
+			t.Errorf("Bad contract type. Received : %v %v. Expected : token 100", row["type"], row["score"])
+		}
+		classification := store.Row("contract_classifications", contractHash+":"+config.ConfigParsed.ContractTypesVersion())
+		if candidates, _ := classification["candidates"].([]contract.Candidate); len(candidates) != 1 || candidates[0].NamedKeys != 1 {
+			t.Errorf("Bad candidates. Received : %v. Expected : token with 1 named key", classification["candidates"])
+		}
+	})
+}
+
+func TestHandleContractKnownTaskWithMemoryStore(t *testing.T) {
+	const contractHash = "a37f861cad9bb577d6062512b85695083579056bfcb3c5db56650cc7687e7f17"
+	const rawContract = `{"stored_value":{"Contract":{"contract_package_hash":"contract-package-wasmaa","entry_points":[{"name":"transfer","args":[{"name":"recipient"},{"name":"amount"}]}],"named_keys":[{"name":"balances","key":"uref-bb-007"}]}}}`
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	defer func(cfg config.Config) { config.ConfigParsed = cfg }(config.ConfigParsed)
+	config.ConfigParsed = config.Config{ContractTypes: map[string]config.ContractType{
+		"token":  {Entrypoints: []config.Entrypoint{{Name: "transfer", Args: []string{"recipient", "amount"}}}, NamedKeys: []string{"balances"}},
+		"faucet": {Entrypoints: []config.Entrypoint{{Name: "call_faucet", Args: []string{"target"}}}},
+	}}
+	if err := store.InsertContract(ctx, contractHash, "aa", "", "", "unknown", 0, "{}", rawContract); err != nil {
+		t.Fatalf("Unable to insert the contract : %s", err)
+	}
+	task, err := NewContractKnownTask(contractHash)
+	if err != nil {
+		t.Fatalf("Unable to create a NewContractKnownTask : %s", err)
+	}
+	if err := HandleContractKnownTask(ctx, task); err != nil {
+		t.Fatalf("Unable to run HandleContractKnownTask : %s", err)
+	}
+
+	t.Run("Should update the type of the contract with its named keys", func(t *testing.T) {
+		row := store.Row("contracts", contractHash)
+		if row["type"] != "token" || row["score"] != 100.0 {
+			t.Errorf("Bad contract type. Received : %v %v. Expected : token 100", row["type"], row["score"])
+		}
+	})
+
+	t.Run("Should store the classification for the config version", func(t *testing.T) {
+		row := store.Row("contract_classifications", contractHash+":"+config.ConfigParsed.ContractTypesVersion())
+		if row == nil {
+			t.Fatalf("Classification not stored for the version %s", config.ConfigParsed.ContractTypesVersion())
+		}
+		if considered, _ := row["considered"].([]string); len(considered) != 2 {
+			t.Errorf("Bad types considered. Received : %v. Expected : faucet and token", row["considered"])
+		}
+	})
+}
+
+func TestHandleContractKnownTaskNFTCollectionWithMemoryStore(t *testing.T) {
+	const contractHash = "b37f861cad9bb577d6062512b85695083579056bfcb3c5db56650cc7687e7f17"
+	packageHash := strings.Repeat("aa", 32)
+	rawContract := `{"stored_value":{"Contract":{"contract_package_hash":"contract-package-wasm` + packageHash + `","entry_points":[{"name":"mint","args":[{"name":"recipient"},{"name":"token_ids"}]}],"named_keys":[{"name":"name","key":"uref-01-007"},{"name":"symbol","key":"uref-02-007"}]}}}`
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	defer func(cfg config.Config) { config.ConfigParsed = cfg }(config.ConfigParsed)
+	config.ConfigParsed = config.Config{ContractTypes: map[string]config.ContractType{
+		nft.ContractTypeCEP47: {Entrypoints: []config.Entrypoint{{Name: "mint", Args: []string{"recipient", "token_ids"}}}, NamedKeys: []string{"name", "symbol"}},
+	}}
+	if err := store.InsertContract(ctx, contractHash, packageHash, "", "", "unknown", 0, "{}", rawContract); err != nil {
+		t.Fatalf("Unable to insert the contract : %s", err)
+	}
+	if err := store.InsertNamedKey(ctx, "uref-01-007", "name", false, `"Collection"`, contractHash); err != nil {
+		t.Fatalf("Unable to insert the named key : %s", err)
+	}
+	if err := store.InsertNamedKey(ctx, "uref-02-007", "symbol", false, `"COL"`, contractHash); err != nil {
+		t.Fatalf("Unable to insert the named key : %s", err)
+	}
+	task, err := NewContractKnownTask(contractHash)
+	if err != nil {
+		t.Fatalf("Unable to create a NewContractKnownTask : %s", err)
+	}
+
+	t.Run("Should add the collection of a contract classified again as CEP-47", func(t *testing.T) {
+		if err := HandleContractKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleContractKnownTask : %s", err)
+		}
+		collection, _ := store.GetNFTCollection(ctx, packageHash)
+		if collection.Standard != nft.StandardCEP47 || collection.ContractHash != contractHash || collection.Name != "Collection" || collection.Symbol != "COL" {
+			t.Errorf("Bad collection. Received : %+v. Expected : the CEP-47 collection Collection COL", collection)
+		}
+	})
+
+	t.Run("Should keep the collection already stored", func(t *testing.T) {
+		if err := store.InsertNFTCollection(ctx, nft.Collection{PackageHash: packageHash, ContractHash: strings.Repeat("cd", 32), Standard: nft.StandardCEP47}); err != nil {
+			t.Fatalf("Unable to insert the collection : %s", err)
+		}
+		if err := HandleContractKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleContractKnownTask : %s", err)
+		}
+		if collection, _ := store.GetNFTCollection(ctx, packageHash); collection.ContractHash != strings.Repeat("cd", 32) {
+			t.Errorf("Bad collection contract. Received : %s. Expected : %s", collection.ContractHash, strings.Repeat("cd", 32))
+		}
+	})
+}
+
+func TestInsertTokenWithMemoryStore(t *testing.T) {
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	uref := func(b string) string { return "uref-" + strings.Repeat(b, 32) + "-007" }
+	cep47 := []NamedKey{
+		{Name: "name", Uref: uref("01"), InitialValue: `"Collection"`},
+		{Name: "symbol", Uref: uref("02"), InitialValue: `"COL"`},
+		{Name: "balances", Uref: uref("03"), InitialValue: "null"},
+		{Name: "total_supply", Uref: uref("04"), InitialValue: `"3"`},
+		{Name: "owners", Uref: uref("05"), InitialValue: "null"},
+		{Name: "metadata", Uref: uref("06"), InitialValue: "null"},
+	}
+
+	t.Run("Should not register a CEP-47 collection as a token", func(t *testing.T) {
+		packageHash := strings.Repeat("aa", 32)
+		if err := insertToken(ctx, strings.Repeat("ab", 32), packageHash, "unknown", cep47); err != nil {
+			t.Fatalf("Unable to run insertToken : %s", err)
+		}
+		if tok, _ := store.GetToken(ctx, packageHash); tok.PackageHash != "" {
+			t.Errorf("CEP-47 collection registered as a token. Received : %+v", tok)
+		}
+	})
+
+	t.Run("Should not register a contract classified as a collection", func(t *testing.T) {
+		packageHash := strings.Repeat("bb", 32)
+		namedKeys := append(cep47, NamedKey{Name: "decimals", Uref: uref("07"), InitialValue: "0"})
+		if err := insertToken(ctx, strings.Repeat("bc", 32), packageHash, nft.ContractTypeCEP47, namedKeys); err != nil {
+			t.Fatalf("Unable to run insertToken : %s", err)
+		}
+		if tok, _ := store.GetToken(ctx, packageHash); tok.PackageHash != "" {
+			t.Errorf("CEP-47 collection registered as a token. Received : %+v", tok)
+		}
+	})
+
+	t.Run("Should register a CEP-18 token", func(t *testing.T) {
+		packageHash := strings.Repeat("cc", 32)
+		namedKeys := append(cep47[:4:4], NamedKey{Name: "decimals", Uref: uref("07"), InitialValue: "9"})
+		if err := insertToken(ctx, strings.Repeat("cd", 32), packageHash, "erc20", namedKeys); err != nil {
+			t.Fatalf("Unable to run insertToken : %s", err)
+		}
+		if tok, _ := store.GetToken(ctx, packageHash); tok.PackageHash != packageHash || tok.Decimals != 9 {
+			t.Errorf("Bad token. Received : %+v. Expected : the package %s with 9 decimals", tok, packageHash)
+		}
+	})
+}
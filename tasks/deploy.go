@@ -2,19 +2,26 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/types/amount"
+	"casperParser/types/ces"
+	"casperParser/types/deploy"
+	"casperParser/types/nft"
+	"casperParser/types/token"
+	"casperParser/utils"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strconv"
 	"strings"
 
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 )
 
 // TypeDeployRaw Task deploy raw type
 // TypeDeployKnown Task deploy known type
+// TypeDeployInfoRaw Task deploy info raw type
+// TypeDeployInfoKnown Task deploy info known type
 const (
 	TypeDeployRaw       = "deploy:raw"
 	TypeDeployKnown     = "deploy:known"
@@ -22,9 +29,9 @@ const (
 	TypeDeployInfoKnown = "deployinfo:known"
 )
 
-// NewDeployRawTask Used for not yet parsed deploy
-func NewDeployRawTask(hash string) (*asynq.Task, error) {
-	payload, err := json.Marshal(DeployRawPayload{DeployHash: hash})
+// NewDeployRawTask Used for not yet parsed deploy, index is the position of the deploy in the block at blockHeight
+func NewDeployRawTask(hash string, blockHeight int, index int) (*asynq.Task, error) {
+	payload, err := json.Marshal(DeployRawPayload{DeployHash: hash, BlockHeight: blockHeight, Index: index})
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +47,18 @@ func NewDeployInfoRawTask(hash string, blockHash string, stateRootHash string, d
 	return asynq.NewTask(TypeDeployInfoRaw, payload), nil
 }
 
-// NewDeployKnownTask used for already parsed deploy
-func NewDeployKnownTask(hash string) (*asynq.Task, error) {
-	payload, err := json.Marshal(DeployKnownPayload{DeployHash: hash})
+// NewDeployInfoKnownTask used for already parsed deploy info
+func NewDeployInfoKnownTask(hash string, blockHash string, deployTimestamp string) (*asynq.Task, error) {
+	payload, err := json.Marshal(DeployInfoKnownPayload{DeployInfoHash: hash, Block: blockHash, DeployTimestamp: deployTimestamp})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeDeployInfoKnown, payload), nil
+}
+
+// NewDeployKnownTask used for already parsed deploy, index is the position of the deploy in the block at blockHeight. A zero height skips the purse balance history
+func NewDeployKnownTask(hash string, blockHeight int, index int) (*asynq.Task, error) {
+	payload, err := json.Marshal(DeployKnownPayload{DeployHash: hash, BlockHeight: blockHeight, Index: index})
 	if err != nil {
 		return nil, err
 	}
@@ -55,18 +71,17 @@ func HandleDeployRawTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployHash})
 
-	rpcDeploy, resp, err := WorkerRpcClient.GetDeploy(p.DeployHash)
+	rpcDeploy, resp, err := WorkerRpcClient.GetDeploy(ctx, p.DeployHash)
 	if err != nil {
-		println("ERROR WorkerRpcClient.GetDeploy(p.DeployHash)")
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithError(err).Error("can't get the deploy from the rpc")
 		return err
 	}
 
 	result, cost, errorMessage, err := rpcDeploy.GetResultAndCost()
 	if err != nil {
-		println("ERROR on rpcDeploy.GetResultAndCost()")
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithError(err).Error("can't get the deploy result and cost")
 		return err
 	}
 	metadataDeployType, metadata := rpcDeploy.GetDeployMetadata()
@@ -77,27 +92,77 @@ func HandleDeployRawTask(ctx context.Context, t *asynq.Task) error {
 	// Quand on cherche un contrat à partir d'un contratHash de la table Deploy, on ne le retrouve pas à chaque fois
 	contractName := rpcDeploy.GetName()
 	entrypoint, _ := rpcDeploy.GetEntrypoint()
-	var database = db.DB{Postgres: WorkerPool}
+	var database = WorkerStore
 	metadata = strings.ReplaceAll(metadata, "\\u0000", "")
 	err = database.InsertDeploy(ctx, rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account, cost, result, errorMessage, rpcDeploy.Deploy.Header.Timestamp, rpcDeploy.ExecutionResults[0].BlockHash, rpcDeploy.GetType(), jsonString, metadataDeployType, contractHash, contractName, entrypoint, metadata, events)
 	if err != nil {
-		println("ERROR on database.InsertDeploy")
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy")
+		return err
+	}
+
+	err = database.InsertDeployFee(ctx, rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account, rpcDeploy.Deploy.Header.Timestamp, contractHash, rpcDeploy.GetFee())
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy fee")
 		return err
 	}
 
-	addAccountToQueue(rpcDeploy.Deploy.Header.Account)
+	err = insertDeployApprovals(ctx, rpcDeploy)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy approvals")
+		return err
+	}
+
+	err = database.InsertTransforms(ctx, rpcDeploy.Deploy.Hash, rpcDeploy.GetEffect().Transforms)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the transforms")
+		return err
+	}
+	contractEvents, err := insertContractEvents(ctx, rpcDeploy)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the contract events")
+		return err
+	}
+
+	// The tasks enqueued before the block height was carried have no height, their balance changes can't be placed in the history
+	if p.BlockHeight > 0 {
+		err = database.InsertPurseBalanceHistory(ctx, p.BlockHeight, p.Index, rpcDeploy.Deploy.Hash, rpcDeploy.GetPurseBalanceChanges())
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the purse balance history")
+			return err
+		}
+		err = insertTokenChanges(ctx, p.BlockHeight, p.Index, rpcDeploy, contractEvents)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the token balances")
+			return err
+		}
+		err = insertNFTActions(ctx, p.BlockHeight, p.Index, rpcDeploy, contractEvents)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the NFT actions")
+			return err
+		}
+	}
+
+	err = addAccountToQueue(ctx, rpcDeploy.Deploy.Header.Account)
+	if err != nil {
+		return err
+	}
 
 	writeContracts := rpcDeploy.GetWriteContract()
 
 	for _, writeContract := range writeContracts {
-		addContractToQueue(strings.ReplaceAll(writeContract, "hash-", ""), rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account)
+		err = addContractToQueue(ctx, strings.ReplaceAll(writeContract, "hash-", ""), rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account)
+		if err != nil {
+			return err
+		}
 	}
 
 	writeContractPackages := rpcDeploy.GetWriteContractPackage()
 
 	for _, writeContractPackage := range writeContractPackages {
-		addContractPackageToQueue(strings.ReplaceAll(writeContractPackage, "hash-", ""), rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account)
+		err = addContractPackageToQueue(ctx, strings.ReplaceAll(writeContractPackage, "hash-", ""), rpcDeploy.Deploy.Hash, rpcDeploy.Deploy.Header.Account)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -108,47 +173,90 @@ func HandleDeployInfoRawTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployInfoHash, logger.FieldBlockHash: p.Block})
 
-	var database = db.DB{Postgres: WorkerPool}
-	rpcDeployInfo, resp, err := WorkerRpcClient.GetDeployInfo(p.StateRootHash, p.DeployInfoHash)
+	var database = WorkerStore
+	rpcDeployInfo, resp, err := WorkerRpcClient.GetDeployInfo(ctx, p.StateRootHash, p.DeployInfoHash)
 	if err != nil {
-		errdb := database.InsertDeployInfo(ctx, p.DeployInfoHash, p.Block, "", "", 0, "\"ERROR\"", "")
+		errdb := database.InsertDeployInfo(ctx, p.DeployInfoHash, p.Block, "", "", amount.Amount{}, "\"ERROR\"", "")
 		if errdb != nil {
-			fmt.Printf("%v", errdb)
+			logger.FromContext(ctx).WithError(errdb).Error("can't insert the errored deploy info")
 			return errdb
 		}
+		logger.FromContext(ctx).WithError(err).Warn("can't get the deploy info from the rpc")
 		return err
 	}
 
 	strTransfers := strings.Join(rpcDeployInfo.StoredValue.DeployInfo.Transfers, ", ")
 	jsonString := strings.ReplaceAll(string(resp), "\\u0000", "")
-	gas, err := strconv.Atoi(rpcDeployInfo.StoredValue.DeployInfo.Gas)
-	err = database.InsertDeployInfo(ctx, p.DeployInfoHash, p.Block, rpcDeployInfo.StoredValue.DeployInfo.From, rpcDeployInfo.StoredValue.DeployInfo.Source, gas, jsonString, strTransfers)
+	err = database.InsertDeployInfo(ctx, p.DeployInfoHash, p.Block, rpcDeployInfo.StoredValue.DeployInfo.From, rpcDeployInfo.StoredValue.DeployInfo.Source, rpcDeployInfo.StoredValue.DeployInfo.Gas, jsonString, strTransfers)
 
 	if err != nil {
-		fmt.Printf("%v", err)
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy info")
 		return err
 	}
 
 	for _, transfer := range rpcDeployInfo.StoredValue.DeployInfo.Transfers {
-		addTransferToQueue(transfer, p.Block, p.DeployInfoHash, p.DeployTimestamp, p.StateRootHash)
+		err = addTransferToQueue(ctx, transfer, p.Block, p.DeployInfoHash, p.DeployTimestamp, p.StateRootHash)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// HandleDeployKnownTask fetch a deploy from the database, parse it, and insert it in the database
+// HandleDeployInfoKnownTask fetch a deploy info from the database, parse it, and update it in the database
+func HandleDeployInfoKnownTask(ctx context.Context, t *asynq.Task) error {
+	var p DeployInfoKnownPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
+	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployInfoHash, logger.FieldBlockHash: p.Block})
+
+	var database = WorkerStore
+	dbDeployInfo, err := database.GetDeployInfo(ctx, strings.ToLower(p.DeployInfoHash))
+	if err != nil {
+		return err
+	}
+	if dbDeployInfo.StoredValue.DeployInfo.Deploy == "" {
+		logger.FromContext(ctx).Warn("deploy info not found in raw_deploy_infos or errored, reparse its block from the rpc")
+		return nil
+	}
+
+	strTransfers := strings.Join(dbDeployInfo.StoredValue.DeployInfo.Transfers, ", ")
+	err = database.UpdateDeployInfo(ctx, p.DeployInfoHash, p.Block, dbDeployInfo.StoredValue.DeployInfo.From, dbDeployInfo.StoredValue.DeployInfo.Source, dbDeployInfo.StoredValue.DeployInfo.Gas, strTransfers)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't update the deploy info")
+		return err
+	}
+
+	for _, transfer := range dbDeployInfo.StoredValue.DeployInfo.Transfers {
+		task, err := NewTransferKnownTask(transfer, p.Block, p.DeployInfoHash, p.DeployTimestamp)
+		if err := enqueueTask(ctx, task, err, "transfers"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleDeployKnownTask fetch a deploy from the database, parse it, and update it in the database.
+// The contracts written by the deploy aren't fetched again, reparse them with the contract known tasks.
 func HandleDeployKnownTask(ctx context.Context, t *asynq.Task) error {
 	var p DeployKnownPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
-	var database = db.DB{Postgres: WorkerPool}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.DeployHash})
+	var database = WorkerStore
 	dbDeploy, err := database.GetDeploy(ctx, p.DeployHash)
 	if err != nil {
-		log.Printf("Can't find deploy %s\n", p.DeployHash)
+		logger.FromContext(ctx).WithError(err).Error("can't find the deploy")
 		return err
 	}
+	if len(dbDeploy.ExecutionResults) == 0 {
+		return fmt.Errorf("deploy %s not found in raw_deploys", p.DeployHash)
+	}
 
 	result, cost, errorMessage, err := dbDeploy.GetResultAndCost()
 	if err != nil {
@@ -156,73 +264,324 @@ func HandleDeployKnownTask(ctx context.Context, t *asynq.Task) error {
 	}
 	metadataDeployType, metadata := dbDeploy.GetDeployMetadata()
 	events := dbDeploy.GetEvents()
-	if metadata != "" {
-		log.Printf("New metadata found for %s of type : %s\n", p.DeployHash, metadataDeployType)
-		contractHash, _ := dbDeploy.GetStoredContractHash()
-		contractName := dbDeploy.GetName()
-		entrypoint, _ := dbDeploy.GetEntrypoint()
-		metadata = strings.ReplaceAll(metadata, "\\u0000", "")
-		err = database.UpdateDeploy(ctx, dbDeploy.Deploy.Hash, dbDeploy.Deploy.Header.Account, cost, result, errorMessage, dbDeploy.Deploy.Header.Timestamp, dbDeploy.ExecutionResults[0].BlockHash, dbDeploy.GetType(), metadataDeployType, contractHash, contractName, entrypoint, metadata, events)
+	contractHash, _ := dbDeploy.GetStoredContractHash()
+	contractName := dbDeploy.GetName()
+	entrypoint, _ := dbDeploy.GetEntrypoint()
+	metadata = strings.ReplaceAll(metadata, "\\u0000", "")
+	err = database.UpdateDeploy(ctx, dbDeploy.Deploy.Hash, dbDeploy.Deploy.Header.Account, cost, result, errorMessage, dbDeploy.Deploy.Header.Timestamp, dbDeploy.ExecutionResults[0].BlockHash, dbDeploy.GetType(), metadataDeployType, contractHash, contractName, entrypoint, metadata, events)
+	if err != nil {
+		return err
+	}
+
+	err = database.InsertDeployFee(ctx, dbDeploy.Deploy.Hash, dbDeploy.Deploy.Header.Account, dbDeploy.Deploy.Header.Timestamp, contractHash, dbDeploy.GetFee())
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy fee")
+		return err
+	}
+
+	err = insertDeployApprovals(ctx, dbDeploy)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the deploy approvals")
+		return err
+	}
+
+	err = database.InsertTransforms(ctx, dbDeploy.Deploy.Hash, dbDeploy.GetEffect().Transforms)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the transforms")
+		return err
+	}
+	contractEvents, err := insertContractEvents(ctx, dbDeploy)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("can't insert the contract events")
+		return err
+	}
+
+	if p.BlockHeight > 0 {
+		err = database.InsertPurseBalanceHistory(ctx, p.BlockHeight, p.Index, dbDeploy.Deploy.Hash, dbDeploy.GetPurseBalanceChanges())
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the purse balance history")
+			return err
+		}
+		err = insertTokenChanges(ctx, p.BlockHeight, p.Index, dbDeploy, contractEvents)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the token balances")
+			return err
+		}
+		err = insertNFTActions(ctx, p.BlockHeight, p.Index, dbDeploy, contractEvents)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Error("can't insert the NFT actions")
+			return err
+		}
+	}
+	return nil
+}
+
+// insertDeployApprovals verify the hashes and the approvals of the deploy and store them, a deploy not valid is logged
+func insertDeployApprovals(ctx context.Context, d deploy.Result) error {
+	var database = WorkerStore
+	verification := d.Verify()
+	if !verification.Valid() {
+		logger.FromContext(ctx).WithFields(log.Fields{"deploy_hash": d.Deploy.Hash, "body_hash_valid": verification.BodyHashValid, "hash_valid": verification.HashValid, "approvals_valid": verification.ApprovalsValid(), "error": verification.Error}).Warn("the deploy isn't valid")
+	}
+	return database.InsertDeployApprovals(ctx, d.Deploy.Hash, d.Deploy.Approvals, verification)
+}
+
+// insertContractEvents decode the events written by the deploy in the events dictionary of the CES contracts and return them.
+// The events of a contract not stored yet are skipped, reparse the deploy once the contract is stored.
+func insertContractEvents(ctx context.Context, d deploy.Result) ([]ces.Event, error) {
+	var database = WorkerStore
+	var events []ces.Event
+	for _, transform := range d.GetEffect().Transforms {
+		value, ok := transform.DictionaryValue()
+		if !ok || !ces.IsEvent(value) {
+			continue
+		}
+		contractHash, schemas, err := database.GetCESContract(ctx, value.SeedURef)
+		if err != nil {
+			return nil, err
+		}
+		if contractHash == "" {
+			continue
+		}
+		event, err := schemas.ParseEvent(value)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).WithField("contract_hash", contractHash).Warn("can't decode the CES event")
+			continue
+		}
+		event.ContractHash = contractHash
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return events, database.InsertContractEvents(ctx, d.Deploy.Hash, events)
+}
+
+// insertTokenChanges store the balances and the supplies written by the deploy at index in the block at blockHeight
+// in the CEP-18 tokens, and its transfers from the CES events of the tokens or from the entrypoint called when the token
+// emitted no event. The changes of a token not stored yet are skipped, reparse the deploy once the token is stored.
+func insertTokenChanges(ctx context.Context, blockHeight int, index int, d deploy.Result, contractEvents []ces.Event) error {
+	var database = WorkerStore
+	tokens := make(map[string]token.Token)
+	getToken := func(uref string) (token.Token, error) {
+		if t, ok := tokens[uref]; ok {
+			return t, nil
+		}
+		t, err := database.GetTokenByURef(ctx, uref)
+		tokens[uref] = t
+		return t, err
+	}
+	balances := make(map[string][]token.Balance)
+	for _, transform := range d.GetEffect().Transforms {
+		if value, ok := transform.DictionaryValue(); ok && value.CLType == "U256" {
+			t, err := getToken(value.SeedURef)
+			if err != nil {
+				return err
+			}
+			if t.PackageHash == "" || t.BalancesURef != value.SeedURef {
+				continue
+			}
+			balance, err := token.ParseBalance(value)
+			if err != nil {
+				logger.FromContext(ctx).WithError(err).WithField("package_hash", t.PackageHash).Warn("can't decode the token balance")
+				continue
+			}
+			balances[t.PackageHash] = append(balances[t.PackageHash], balance)
+			continue
+		}
+		if transform.CLValue == nil || !strings.HasPrefix(transform.Key, "uref-") || transform.CLValue.ClType != "U256" {
+			continue
+		}
+		t, err := getToken(ces.URefAddress(transform.Key))
+		if err != nil {
+			return err
+		}
+		if t.PackageHash == "" || t.TotalSupplyURef != ces.URefAddress(transform.Key) {
+			continue
+		}
+		supply, err := token.ParseTotalSupply(*transform.CLValue)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).WithField("package_hash", t.PackageHash).Warn("can't decode the token supply")
+			continue
+		}
+		err = database.UpdateTokenSupply(ctx, blockHeight, index, t.PackageHash, supply)
+		if err != nil {
+			return err
+		}
+	}
+	for packageHash, tokenBalances := range balances {
+		err := database.InsertTokenBalances(ctx, blockHeight, index, d.Deploy.Hash, packageHash, tokenBalances)
 		if err != nil {
 			return err
 		}
-		writeContractPackages := dbDeploy.GetWriteContractPackage()
+	}
+	var transfers []token.Transfer
+	for _, event := range contractEvents {
+		transfer, ok := token.ParseCESEvent(event)
+		if !ok {
+			continue
+		}
+		t, err := database.GetToken(ctx, event.ContractHash)
+		if err != nil {
+			return err
+		}
+		if t.PackageHash == "" {
+			continue
+		}
+		transfer.PackageHash = t.PackageHash
+		transfers = append(transfers, transfer)
+	}
+	result, _, _, _ := d.GetResultAndCost()
+	contractHash, errHash := d.GetStoredContractHash()
+	entrypoint, errEntrypoint := d.GetEntrypoint()
+	if result == "success" && errHash == nil && errEntrypoint == nil {
+		t, err := database.GetToken(ctx, strings.ReplaceAll(contractHash, "hash-", ""))
+		if err != nil {
+			return err
+		}
+		if t.PackageHash != "" && !hasTokenTransfer(transfers, t.PackageHash) {
+			if transfer, ok := token.ParseEntrypoint(entrypoint, d.MapArgs(), callerKey(d)); ok {
+				transfer.PackageHash = t.PackageHash
+				transfers = append(transfers, transfer)
+			}
+		}
+	}
+	if len(transfers) == 0 {
+		return nil
+	}
+	return database.InsertTokenTransfers(ctx, blockHeight, index, d.Deploy.Hash, transfers)
+}
 
-		for _, writeContractPackage := range writeContractPackages {
-			addContractPackageToQueue(strings.ReplaceAll(writeContractPackage, "hash-", ""), dbDeploy.Deploy.Hash, dbDeploy.Deploy.Header.Account)
+// hasTokenTransfer check if one of the transfers is of the token
+func hasTokenTransfer(transfers []token.Transfer, packageHash string) bool {
+	for _, transfer := range transfers {
+		if transfer.PackageHash == packageHash {
+			return true
 		}
+	}
+	return false
+}
 
-		writeContracts := dbDeploy.GetWriteContract()
+// callerKey the key of the account of the deploy, account-hash-<hex>. Empty if the public key is invalid.
+func callerKey(d deploy.Result) string {
+	if accountHash := utils.AccountHash(d.Deploy.Header.Account); accountHash != "" {
+		return "account-hash-" + accountHash
+	}
+	return ""
+}
 
-		for _, writeContract := range writeContracts {
-			addContractToQueue(strings.ReplaceAll(writeContract, "hash-", ""), dbDeploy.Deploy.Hash, dbDeploy.Deploy.Header.Account)
+// insertNFTActions store the actions of the deploy at index in the block at blockHeight on the tokens of the NFT
+// collections, from the CEP-47 events and the CES events of the deploy. The entrypoint called is used when the
+// collection emitted no event. The actions of a collection not stored yet are skipped, reparse the deploy once it's stored.
+func insertNFTActions(ctx context.Context, blockHeight int, index int, d deploy.Result, contractEvents []ces.Event) error {
+	var database = WorkerStore
+	collections := make(map[string]nft.Collection)
+	getCollection := func(hash string) (nft.Collection, error) {
+		if c, ok := collections[hash]; ok {
+			return c, nil
 		}
+		c, err := database.GetNFTCollection(ctx, hash)
+		collections[hash] = c
+		return c, err
 	}
-	return nil
+	var actions []nft.Action
+	var cep47Events []map[string]string
+	if events := d.GetEvents(); events != "" {
+		if err := json.Unmarshal([]byte(events), &cep47Events); err != nil {
+			return err
+		}
+	}
+	for _, event := range cep47Events {
+		action, ok := nft.ParseCEP47Event(event)
+		if !ok {
+			continue
+		}
+		c, err := getCollection(action.PackageHash)
+		if err != nil {
+			return err
+		}
+		if c.PackageHash == "" {
+			continue
+		}
+		action.PackageHash = c.PackageHash
+		actions = append(actions, action)
+	}
+	for _, event := range contractEvents {
+		action, ok := nft.ParseCESEvent(event)
+		if !ok {
+			continue
+		}
+		c, err := getCollection(event.ContractHash)
+		if err != nil {
+			return err
+		}
+		if c.PackageHash == "" {
+			continue
+		}
+		action.PackageHash = c.PackageHash
+		actions = append(actions, action)
+	}
+	result, _, _, _ := d.GetResultAndCost()
+	contractHash, errHash := d.GetStoredContractHash()
+	entrypoint, errEntrypoint := d.GetEntrypoint()
+	if result == "success" && errHash == nil && errEntrypoint == nil {
+		c, err := getCollection(strings.ReplaceAll(contractHash, "hash-", ""))
+		if err != nil {
+			return err
+		}
+		if c.PackageHash != "" && !hasNFTAction(actions, c.PackageHash) {
+			for _, action := range nft.ParseEntrypoint(c.Standard, entrypoint, d.MapArgs(), callerKey(d)) {
+				action.PackageHash = c.PackageHash
+				actions = append(actions, action)
+			}
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return database.InsertNFTActions(ctx, blockHeight, index, d.Deploy.Hash, actions)
+}
+
+// hasNFTAction check if one of the actions is on a token of the collection
+func hasNFTAction(actions []nft.Action, packageHash string) bool {
+	for _, action := range actions {
+		if action.PackageHash == packageHash {
+			return true
+		}
+	}
+	return false
 }
 
 // addDeployToQueue a deploy hash to the queue
-func addContractToQueue(hash string, deployhash string, from string) {
+func addContractToQueue(ctx context.Context, hash string, deployhash string, from string) error {
 	task, err := NewContractRawTask(hash, deployhash, from)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("contracts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "contracts")
 }
 
 // addDeployToQueue a deploy hash to the queue
-func addContractPackageToQueue(hash string, deployhash string, from string) {
+func addContractPackageToQueue(ctx context.Context, hash string, deployhash string, from string) error {
 	task, err := NewContractPackageRawTask(hash, deployhash, from)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("contracts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "contracts")
 }
 
 // addAccountToQueue add a account publicKey to the queue
-func addAccountToQueue(publicKey string) {
+func addAccountToQueue(ctx context.Context, publicKey string) error {
 	task, err := NewAccountTask(publicKey)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("accounts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "accounts")
 }
 
 type DeployRawPayload struct {
-	DeployHash string
+	DeployHash  string
+	BlockHeight int
+	// Index of the deploy in the block, deploy hashes first then transfer hashes
+	Index int
 }
 
 type DeployKnownPayload struct {
-	DeployHash string
+	DeployHash  string
+	BlockHeight int
+	// Index of the deploy in the block, deploy hashes first then transfer hashes
+	Index int
 }
 
 type DeployInfoRawPayload struct {
@@ -233,7 +592,7 @@ type DeployInfoRawPayload struct {
 }
 
 type DeployInfoKnownPayload struct {
-	DeployInfoHash string
-	Block          string
-	StateRootHash  string
+	DeployInfoHash  string
+	Block           string
+	DeployTimestamp string
 }
@@ -3,14 +3,21 @@ package tasks
 import (
 	"casperParser/db"
 	"casperParser/rpc"
+	"casperParser/types/amount"
+	"casperParser/types/ces"
+	"casperParser/types/nft"
+	"casperParser/types/token"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"github.com/hibiken/asynq"
 	"os"
+	"strings"
 	"testing"
 )
 
 func TestNewDeployRawTask(t *testing.T) {
-	task, err := NewDeployRawTask("test")
+	task, err := NewDeployRawTask("test", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -20,7 +27,7 @@ func TestNewDeployRawTask(t *testing.T) {
 }
 
 func TestHandleDeployKnownTask(t *testing.T) {
-	task, err := NewDeployKnownTask("test")
+	task, err := NewDeployKnownTask("test", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -36,12 +43,14 @@ func TestHandleDeployRawTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
-	task, err := NewDeployRawTask("00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2")
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
+	task, err := NewDeployRawTask("00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -49,7 +58,7 @@ func TestHandleDeployRawTask(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
-	task, err = NewDeployKnownTask("00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2")
+	task, err = NewDeployKnownTask("00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -57,7 +66,7 @@ func TestHandleDeployRawTask(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
-	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950")
+	task, err = NewDeployRawTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -65,7 +74,7 @@ func TestHandleDeployRawTask(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
-	task, err = NewDeployKnownTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950")
+	task, err = NewDeployKnownTask("03eb82b2e02c5880cd03fcc75580505571c69d476ce28d6cdbb0ee1930cf5950", 0, 0)
 	if err != nil {
 		t.Errorf("Unable to create a NewBlockRawTask : %s", err)
 	}
@@ -74,3 +83,300 @@ func TestHandleDeployRawTask(t *testing.T) {
 		t.Errorf("Unable to run HandleBlockRawTask : %s", err)
 	}
 }
+
+func TestHandleDeployKnownTaskWithMemoryStore(t *testing.T) {
+	const deployHash = "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2"
+	const purse = "8d5afc3b94aef156a2462d0173ae23b563d739266e9d8c7cf5bbdfc9d30dd38d"
+	const rawDeploy = `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z","gas_price":1},` +
+		`"payment":{"ModuleBytes":{"module_bytes":"","args":[["amount",{"bytes":"0400e1f505","parsed":"100000000","cl_type":"U512"}]]}},"session":{"ModuleBytes":{"args":[]}}},` +
+		`"execution_results":[{"block_hash":"fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f","result":{"Success":{"cost":"100","transfers":[],"effect":{"operations":[],"transforms":[` +
+		`{"key":"hash-` + purse + `","transform":"Identity"},` +
+		`{"key":"balance-` + purse + `","transform":{"WriteCLValue":{"bytes":"0400ca9a3b","parsed":"1000000000","cl_type":"U512"}}},` +
+		`{"key":"balance-` + purse + `","transform":{"AddUInt512":"25"}}]}}}}]}`
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+		t.Fatalf("Unable to insert the raw deploy : %s", err)
+	}
+	task, err := NewDeployKnownTask(deployHash, 10, 0)
+	if err != nil {
+		t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+	}
+
+	t.Run("Should insert the transforms of the deploy", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if store.Count("transforms") != 3 {
+			t.Errorf("Bad number of transforms. Received : %d. Expected : %d", store.Count("transforms"), 3)
+		}
+		row := store.Row("transforms", deployHash+":1")
+		if row["kind"] != "WriteCLValue" || row["value"] != `"1000000000"` {
+			t.Errorf("Bad transform. Received : %s %v. Expected : %s %s", row["kind"], row["value"], "WriteCLValue", `"1000000000"`)
+		}
+		if row := store.Row("transforms", deployHash+":0"); row["value"] != nil {
+			t.Errorf("Bad Identity value. Received : %v. Expected : nil", row["value"])
+		}
+	})
+
+	t.Run("Should replace the transforms when the deploy is reparsed", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if store.Count("transforms") != 3 {
+			t.Errorf("Bad number of transforms. Received : %d. Expected : %d", store.Count("transforms"), 3)
+		}
+	})
+
+	t.Run("Should fold the balance changes of a purse in a single row", func(t *testing.T) {
+		if store.Count("purse_balance_history") != 1 {
+			t.Errorf("Bad number of balance changes. Received : %d. Expected : %d", store.Count("purse_balance_history"), 1)
+		}
+		row := store.Row("purse_balance_history", "uref-"+purse+":10:0")
+		balance, _ := row["balance"].(*amount.Amount)
+		if balance == nil || balance.String() != "1000000025" {
+			t.Errorf("Bad balance. Received : %v. Expected : %s", row["balance"], "1000000025")
+		}
+	})
+
+	t.Run("Should insert the payment and the fee of the deploy", func(t *testing.T) {
+		row := store.Row("deploy_fees", deployHash)
+		payment, _ := row["payment_amount"].(amount.Amount)
+		unused, _ := row["unused"].(amount.Amount)
+		if payment.String() != "100000000" || row["gas_price"] != 1 || unused.String() != "99999900" {
+			t.Errorf("Bad deploy fee. Received : %s %v %s. Expected : %s %d %s", payment.String(), row["gas_price"], unused.String(), "100000000", 1, "99999900")
+		}
+	})
+
+	t.Run("Should flag the deploy when its hash can't be verified", func(t *testing.T) {
+		row := store.Row("deploy_verifications", deployHash)
+		if row == nil {
+			t.Fatalf("Bad deploy verification. Received : nil. Expected : a row")
+		}
+		if row["hash_valid"] != nil || row["body_hash_valid"] != nil || row["error"] == "" {
+			t.Errorf("Bad deploy verification. Received : %v %v %v. Expected : no hash validity and an error", row["hash_valid"], row["body_hash_valid"], row["error"])
+		}
+		if store.Count("deploy_approvals") != 0 {
+			t.Errorf("Bad number of approvals. Received : %d. Expected : %d", store.Count("deploy_approvals"), 0)
+		}
+	})
+}
+
+func TestHandleDeployKnownTaskCESEventsWithMemoryStore(t *testing.T) {
+	const deployHash = "00a7445d3be6c6b89308daf62bd055e01d3e96f1a2f6e3efe586dfb915e3dfe2"
+	const contractHash = "e3523b4d8c4b7ae3a4a8a7bd1e0a2b1eea01bac4cc0d5a1cb30e3ec7e6a9e2b1"
+	seed := strings.Repeat("ab", 32)
+	// a Transfer event from the account hash 00..00 of 100 motes, with the id 0 in the events dictionary
+	event := "0e000000" + hex.EncodeToString([]byte("event_Transfer")) + "00" + strings.Repeat("00", 32) + "0164"
+	dictionaryValue := "39000000" + "35000000" + event + "0e03" + seed + "01000000" + "30"
+	rawDeploy := `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z"},"session":{"ModuleBytes":{"args":[]}}},` +
+		`"execution_results":[{"block_hash":"fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f","result":{"Success":{"cost":"100","transfers":[],"effect":{"operations":[],"transforms":[` +
+		`{"key":"dictionary-` + strings.Repeat("cd", 32) + `","transform":{"WriteCLValue":{"bytes":"` + dictionaryValue + `","parsed":null,"cl_type":"Any"}}}]}}}}]}`
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+		t.Fatalf("Unable to insert the raw deploy : %s", err)
+	}
+	task, err := NewDeployKnownTask(deployHash, 10, 0)
+	if err != nil {
+		t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+	}
+
+	t.Run("Should skip the events of a contract not stored", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if store.Count("contract_events") != 0 {
+			t.Errorf("Bad number of events. Received : %d. Expected : %d", store.Count("contract_events"), 0)
+		}
+	})
+
+	t.Run("Should insert the events of a CES contract", func(t *testing.T) {
+		schemas := ces.Schemas{"Transfer": {{Name: "from", ClType: "Key"}, {Name: "amount", ClType: "U256"}}}
+		if err := store.InsertCESContract(ctx, contractHash, seed, schemas); err != nil {
+			t.Fatalf("Unable to insert the CES contract : %s", err)
+		}
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		row := store.Row("contract_events", seed+":0")
+		if row == nil || row["contract_hash"] != contractHash || row["name"] != "Transfer" {
+			t.Fatalf("Bad event. Received : %v. Expected : a Transfer event of %s", row, contractHash)
+		}
+		if amount := row["data"].(map[string]interface{})["amount"]; amount != "100" {
+			t.Errorf("Bad event amount. Received : %v. Expected : %s", amount, "100")
+		}
+	})
+}
+
+func TestHandleDeployKnownTaskTokensWithMemoryStore(t *testing.T) {
+	const deployHash = "7c2a8e4f9d3b1a6c5e0f2d4b8a9c7e1f3d5b2a4c6e8f0a1b3c5d7e9f2a4b6c8d"
+	const packageHash = "5d9b8f1c2e3a4b6d7f8e9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d"
+	balancesURef := strings.Repeat("ab", 32)
+	supplyURef := strings.Repeat("ef", 32)
+	holder := base64.StdEncoding.EncodeToString(make([]byte, 33))
+	// a balance of 1000 for the account hash 00..00 in the balances dictionary, and a supply of 1000
+	dictionaryValue := "03000000" + "02e803" + "07" + balancesURef + "2c000000" + hex.EncodeToString([]byte(holder))
+	rawDeploy := `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z"},"session":{"ModuleBytes":{"args":[]}}},` +
+		`"execution_results":[{"block_hash":"fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f","result":{"Success":{"cost":"100","transfers":[],"effect":{"operations":[],"transforms":[` +
+		`{"key":"dictionary-` + strings.Repeat("cd", 32) + `","transform":{"WriteCLValue":{"bytes":"` + dictionaryValue + `","parsed":null,"cl_type":"Any"}}},` +
+		`{"key":"uref-` + supplyURef + `-007","transform":{"WriteCLValue":{"bytes":"02e803","parsed":"1000","cl_type":"U256"}}}]}}}}]}`
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+		t.Fatalf("Unable to insert the raw deploy : %s", err)
+	}
+	task, err := NewDeployKnownTask(deployHash, 10, 1)
+	if err != nil {
+		t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+	}
+	balanceKey := packageHash + ":account-hash-" + strings.Repeat("00", 32)
+
+	t.Run("Should skip the balances of a token not stored", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if store.Count("token_balances") != 0 {
+			t.Errorf("Bad number of balances. Received : %d. Expected : %d", store.Count("token_balances"), 0)
+		}
+	})
+
+	t.Run("Should insert the balances and the supply of a token", func(t *testing.T) {
+		tok := token.Token{PackageHash: packageHash, ContractHash: strings.Repeat("01", 32), BalancesURef: balancesURef, TotalSupplyURef: supplyURef, TotalSupply: amount.FromInt64(1)}
+		if err := store.InsertToken(ctx, tok); err != nil {
+			t.Fatalf("Unable to insert the token : %s", err)
+		}
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		row := store.Row("token_balances", balanceKey)
+		if row == nil || row["balance"].(amount.Amount).String() != "1000" {
+			t.Fatalf("Bad balance. Received : %v. Expected : %s", row, "1000")
+		}
+		if store.Row("token_balance_history", balanceKey+":10:1") == nil {
+			t.Errorf("Balance not in the history")
+		}
+		if supply := store.Row("tokens", packageHash)["total_supply"].(amount.Amount); supply.String() != "1000" {
+			t.Errorf("Bad supply. Received : %s. Expected : %s", supply.String(), "1000")
+		}
+	})
+
+	t.Run("Should keep the balance written by a later deploy", func(t *testing.T) {
+		if err := store.InsertTokenBalances(ctx, 11, 0, deployHash, packageHash, []token.Balance{{Holder: "account-hash-" + strings.Repeat("00", 32), Balance: amount.FromInt64(5)}}); err != nil {
+			t.Fatalf("Unable to insert the token balances : %s", err)
+		}
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if balance := store.Row("token_balances", balanceKey)["balance"].(amount.Amount); balance.String() != "5" {
+			t.Errorf("Bad balance. Received : %s. Expected : %s", balance.String(), "5")
+		}
+	})
+}
+
+func TestHandleDeployKnownTaskTokenTransfersWithMemoryStore(t *testing.T) {
+	const deployHash = "3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a"
+	const packageHash = "6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d"
+	recipient := "account-hash-" + strings.Repeat("02", 32)
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	tok := token.Token{PackageHash: packageHash, ContractHash: strings.Repeat("04", 32), BalancesURef: strings.Repeat("ab", 32), TotalSupplyURef: strings.Repeat("ef", 32)}
+	if err := store.InsertToken(ctx, tok); err != nil {
+		t.Fatalf("Unable to insert the token : %s", err)
+	}
+	rawDeploy := `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z"},` +
+		`"session":{"StoredVersionedContractByHash":{"hash":"` + packageHash + `","version":null,"entry_point":"transfer","args":[` +
+		`["recipient",{"bytes":"00` + strings.Repeat("02", 32) + `","parsed":{"Account":"` + recipient + `"},"cl_type":"Key"}],` +
+		`["amount",{"bytes":"0164","parsed":"100","cl_type":"U256"}]]}}},` +
+		`"execution_results":[{"block_hash":"fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f","result":{"Success":{"cost":"100","transfers":[],"effect":{"operations":[],"transforms":[]}}}}]}`
+	if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+		t.Fatalf("Unable to insert the raw deploy : %s", err)
+	}
+	task, err := NewDeployKnownTask(deployHash, 10, 2)
+	if err != nil {
+		t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+	}
+
+	t.Run("Should insert the transfer of the entrypoint called without event", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		row := store.Row("token_transfers", deployHash+":0")
+		if row == nil || row["kind"] != token.TransferTransfer || row["to"] != recipient || row["amount"].(amount.Amount).String() != "100" || row["deploy_index"] != 2 {
+			t.Errorf("Bad transfer. Received : %v. Expected : a transfer of %s to %s", row, "100", recipient)
+		}
+	})
+
+	t.Run("Should replace the transfers when the deploy is reparsed", func(t *testing.T) {
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if store.Count("token_transfers") != 1 {
+			t.Errorf("Bad number of transfers. Received : %d. Expected : %d", store.Count("token_transfers"), 1)
+		}
+	})
+}
+
+func TestHandleDeployKnownTaskNFTsWithMemoryStore(t *testing.T) {
+	const packageHash = "9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+	sender := "account-hash-" + strings.Repeat("01", 32)
+	recipient := "account-hash-" + strings.Repeat("02", 32)
+	store := db.NewMemory()
+	WorkerStore = store
+	ctx := context.Background()
+	if err := store.InsertNFTCollection(ctx, nft.Collection{PackageHash: packageHash, ContractHash: strings.Repeat("03", 32), Standard: nft.StandardCEP78}); err != nil {
+		t.Fatalf("Unable to insert the collection : %s", err)
+	}
+	executionResult := `"execution_results":[{"block_hash":"fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f","result":{"Success":{"cost":"100","transfers":[],"effect":{"operations":[],"transforms":[`
+
+	t.Run("Should transfer the token of a CEP-47 event", func(t *testing.T) {
+		const deployHash = "1f2e3d4c5b6a79880a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f6071"
+		event := `[{"key":"event_type","value":"cep47_transfer_token"},{"key":"contract_package_hash","value":"` + packageHash + `"},` +
+			`{"key":"sender","value":"Key::Account(` + strings.Repeat("01", 32) + `)"},{"key":"recipient","value":"Key::Account(` + strings.Repeat("02", 32) + `)"},{"key":"token_id","value":"7"}]`
+		rawDeploy := `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z"},"session":{"ModuleBytes":{"args":[]}}},` + executionResult +
+			`{"key":"uref-` + strings.Repeat("ef", 32) + `-007","transform":{"WriteCLValue":{"bytes":"","parsed":` + event + `,"cl_type":{"Map":{"key":"String","value":"String"}}}}}]}}}}]}`
+		if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+			t.Fatalf("Unable to insert the raw deploy : %s", err)
+		}
+		task, err := NewDeployKnownTask(deployHash, 10, 0)
+		if err != nil {
+			t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+		}
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if row := store.Row("nft_tokens", packageHash+":7"); row == nil || row["owner"] != recipient {
+			t.Errorf("Bad token. Received : %v. Expected : owned by %s", row, recipient)
+		}
+		if row := store.Row("nft_transfers", deployHash+":0"); row == nil || row["from"] != sender || row["kind"] != nft.ActionTransfer {
+			t.Errorf("Bad transfer. Received : %v. Expected : a transfer from %s", row, sender)
+		}
+	})
+
+	t.Run("Should burn the token of the entrypoint called without event", func(t *testing.T) {
+		const deployHash = "2a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f70819"
+		rawDeploy := `{"deploy":{"hash":"` + deployHash + `","header":{"account":"01aa","timestamp":"2022-01-01T00:00:00.000Z"},` +
+			`"session":{"StoredVersionedContractByHash":{"hash":"` + packageHash + `","version":null,"entry_point":"burn","args":[["token_id",{"bytes":"0700000000000000","parsed":7,"cl_type":"U64"}]]}}},` +
+			executionResult + `]}}}}]}`
+		if err := store.InsertRawDeploy(ctx, deployHash, "2022-01-01T00:00:00.000Z", rawDeploy); err != nil {
+			t.Fatalf("Unable to insert the raw deploy : %s", err)
+		}
+		task, err := NewDeployKnownTask(deployHash, 11, 0)
+		if err != nil {
+			t.Fatalf("Unable to create a NewDeployKnownTask : %s", err)
+		}
+		if err := HandleDeployKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleDeployKnownTask : %s", err)
+		}
+		if row := store.Row("nft_tokens", packageHash+":7"); row == nil || row["burnt"] != true || row["owner"] != recipient {
+			t.Errorf("Bad token. Received : %v. Expected : burnt and owned by %s", row, recipient)
+		}
+		if row := store.Row("nft_transfers", deployHash+":0"); row == nil || row["kind"] != nft.ActionBurn {
+			t.Errorf("Bad transfer. Received : %v. Expected : a burn", row)
+		}
+	})
+}
@@ -2,17 +2,22 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
+	"casperParser/types/amount"
+	"casperParser/types/reward"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 	"strings"
 )
 
 // TypeReward Task reward type
+// TypeRewardKnown Task reward known type
 const (
-	TypeReward = "reward:raw"
+	TypeReward      = "reward:raw"
+	TypeRewardKnown = "reward:known"
 )
 
 // NewRewardTask Used for reward
@@ -24,45 +29,79 @@ func NewRewardTask(hash string) (*asynq.Task, error) {
 	return asynq.NewTask(TypeReward, payload), nil
 }
 
-// HandleRewardTask fetch era rewards from the rpc endpoint, parse it, and insert it in the database
+// NewRewardKnownTask used for the rewards of an era info already stored
+func NewRewardKnownTask(hash string) (*asynq.Task, error) {
+	payload, err := json.Marshal(RewardPayload{BlockHash: hash})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeRewardKnown, payload), nil
+}
+
+// HandleRewardTask fetch era rewards from the rpc endpoint, parse it, and insert it in the database with the rewards rollups
 func HandleRewardTask(ctx context.Context, t *asynq.Task) error {
 	var p RewardPayload
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHash: p.BlockHash})
+
+	eraParsed, resp, err := WorkerRpcClient.GetEraInfo(ctx, strings.ToLower(p.BlockHash))
+	if err != nil {
+		return err
+	}
+
+	var database = WorkerStore
+	err = database.InsertRawEraInfo(ctx, p.BlockHash, string(resp))
+	if err != nil {
+		return err
+	}
+	return insertRewards(ctx, eraParsed)
+}
+
+// HandleRewardKnownTask fetch an era info from the database, parse it, and insert its rewards in the database with the rewards rollups
+func HandleRewardKnownTask(ctx context.Context, t *asynq.Task) error {
+	var p RewardPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
+	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldBlockHash: p.BlockHash})
 
-	eraParsed, err := WorkerRpcClient.GetEraInfo(strings.ToLower(p.BlockHash))
+	var database = WorkerStore
+	eraParsed, err := database.GetRawEraInfo(ctx, strings.ToLower(p.BlockHash))
 	if err != nil {
 		return err
 	}
+	if eraParsed.EraSummary == nil {
+		return fmt.Errorf("era info of the block %s not found in raw_era_infos", p.BlockHash)
+	}
+	return insertRewards(ctx, eraParsed)
+}
 
+// insertRewards of the era info, replacing the ones already stored
+func insertRewards(ctx context.Context, eraParsed reward.Result) error {
 	var rowsToInsert [][]interface{}
 	for _, s := range eraParsed.EraSummary.StoredValue.EraInfo.SeigniorageAllocations {
 		var dpk *string
 		dpk = nil
 		vpk := ""
-		amount := ""
+		var rewardAmount amount.Amount
 		if s.Delegator != nil {
 			dpk = &s.Delegator.DelegatorPublicKey
 			vpk = s.Delegator.ValidatorPublicKey
-			amount = s.Delegator.Amount
+			rewardAmount = s.Delegator.Amount
 		}
 		if s.Validator != nil {
 			vpk = s.Validator.ValidatorPublicKey
-			amount = s.Validator.Amount
+			rewardAmount = s.Validator.Amount
 		}
 		block := strings.ToLower(eraParsed.EraSummary.BlockHash)
-		row := []interface{}{block, eraParsed.EraSummary.EraId, dpk, vpk, amount}
+		row := []interface{}{block, eraParsed.EraSummary.EraId, dpk, vpk, rewardAmount}
 		rowsToInsert = append(rowsToInsert, row)
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
-	err = database.InsertRewards(ctx, rowsToInsert)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	var database = WorkerStore
+	return database.InsertRewards(ctx, eraParsed.EraSummary.EraId, rowsToInsert)
 }
 
 type RewardPayload struct {
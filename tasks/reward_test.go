@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"casperParser/db"
+	"casperParser/queue"
 	"casperParser/rpc"
 	"context"
 	"github.com/hibiken/asynq"
@@ -26,11 +27,13 @@ func TestHandleRewardTask(t *testing.T) {
 		Addr: redis,
 	}
 	rpcendpointurl := os.Getenv("CASPER_PARSER_RPC")
-	WorkerPool, _ = db.NewPGXPool(context.Background(), dbconstring, 10)
+	workerPool, _ := db.NewPGXPool(context.Background(), dbconstring, 10)
+	WorkerStore = &db.DB{Postgres: workerPool}
 	WorkerRpcClient = rpc.NewRpcClient(rpcendpointurl)
-	WorkerAsyncClient = asynq.NewClient(redisConf)
-	defer WorkerAsyncClient.Close()
-	defer WorkerPool.Close()
+	asyncClient := asynq.NewClient(redisConf)
+	defer asyncClient.Close()
+	WorkerAsyncClient = asyncClient
+	defer workerPool.Close()
 	task, err := NewRewardTask("fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f")
 	if err != nil {
 		t.Errorf("Unable to create a NewRewardTask : %s", err)
@@ -40,3 +43,42 @@ func TestHandleRewardTask(t *testing.T) {
 		t.Errorf("Unable to run HandleRewardTask : %s", err)
 	}
 }
+
+func TestHandleRewardKnownTaskWithMemoryStore(t *testing.T) {
+	const blockHash = "fc204a0bc7788604fd0ded0ac19a73b687d12a8d735ccf57f3c65ce58d6f4d1f"
+	const rawEraInfo = `{"era_summary":{"block_hash":"` + blockHash + `","era_id":12,"stored_value":{"EraInfo":{"seigniorage_allocations":[` +
+		`{"Validator":{"validator_public_key":"01aa","amount":"100"}},` +
+		`{"Delegator":{"delegator_public_key":"01bb","validator_public_key":"01aa","amount":"50"}}]}}}}`
+	store := db.NewMemory()
+	WorkerStore = store
+	WorkerAsyncClient = queue.NewMemory(queue.Queues, 100)
+	ctx := context.Background()
+	if err := store.InsertRawEraInfo(ctx, blockHash, rawEraInfo); err != nil {
+		t.Fatalf("Unable to insert the era info : %s", err)
+	}
+	task, err := NewRewardKnownTask(blockHash)
+	if err != nil {
+		t.Fatalf("Unable to create a NewRewardKnownTask : %s", err)
+	}
+	if task.Type() != TypeRewardKnown {
+		t.Errorf("NewRewardKnownTask has a bad name. Received : %s. Expected : %s", task.Type(), TypeRewardKnown)
+	}
+
+	t.Run("Should insert the rewards of the stored era info", func(t *testing.T) {
+		if err := HandleRewardKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleRewardKnownTask : %s", err)
+		}
+		if store.Count("rewards") != 2 {
+			t.Errorf("Bad number of rewards. Received : %d. Expected : %d", store.Count("rewards"), 2)
+		}
+	})
+
+	t.Run("Should replace the rewards when the era is reparsed", func(t *testing.T) {
+		if err := HandleRewardKnownTask(ctx, task); err != nil {
+			t.Fatalf("Unable to run HandleRewardKnownTask : %s", err)
+		}
+		if store.Count("rewards") != 2 {
+			t.Errorf("Bad number of rewards. Received : %d. Expected : %d", store.Count("rewards"), 2)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+// Package tasks Define the scheduled jobs tasks and handlers
+package tasks
+
+import (
+	"casperParser/logger"
+	"context"
+
+	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
+)
+
+// TypeScheduledPurses Task add the missing accounts and purses and refresh every purse balance
+// TypeScheduledViews Task refresh the staking operations materialized views
+// TypeScheduledVerify Task verify the blocks not validated yet
+// TypeScheduledMissingBlocks Task add the blocks missing in the database
+// TypeScheduledPartitions Task create the next monthly partitions
+const (
+	TypeScheduledPurses        = "scheduled:purses"
+	TypeScheduledViews         = "scheduled:views"
+	TypeScheduledVerify        = "scheduled:verify"
+	TypeScheduledMissingBlocks = "scheduled:missingBlocks"
+	TypeScheduledPartitions    = "scheduled:partitions"
+)
+
+// partitionsMonthsAhead number of months created ahead by the partitions job, same as the maintenance command default
+const partitionsMonthsAhead = 3
+
+// ScheduledJob a task enqueued periodically by the scheduler command
+type ScheduledJob struct {
+	// Type of the task, it has no payload
+	Type string
+	// Queue the task is enqueued in
+	Queue string
+}
+
+// ScheduledJobs by their name in the schedule key of the config file
+var ScheduledJobs = map[string]ScheduledJob{
+	"auction":        {Type: TypeAuction, Queue: "auction"},
+	"purses":         {Type: TypeScheduledPurses, Queue: "scheduled"},
+	"views":          {Type: TypeScheduledViews, Queue: "scheduled"},
+	"verify":         {Type: TypeScheduledVerify, Queue: "scheduled"},
+	"missing-blocks": {Type: TypeScheduledMissingBlocks, Queue: "scheduled"},
+	"partitions":     {Type: TypeScheduledPartitions, Queue: "scheduled"},
+}
+
+// HandleScheduledPursesTask add the accounts and purses missing in the database then refresh the balance of every purse, like reparse accountPurses
+func HandleScheduledPursesTask(ctx context.Context, t *asynq.Task) error {
+	var database = WorkerJobStore
+	err := database.ForEachMissingPublicKey(ctx, func(publicKey string) error {
+		task, err := NewAccountTask(publicKey)
+		return enqueueTask(ctx, task, err, "accounts")
+	})
+	if err != nil {
+		return err
+	}
+	err = database.ForEachMissingAccountHash(ctx, func(hash string) error {
+		task, err := NewAccountHashTask(hash)
+		return enqueueTask(ctx, task, err, "accounts")
+	})
+	if err != nil {
+		return err
+	}
+	err = database.ForEachMissingPurse(ctx, func(uref string) error {
+		task, err := NewPurseTask(uref)
+		return enqueueTask(ctx, task, err, "accounts")
+	})
+	if err != nil {
+		return err
+	}
+	return database.ForEachPurse(ctx, func(uref string) error {
+		task, err := NewFetchPurseTask(uref)
+		return enqueueTask(ctx, task, err, "accounts")
+	})
+}
+
+// HandleScheduledViewsTask refresh the materialized views
+func HandleScheduledViewsTask(ctx context.Context, t *asynq.Task) error {
+	return WorkerJobStore.RefreshMaterializedViews(ctx)
+}
+
+// HandleScheduledVerifyTask add a verify task for every block not validated yet, like the verify command
+func HandleScheduledVerifyTask(ctx context.Context, t *asynq.Task) error {
+	return WorkerJobStore.ForEachUnvalidatedBlockHash(ctx, func(hash string) error {
+		task, err := NewBlockVerifyTask(hash)
+		return enqueueTask(ctx, task, err, "blocks")
+	})
+}
+
+// HandleScheduledMissingBlocksTask add the blocks missing between the first and the last block of the database
+func HandleScheduledMissingBlocksTask(ctx context.Context, t *asynq.Task) error {
+	heights, err := WorkerJobStore.GetMissingBlocks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, height := range heights {
+		task, err := NewBlockRawTask(height)
+		if err := enqueueTask(ctx, task, err, "blocks"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleScheduledPartitionsTask create the missing monthly partitions, like the maintenance command
+func HandleScheduledPartitionsTask(ctx context.Context, t *asynq.Task) error {
+	created, err := WorkerJobStore.CreatePartitions(ctx, partitionsMonthsAhead)
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).WithFields(log.Fields{"partitions": created}).Info("partitions created")
+	return nil
+}
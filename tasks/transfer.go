@@ -2,15 +2,14 @@
 package tasks
 
 import (
-	"casperParser/db"
+	"casperParser/logger"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strconv"
 	"strings"
 
 	"github.com/hibiken/asynq"
+	log "github.com/sirupsen/logrus"
 )
 
 // TypeTransferRaw Task transfer raw type
@@ -22,21 +21,21 @@ const (
 
 // NewTransferRawTask Used for not yet parsed transfer
 func NewTransferRawTask(hash string, blockHash string, deployHash string, deployTimestamp string, stateRootHash string) (*asynq.Task, error) {
-	payload, err := json.Marshal(TransferRawPayload{TransferHash: hash, Block: blockHash, Deploy: deployHash, StateRootHash: stateRootHash})
+	payload, err := json.Marshal(TransferRawPayload{TransferHash: hash, Block: blockHash, Deploy: deployHash, StateRootHash: stateRootHash, Timestamp: deployTimestamp})
 	if err != nil {
 		return nil, err
 	}
 	return asynq.NewTask(TypeTransferRaw, payload), nil
 }
 
-// NewTransferKnownTask used for already parsed transfer
-//func NewTransferKnownTask(hash string, blockHash string, deployHash string, stateRootHash string) (*asynq.Task, error) {
-//	payload, err := json.Marshal(TransferKnownPayload{TransferHash: hash, Block: blockHash, Deploy: deployHash, StateRootHash: stateRootHash})
-//	if err != nil {
-//		return nil, err
-//	}
-//	return asynq.NewTask(TypeTransferKnown, payload), nil
-//}
+// NewTransferKnownTask used for already parsed transfer, deployTimestamp is the one of the block like for the raw transfers
+func NewTransferKnownTask(hash string, blockHash string, deployHash string, deployTimestamp string) (*asynq.Task, error) {
+	payload, err := json.Marshal(TransferKnownPayload{TransferHash: hash, Block: blockHash, Deploy: deployHash, Timestamp: deployTimestamp})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeTransferKnown, payload), nil
+}
 
 // HandleTransferRawTask fetch a transfer from the rpc endpoint, parse it, and insert it in the database
 func HandleTransferRawTask(ctx context.Context, t *asynq.Task) error {
@@ -44,99 +43,81 @@ func HandleTransferRawTask(ctx context.Context, t *asynq.Task) error {
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.Deploy, logger.FieldBlockHash: p.Block, "transfer_hash": p.TransferHash})
 
-	rpcTransfer, resp, err := WorkerRpcClient.GetTransfer(p.StateRootHash, p.TransferHash)
+	rpcTransfer, resp, err := WorkerRpcClient.GetTransfer(ctx, p.StateRootHash, p.TransferHash)
 	if err != nil {
-		println("ERROR on WorkerRpcClient.GetTransfer(p.StateRootHash, p.TransferHash)")
-		println(p.StateRootHash)
-		println(p.TransferHash)
+		logger.FromContext(ctx).WithError(err).WithField("state_root_hash", p.StateRootHash).Error("can't get the transfer from the rpc")
 		return err
 	}
 
-	var database = db.DB{Postgres: WorkerPool}
-	jsonString := strings.ReplaceAll(string(resp), "\\u0000", "")
-	amount, err := strconv.Atoi(rpcTransfer.StoredValue.Transfer.Amount)
-	if err != nil {
-		return err
-	}
-	gas, err := strconv.Atoi(rpcTransfer.StoredValue.Transfer.Gas)
-	if err != nil {
-		return err
+	var database = WorkerStore
+	timestamp := p.Timestamp
+	if timestamp == "" {
+		// Tasks enqueued before the transfers were partitioned don't carry the timestamp, it's the one of the block
+		block, err := database.GetRawBlock(ctx, p.Block)
+		if err != nil {
+			return err
+		}
+		timestamp = block.Block.Header.Timestamp
 	}
-	err = database.InsertTransfer(ctx, p.TransferHash, p.Block, p.Deploy, rpcTransfer.StoredValue.Transfer.From, rpcTransfer.StoredValue.Transfer.To, rpcTransfer.StoredValue.Transfer.Source, rpcTransfer.StoredValue.Transfer.Target, amount, gas, jsonString, fmt.Sprint(rpcTransfer.StoredValue.Transfer.Id))
+	jsonString := strings.ReplaceAll(string(resp), "\\u0000", "")
+	err = database.InsertTransfer(ctx, p.TransferHash, p.Block, p.Deploy, timestamp, rpcTransfer.StoredValue.Transfer.From, rpcTransfer.StoredValue.Transfer.To, rpcTransfer.StoredValue.Transfer.Source, rpcTransfer.StoredValue.Transfer.Target, rpcTransfer.StoredValue.Transfer.Amount, rpcTransfer.StoredValue.Transfer.Gas, jsonString, fmt.Sprint(rpcTransfer.StoredValue.Transfer.Id))
 	if err != nil {
-		println("ERROR on InsertTransfer")
-		//println(p.TransferHash, p.Block, p.Deploy, rpcTransfer.StoredValue.Transfer.From, rpcTransfer.StoredValue.Transfer.To, rpcTransfer.StoredValue.Transfer.Source, rpcTransfer.StoredValue.Transfer.Target, rpcTransfer.StoredValue.Transfer.Amount, rpcTransfer.StoredValue.Transfer.Gas, rpcTransfer.StoredValue.Transfer.Id)
+		logger.FromContext(ctx).WithError(err).Error("can't insert the transfer")
 		return err
 	}
 
 	prefix := "account-hash-"
 
-	accountHashFromTask, err := NewAccountHashTask(strings.TrimPrefix(rpcTransfer.StoredValue.Transfer.From, prefix))
+	err = addAccountHashToQueue(ctx, strings.TrimPrefix(rpcTransfer.StoredValue.Transfer.From, prefix))
 	if err != nil {
-		log.Fatalf("could not create task: %v", err)
+		return err
 	}
-	_, err = WorkerAsyncClient.Enqueue(accountHashFromTask, asynq.Queue("accounts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
+	return addAccountHashToQueue(ctx, strings.TrimPrefix(rpcTransfer.StoredValue.Transfer.To, prefix))
+}
+
+// HandleTransferKnownTask fetch a transfer from the database, parse it, and update it in the database
+func HandleTransferKnownTask(ctx context.Context, t *asynq.Task) error {
+	var p TransferKnownPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v", err)
 	}
+	ctx = logger.WithFields(ctx, log.Fields{logger.FieldDeployHash: p.Deploy, logger.FieldBlockHash: p.Block, "transfer_hash": p.TransferHash})
 
-	accountHashToTask, err := NewAccountHashTask(strings.TrimPrefix(rpcTransfer.StoredValue.Transfer.To, prefix))
+	var database = WorkerStore
+	dbTransfer, err := database.GetTransfer(ctx, strings.ToLower(p.TransferHash))
 	if err != nil {
-		log.Fatalf("could not create task: %v", err)
+		return err
+	}
+	if dbTransfer.StoredValue.Transfer.Deploy == "" {
+		return fmt.Errorf("transfer %s not found in raw_transfers", p.TransferHash)
 	}
-	_, err = WorkerAsyncClient.Enqueue(accountHashToTask, asynq.Queue("accounts"))
+	err = database.UpdateTransfer(ctx, p.TransferHash, p.Block, p.Deploy, p.Timestamp, dbTransfer.StoredValue.Transfer.From, dbTransfer.StoredValue.Transfer.To, dbTransfer.StoredValue.Transfer.Source, dbTransfer.StoredValue.Transfer.Target, dbTransfer.StoredValue.Transfer.Amount, dbTransfer.StoredValue.Transfer.Gas, fmt.Sprint(dbTransfer.StoredValue.Transfer.Id))
 	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
+		logger.FromContext(ctx).WithError(err).Error("can't update the transfer")
+		return err
 	}
-
-	return nil
-}
-
-// HandleTransferKnownTask fetch a transfer from the database, parse it, and insert it in the database
-func HandleTransferKnownTask(ctx context.Context, t *asynq.Task) error {
-	//var p TransferKnownPayload
-	//if err := json.Unmarshal(t.Payload(), &p); err != nil {
-	//	return fmt.Errorf("json.Unmarshal failed: %v", err)
-	//}
-	//var database = db.DB{Postgres: WorkerPool}
-	//dbTransfer, err := database.GetTransfer(ctx, p.TransferHash)
-	//if err != nil {
-	//	log.Printf("Can't find transfer %s\n", p.TransferHash)
-	//	return err
-	//}
-	//
-	//var database = db.DB{Postgres: WorkerPool}
-	//err = database.UpdateTransfer(ctx, p.TransferHash, p.Block, p.Deploy, rpcTransfer.StoredValue.from, rpcTransfer.StoredValue.to, rpcTransfer.StoredValue.source, rpcTransfer.StoredValue.target, rpcTransfer.StoredValue.amount, rpcTransfer.StoredValue.gas, rpcTransfer.StoredValue.id)
-	//if err != nil {
-	//	return err
-	//}
-
 	return nil
 }
 
 // addAccountHashToQueue add a account hash to the queue
-func addAccountHashToQueue(hash string) {
+func addAccountHashToQueue(ctx context.Context, hash string) error {
 	task, err := NewAccountHashTask(hash)
-	if err != nil {
-		log.Fatalf("could not create task: %v", err)
-	}
-	_, err = WorkerAsyncClient.Enqueue(task, asynq.Queue("accounts"))
-	if err != nil {
-		log.Fatalf("could not enqueue task: %v", err)
-	}
+	return enqueueTask(ctx, task, err, "accounts")
 }
 
 type TransferRawPayload struct {
-	TransferHash    string
-	Block           string
-	Deploy          string
-	StateRootHash   string
+	TransferHash  string
+	Block         string
+	Deploy        string
+	StateRootHash string
+	Timestamp     string
 }
 
 type TransferKnownPayload struct {
-	TransferHash    string
-	Block           string
-	Deploy          string
-	StateRootHash   string
+	TransferHash string
+	Block        string
+	Deploy       string
+	Timestamp    string
 }
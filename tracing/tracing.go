@@ -0,0 +1,124 @@
+// Package tracing setup OpenTelemetry and carry the trace context through the tasks payloads
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PayloadField name of the json field added to the task payloads to carry the trace context
+const PayloadField = "TraceContext"
+
+const tracerName = "casperParser"
+
+var propagator = propagation.TraceContext{}
+
+// Init the global tracer provider with an OTLP http exporter. An empty endpoint keep the default noop provider.
+// The returned function flush and stop the exporter.
+func Init(ctx context.Context, endpoint string, insecure bool, serviceName string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// Start a new span as a child of the span in the context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartRpc start a span around a rpc call
+func StartRpc(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCSystemKey.String("jsonrpc"), semconv.RPCMethodKey.String(method)))
+}
+
+// StartDB start a span around a database statement
+func StartDB(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationKey.String(operation)))
+}
+
+// RecordError mark the span as failed if err is not nil
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Inject the trace context of ctx into the task payload. Tasks without a json object payload get one.
+func Inject(ctx context.Context, task *asynq.Task) *asynq.Task {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return task
+	}
+	fields := make(map[string]json.RawMessage)
+	if len(task.Payload()) > 0 {
+		if err := json.Unmarshal(task.Payload(), &fields); err != nil {
+			return task
+		}
+	}
+	traceContext, err := json.Marshal(carrier)
+	if err != nil {
+		return task
+	}
+	fields[PayloadField] = traceContext
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return task
+	}
+	return asynq.NewTask(task.Type(), payload)
+}
+
+// Extract the trace context carried by the task payload
+func Extract(ctx context.Context, task *asynq.Task) context.Context {
+	var p struct {
+		TraceContext propagation.MapCarrier
+	}
+	if err := json.Unmarshal(task.Payload(), &p); err != nil || p.TraceContext == nil {
+		return ctx
+	}
+	return propagator.Extract(ctx, p.TraceContext)
+}
+
+// Middleware start a consumer span for every task processed, linked to the span that enqueued it
+func Middleware(h asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		ctx, span := otel.Tracer(tracerName).Start(Extract(ctx, t), "task "+t.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attribute.String("task.type", t.Type())))
+		defer span.End()
+		err := h.ProcessTask(ctx, t)
+		RecordError(span, err)
+		return err
+	})
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	t.Run("Should carry the trace context in the payload", func(t *testing.T) {
+		task := Inject(ctx, asynq.NewTask("block:raw", []byte(`{"BlockHeight":64}`)))
+		var p struct {
+			BlockHeight int
+		}
+		err := json.Unmarshal(task.Payload(), &p)
+		if err != nil || p.BlockHeight != 64 {
+			t.Errorf("Payload fields should be kept. Received : %s", task.Payload())
+		}
+		extracted := trace.SpanContextFromContext(Extract(context.Background(), task))
+		if extracted.TraceID() != traceID || extracted.SpanID() != spanID {
+			t.Errorf("Bad trace context extracted. Received : %s %s. Expected : %s %s", extracted.TraceID(), extracted.SpanID(), traceID, spanID)
+		}
+	})
+	t.Run("Should add a payload to tasks without one", func(t *testing.T) {
+		task := Inject(ctx, asynq.NewTask("auction:raw", nil))
+		extracted := trace.SpanContextFromContext(Extract(context.Background(), task))
+		if extracted.TraceID() != traceID {
+			t.Errorf("Bad trace id extracted. Received : %s. Expected : %s", extracted.TraceID(), traceID)
+		}
+	})
+	t.Run("Should not touch the payload without a trace", func(t *testing.T) {
+		task := Inject(context.Background(), asynq.NewTask("block:raw", []byte(`{"BlockHeight":64}`)))
+		if string(task.Payload()) != `{"BlockHeight":64}` {
+			t.Errorf("Payload should not change. Received : %s", task.Payload())
+		}
+	})
+}
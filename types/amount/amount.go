@@ -0,0 +1,89 @@
+// Package amount provide an arbitrary precision integer for the U128, U256 and U512 values of the Casper Blockchain
+package amount
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/jackc/pgtype"
+)
+
+// Amount a U128, U256 or U512 value. The RPC send them as json strings, the era report rewards as json numbers, both are accepted.
+// It's stored as a NUMERIC
+type Amount struct {
+	big.Int
+}
+
+// Parse a base 10 amount
+func Parse(s string) (Amount, error) {
+	var a Amount
+	if _, ok := a.SetString(s, 10); !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return a, nil
+}
+
+// FromInt64 return the amount of an int64
+func FromInt64(i int64) Amount {
+	var a Amount
+	a.SetInt64(i)
+	return a
+}
+
+// String the base 10 amount
+func (a Amount) String() string {
+	return a.Int.String()
+}
+
+// UnmarshalJSON from a json string or number
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	parsed, err := Parse(string(bytes.Trim(data, `"`)))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON as a json string, the json numbers lose the precision above 2^53 in most decoders
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.String() + `"`), nil
+}
+
+// EncodeBinary as a NUMERIC, used by pgx for the queries and the COPY
+func (a Amount) EncodeBinary(ci *pgtype.ConnInfo, buf []byte) ([]byte, error) {
+	n := pgtype.Numeric{Int: new(big.Int).Set(&a.Int), Status: pgtype.Present}
+	return n.EncodeBinary(ci, buf)
+}
+
+// DecodeBinary from a NUMERIC without fractional part
+func (a *Amount) DecodeBinary(ci *pgtype.ConnInfo, src []byte) error {
+	var n pgtype.Numeric
+	if err := n.DecodeBinary(ci, src); err != nil {
+		return err
+	}
+	if n.Status != pgtype.Present {
+		*a = Amount{}
+		return nil
+	}
+	i := new(big.Int).Set(n.Int)
+	exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(n.Exp))), nil)
+	if n.Exp >= 0 {
+		i.Mul(i, exp)
+	} else if _, rem := i.QuoRem(i, exp, new(big.Int)); rem.Sign() != 0 {
+		return fmt.Errorf("amount %se%d is not an integer", n.Int, n.Exp)
+	}
+	a.Int = *i
+	return nil
+}
+
+func abs(i int32) int32 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,69 @@
+package amount
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgtype"
+)
+
+func TestAmount_UnmarshalJSON(t *testing.T) {
+	const u512 = "13407807929942597099574024998205846127479365820592393377723561443721764030073546976801874298166903427690031858186486050853753882811946569946433649006084095"
+	t.Run("Should parse a json string above 2^64", func(t *testing.T) {
+		var a Amount
+		if err := json.Unmarshal([]byte(`"`+u512+`"`), &a); err != nil {
+			t.Fatalf("Unable to unmarshal the amount : %s", err)
+		}
+		if a.String() != u512 {
+			t.Errorf("Bad amount. Received : %s. Expected : %s", a.String(), u512)
+		}
+	})
+	t.Run("Should parse a json number without float rounding", func(t *testing.T) {
+		var a Amount
+		if err := json.Unmarshal([]byte(`9007199254740993`), &a); err != nil {
+			t.Fatalf("Unable to unmarshal the amount : %s", err)
+		}
+		if a.String() != "9007199254740993" {
+			t.Errorf("Bad amount. Received : %s. Expected : %s", a.String(), "9007199254740993")
+		}
+	})
+	t.Run("Should refuse an invalid amount", func(t *testing.T) {
+		var a Amount
+		if err := json.Unmarshal([]byte(`"12a"`), &a); err == nil {
+			t.Errorf("Invalid amount parsed")
+		}
+	})
+}
+
+func TestAmount_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{FromInt64(42)})
+	if err != nil {
+		t.Fatalf("Unable to marshal the amount : %s", err)
+	}
+	if string(data) != `{"amount":"42"}` {
+		t.Errorf("Bad json. Received : %s. Expected : %s", data, `{"amount":"42"}`)
+	}
+}
+
+func TestAmount_Binary(t *testing.T) {
+	ci := pgtype.NewConnInfo()
+	for _, value := range []string{"0", "1000", "123456789012345678901234567890"} {
+		a, err := Parse(value)
+		if err != nil {
+			t.Fatalf("Unable to parse %s : %s", value, err)
+		}
+		buf, err := a.EncodeBinary(ci, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode %s : %s", value, err)
+		}
+		var decoded Amount
+		if err := decoded.DecodeBinary(ci, buf); err != nil {
+			t.Fatalf("Unable to decode %s : %s", value, err)
+		}
+		if decoded.String() != value {
+			t.Errorf("Bad decoded amount. Received : %s. Expected : %s", decoded.String(), value)
+		}
+	}
+}
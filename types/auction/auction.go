@@ -1,5 +1,7 @@
 package auction
 
+import "casperParser/types/amount"
+
 type Result struct {
 	AuctionState struct {
 		StateRootHash string `json:"state_root_hash"`
@@ -7,21 +9,21 @@ type Result struct {
 		EraValidators []struct {
 			EraID            int `json:"era_id"`
 			ValidatorWeights []struct {
-				PublicKey string `json:"public_key"`
-				Weight    string `json:"weight"`
+				PublicKey string        `json:"public_key"`
+				Weight    amount.Amount `json:"weight"`
 			} `json:"validator_weights"`
 		} `json:"era_validators"`
 		Bids []struct {
 			PublicKey string `json:"public_key"`
 			Bid       struct {
-				BondingPurse   string `json:"bonding_purse"`
-				StakedAmount   string `json:"staked_amount"`
-				DelegationRate int    `json:"delegation_rate"`
+				BondingPurse   string        `json:"bonding_purse"`
+				StakedAmount   amount.Amount `json:"staked_amount"`
+				DelegationRate int           `json:"delegation_rate"`
 				Delegators     []struct {
-					PublicKey    string `json:"public_key"`
-					StakedAmount string `json:"staked_amount"`
-					BondingPurse string `json:"bonding_purse"`
-					Delegatee    string `json:"delegatee"`
+					PublicKey    string        `json:"public_key"`
+					StakedAmount amount.Amount `json:"staked_amount"`
+					BondingPurse string        `json:"bonding_purse"`
+					Delegatee    string        `json:"delegatee"`
 				} `json:"delegators"`
 				Inactive bool `json:"inactive"`
 			} `json:"bid"`
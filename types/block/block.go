@@ -1,7 +1,7 @@
 // Package block provide a struct for unmarshalling a json Block response from Casper RPC
 package block
 
-import "math/big"
+import "casperParser/types/amount"
 
 type Result struct {
 	Block struct {
@@ -20,14 +20,14 @@ type Result struct {
 				EraReport struct {
 					Equivocators []string `json:"equivocators"`
 					Rewards      []struct {
-						Validator string  `json:"validator"`
-						Amount    big.Int `json:"amount"`
+						Validator string        `json:"validator"`
+						Amount    amount.Amount `json:"amount"`
 					} `json:"rewards"`
 					InactiveValidators []string `json:"inactiveValidators"`
 				} `json:"era_report"`
 				NextEraValidatorWeights []struct {
-					Validator string `json:"validator"`
-					Weight    string `json:"weight"`
+					Validator string        `json:"validator"`
+					Weight    amount.Amount `json:"weight"`
 				} `json:"next_era_validator_weights"`
 			} `json:"era_end"`
 		} `json:"header"`
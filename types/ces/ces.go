@@ -0,0 +1,148 @@
+// Package ces decode the events of the contracts following the Casper Event Standard.
+// A contract stores its events in the dictionary of its __events named key, each item is the bytes of an event.
+// The fields of each event are described by the schemas of its __events_schema named key.
+package ces
+
+import (
+	"casperParser/types/clvalue"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The named keys of a CES contract
+const (
+	EventsNamedKey  = "__events"
+	SchemasNamedKey = "__events_schema"
+)
+
+// eventPrefix of the name of an event in its bytes, not in the schemas
+const eventPrefix = "event_"
+
+// Field of an event schema, the cl_type is in its json form
+type Field struct {
+	Name   string      `json:"name"`
+	ClType interface{} `json:"cl_type"`
+}
+
+// Schemas of the events of a contract, the fields in their order in the event bytes by event name
+type Schemas map[string][]Field
+
+// Event of a CES contract
+type Event struct {
+	// ContractHash of the contract emitting the event
+	ContractHash string
+	// EventsURef address of the events dictionary, in hex without the access rights
+	EventsURef string
+	// ID of the event, its key in the events dictionary
+	ID int64
+	// Name of the event in the schemas
+	Name string
+	// Data of the event by field name, in the form of the clvalue package
+	Data map[string]interface{}
+}
+
+// URefAddress of a uref-<address>-<access rights> key
+func URefAddress(uref string) string {
+	address := strings.TrimPrefix(uref, "uref-")
+	if i := strings.Index(address, "-"); i >= 0 {
+		address = address[:i]
+	}
+	return strings.ToLower(address)
+}
+
+// ParseSchemas decode the bytes of the __events_schema CLValue, a map of event name to the list of its field names and cl_types
+func ParseSchemas(data []byte) (Schemas, error) {
+	count, data, err := readLength(data)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make(Schemas)
+	for i := 0; i < count; i++ {
+		var name interface{}
+		name, data, err = clvalue.DecodeNext("String", data)
+		if err != nil {
+			return nil, err
+		}
+		var fieldCount int
+		fieldCount, data, err = readLength(data)
+		if err != nil {
+			return nil, err
+		}
+		var fields []Field
+		for j := 0; j < fieldCount; j++ {
+			var fieldName, clType interface{}
+			fieldName, data, err = clvalue.DecodeNext("String", data)
+			if err != nil {
+				return nil, err
+			}
+			clType, data, err = clvalue.DecodeCLType(data)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, Field{Name: fieldName.(string), ClType: clType})
+		}
+		schemas[name.(string)] = fields
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("%d bytes left after decoding the schemas", len(data))
+	}
+	return schemas, nil
+}
+
+// IsEvent check if the dictionary value can be an event, the events are stored as bytes under their numeric id
+func IsEvent(value clvalue.DictionaryValue) bool {
+	list, ok := value.CLType.(map[string]interface{})
+	if !ok || list["List"] != "U8" {
+		return false
+	}
+	_, err := strconv.ParseInt(value.ItemKey, 10, 64)
+	return err == nil
+}
+
+// ParseEvent decode an item of the events dictionary with the schemas of the contract
+func (s Schemas) ParseEvent(value clvalue.DictionaryValue) (Event, error) {
+	if !IsEvent(value) {
+		return Event{}, errors.New("the dictionary value is not an event")
+	}
+	id, _ := strconv.ParseInt(value.ItemKey, 10, 64)
+	length, data, err := readLength(value.Bytes)
+	if err != nil {
+		return Event{}, err
+	}
+	if length != len(data) {
+		return Event{}, fmt.Errorf("event %d has %d bytes, %d expected", id, len(data), length)
+	}
+	name, data, err := clvalue.DecodeNext("String", data)
+	if err != nil {
+		return Event{}, err
+	}
+	eventName := strings.TrimPrefix(name.(string), eventPrefix)
+	fields, ok := s[eventName]
+	if !ok {
+		return Event{}, fmt.Errorf("event %s not in the schemas", eventName)
+	}
+	event := Event{EventsURef: value.SeedURef, ID: id, Name: eventName, Data: make(map[string]interface{}, len(fields))}
+	for _, field := range fields {
+		var fieldValue interface{}
+		fieldValue, data, err = clvalue.DecodeNext(field.ClType, data)
+		if err != nil {
+			return Event{}, fmt.Errorf("can't decode the %s field of the %s event: %w", field.Name, eventName, err)
+		}
+		event.Data[field.Name] = fieldValue
+	}
+	if len(data) > 0 {
+		return Event{}, fmt.Errorf("%d bytes left after decoding the %s event", len(data), eventName)
+	}
+	return event, nil
+}
+
+// readLength read the u32 length prefix of a list or a map
+func readLength(data []byte) (int, []byte, error) {
+	if len(data) < 4 {
+		return 0, nil, errors.New("unexpected end of the bytes")
+	}
+	return int(binary.LittleEndian.Uint32(data)), data[4:], nil
+}
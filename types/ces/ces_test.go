@@ -0,0 +1,100 @@
+package ces
+
+import (
+	"casperParser/types/clvalue"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// u32 the little endian bytes of n appended to data
+func u32(data []byte, n int) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, uint32(n))
+	return append(data, b...)
+}
+
+// serializedString the bytes of a CLValue string, its u32 length then its bytes
+func serializedString(s string) []byte {
+	return append(u32(nil, len(s)), s...)
+}
+
+// transferSchemas the bytes of the schemas of a Transfer event with a Key and a U256 field
+func transferSchemas() []byte {
+	data := u32(nil, 1)
+	data = append(data, serializedString("Transfer")...)
+	data = u32(data, 2)
+	data = append(append(data, serializedString("from")...), 11)
+	data = append(append(data, serializedString("amount")...), 7)
+	return data
+}
+
+// transferEvent the bytes of a Transfer event stored in the events dictionary at id
+func transferEvent(id string) clvalue.DictionaryValue {
+	event := serializedString("event_Transfer")
+	event = append(event, 0)
+	event = append(event, make([]byte, 32)...)
+	event = append(event, 1, 100)
+	return clvalue.DictionaryValue{
+		CLType:   map[string]interface{}{"List": "U8"},
+		Bytes:    append(u32(nil, len(event)), event...),
+		SeedURef: strings.Repeat("ab", 32),
+		ItemKey:  id,
+	}
+}
+
+func TestParseSchemas(t *testing.T) {
+	t.Run("Should decode the fields and their cl_type", func(t *testing.T) {
+		schemas, err := ParseSchemas(transferSchemas())
+		if err != nil {
+			t.Fatalf("Unable to parse the schemas : %s", err)
+		}
+		data, _ := json.Marshal(schemas)
+		expected := `{"Transfer":[{"name":"from","cl_type":"Key"},{"name":"amount","cl_type":"U256"}]}`
+		if string(data) != expected {
+			t.Errorf("Bad schemas. Received : %s. Expected : %s", data, expected)
+		}
+	})
+	t.Run("Should refuse truncated schemas", func(t *testing.T) {
+		data := transferSchemas()
+		if _, err := ParseSchemas(data[:len(data)-1]); err == nil {
+			t.Errorf("Truncated schemas parsed")
+		}
+	})
+}
+
+func TestSchemas_ParseEvent(t *testing.T) {
+	schemas, err := ParseSchemas(transferSchemas())
+	if err != nil {
+		t.Fatalf("Unable to parse the schemas : %s", err)
+	}
+	t.Run("Should decode the event with its schema", func(t *testing.T) {
+		event, err := schemas.ParseEvent(transferEvent("3"))
+		if err != nil {
+			t.Fatalf("Unable to parse the event : %s", err)
+		}
+		data, _ := json.Marshal(event.Data)
+		expected := `{"amount":"100","from":{"Account":"account-hash-` + strings.Repeat("00", 32) + `"}}`
+		if event.ID != 3 || event.Name != "Transfer" || string(data) != expected {
+			t.Errorf("Bad event. Received : %d %s %s. Expected : %d %s %s", event.ID, event.Name, data, 3, "Transfer", expected)
+		}
+	})
+	t.Run("Should refuse an event not in the schemas", func(t *testing.T) {
+		if _, err := (Schemas{}).ParseEvent(transferEvent("3")); err == nil {
+			t.Errorf("Event parsed without its schema")
+		}
+	})
+	t.Run("Should not take a dictionary value with a non numeric key for an event", func(t *testing.T) {
+		if IsEvent(transferEvent("balances")) {
+			t.Errorf("Dictionary value with a non numeric key taken for an event")
+		}
+	})
+}
+
+func TestURefAddress(t *testing.T) {
+	address := URefAddress("uref-" + strings.Repeat("AB", 32) + "-007")
+	if address != strings.Repeat("ab", 32) {
+		t.Errorf("Bad uref address. Received : %s. Expected : %s", address, strings.Repeat("ab", 32))
+	}
+}
@@ -0,0 +1,581 @@
+// Package clvalue decode the CLValues of the Casper Blockchain from their bytes and their cl_type
+package clvalue
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CLValue as sent by the RPC, the bytes in hex and the cl_type in its json form. Parsed is the value rendered by the node.
+type CLValue struct {
+	Bytes  string      `json:"bytes"`
+	Parsed interface{} `json:"parsed"`
+	ClType interface{} `json:"cl_type"`
+}
+
+// Value of the CLValue decoded from its bytes, the parsed value of the node when they can't be decoded
+func (v CLValue) Value() interface{} {
+	value, err := DecodeHex(v.ClType, v.Bytes)
+	if err != nil {
+		return v.Parsed
+	}
+	return value
+}
+
+// Value of a CLValue unmarshalled in a map, with the bytes, parsed and cl_type keys. Return nil if it's not a CLValue.
+func Value(v interface{}) interface{} {
+	unboxed, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	bytes, _ := unboxed["bytes"].(string)
+	return CLValue{Bytes: bytes, Parsed: unboxed["parsed"], ClType: unboxed["cl_type"]}.Value()
+}
+
+// DecodeHex decode the hex bytes of a CLValue against its cl_type
+func DecodeHex(clType interface{}, s string) (interface{}, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(clType, data)
+}
+
+// Decode the bytes of a CLValue against its cl_type, in the json form of the RPC.
+// The value has the shape of the parsed field of the node: the integers up to 64 bits are json.Number, the bigger ones
+// base 10 strings, the byte arrays hex strings, the urefs formatted strings, the keys an object
+// with the formatted key by the name of its variant, a map a list of key and value
+// objects, a tuple a list and a result an object with an Ok or an Err key. Any can't be decoded, its bytes are returned in hex.
+// A nested Any is an error, its length is unknown.
+func Decode(clType interface{}, data []byte) (interface{}, error) {
+	if clType == "Any" {
+		return hex.EncodeToString(data), nil
+	}
+	value, rest, err := DecodeNext(clType, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes left after decoding the %v value", len(rest), clType)
+	}
+	return value, nil
+}
+
+// DecodeNext decode the value of the type at the start of the bytes, return the bytes left after it
+func DecodeNext(clType interface{}, data []byte) (interface{}, []byte, error) {
+	d := decoder{data: data}
+	value, err := d.decode(clType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, d.data, nil
+}
+
+// DecodeCLType decode a serialized CLType at the start of the bytes in its json form, return the bytes left after it
+func DecodeCLType(data []byte) (interface{}, []byte, error) {
+	d := decoder{data: data}
+	clType, err := d.decodeCLType()
+	if err != nil {
+		return nil, nil, err
+	}
+	return clType, d.data, nil
+}
+
+// EncodeCLType serialize a CLType in its json form, the reverse of DecodeCLType
+func EncodeCLType(clType interface{}) ([]byte, error) {
+	switch t := clType.(type) {
+	case string:
+		for tag, name := range simpleTypes {
+			if name != "" && name == t {
+				return []byte{byte(tag)}, nil
+			}
+		}
+	case map[string]interface{}:
+		for name, inner := range t {
+			return encodeComplexCLType(name, inner)
+		}
+	}
+	return nil, fmt.Errorf("unknown cl_type %v", clType)
+}
+
+// encodeComplexCLType serialize a CLType with parameters
+func encodeComplexCLType(name string, inner interface{}) ([]byte, error) {
+	encode := func(tag byte, types ...interface{}) ([]byte, error) {
+		data := []byte{tag}
+		for _, t := range types {
+			b, err := EncodeCLType(t)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, b...)
+		}
+		return data, nil
+	}
+	switch name {
+	case "Option":
+		return encode(13, inner)
+	case "List":
+		return encode(14, inner)
+	case "ByteArray":
+		length, err := typeLength(inner)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, 5)
+		data[0] = 15
+		binary.LittleEndian.PutUint32(data[1:], uint32(length))
+		return data, nil
+	case "Result":
+		params, _ := inner.(map[string]interface{})
+		return encode(16, params["ok"], params["err"])
+	case "Map":
+		params, _ := inner.(map[string]interface{})
+		return encode(17, params["key"], params["value"])
+	case "Tuple1", "Tuple2", "Tuple3":
+		types, _ := inner.([]interface{})
+		if len(types) != int(name[5]-'0') {
+			return nil, fmt.Errorf("%s with %d types", name, len(types))
+		}
+		return encode(byte(17+len(types)), types...)
+	}
+	return nil, fmt.Errorf("unknown cl_type %s", name)
+}
+
+// DictionaryValue the value of a dictionary item, written at dictionary-<address> with the Any cl_type
+type DictionaryValue struct {
+	// CLType of the value in its json form and Bytes its serialized value
+	CLType interface{}
+	Bytes  []byte
+	// SeedURef address of the uref of the dictionary, in hex without the access rights
+	SeedURef string
+	// ItemKey of the value in the dictionary
+	ItemKey string
+}
+
+// DecodeDictionaryValue decode the bytes of a dictionary item: its CLValue, the address of the dictionary seed uref and its key
+func DecodeDictionaryValue(data []byte) (DictionaryValue, error) {
+	d := decoder{data: data}
+	length, err := d.length()
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	value, err := d.read(length)
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	clType, err := d.decodeCLType()
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	seedURef, err := d.read(32)
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	length, err = d.length()
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	itemKey, err := d.read(length)
+	if err != nil {
+		return DictionaryValue{}, err
+	}
+	if len(d.data) > 0 {
+		return DictionaryValue{}, fmt.Errorf("%d bytes left after decoding the dictionary value", len(d.data))
+	}
+	return DictionaryValue{CLType: clType, Bytes: value, SeedURef: hex.EncodeToString(seedURef), ItemKey: string(itemKey)}, nil
+}
+
+// errShort the bytes end before the value
+var errShort = errors.New("unexpected end of the CLValue bytes")
+
+// keyVariants of the keys by tag: the name of the variant, the prefix of the formatted key and the length of its address
+var keyVariants = []struct {
+	name   string
+	prefix string
+	length int
+}{
+	{"Account", "account-hash-", 32},
+	{"Hash", "hash-", 32},
+	{"URef", "uref-", 33},
+	{"Transfer", "transfer-", 32},
+	{"DeployInfo", "deploy-", 32},
+	{"EraInfo", "era-", 8},
+	{"Balance", "balance-", 32},
+	{"Bid", "bid-", 32},
+	{"Withdraw", "withdraw-", 32},
+	{"Dictionary", "dictionary-", 32},
+	{"SystemContractRegistry", "system-contract-registry-", 32},
+	{"EraSummary", "era-summary-", 32},
+	{"Unbond", "unbond-", 32},
+	{"ChainspecRegistry", "chainspec-registry-", 32},
+	{"ChecksumRegistry", "checksum-registry-", 32},
+}
+
+// publicKeyLengths of the public keys by tag: system, ed25519 and secp256k1
+var publicKeyLengths = []int{0, 32, 33}
+
+// decoder consume the bytes of a CLValue
+type decoder struct {
+	data []byte
+}
+
+// read the next n bytes
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data) < n {
+		return nil, errShort
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+// tag read a one byte tag
+func (d *decoder) tag() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// length read the u32 length of a string, a list or a map
+func (d *decoder) length() (int, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	length := int(binary.LittleEndian.Uint32(b))
+	// every item but a unit takes at least one byte
+	if length > len(d.data) && length > 1024 {
+		return 0, errShort
+	}
+	return length, nil
+}
+
+// decode the next value of the type
+func (d *decoder) decode(clType interface{}) (interface{}, error) {
+	switch t := clType.(type) {
+	case string:
+		return d.decodeSimple(t)
+	case map[string]interface{}:
+		if len(t) != 1 {
+			return nil, fmt.Errorf("invalid cl_type %v", t)
+		}
+		for name, inner := range t {
+			return d.decodeComplex(name, inner)
+		}
+	}
+	return nil, fmt.Errorf("invalid cl_type %v", clType)
+}
+
+// decodeSimple decode a type without parameter
+func (d *decoder) decodeSimple(clType string) (interface{}, error) {
+	switch clType {
+	case "Bool":
+		b, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		if b > 1 {
+			return nil, fmt.Errorf("invalid bool %d", b)
+		}
+		return b == 1, nil
+	case "I32":
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(b))), 10)), nil
+	case "I64":
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatInt(int64(binary.LittleEndian.Uint64(b)), 10)), nil
+	case "U8":
+		b, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(uint64(b), 10)), nil
+	case "U32":
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10)), nil
+	case "U64":
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(binary.LittleEndian.Uint64(b), 10)), nil
+	case "U128":
+		return d.decodeBigInt(16)
+	case "U256":
+		return d.decodeBigInt(32)
+	case "U512":
+		return d.decodeBigInt(64)
+	case "Unit":
+		return nil, nil
+	case "String":
+		length, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(length)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, errors.New("invalid utf-8 string")
+		}
+		return string(b), nil
+	case "Key":
+		return d.decodeKey()
+	case "URef":
+		b, err := d.read(33)
+		if err != nil {
+			return nil, err
+		}
+		return formatURef(b), nil
+	case "PublicKey":
+		tag, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		if int(tag) >= len(publicKeyLengths) {
+			return nil, fmt.Errorf("invalid public key tag %d", tag)
+		}
+		b, err := d.read(publicKeyLengths[tag])
+		if err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(append([]byte{tag}, b...)), nil
+	case "Any":
+		return nil, errors.New("a nested Any can't be decoded, its length is unknown")
+	}
+	return nil, fmt.Errorf("unknown cl_type %s", clType)
+}
+
+// simpleTypes the types without parameter by tag of their serialized CLType, an empty name is a type with parameters
+var simpleTypes = []string{"Bool", "I32", "I64", "U8", "U32", "U64", "U128", "U256", "U512", "Unit", "String", "Key", "URef",
+	"", "", "", "", "", "", "", "", "Any", "PublicKey"}
+
+// decodeCLType decode the next serialized CLType
+func (d *decoder) decodeCLType() (interface{}, error) {
+	tag, err := d.tag()
+	if err != nil {
+		return nil, err
+	}
+	if int(tag) < len(simpleTypes) && simpleTypes[tag] != "" {
+		return simpleTypes[tag], nil
+	}
+	switch tag {
+	case 13, 14:
+		inner, err := d.decodeCLType()
+		if err != nil {
+			return nil, err
+		}
+		if tag == 13 {
+			return map[string]interface{}{"Option": inner}, nil
+		}
+		return map[string]interface{}{"List": inner}, nil
+	case 15:
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"ByteArray": json.Number(strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10))}, nil
+	case 16, 17:
+		first, err := d.decodeCLType()
+		if err != nil {
+			return nil, err
+		}
+		second, err := d.decodeCLType()
+		if err != nil {
+			return nil, err
+		}
+		if tag == 16 {
+			return map[string]interface{}{"Result": map[string]interface{}{"ok": first, "err": second}}, nil
+		}
+		return map[string]interface{}{"Map": map[string]interface{}{"key": first, "value": second}}, nil
+	case 18, 19, 20:
+		types := make([]interface{}, 0, tag-17)
+		for i := 0; i < int(tag-17); i++ {
+			t, err := d.decodeCLType()
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, t)
+		}
+		return map[string]interface{}{"Tuple" + strconv.Itoa(int(tag-17)): types}, nil
+	}
+	return nil, fmt.Errorf("invalid cl_type tag %d", tag)
+}
+
+// decodeComplex decode a type with parameters
+func (d *decoder) decodeComplex(name string, inner interface{}) (interface{}, error) {
+	switch name {
+	case "Option":
+		tag, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case 0:
+			return nil, nil
+		case 1:
+			return d.decode(inner)
+		}
+		return nil, fmt.Errorf("invalid option tag %d", tag)
+	case "List":
+		length, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			value, err := d.decode(inner)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "ByteArray":
+		length, err := typeLength(inner)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(length)
+		if err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(b), nil
+	case "Result":
+		types, ok := inner.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid Result cl_type %v", inner)
+		}
+		tag, err := d.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case 0:
+			value, err := d.decode(types["err"])
+			return map[string]interface{}{"Err": value}, err
+		case 1:
+			value, err := d.decode(types["ok"])
+			return map[string]interface{}{"Ok": value}, err
+		}
+		return nil, fmt.Errorf("invalid result tag %d", tag)
+	case "Map":
+		types, ok := inner.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid Map cl_type %v", inner)
+		}
+		length, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			key, err := d.decode(types["key"])
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode(types["value"])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, map[string]interface{}{"key": key, "value": value})
+		}
+		return values, nil
+	case "Tuple1", "Tuple2", "Tuple3":
+		types, ok := inner.([]interface{})
+		if !ok || strconv.Itoa(len(types)) != name[len("Tuple"):] {
+			return nil, fmt.Errorf("invalid %s cl_type %v", name, inner)
+		}
+		values := make([]interface{}, 0, len(types))
+		for _, t := range types {
+			value, err := d.decode(t)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unknown cl_type %s", name)
+}
+
+// decodeBigInt decode a U128, U256 or U512, prefixed by its length in bytes
+func (d *decoder) decodeBigInt(maxLength int) (interface{}, error) {
+	length, err := d.tag()
+	if err != nil {
+		return nil, err
+	}
+	if int(length) > maxLength {
+		return nil, fmt.Errorf("invalid integer length %d", length)
+	}
+	b, err := d.read(int(length))
+	if err != nil {
+		return nil, err
+	}
+	bigEndian := make([]byte, len(b))
+	for i := range b {
+		bigEndian[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(bigEndian).String(), nil
+}
+
+// decodeKey decode a key in an object with its formatted string by the name of its variant, like the node
+func (d *decoder) decodeKey() (interface{}, error) {
+	tag, err := d.tag()
+	if err != nil {
+		return nil, err
+	}
+	if int(tag) >= len(keyVariants) {
+		return nil, fmt.Errorf("invalid key tag %d", tag)
+	}
+	variant := keyVariants[tag]
+	b, err := d.read(variant.length)
+	if err != nil {
+		return nil, err
+	}
+	formatted := variant.prefix + hex.EncodeToString(b)
+	switch variant.name {
+	case "URef":
+		formatted = formatURef(b)
+	case "EraInfo":
+		formatted = variant.prefix + strconv.FormatUint(binary.LittleEndian.Uint64(b), 10)
+	}
+	return map[string]interface{}{variant.name: formatted}, nil
+}
+
+// formatURef the 32 bytes address and the access rights of a uref
+func formatURef(b []byte) string {
+	return fmt.Sprintf("uref-%s-%03o", hex.EncodeToString(b[:32]), b[32])
+}
+
+// typeLength the length of a ByteArray, a json number
+func typeLength(v interface{}) (int, error) {
+	switch length := v.(type) {
+	case json.Number:
+		i, err := length.Int64()
+		return int(i), err
+	case float64:
+		return int(length), nil
+	case int:
+		return length, nil
+	}
+	return 0, fmt.Errorf("invalid ByteArray length %v", v)
+}
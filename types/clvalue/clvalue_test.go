@@ -0,0 +1,164 @@
+package clvalue
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// decodeJSON decode the hex bytes against the json cl_type and return the value in json
+func decodeJSON(clType string, hexBytes string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(clType)))
+	decoder.UseNumber()
+	var t interface{}
+	if err := decoder.Decode(&t); err != nil {
+		return "", err
+	}
+	value, err := DecodeHex(t, hexBytes)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+func TestDecode(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		name     string
+		clType   string
+		bytes    string
+		expected string
+	}{
+		{"Should decode a bool", `"Bool"`, "01", `true`},
+		{"Should decode a negative I32", `"I32"`, "ffffffff", `-1`},
+		{"Should decode a U8", `"U8"`, "12", `18`},
+		{"Should decode a U64 above 2^53", `"U64"`, "ffffffffffffffff", `18446744073709551615`},
+		{"Should decode a U512", `"U512"`, "0400ca9a3b", `"1000000000"`},
+		{"Should decode a zero U256", `"U256"`, "00", `"0"`},
+		{"Should decode a unit", `"Unit"`, "", `null`},
+		{"Should decode a string", `"String"`, "05000000776f726c64", `"world"`},
+		{"Should decode an account key", `"Key"`, "00" + hash, `{"Account":"account-hash-` + hash + `"}`},
+		{"Should decode a uref key", `"Key"`, "02" + hash + "07", `{"URef":"uref-` + hash + `-007"}`},
+		{"Should decode an era info key", `"Key"`, "05" + "0a00000000000000", `{"EraInfo":"era-10"}`},
+		{"Should decode a uref", `"URef"`, hash + "07", `"uref-` + hash + `-007"`},
+		{"Should decode an ed25519 public key", `"PublicKey"`, "01" + hash, `"01` + hash + `"`},
+		{"Should decode a byte array", `{"ByteArray": 32}`, hash, `"` + hash + `"`},
+		{"Should decode an empty option", `{"Option": "U64"}`, "00", `null`},
+		{"Should decode an option", `{"Option": "U64"}`, "010100000000000000", `1`},
+		{"Should decode a list", `{"List": "U256"}`, "01000000011a", `["26"]`},
+		{"Should decode a map", `{"Map": {"key": "String", "value": "U8"}}`, "01000000010000006105", `[{"key":"a","value":5}]`},
+		{"Should decode a tuple", `{"Tuple2": ["Bool", "I32"]}`, "01ffffffff", `[true,-1]`},
+		{"Should decode a result error", `{"Result": {"ok": "Unit", "err": "U32"}}`, "0002000000", `{"Err":2}`},
+		{"Should decode a result ok", `{"Result": {"ok": "String", "err": "U32"}}`, "010100000061", `{"Ok":"a"}`},
+		{"Should decode a nested option in a map", `{"Map": {"key": "String", "value": {"Option": "Key"}}}`, "0100000001000000610101" + hash, `[{"key":"a","value":{"Hash":"hash-` + hash + `"}}]`},
+		{"Should decode any in hex", `"Any"`, "0102", `"0102"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := decodeJSON(test.clType, test.bytes)
+			if err != nil {
+				t.Fatalf("Unable to decode the %s value : %s", test.clType, err)
+			}
+			if value != test.expected {
+				t.Errorf("Bad value. Received : %s. Expected : %s", value, test.expected)
+			}
+		})
+	}
+	t.Run("Should refuse the bytes left after the value", func(t *testing.T) {
+		if _, err := decodeJSON(`"U8"`, "0102"); err == nil {
+			t.Errorf("Value decoded with bytes left")
+		}
+	})
+	t.Run("Should refuse truncated bytes", func(t *testing.T) {
+		if _, err := decodeJSON(`{"List": "U64"}`, "0200000001"); err == nil {
+			t.Errorf("Truncated value decoded")
+		}
+	})
+	t.Run("Should refuse a nested any", func(t *testing.T) {
+		if _, err := decodeJSON(`{"List": "Any"}`, "0100000001"); err == nil {
+			t.Errorf("Nested any decoded")
+		}
+	})
+	t.Run("Should refuse an unknown cl_type", func(t *testing.T) {
+		if _, err := decodeJSON(`"U1024"`, "00"); err == nil {
+			t.Errorf("Unknown cl_type decoded")
+		}
+	})
+}
+
+func TestCLValue_Value(t *testing.T) {
+	t.Run("Should keep the parsed value when the bytes can't be decoded", func(t *testing.T) {
+		value := CLValue{Bytes: "", Parsed: "parsed", ClType: "U64"}.Value()
+		if value != "parsed" {
+			t.Errorf("Bad value. Received : %v. Expected : %s", value, "parsed")
+		}
+	})
+	t.Run("Should decode the bytes of an unmarshalled CLValue", func(t *testing.T) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(`{"bytes": "05000000776f726c64", "parsed": null, "cl_type": "String"}`), &v); err != nil {
+			t.Fatalf("Unable to unmarshal the CLValue : %s", err)
+		}
+		if value := Value(v); value != "world" {
+			t.Errorf("Bad value. Received : %v. Expected : %s", value, "world")
+		}
+	})
+}
+
+func TestDecodeCLType(t *testing.T) {
+	t.Run("Should decode a serialized cl_type in its json form", func(t *testing.T) {
+		// Map(String, Tuple2(ByteArray(32), Option(U512))) then one byte left
+		clType, rest, err := DecodeCLType([]byte{17, 10, 19, 15, 32, 0, 0, 0, 13, 8, 0xff})
+		if err != nil {
+			t.Fatalf("Unable to decode the cl_type : %s", err)
+		}
+		data, _ := json.Marshal(clType)
+		expected := `{"Map":{"key":"String","value":{"Tuple2":[{"ByteArray":32},{"Option":"U512"}]}}}`
+		if string(data) != expected || len(rest) != 1 {
+			t.Errorf("Bad cl_type. Received : %s %d bytes left. Expected : %s 1 byte left", data, len(rest), expected)
+		}
+	})
+	t.Run("Should refuse an unknown tag", func(t *testing.T) {
+		if _, _, err := DecodeCLType([]byte{42}); err == nil {
+			t.Errorf("Unknown cl_type tag decoded")
+		}
+	})
+}
+
+func TestEncodeCLType(t *testing.T) {
+	t.Run("Should serialize a cl_type in its json form", func(t *testing.T) {
+		var clType interface{}
+		decoder := json.NewDecoder(strings.NewReader(`{"Map":{"key":"String","value":{"Tuple2":[{"ByteArray":32},{"Option":"U512"}]}}}`))
+		decoder.UseNumber()
+		if err := decoder.Decode(&clType); err != nil {
+			t.Fatalf("Unable to unmarshal the cl_type : %s", err)
+		}
+		data, err := EncodeCLType(clType)
+		if err != nil {
+			t.Fatalf("Unable to encode the cl_type : %s", err)
+		}
+		if expected := []byte{17, 10, 19, 15, 32, 0, 0, 0, 13, 8}; !bytes.Equal(data, expected) {
+			t.Errorf("Bad cl_type bytes. Received : %v. Expected : %v", data, expected)
+		}
+	})
+	t.Run("Should fail on an unknown cl_type", func(t *testing.T) {
+		if _, err := EncodeCLType("U1024"); err == nil {
+			t.Errorf("Should have thrown an error")
+		}
+	})
+}
+
+func TestDecodeDictionaryValue(t *testing.T) {
+	seed := strings.Repeat("cd", 32)
+	// a U8 value 5, its cl_type, the seed uref address and the item key "key"
+	data, _ := hex.DecodeString("0100000005" + "03" + seed + "03000000" + hex.EncodeToString([]byte("key")))
+	value, err := DecodeDictionaryValue(data)
+	if err != nil {
+		t.Fatalf("Unable to decode the dictionary value : %s", err)
+	}
+	if value.CLType != "U8" || hex.EncodeToString(value.Bytes) != "05" || value.SeedURef != seed || value.ItemKey != "key" {
+		t.Errorf("Bad dictionary value. Received : %v %x %s %s. Expected : %s %s %s %s", value.CLType, value.Bytes, value.SeedURef, value.ItemKey, "U8", "05", seed, "key")
+	}
+}
@@ -1,5 +1,11 @@
 package config
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
 var ConfigParsed Config
 
 type ContractType struct {
@@ -18,7 +24,30 @@ type ModuleByte struct {
 	Events     []string `mapstructure:",omitempty"`
 }
 
+// KnownWasm a session wasm identified by the blake2b hash of its module bytes
+type KnownWasm struct {
+	// Type of the deploys sending the wasm, used as their metadata type
+	Type string
+	// Name of the wasm, like the file of the official wasm
+	Name string `mapstructure:",omitempty"`
+}
+
 type Config struct {
+	// Version of the config, set it when the contract types change to tell the classifications apart
+	Version       string
 	ContractTypes map[string]ContractType
 	ModuleBytes   map[string]ModuleByte
+	// KnownWasms by the hex blake2b hash of their module bytes, checked before the args of the ModuleBytes
+	KnownWasms map[string]KnownWasm
+}
+
+// ContractTypesVersion the version of the config, or the sha256 of its contract types when it has none
+func (c Config) ContractTypesVersion() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	// The keys of the maps are sorted by json.Marshal, the same contract types always have the same hash
+	data, _ := json.Marshal(c.ContractTypes)
+	hash := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(hash[:6])
 }
@@ -0,0 +1,109 @@
+package contract
+
+import (
+	"casperParser/types/config"
+	"sort"
+
+	"golang.org/x/exp/slices"
+)
+
+// DefaultTopCandidates number of candidates kept in a classification
+const DefaultTopCandidates = 3
+
+// UnknownType of a contract matching no definition
+const UnknownType = "unknown"
+
+// Candidate definition of a contract type scored against a contract
+type Candidate struct {
+	Type string `json:"type"`
+	// Entrypoints of the definition found in the contract
+	Entrypoints int `json:"entrypoints"`
+	// Args of the entrypoints found also in the definition
+	Args int `json:"args"`
+	// NamedKeys of the contract in the definition
+	NamedKeys int `json:"named_keys"`
+	// Total of the entrypoints, the args and the named keys
+	Total int `json:"total"`
+	// PerfectScore of the definition, the number of its entrypoints, args and named keys
+	PerfectScore int `json:"perfect_score"`
+	// Threshold the total must reach for the type to be picked, a quarter of the perfect score
+	Threshold int `json:"threshold"`
+	// Eligible when the total reaches the threshold
+	Eligible bool `json:"eligible"`
+}
+
+// Classification of a contract against the contract types of the config
+type Classification struct {
+	// Type of the eligible candidate with the best total, unknown if there is none
+	Type string `json:"type"`
+	// Score total of the type out of the perfect score of the contract, in percent
+	Score float64 `json:"score"`
+	// ConfigVersion of the contract types used
+	ConfigVersion string `json:"config_version"`
+	// Considered the types of the config, by name
+	Considered []string `json:"considered"`
+	// Candidates the best candidates by total then by type
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Classify the contract against the contract types of the config, keeping the top candidates. Every candidate is kept when top is 0.
+// The type picked is the eligible candidate with the best total, the first by name on a tie.
+func (c Result) Classify(cfg config.Config, top int) Classification {
+	flatEntrypointArgs := make(map[string][]string)
+	contractPerfectScore := len(c.StoredValue.Contract.EntryPoints) + len(c.StoredValue.Contract.NamedKeys)
+	for _, entrypoint := range c.StoredValue.Contract.EntryPoints {
+		var flatArgs []string
+		for _, arg := range entrypoint.Args {
+			flatArgs = append(flatArgs, arg.Name)
+		}
+		contractPerfectScore += len(entrypoint.Args)
+		flatEntrypointArgs[entrypoint.Name] = flatArgs
+	}
+
+	classification := Classification{Type: UnknownType, ConfigVersion: cfg.ContractTypesVersion()}
+	var candidates []Candidate
+	for contractType, definition := range cfg.ContractTypes {
+		candidate := Candidate{Type: contractType, NamedKeys: c.GetNamedKeysScore(definition.NamedKeys)}
+		candidate.PerfectScore = len(definition.Entrypoints) + len(definition.NamedKeys)
+		for _, definitionEntrypoint := range definition.Entrypoints {
+			candidate.PerfectScore += len(definitionEntrypoint.Args)
+			args, ok := flatEntrypointArgs[definitionEntrypoint.Name]
+			if !ok {
+				continue
+			}
+			candidate.Entrypoints++
+			for _, arg := range args {
+				if slices.Contains(definitionEntrypoint.Args, arg) {
+					candidate.Args++
+				}
+			}
+		}
+		candidate.Total = candidate.Entrypoints + candidate.Args + candidate.NamedKeys
+		candidate.Threshold = candidate.PerfectScore / 4
+		candidate.Eligible = candidate.Total > 0 && candidate.Total >= candidate.Threshold
+		candidates = append(candidates, candidate)
+		classification.Considered = append(classification.Considered, contractType)
+	}
+	sort.Strings(classification.Considered)
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Total != candidates[j].Total {
+			return candidates[i].Total > candidates[j].Total
+		}
+		return candidates[i].Type < candidates[j].Type
+	})
+	for _, candidate := range candidates {
+		if !candidate.Eligible {
+			continue
+		}
+		classification.Type = candidate.Type
+		if contractPerfectScore > 0 {
+			classification.Score = float64(candidate.Total) / float64(contractPerfectScore) * 100
+		}
+		break
+	}
+	if top > 0 && len(candidates) > top {
+		candidates = candidates[:top]
+	}
+	classification.Candidates = candidates
+	return classification
+}
@@ -0,0 +1,67 @@
+package contract
+
+import (
+	"casperParser/types/config"
+	"testing"
+)
+
+// classifyConfig two contract types sharing the transfer entrypoint
+var classifyConfig = config.Config{
+	ContractTypes: map[string]config.ContractType{
+		"token": {
+			Entrypoints: []config.Entrypoint{{Name: "transfer", Args: []string{"recipient", "amount"}}, {Name: "approve", Args: []string{"spender", "amount"}}},
+			NamedKeys:   []string{"balances", "allowances"},
+		},
+		"nft": {
+			Entrypoints: []config.Entrypoint{{Name: "transfer", Args: []string{"recipient", "token_ids"}}, {Name: "mint", Args: []string{"recipient", "token_ids", "token_metas"}}},
+			NamedKeys:   []string{"owners", "metadata", "balances"},
+		},
+		"faucet": {
+			Entrypoints: []config.Entrypoint{{Name: "call_faucet", Args: []string{"target", "amount"}}},
+		},
+	},
+}
+
+// tokenContract a contract with the transfer entrypoint of a token and its balances named key
+func tokenContract() Result {
+	var r Result
+	r.StoredValue.Contract.EntryPoints = []Entrypoint{{Name: "transfer", Args: []Arg{{Name: "recipient"}, {Name: "amount"}}}}
+	r.StoredValue.Contract.NamedKeys = []NamedKey{{Name: "balances"}}
+	return r
+}
+
+func TestResult_Classify(t *testing.T) {
+	t.Run("Should pick the eligible candidate with the best total", func(t *testing.T) {
+		classification := tokenContract().Classify(classifyConfig, 0)
+		if classification.Type != "token" || classification.Score != 100 {
+			t.Errorf("Bad classification. Received : %s %f. Expected : %s %f", classification.Type, classification.Score, "token", 100.0)
+		}
+		token := classification.Candidates[0]
+		if token.Type != "token" || token.Entrypoints != 1 || token.Args != 2 || token.NamedKeys != 1 || token.PerfectScore != 8 || token.Threshold != 2 || !token.Eligible {
+			t.Errorf("Bad candidate. Received : %+v. Expected : the token candidate with 1 entrypoint, 2 args and 1 named key", token)
+		}
+		if len(classification.Candidates) != 3 || classification.Candidates[2].Type != "faucet" || classification.Candidates[2].Eligible {
+			t.Errorf("Bad candidates. Received : %+v. Expected : the faucet last and not eligible", classification.Candidates)
+		}
+	})
+	t.Run("Should keep the top candidates and every type considered", func(t *testing.T) {
+		classification := tokenContract().Classify(classifyConfig, 1)
+		if len(classification.Candidates) != 1 || len(classification.Considered) != 3 || classification.Considered[0] != "faucet" {
+			t.Errorf("Bad classification. Received : %+v %v. Expected : 1 candidate and 3 types considered", classification.Candidates, classification.Considered)
+		}
+	})
+	t.Run("Should be unknown without eligible candidate", func(t *testing.T) {
+		if classification := (Result{}).Classify(classifyConfig, 0); classification.Type != UnknownType {
+			t.Errorf("Bad type. Received : %s. Expected : %s", classification.Type, UnknownType)
+		}
+	})
+	t.Run("Should version the config by its contract types", func(t *testing.T) {
+		version := classifyConfig.ContractTypesVersion()
+		if version != classifyConfig.ContractTypesVersion() || version == (config.Config{}).ContractTypesVersion() {
+			t.Errorf("Bad config version. Received : %s. Expected : a stable hash of the contract types", version)
+		}
+		if version := (config.Config{Version: "2"}).ContractTypesVersion(); version != "2" {
+			t.Errorf("Bad config version. Received : %s. Expected : %s", version, "2")
+		}
+	})
+}
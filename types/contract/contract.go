@@ -3,58 +3,18 @@ package contract
 
 import (
 	"casperParser/types/config"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/exp/slices"
-	"log"
 )
 
+// GetContractTypeAndScore the type of the contract with the contract types of the config and its score, in percent
 func (c Result) GetContractTypeAndScore() (string, float64) {
-	contractType := "unknown"
-	previousCount := 0
-	count := 0
-	perfectScore := 0
-	flatEntrypointArgs := make(map[string][]string)
-	contractPerfectScore := 0
-	contractPerfectScore += len(c.StoredValue.Contract.EntryPoints)
-	contractPerfectScore += len(c.StoredValue.Contract.NamedKeys)
-	for _, entrypoint := range c.StoredValue.Contract.EntryPoints {
-		var flatArgs []string
-		for _, arg := range entrypoint.Args {
-			flatArgs = append(flatArgs, arg.Name)
-		}
-		contractPerfectScore += len(entrypoint.Args)
-		flatEntrypointArgs[entrypoint.Name] = flatArgs
-	}
-
-	for contractName, contractDefinitions := range config.ConfigParsed.ContractTypes {
-		perfectScore = perfectScore + len(contractDefinitions.Entrypoints)
-		for _, contractDefEntrypoint := range contractDefinitions.Entrypoints {
-			perfectScore = perfectScore + len(contractDefEntrypoint.Args)
-			if _, ok := flatEntrypointArgs[contractDefEntrypoint.Name]; ok {
-				count++
-				for _, arg := range flatEntrypointArgs[contractDefEntrypoint.Name] {
-					if slices.Contains(contractDefEntrypoint.Args, arg) {
-						count++
-					}
-				}
-			}
-		}
-		perfectScore = perfectScore + len(contractDefinitions.NamedKeys)
-		count = count + c.GetNamedKeysScore(contractDefinitions.NamedKeys)
-		if count > previousCount && count >= (perfectScore/4) {
-			contractType = contractName
-			previousCount = count
-		}
-		perfectScore = 0
-		count = 0
-	}
-	log.Printf("Result - Contract name: %s Score: %d Perfect Score: %d Accuracy: %v \n", contractType, previousCount, contractPerfectScore, (float64(previousCount)/float64(contractPerfectScore))*100)
-	score := 0.0
-	if contractPerfectScore > 0.0 {
-		score = (float64(previousCount) / float64(contractPerfectScore)) * 100
-	}
-	return contractType, score
+	classification := c.Classify(config.ConfigParsed, DefaultTopCandidates)
+	log.WithFields(log.Fields{"contract_type": classification.Type, "accuracy": classification.Score, "config_version": classification.ConfigVersion}).Debug("contract type found")
+	return classification.Type, classification.Score
 }
 
+// GetNamedKeysScore the number of named keys of the contract in the list
 func (c Result) GetNamedKeysScore(namedKeys []string) int {
 	count := 0
 	for _, namedKey := range c.StoredValue.Contract.NamedKeys {
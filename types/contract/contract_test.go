@@ -4,6 +4,7 @@ import (
 	"casperParser/rpc"
 	"casperParser/types/config"
 	"casperParser/utils"
+	"context"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 	"log"
@@ -21,6 +22,6 @@ func TestResult_GetContractType(t *testing.T) {
 	log.Println(dt)
 	err = mapstructure.Decode(dt, &config.ConfigParsed)
 	log.Println(config.ConfigParsed)
-	r, err := rpcClient.GetContract("31bfdc9591902bda8f921d6c31f3e974bda18ec5614222f3bec55390decd05a0")
+	r, _, err := rpcClient.GetContract(context.Background(), "31bfdc9591902bda8f921d6c31f3e974bda18ec5614222f3bec55390decd05a0")
 	println(r.GetContractTypeAndScore())
 }
@@ -2,16 +2,22 @@
 package deploy
 
 import (
+	"bytes"
+	"casperParser/logger"
+	"casperParser/types/amount"
+	"casperParser/types/clvalue"
 	"casperParser/types/config"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/big"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/Jeffail/gabs/v2"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/blake2b"
 )
 
 // GetDeployMetadata Retrieve deploy metadata
@@ -53,10 +59,14 @@ func (d Result) getTransferMetadata() (string, string) {
 	return "transfer", string(metadataString)
 }
 
-// getModuleByteMetadata retrieve module bytes metadata
+// getModuleByteMetadata retrieve module bytes metadata. A known wasm is identified by the hash of its module bytes,
+// the others by the names of their args. Only an unknown stacking wasm is told delegate or undelegate by its cost.
 func (d Result) getModuleByteMetadata() (string, string) {
 	deployArgs := d.MapArgs()
 	metadataString, _ := json.Marshal(deployArgs)
+	if wasm, ok := config.ConfigParsed.KnownWasms[d.GetModuleBytesHash()]; ok && wasm.Type != "" {
+		return wasm.Type, string(metadataString)
+	}
 	for deployType, argConf := range config.ConfigParsed.ModuleBytes {
 		ok := d.CheckArgs(argConf.StrictArgs, argConf.Args, deployArgs)
 		if ok {
@@ -64,9 +74,7 @@ func (d Result) getModuleByteMetadata() (string, string) {
 			if resolvedDeployType == "stackingOperation" {
 				resolvedDeployType = "undelegate"
 				_, cost, _, _ := d.GetResultAndCost()
-				bigCost := new(big.Int)
-				bigCost.SetString(cost, 10)
-				if bigCost.Cmp(big.NewInt(1000000000)) == 1 {
+				if cost.Cmp(big.NewInt(1000000000)) == 1 {
 					resolvedDeployType = "delegate"
 				}
 			}
@@ -77,6 +85,19 @@ func (d Result) getModuleByteMetadata() (string, string) {
 	return "moduleBytes", string(metadataString)
 }
 
+// GetModuleBytesHash the hex blake2b hash of the module bytes of the session, empty if the session has none
+func (d Result) GetModuleBytesHash() string {
+	if d.Deploy.Session.ModuleBytes == nil || d.Deploy.Session.ModuleBytes.ModuleBytes == "" {
+		return ""
+	}
+	moduleBytes, err := hex.DecodeString(d.Deploy.Session.ModuleBytes.ModuleBytes)
+	if err != nil {
+		return ""
+	}
+	hash := blake2b.Sum256(moduleBytes)
+	return hex.EncodeToString(hash[:])
+}
+
 // GetType retrieve the deploy session type
 func (d Result) GetType() string {
 	if d.Deploy.Session.Transfer != nil {
@@ -154,13 +175,18 @@ func (d Result) GetStoredContractHash() (string, error) {
 
 // GetStoredContractVersion get the contract version or return an error if none
 func (d Result) GetStoredContractVersion() (int, error) {
+	var version *int
 	if d.Deploy.Session.StoredVersionedContractByHash != nil {
-		return d.Deploy.Session.StoredVersionedContractByHash.Version, nil
+		version = d.Deploy.Session.StoredVersionedContractByHash.Version
+	} else if d.Deploy.Session.StoredVersionedContractByName != nil {
+		version = d.Deploy.Session.StoredVersionedContractByName.Version
+	} else {
+		return 0, fmt.Errorf("deploy %s doesn't have a version", d.Deploy.Hash)
 	}
-	if d.Deploy.Session.StoredVersionedContractByName != nil {
-		return d.Deploy.Session.StoredVersionedContractByName.Version, nil
+	if version == nil {
+		return 0, nil
 	}
-	return 0, fmt.Errorf("deploy %s doesn't have a version", d.Deploy.Hash)
+	return *version, nil
 }
 
 // GetName get the contract name or return an empty string if none
@@ -175,12 +201,12 @@ func (d Result) GetName() string {
 }
 
 // GetResultAndCost retrieve the result and cost of a deploy. Return an error if no result found (this can happen when a node is not sync properly)
-func (d Result) GetResultAndCost() (string, string, string, error) {
+func (d Result) GetResultAndCost() (string, amount.Amount, string, error) {
 	if len(d.ExecutionResults) > 1 {
-		fmt.Printf("More than 1 element in ExecutionResults for deploy %s", d.Deploy.Hash)
+		log.WithField(logger.FieldDeployHash, d.Deploy.Hash).Warn("more than 1 element in ExecutionResults")
 	}
 	if len(d.ExecutionResults) > 0 {
-		var cost string
+		var cost amount.Amount
 		var result string
 		var errorMessage string
 		if d.ExecutionResults[0].Result.Success != nil {
@@ -194,21 +220,51 @@ func (d Result) GetResultAndCost() (string, string, string, error) {
 		}
 		return result, cost, errorMessage, nil
 	}
-	return "NO_RESULT", "", "", fmt.Errorf("no result found for deploy : %s", d.Deploy.Hash)
+	return "NO_RESULT", amount.Amount{}, "", fmt.Errorf("no result found for deploy : %s", d.Deploy.Hash)
+}
+
+// Fee of a deploy, the payment made for its execution and what's left of it once the execution is paid
+type Fee struct {
+	// Payment amount arg of the payment code, zero for a custom payment without one
+	Payment  amount.Amount
+	GasPrice int
+	// Cost of the execution, in motes
+	Cost amount.Amount
+	// Unused part of the payment above the cost, the part refunded to the account depends on the refund ratio of the chainspec
+	Unused amount.Amount
 }
 
-// MapArgs maps the arguments of a deploy within a map
+// GetFee retrieve the payment, the gas price and the cost of the deploy
+func (d Result) GetFee() Fee {
+	fee := Fee{GasPrice: d.Deploy.Header.GasPrice}
+	if d.Deploy.Payment.ModuleBytes != nil {
+		if payment, ok := mapArgs(d.Deploy.Payment.ModuleBytes.Args)["amount"].(string); ok {
+			fee.Payment, _ = amount.Parse(payment)
+		}
+	}
+	_, fee.Cost, _, _ = d.GetResultAndCost()
+	if fee.Payment.Cmp(&fee.Cost.Int) > 0 {
+		fee.Unused.Sub(&fee.Payment.Int, &fee.Cost.Int)
+	}
+	return fee
+}
+
+// MapArgs maps the arguments of a deploy within a map, the values are decoded from their bytes and their cl_type
 func (d Result) MapArgs() map[string]interface{} {
-	args := d.GetArgs()
+	return mapArgs(d.GetArgs())
+}
+
+// mapArgs maps the arguments of a session or a payment within a map
+func mapArgs(args [][]interface{}) map[string]interface{} {
 	values := make(map[string]interface{})
 	for _, t := range args {
 		var value interface{}
 		name, ok := t[0].(string)
 		if !ok {
 			name = t[1].(string)
-			value = getValue(t[0].(map[string]interface{})["parsed"])
+			value = getValue(clvalue.Value(t[0]))
 		} else {
-			value = getValue(t[1].(map[string]interface{})["parsed"])
+			value = getValue(clvalue.Value(t[1]))
 		}
 		values[name] = value
 	}
@@ -241,8 +297,10 @@ func getValue(v interface{}) interface{} {
 		return ""
 	case bool:
 		return strconv.FormatBool(v.(bool))
+	case json.Number:
+		return v.(json.Number).String()
 	case float64:
-		return strconv.Itoa(int(v.(float64)))
+		return strconv.FormatFloat(v.(float64), 'f', -1, 64)
 	case int:
 		return strconv.Itoa(v.(int))
 	case string:
@@ -256,7 +314,7 @@ func getValue(v interface{}) interface{} {
 func (d Result) ParseStoredContract() (string, string) {
 	entrypoint, e := d.GetEntrypoint()
 	if e != nil {
-		log.Println(e)
+		log.WithField(logger.FieldDeployHash, d.Deploy.Hash).WithError(e).Warn("can't find the stored contract entrypoint")
 		return "unknown", ""
 	}
 	deployArgs := d.MapArgs()
@@ -279,88 +337,110 @@ func (d Result) CheckArgs(strict bool, args []string, deployArgs map[string]inte
 
 // GetWriteContract retrieve written contract
 func (d Result) GetWriteContract() []string {
-	var transforms *gabs.Container
-	if d.ExecutionResults[0].Result.Success != nil {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Success.Effect)
-	} else {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Failure.Effect)
-	}
-	var contracts []string
-	for _, child := range transforms.S("transforms").Children() {
-		transform, ok := child.S("transform").Data().(string)
-		if ok && transform == "WriteContract" {
-			contractHash, found := child.S("key").Data().(string)
-			if found {
-				contracts = append(contracts, contractHash)
-			}
-		}
-	}
-	return contracts
+	return d.getWrittenKeys(TransformWriteContract)
 }
 
 // GetWriteContractPackage retrieve written contract package
 func (d Result) GetWriteContractPackage() []string {
-	var transforms *gabs.Container
-	if d.ExecutionResults[0].Result.Success != nil {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Success.Effect)
-	} else {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Failure.Effect)
-	}
+	return d.getWrittenKeys(TransformWriteContractPackage)
+}
 
-	var contractPackages []string
-	for _, child := range transforms.S("transforms").Children() {
-		transform, ok := child.S("transform").Data().(string)
-		if ok && transform == "WriteContractPackage" {
-			contractHash, found := child.S("key").Data().(string)
-			if found {
-				contractPackages = append(contractPackages, contractHash)
-			}
+// getWrittenKeys retrieve the keys of the transforms of this kind
+func (d Result) getWrittenKeys(kind string) []string {
+	var keys []string
+	for _, transform := range d.GetEffect().Transforms {
+		if transform.Kind == kind {
+			keys = append(keys, transform.Key)
 		}
 	}
-	return contractPackages
+	return keys
 }
 
 // GetURef retrieve uref transform in the deploy
 func (d Result) MapUrefs() map[string]interface{} {
-	var transforms *gabs.Container
-	if d.ExecutionResults[0].Result.Success != nil {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Success.Effect)
-	} else {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Failure.Effect)
-	}
 	accessRights := regexp.MustCompile(`-\d{3}$`)
 	values := make(map[string]interface{})
-	for _, child := range transforms.S("transforms").Children() {
-		key, ok := child.S("key").Data().(string)
-		if ok && strings.Contains(key, "uref-") {
-			parsed, okCLValue := child.S("transform", "WriteCLValue", "parsed").Data().(interface{})
-			if okCLValue {
-				urefHash := accessRights.ReplaceAllString(key, "")
-				values[urefHash] = getValue(parsed)
-			}
+	for _, transform := range d.GetEffect().Transforms {
+		if transform.CLValue == nil {
+			continue
 		}
-		if ok && strings.Contains(key, "balance-") {
-			parsed, okCLValue := child.S("transform", "WriteCLValue", "parsed").Data().(interface{})
-			if okCLValue {
-				values[key] = getValue(parsed)
-			}
+		if strings.Contains(transform.Key, "uref-") {
+			urefHash := accessRights.ReplaceAllString(transform.Key, "")
+			values[urefHash] = getValue(transform.CLValue.Value())
+		}
+		if strings.Contains(transform.Key, "balance-") {
+			values[transform.Key] = getValue(transform.CLValue.Value())
 		}
 	}
 	return values
 }
 
+// PurseBalanceChange the change of the balance of a purse made by a deploy. Balance is set when the balance is written, Delta when amounts are only added to it.
+type PurseBalanceChange struct {
+	// Purse uref-<address> of the purse, without the access rights
+	Purse   string
+	Balance *amount.Amount
+	Delta   *amount.Amount
+}
+
+// GetPurseBalanceChanges retrieve a change per purse, folding the balance- transforms of the deploy in their order :
+// the last balance written plus the amounts added after it, or the sum of the amounts added when no balance is written. Sorted by purse.
+func (d Result) GetPurseBalanceChanges() []PurseBalanceChange {
+	changes := make(map[string]*PurseBalanceChange)
+	for _, transform := range d.GetEffect().Transforms {
+		if !strings.HasPrefix(transform.Key, "balance-") {
+			continue
+		}
+		var value amount.Amount
+		switch {
+		case transform.Kind == TransformWriteCLValue && transform.CLValue != nil:
+			written, ok := getValue(transform.CLValue.Value()).(string)
+			if !ok {
+				continue
+			}
+			balance, err := amount.Parse(written)
+			if err != nil {
+				continue
+			}
+			value = balance
+		case transform.Kind == TransformAddUInt512 && transform.Amount != nil:
+			value.Set(&transform.Amount.Int)
+		default:
+			continue
+		}
+		purse := "uref-" + strings.TrimPrefix(transform.Key, "balance-")
+		change, ok := changes[purse]
+		if !ok {
+			change = &PurseBalanceChange{Purse: purse}
+			changes[purse] = change
+		}
+		switch {
+		case transform.Kind == TransformWriteCLValue:
+			change.Balance, change.Delta = &value, nil
+		case change.Balance != nil:
+			change.Balance.Add(&change.Balance.Int, &value.Int)
+		case change.Delta != nil:
+			change.Delta.Add(&change.Delta.Int, &value.Int)
+		default:
+			change.Delta = &value
+		}
+	}
+	var sorted []PurseBalanceChange
+	for _, change := range changes {
+		sorted = append(sorted, *change)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Purse < sorted[j].Purse })
+	return sorted
+}
+
 // GetEvents retrieve deploy events
 func (d Result) GetEvents() string {
 	var retrievedEvents []map[string]string
-	var transforms *gabs.Container
-	if d.ExecutionResults[0].Result.Success != nil {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Success.Effect)
-	} else {
-		transforms = gabs.Wrap(d.ExecutionResults[0].Result.Failure.Effect)
-	}
-
-	for _, child := range transforms.S("transforms").Children() {
-		writeCLValue, ok := child.S("transform", "WriteCLValue", "parsed").Data().([]interface{})
+	for _, transform := range d.GetEffect().Transforms {
+		if transform.CLValue == nil {
+			continue
+		}
+		writeCLValue, ok := transform.CLValue.Value().([]interface{})
 		if ok {
 			isEvent := false
 			tempMap := make(map[string]string)
@@ -375,7 +455,7 @@ func (d Result) GetEvents() string {
 							if value == nil {
 								tempMap[key] = ""
 							}
-							value, converted := value.(string)
+							value, converted := getValue(value).(string)
 							if converted {
 								tempMap[key] = value
 							} else {
@@ -405,6 +485,14 @@ type Result struct {
 	ExecutionResults []ExecutionResult `json:"execution_results"`
 }
 
+// UnmarshalJSON keep the numbers of the args and effects as json.Number, a float64 can't hold a U64
+func (d *Result) UnmarshalJSON(data []byte) error {
+	type result Result
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode((*result)(d))
+}
+
 type JsonDeploy struct {
 	Hash   string `json:"hash"`
 	Header struct {
@@ -416,78 +504,68 @@ type JsonDeploy struct {
 		Dependencies []string `json:"dependencies"`
 		ChainName    string   `json:"chain_name"`
 	} `json:"header"`
-	Payment struct {
-		ModuleBytes struct {
-			ModuleBytes string          `json:"module_bytes"`
-			Args        [][]interface{} `json:"args"`
-		} `json:"ModuleBytes"`
-	} `json:"payment"`
-	Session struct {
-		Transfer *struct {
-			Args [][]interface{} `json:"args"`
-		} `json:"Transfer"`
-		StoredContractByHash *struct {
-			Hash       string          `json:"hash"`
-			EntryPoint string          `json:"entry_point"`
-			Args       [][]interface{} `json:"args"`
-		} `json:"StoredContractByHash"`
-		StoredContractByName *struct {
-			Name       string          `json:"name"`
-			EntryPoint string          `json:"entry_point"`
-			Args       [][]interface{} `json:"args"`
-		} `json:"StoredContractByName"`
-		StoredVersionedContractByHash *struct {
-			Hash       string          `json:"hash"`
-			Version    int             `json:"version"`
-			EntryPoint string          `json:"entry_point"`
-			Args       [][]interface{} `json:"args"`
-		} `json:"StoredVersionedContractByHash"`
-		StoredVersionedContractByName *struct {
-			Name       string          `json:"name"`
-			Version    int             `json:"version"`
-			EntryPoint string          `json:"entry_point"`
-			Args       [][]interface{} `json:"args"`
-		} `json:"StoredVersionedContractByName"`
-		ModuleBytes *struct {
-			Args [][]interface{} `json:"args"`
-		} `json:"ModuleBytes"`
-	} `json:"session"`
-	Approvals []struct {
-		Signer    string `json:"signer"`
-		Signature string `json:"signature"`
-	} `json:"approvals"`
+	Payment   ExecutableDeployItem `json:"payment"`
+	Session   ExecutableDeployItem `json:"session"`
+	Approvals []Approval           `json:"approvals"`
+}
+
+// ExecutableDeployItem the payment or the session code of a deploy, only one of the variants is set
+type ExecutableDeployItem struct {
+	Transfer *struct {
+		Args [][]interface{} `json:"args"`
+	} `json:"Transfer"`
+	StoredContractByHash *struct {
+		Hash       string          `json:"hash"`
+		EntryPoint string          `json:"entry_point"`
+		Args       [][]interface{} `json:"args"`
+	} `json:"StoredContractByHash"`
+	StoredContractByName *struct {
+		Name       string          `json:"name"`
+		EntryPoint string          `json:"entry_point"`
+		Args       [][]interface{} `json:"args"`
+	} `json:"StoredContractByName"`
+	StoredVersionedContractByHash *struct {
+		Hash string `json:"hash"`
+		// Version of the contract, nil for the last one
+		Version    *int            `json:"version"`
+		EntryPoint string          `json:"entry_point"`
+		Args       [][]interface{} `json:"args"`
+	} `json:"StoredVersionedContractByHash"`
+	StoredVersionedContractByName *struct {
+		Name       string          `json:"name"`
+		Version    *int            `json:"version"`
+		EntryPoint string          `json:"entry_point"`
+		Args       [][]interface{} `json:"args"`
+	} `json:"StoredVersionedContractByName"`
+	ModuleBytes *struct {
+		ModuleBytes string          `json:"module_bytes"`
+		Args        [][]interface{} `json:"args"`
+	} `json:"ModuleBytes"`
+}
+
+// Approval of a deploy, the signature of its hash by the signer public key
+type Approval struct {
+	Signer    string `json:"signer"`
+	Signature string `json:"signature"`
 }
 
 type ExecutionResult struct {
 	BlockHash string `json:"block_hash"`
 	Result    struct {
 		Success *struct {
-			Effect    interface{} `json:"effect"`
-			Transfers []string    `json:"transfers"`
-			Cost      string      `json:"cost"`
+			Effect    Effect        `json:"effect"`
+			Transfers []string      `json:"transfers"`
+			Cost      amount.Amount `json:"cost"`
 		} `json:"Success"`
 		Failure *struct {
-			Effect       interface{} `json:"effect"`
-			Transfers    []string    `json:"transfers"`
-			Cost         string      `json:"cost"`
-			ErrorMessage string      `json:"error_message"`
+			Effect       Effect        `json:"effect"`
+			Transfers    []string      `json:"transfers"`
+			Cost         amount.Amount `json:"cost"`
+			ErrorMessage string        `json:"error_message"`
 		} `json:"Failure"`
 	} `json:"result"`
 }
 
-type Effect struct {
-	Operations []struct {
-		Key  string `json:"key"`
-		Kind string `json:"kind"`
-	} `json:"operations"`
-	Transforms []map[string]interface{} `json:"transforms"`
-}
-
-type Transforms []struct {
-	Key                string      `json:"key"`
-	TransformOperation interface{} `json:"transform"`
-}
-
 type TransferMetadata struct {
 	ID     string `json:"id"`
 	From   string `json:"from"`
@@ -5,6 +5,7 @@ import (
 	"casperParser/utils"
 	"encoding/json"
 	"log"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/mapstructure"
@@ -96,6 +97,60 @@ func TestResult_GetDeployMetadata(t *testing.T) {
 			t.Errorf("moduleBytesDeploy metadata bad parsing detected. Received : %s %s. Expected: %s %s", deployType, metadata, "addbid", `{"amount":"900000000000","delegation_rate":"10","public_key":"01624b4b573e42137c9e379ad130c296a46b7e08c1cef7a5c54e0e9ab4f11d0231"}`)
 		}
 	})
+	t.Run("Should identify a known wasm by its hash before its args", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(moduleBytesDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal moduleBytesDeploy deploy : %s", err)
+		}
+		deployResult.Deploy.Session.ModuleBytes.ModuleBytes = "0061736d01000000"
+		defer func(knownWasms map[string]config.KnownWasm) { config.ConfigParsed.KnownWasms = knownWasms }(config.ConfigParsed.KnownWasms)
+		config.ConfigParsed.KnownWasms = map[string]config.KnownWasm{deployResult.GetModuleBytesHash(): {Type: "delegate", Name: "delegate.wasm"}}
+		deployType, _ := deployResult.GetDeployMetadata()
+		if deployType != "delegate" {
+			t.Errorf("moduleBytesDeploy known wasm not identified. Received : %s. Expected: %s", deployType, "delegate")
+		}
+	})
+	t.Run("Should not guess a known stacking wasm from its cost", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(`{"deploy": {"hash": "01", "header": {"account": "01aa"}, "session": {"ModuleBytes": {"module_bytes": "0061736d01000000", "args": [`+
+			`["delegator", {"bytes": "01aa", "parsed": "01aa", "cl_type": "PublicKey"}], ["validator", {"bytes": "01bb", "parsed": "01bb", "cl_type": "PublicKey"}], `+
+			`["amount", {"bytes": "0400ca9a3b", "parsed": "1000000000", "cl_type": "U512"}]]}}}, `+
+			`"execution_results": [{"block_hash": "02", "result": {"Success": {"cost": "2500000000", "transfers": [], "effect": {"operations": [], "transforms": []}}}}]}`), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal the stacking deploy : %s", err)
+		}
+		defer func(knownWasms map[string]config.KnownWasm) { config.ConfigParsed.KnownWasms = knownWasms }(config.ConfigParsed.KnownWasms)
+		config.ConfigParsed.KnownWasms = map[string]config.KnownWasm{deployResult.GetModuleBytesHash(): {Type: "undelegate", Name: "undelegate.wasm"}}
+		deployType, _ := deployResult.GetDeployMetadata()
+		if deployType != "undelegate" {
+			t.Errorf("Known undelegate wasm guessed from its cost. Received : %s. Expected: %s", deployType, "undelegate")
+		}
+	})
+}
+
+func TestResult_GetModuleBytesHash(t *testing.T) {
+	t.Run("Should hash the module bytes of the session", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(moduleBytesDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal moduleBytesDeploy deploy : %s", err)
+		}
+		deployResult.Deploy.Session.ModuleBytes.ModuleBytes = "0061736d01000000"
+		if hash := deployResult.GetModuleBytesHash(); hash != "f6a5dbf080e9c9d7834145653bce4c8cded62e664d7ddcdb5c526f5877006d74" {
+			t.Errorf("Bad module bytes hash. Received : %s. Expected : %s", hash, "f6a5dbf080e9c9d7834145653bce4c8cded62e664d7ddcdb5c526f5877006d74")
+		}
+	})
+	t.Run("Should not hash a transfer", func(t *testing.T) {
+		var transferResult Result
+		err := json.Unmarshal([]byte(transferDeploy), &transferResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal transfer deploy : %s", err)
+		}
+		if hash := transferResult.GetModuleBytesHash(); hash != "" {
+			t.Errorf("Bad module bytes hash. Received : %s. Expected : none", hash)
+		}
+	})
 }
 
 func TestResult_GetEvents(t *testing.T) {
@@ -131,7 +186,7 @@ func TestResult_GetResultAndCost(t *testing.T) {
 			t.Errorf("Unable to unmarshal transferDeploy deploy : %s", err)
 		}
 		result, cost, errorMessage, err := deployResult.GetResultAndCost()
-		if err != nil || cost != "10000" || result != "success" || errorMessage != "error_message" {
+		if err != nil || cost.String() != "10000" || result != "success" || errorMessage != "error_message" {
 			t.Errorf("deploy cost and result bad parsing detected. Received : %s %s %s. Expected: %s %s %s", result, cost, errorMessage, "success", "10000", "error_message")
 		}
 	})
@@ -142,12 +197,26 @@ func TestResult_GetResultAndCost(t *testing.T) {
 			t.Errorf("Unable to unmarshal storedContractByNameDeploy deploy : %s", err)
 		}
 		result, cost, errorMessage, err := deployResult.GetResultAndCost()
-		if err != nil || cost != "11406830" || result != "failure" || errorMessage != "" {
+		if err != nil || cost.String() != "11406830" || result != "failure" || errorMessage != "" {
 			t.Errorf("deploy cost and result bad parsing detected. Received : %s %s %s. Expected: %s %s %s ", result, cost, errorMessage, "failure", "11406830", "")
 		}
 	})
 }
 
+func TestResult_GetFee(t *testing.T) {
+	t.Run("Should parse the payment, the gas price and the cost of transferDeploy", func(t *testing.T) {
+		var transferResult Result
+		err := json.Unmarshal([]byte(transferDeploy), &transferResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal transfer deploy : %s", err)
+		}
+		fee := transferResult.GetFee()
+		if fee.Payment.String() != "1000000000" || fee.GasPrice != 1 || fee.Cost.String() != "10000" || fee.Unused.String() != "999990000" {
+			t.Errorf("transferDeploy fee bad parsing detected. Received : %s %d %s %s. Expected : %s %d %s %s", fee.Payment.String(), fee.GasPrice, fee.Cost.String(), fee.Unused.String(), "1000000000", 1, "10000", "999990000")
+		}
+	})
+}
+
 func TestResult_GetType(t *testing.T) {
 	t.Run("Should parse a transfer deploy", func(t *testing.T) {
 		var transferResult Result
@@ -362,3 +431,185 @@ func TestResult_MapUrefs(t *testing.T) {
 		log.Println(string(metadataString))
 	})
 }
+
+func TestResult_MapArgs(t *testing.T) {
+	t.Run("Should keep the precision of a U64 arg", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(`{"deploy": {"session": {"ModuleBytes": {"args": [["id", {"bytes": "", "parsed": 18446744073709551615, "cl_type": "U64"}]]}}}}`), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal the deploy : %s", err)
+		}
+		id := deployResult.MapArgs()["id"]
+		if id != "18446744073709551615" {
+			t.Errorf("U64 arg bad parsing detected. Received : %s. Expected: %s", id, "18446744073709551615")
+		}
+	})
+	t.Run("Should decode an arg the node can't render from its bytes", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(`{"deploy": {"session": {"ModuleBytes": {"args": [["meta", {"bytes": "010000000100000061010000006201", "parsed": null, "cl_type": {"Map": {"key": "String", "value": {"Tuple2": ["String", "Bool"]}}}}]]}}}}`), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal the deploy : %s", err)
+		}
+		meta, _ := json.Marshal(deployResult.MapArgs()["meta"])
+		if string(meta) != `[{"key":"a","value":["b",true]}]` {
+			t.Errorf("Map arg bad parsing detected. Received : %s. Expected: %s", meta, `[{"key":"a","value":["b",true]}]`)
+		}
+	})
+}
+
+func TestResult_GetPurseBalanceChanges(t *testing.T) {
+	t.Run("Should retrieve the balances written and the amounts added", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(transferDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal the deploy : %s", err)
+		}
+		changes := deployResult.GetPurseBalanceChanges()
+		if len(changes) != 3 {
+			t.Fatalf("Bad number of balance changes. Received : %d. Expected : %d", len(changes), 3)
+		}
+		if changes[0].Purse != "uref-0a24ef56971d46bfefbd5590afe20e5f3482299aba74e1a0fc33a55008cf9453" || changes[0].Delta == nil || changes[0].Delta.String() != "998000000000" {
+			t.Errorf("Bad amount added. Received : %s %v. Expected : %s", changes[0].Purse, changes[0].Delta, "998000000000")
+		}
+		if changes[2].Purse != "uref-f3abd4d174755d6127e2145876b54e14a1280694cfd1f3924565397808cb7c3f" || changes[2].Balance == nil || changes[2].Balance.String() != "1999990000" {
+			t.Errorf("Bad balance written. Received : %s %v. Expected : %s", changes[2].Purse, changes[2].Balance, "1999990000")
+		}
+	})
+}
+
+func TestResult_GetPurseBalanceChangesFolded(t *testing.T) {
+	var deployResult Result
+	err := json.Unmarshal([]byte(`{"execution_results": [{"result": {"Success": {"cost": "10", "effect": {"operations": [], "transforms": [`+
+		`{"key": "balance-aa", "transform": {"AddUInt512": "5"}},`+
+		`{"key": "balance-aa", "transform": {"WriteCLValue": {"bytes": "0164", "parsed": "100", "cl_type": "U512"}}},`+
+		`{"key": "balance-aa", "transform": {"AddUInt512": "20"}},`+
+		`{"key": "balance-aa", "transform": {"AddUInt512": "3"}},`+
+		`{"key": "balance-bb", "transform": {"AddUInt512": "7"}},`+
+		`{"key": "balance-bb", "transform": {"AddUInt512": "8"}}]}}}}]}`), &deployResult)
+	if err != nil {
+		t.Fatalf("Unable to unmarshal the deploy : %s", err)
+	}
+	changes := deployResult.GetPurseBalanceChanges()
+	if len(changes) != 2 {
+		t.Fatalf("Bad number of balance changes. Received : %d. Expected : %d", len(changes), 2)
+	}
+	t.Run("Should add the amounts added after the last balance written", func(t *testing.T) {
+		if changes[0].Purse != "uref-aa" || changes[0].Balance == nil || changes[0].Balance.String() != "123" || changes[0].Delta != nil {
+			t.Errorf("Bad balance. Received : %s %v %v. Expected : %s %s", changes[0].Purse, changes[0].Balance, changes[0].Delta, "uref-aa", "123")
+		}
+	})
+	t.Run("Should sum the amounts added when no balance is written", func(t *testing.T) {
+		if changes[1].Purse != "uref-bb" || changes[1].Delta == nil || changes[1].Delta.String() != "15" || changes[1].Balance != nil {
+			t.Errorf("Bad delta. Received : %s %v %v. Expected : %s %s", changes[1].Purse, changes[1].Balance, changes[1].Delta, "uref-bb", "15")
+		}
+	})
+	t.Run("Should keep the amounts of the transforms", func(t *testing.T) {
+		if amount := deployResult.GetEffect().Transforms[4].Amount; amount.String() != "7" {
+			t.Errorf("Bad transform amount. Received : %s. Expected : %s", amount, "7")
+		}
+	})
+}
+
+func TestResult_GetEffect(t *testing.T) {
+	var deployResult Result
+	err := json.Unmarshal([]byte(`{"execution_results": [{"result": {"Failure": {"error_message": "User error: 1", "cost": "10", "effect": {"operations": [{"key": "hash-aa", "kind": "Write"}], "transforms": [`+
+		`{"key": "hash-aa", "transform": "WriteContract"},`+
+		`{"key": "account-hash-bb", "transform": {"AddKeys": [{"name": "counter", "key": "uref-cc-007"}]}},`+
+		`{"key": "deploy-dd", "transform": {"WriteDeployInfo": {"deploy_hash": "dd", "transfers": [], "from": "account-hash-bb", "source": "uref-cc-007", "gas": "10"}}},`+
+		`{"key": "uref-cc-007", "transform": {"WriteCLValue": {"bytes": "0100000000000000", "parsed": 1, "cl_type": "U64"}}}]}}}}]}`), &deployResult)
+	if err != nil {
+		t.Fatalf("Unable to unmarshal the deploy : %s", err)
+	}
+	effect := deployResult.GetEffect()
+	t.Run("Should parse the operations and the kind of the transforms", func(t *testing.T) {
+		if len(effect.Operations) != 1 || effect.Operations[0].Kind != "Write" {
+			t.Errorf("Bad operations. Received : %v. Expected : %s", effect.Operations, "[{hash-aa Write}]")
+		}
+		var kinds []string
+		for _, transform := range effect.Transforms {
+			kinds = append(kinds, transform.Kind)
+		}
+		if strings.Join(kinds, ",") != "WriteContract,AddKeys,WriteDeployInfo,WriteCLValue" {
+			t.Errorf("Bad transforms. Received : %s. Expected : %s", strings.Join(kinds, ","), "WriteContract,AddKeys,WriteDeployInfo,WriteCLValue")
+		}
+	})
+	t.Run("Should decode the value of the transforms", func(t *testing.T) {
+		if effect.Transforms[1].NamedKeys[0].Key != "uref-cc-007" {
+			t.Errorf("Bad named key. Received : %s. Expected : %s", effect.Transforms[1].NamedKeys[0].Key, "uref-cc-007")
+		}
+		if effect.Transforms[2].DeployInfo.Gas.String() != "10" {
+			t.Errorf("Bad deploy info gas. Received : %s. Expected : %s", effect.Transforms[2].DeployInfo.Gas.String(), "10")
+		}
+		value, _ := effect.Transforms[3].StoredValue()
+		if value != "1" {
+			t.Errorf("Bad CLValue. Received : %v. Expected : %s", value, "1")
+		}
+	})
+	t.Run("Should find the contracts written", func(t *testing.T) {
+		contracts := deployResult.GetWriteContract()
+		if len(contracts) != 1 || contracts[0] != "hash-aa" {
+			t.Errorf("Bad contracts written. Received : %v. Expected : %s", contracts, "[hash-aa]")
+		}
+	})
+}
+
+func TestResult_Verify(t *testing.T) {
+	t.Run("Should verify the hashes and the ed25519 and secp256k1 approvals", func(t *testing.T) {
+		for name, raw := range map[string]string{"transferDeploy": transferDeploy, "storedVersionedContractByHashDeploy": storedVersionedContractByHashDeploy} {
+			var deployResult Result
+			err := json.Unmarshal([]byte(raw), &deployResult)
+			if err != nil {
+				t.Errorf("Unable to unmarshal %s : %s", name, err)
+			}
+			if verification := deployResult.Verify(); !verification.Valid() {
+				t.Errorf("%s not verified. Received : %+v. Expected : valid hashes and approvals", name, verification)
+			}
+		}
+	})
+	t.Run("Should flag a header or an approval not matching the deploy hash", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(storedVersionedContractByHashDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal storedVersionedContractByHashDeploy : %s", err)
+		}
+		deployResult.Deploy.Header.ChainName = "casper"
+		deployResult.Deploy.Approvals[0].Signature = strings.Replace(deployResult.Deploy.Approvals[0].Signature, "8b", "8c", 1)
+		verification := deployResult.Verify()
+		if !verification.BodyHashValid || verification.HashValid || len(verification.Approvals) != 1 || verification.Approvals[0] {
+			t.Errorf("Bad verification. Received : %+v. Expected : only the body hash valid", verification)
+		}
+	})
+	t.Run("Should flag a body not matching the body hash", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(transferDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal transferDeploy : %s", err)
+		}
+		deployResult.Deploy.Session.Transfer.Args = deployResult.Deploy.Session.Transfer.Args[1:]
+		if verification := deployResult.Verify(); verification.BodyHashValid || !verification.HashValid {
+			t.Errorf("Bad verification. Received : %+v. Expected : only the body hash not valid", verification)
+		}
+	})
+	t.Run("Should join the errors of the body and of the header", func(t *testing.T) {
+		var deployResult Result
+		err := json.Unmarshal([]byte(transferDeploy), &deployResult)
+		if err != nil {
+			t.Errorf("Unable to unmarshal transferDeploy : %s", err)
+		}
+		deployResult.Deploy.Session.Transfer.Args = [][]interface{}{{"amount"}}
+		deployResult.Deploy.Header.Timestamp = "yesterday"
+		verification := deployResult.Verify()
+		if !strings.HasPrefix(verification.Error, "body: ") || !strings.Contains(verification.Error, "; header: ") {
+			t.Errorf("Bad verification error. Received : %s. Expected : the errors of the body and of the header", verification.Error)
+		}
+	})
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := map[string]uint64{"30m": 1800000, "1h": 3600000, "1day 12h": 129600000, "500ms": 500, "1s 500us": 1000, "2ms 999999ns": 2, "1500usec 500000nsec": 2, "1msec": 1}
+	for ttl, expected := range tests {
+		if received, err := parseTTL(ttl); err != nil || received != expected {
+			t.Errorf("Bad ttl for %s. Received : %d %v. Expected : %d", ttl, received, err, expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package deploy
+
+import (
+	"bytes"
+	"casperParser/types/amount"
+	"casperParser/types/clvalue"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The kinds of transform of an execution effect. Identity and the writes of the contracts have no value.
+const (
+	TransformIdentity             = "Identity"
+	TransformWriteCLValue         = "WriteCLValue"
+	TransformWriteAccount         = "WriteAccount"
+	TransformWriteContractWasm    = "WriteContractWasm"
+	TransformWriteContract        = "WriteContract"
+	TransformWriteContractPackage = "WriteContractPackage"
+	TransformWriteDeployInfo      = "WriteDeployInfo"
+	TransformWriteEraInfo         = "WriteEraInfo"
+	TransformWriteTransfer        = "WriteTransfer"
+	TransformWriteBid             = "WriteBid"
+	TransformWriteWithdraw        = "WriteWithdraw"
+	TransformWriteUnbonding       = "WriteUnbonding"
+	TransformAddInt32             = "AddInt32"
+	TransformAddUInt64            = "AddUInt64"
+	TransformAddUInt128           = "AddUInt128"
+	TransformAddUInt256           = "AddUInt256"
+	TransformAddUInt512           = "AddUInt512"
+	TransformAddKeys              = "AddKeys"
+	TransformFailure              = "Failure"
+)
+
+// Effect of the execution of a deploy on the global state
+type Effect struct {
+	Operations []Operation `json:"operations"`
+	Transforms []Transform `json:"transforms"`
+}
+
+// Operation on a key of the global state: Read, Write, Add or NoOp
+type Operation struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"`
+}
+
+// Transform of a key of the global state. Kind is the name of the transform, Value its json value, null for the
+// transforms without one. The value of the transforms used by the parser is also decoded in the matching field.
+type Transform struct {
+	Key   string
+	Kind  string
+	Value json.RawMessage
+	// CLValue of a WriteCLValue
+	CLValue *clvalue.CLValue
+	// Account hash of a WriteAccount
+	Account string
+	// DeployInfo of a WriteDeployInfo
+	DeployInfo *DeployInfo
+	// Bid of a WriteBid
+	Bid *Bid
+	// Withdraws of a WriteWithdraw
+	Withdraws []Withdraw
+	// Amount of the Add transforms of an integer
+	Amount *amount.Amount
+	// NamedKeys of an AddKeys
+	NamedKeys []NamedKey
+	// Failure message of a Failure
+	Failure string
+}
+
+// DeployInfo written by a deploy at deploy-<hash>
+type DeployInfo struct {
+	DeployHash string        `json:"deploy_hash"`
+	Transfers  []string      `json:"transfers"`
+	From       string        `json:"from"`
+	Source     string        `json:"source"`
+	Gas        amount.Amount `json:"gas"`
+}
+
+// Bid written at bid-<account hash>, the delegators are only in the value of the transform
+type Bid struct {
+	ValidatorPublicKey string        `json:"validator_public_key"`
+	BondingPurse       string        `json:"bonding_purse"`
+	StakedAmount       amount.Amount `json:"staked_amount"`
+	DelegationRate     int           `json:"delegation_rate"`
+	Inactive           bool          `json:"inactive"`
+}
+
+// Withdraw of an unbonding purse written at withdraw-<account hash>
+type Withdraw struct {
+	BondingPurse       string        `json:"bonding_purse"`
+	ValidatorPublicKey string        `json:"validator_public_key"`
+	UnbonderPublicKey  string        `json:"unbonder_public_key"`
+	EraOfCreation      int           `json:"era_of_creation"`
+	Amount             amount.Amount `json:"amount"`
+}
+
+// NamedKey added by an AddKeys
+type NamedKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+// UnmarshalJSON a transform of the RPC, {"key": ..., "transform": ...}. The transform is the kind alone when it has no
+// value, an object with the value by the kind otherwise.
+func (t *Transform) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key       string          `json:"key"`
+		Transform json.RawMessage `json:"transform"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = Transform{Key: raw.Key}
+	if err := json.Unmarshal(raw.Transform, &t.Kind); err == nil {
+		return nil
+	}
+	var kinds map[string]json.RawMessage
+	if err := json.Unmarshal(raw.Transform, &kinds); err != nil {
+		return err
+	}
+	if len(kinds) != 1 {
+		return fmt.Errorf("invalid transform of %s", raw.Key)
+	}
+	for kind, value := range kinds {
+		t.Kind = kind
+		t.Value = value
+	}
+	var target interface{}
+	switch t.Kind {
+	case TransformWriteCLValue:
+		t.CLValue = &clvalue.CLValue{}
+		target = t.CLValue
+	case TransformWriteAccount:
+		target = &t.Account
+	case TransformWriteDeployInfo:
+		t.DeployInfo = &DeployInfo{}
+		target = t.DeployInfo
+	case TransformWriteBid:
+		t.Bid = &Bid{}
+		target = t.Bid
+	case TransformWriteWithdraw:
+		target = &t.Withdraws
+	case TransformAddInt32, TransformAddUInt64, TransformAddUInt128, TransformAddUInt256, TransformAddUInt512:
+		t.Amount = &amount.Amount{}
+		target = t.Amount
+	case TransformAddKeys:
+		target = &t.NamedKeys
+	case TransformFailure:
+		target = &t.Failure
+	default:
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(t.Value))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return fmt.Errorf("invalid %s transform of %s: %w", t.Kind, t.Key, err)
+	}
+	return nil
+}
+
+// MarshalJSON the transform in the form of the RPC
+func (t Transform) MarshalJSON() ([]byte, error) {
+	var transform interface{} = t.Kind
+	if t.Value != nil {
+		transform = map[string]json.RawMessage{t.Kind: t.Value}
+	}
+	return json.Marshal(struct {
+		Key       string      `json:"key"`
+		Transform interface{} `json:"transform"`
+	}{t.Key, transform})
+}
+
+// StoredValue the json value of the transform stored in the transforms table, the decoded value for a WriteCLValue.
+// Nil for the transforms without value.
+func (t Transform) StoredValue() (interface{}, error) {
+	if t.CLValue != nil {
+		value, err := json.Marshal(t.CLValue.Value())
+		if err != nil {
+			return nil, err
+		}
+		return string(value), nil
+	}
+	if t.Value == nil {
+		return nil, nil
+	}
+	return string(t.Value), nil
+}
+
+// DictionaryValue decode the value of a WriteCLValue of a dictionary item, false if the transform isn't one
+func (t Transform) DictionaryValue() (clvalue.DictionaryValue, bool) {
+	if t.CLValue == nil || !strings.HasPrefix(t.Key, "dictionary-") || t.CLValue.ClType != "Any" {
+		return clvalue.DictionaryValue{}, false
+	}
+	data, err := hex.DecodeString(t.CLValue.Bytes)
+	if err != nil {
+		return clvalue.DictionaryValue{}, false
+	}
+	value, err := clvalue.DecodeDictionaryValue(data)
+	return value, err == nil
+}
+
+// GetEffect retrieve the effect of the execution of the deploy, empty if it wasn't executed
+func (d Result) GetEffect() Effect {
+	if len(d.ExecutionResults) == 0 {
+		return Effect{}
+	}
+	if d.ExecutionResults[0].Result.Success != nil {
+		return d.ExecutionResults[0].Result.Success.Effect
+	}
+	if d.ExecutionResults[0].Result.Failure != nil {
+		return d.ExecutionResults[0].Result.Failure.Effect
+	}
+	return Effect{}
+}
@@ -0,0 +1,269 @@
+package deploy
+
+import (
+	"bytes"
+	"casperParser/types/clvalue"
+	"casperParser/utils"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Verification of a deploy, its hashes computed again from its header, payment and session and the signatures of its approvals
+type Verification struct {
+	// BodyHashValid when the body hash of the header is the hash of the payment and the session
+	BodyHashValid bool
+	// HashValid when the hash of the deploy is the hash of its header
+	HashValid bool
+	// Approvals valid by index, an approval is valid when it's the signature of the deploy hash by its signer
+	Approvals []bool
+	// Error why the deploy can't be serialized, the errors of the body and of the header joined. The hashes are then unknown.
+	Error string
+}
+
+// ApprovalsValid when every approval is valid
+func (v Verification) ApprovalsValid() bool {
+	for _, valid := range v.Approvals {
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid when the hashes and every approval are valid
+func (v Verification) Valid() bool {
+	return v.BodyHashValid && v.HashValid && v.ApprovalsValid()
+}
+
+// Verify the hashes and the approvals of the deploy
+func (d Result) Verify() Verification {
+	var v Verification
+	var errs []string
+	bodyHash, err := d.Deploy.GetBodyHash()
+	if err != nil {
+		errs = append(errs, "body: "+err.Error())
+	} else {
+		v.BodyHashValid = hex.EncodeToString(bodyHash) == d.Deploy.Header.BodyHash
+	}
+	hash, err := d.Deploy.GetHeaderHash()
+	if err != nil {
+		errs = append(errs, "header: "+err.Error())
+	} else {
+		v.HashValid = hex.EncodeToString(hash) == d.Deploy.Hash
+	}
+	v.Error = strings.Join(errs, "; ")
+	message, _ := hex.DecodeString(d.Deploy.Hash)
+	for _, approval := range d.Deploy.Approvals {
+		valid, _ := utils.VerifySignature(approval.Signer, message, approval.Signature)
+		v.Approvals = append(v.Approvals, valid)
+	}
+	return v
+}
+
+// GetBodyHash compute the blake2b hash of the serialized payment and session
+func (d JsonDeploy) GetBodyHash() ([]byte, error) {
+	var s serializer
+	s.item(d.Payment)
+	s.item(d.Session)
+	if s.err != nil {
+		return nil, s.err
+	}
+	hash := blake2b.Sum256(s.buf.Bytes())
+	return hash[:], nil
+}
+
+// GetHeaderHash compute the blake2b hash of the serialized header, the hash of the deploy
+func (d JsonDeploy) GetHeaderHash() ([]byte, error) {
+	var s serializer
+	s.hex(d.Header.Account, -1)
+	timestamp, err := time.Parse(time.RFC3339Nano, d.Header.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %s: %w", d.Header.Timestamp, err)
+	}
+	s.u64(uint64(timestamp.UnixMilli()))
+	ttl, err := parseTTL(d.Header.TTL)
+	if err != nil {
+		return nil, err
+	}
+	s.u64(ttl)
+	s.u64(uint64(d.Header.GasPrice))
+	s.hex(d.Header.BodyHash, 32)
+	s.u32(len(d.Header.Dependencies))
+	for _, dependency := range d.Header.Dependencies {
+		s.hex(dependency, 32)
+	}
+	s.string(d.Header.ChainName)
+	if s.err != nil {
+		return nil, s.err
+	}
+	hash := blake2b.Sum256(s.buf.Bytes())
+	return hash[:], nil
+}
+
+// ttlPart a number and its unit in a ttl like 1h 30m
+var ttlPart = regexp.MustCompile(`(\d+)\s*([a-zA-Zµ]+)`)
+
+// ttlUnits in nanoseconds, the units of the durations formatted by the node
+var ttlUnits = map[string]uint64{
+	"ns": 1, "nsec": 1,
+	"us": 1000, "usec": 1000, "µs": 1000,
+	"ms": 1000000, "msec": 1000000,
+	"s": 1000000000, "sec": 1000000000, "second": 1000000000, "seconds": 1000000000,
+	"m": 60000000000, "min": 60000000000, "minute": 60000000000, "minutes": 60000000000,
+	"h": 3600000000000, "hr": 3600000000000, "hour": 3600000000000, "hours": 3600000000000,
+	"d": 86400000000000, "day": 86400000000000, "days": 86400000000000,
+	"w": 604800000000000, "week": 604800000000000, "weeks": 604800000000000,
+	"M": 2630016000000000, "month": 2630016000000000, "months": 2630016000000000,
+	"y": 31557600000000000, "year": 31557600000000000, "years": 31557600000000000,
+}
+
+// parseTTL in milliseconds, the ttl is formatted by the node like 1h, 30m or 1day 12h. Like the node, the sum of the
+// parts is truncated to the millisecond.
+func parseTTL(ttl string) (uint64, error) {
+	parts := ttlPart.FindAllStringSubmatch(ttl, -1)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid ttl %s", ttl)
+	}
+	var total uint64
+	for _, part := range parts {
+		n, err := strconv.ParseUint(part[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ttl %s: %w", ttl, err)
+		}
+		unit, ok := ttlUnits[part[2]]
+		if !ok {
+			return 0, fmt.Errorf("invalid ttl unit %s", part[2])
+		}
+		total += n * unit
+	}
+	return total / 1000000, nil
+}
+
+// serializer write the values in the bytesrepr format of the node, the first error stops the serialization
+type serializer struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (s *serializer) u8(v byte) {
+	s.buf.WriteByte(v)
+}
+
+func (s *serializer) u32(v int) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(v))
+	s.buf.Write(b[:])
+}
+
+func (s *serializer) u64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	s.buf.Write(b[:])
+}
+
+func (s *serializer) string(v string) {
+	s.u32(len(v))
+	s.buf.WriteString(v)
+}
+
+// hex write the hex value, of the length in bytes or of any length when it's -1
+func (s *serializer) hex(v string, length int) {
+	b, err := hex.DecodeString(v)
+	if err == nil && length >= 0 && len(b) != length {
+		err = fmt.Errorf("%d bytes instead of %d", len(b), length)
+	}
+	if err != nil {
+		s.fail(fmt.Errorf("invalid hex value %s: %w", v, err))
+		return
+	}
+	s.buf.Write(b)
+}
+
+// version of a stored versioned contract, an Option<u32>
+func (s *serializer) version(v *int) {
+	if v == nil {
+		s.u8(0)
+		return
+	}
+	s.u8(1)
+	s.u32(*v)
+}
+
+// args the runtime args, each arg is its name then its CLValue, its bytes and its cl_type
+func (s *serializer) args(args [][]interface{}) {
+	s.u32(len(args))
+	for _, arg := range args {
+		if len(arg) != 2 {
+			s.fail(fmt.Errorf("invalid arg %v", arg))
+			return
+		}
+		name, _ := arg[0].(string)
+		value, _ := arg[1].(map[string]interface{})
+		data, _ := value["bytes"].(string)
+		b, err := hex.DecodeString(data)
+		if err != nil {
+			s.fail(fmt.Errorf("invalid bytes of the arg %s: %w", name, err))
+			return
+		}
+		clType, err := clvalue.EncodeCLType(value["cl_type"])
+		if err != nil {
+			s.fail(fmt.Errorf("invalid cl_type of the arg %s: %w", name, err))
+			return
+		}
+		s.string(name)
+		s.u32(len(b))
+		s.buf.Write(b)
+		s.buf.Write(clType)
+	}
+}
+
+// item an executable deploy item, its tag then its fields
+func (s *serializer) item(item ExecutableDeployItem) {
+	switch {
+	case item.ModuleBytes != nil:
+		s.u8(0)
+		s.u32(len(item.ModuleBytes.ModuleBytes) / 2)
+		s.hex(item.ModuleBytes.ModuleBytes, -1)
+		s.args(item.ModuleBytes.Args)
+	case item.StoredContractByHash != nil:
+		s.u8(1)
+		s.hex(item.StoredContractByHash.Hash, 32)
+		s.string(item.StoredContractByHash.EntryPoint)
+		s.args(item.StoredContractByHash.Args)
+	case item.StoredContractByName != nil:
+		s.u8(2)
+		s.string(item.StoredContractByName.Name)
+		s.string(item.StoredContractByName.EntryPoint)
+		s.args(item.StoredContractByName.Args)
+	case item.StoredVersionedContractByHash != nil:
+		s.u8(3)
+		s.hex(item.StoredVersionedContractByHash.Hash, 32)
+		s.version(item.StoredVersionedContractByHash.Version)
+		s.string(item.StoredVersionedContractByHash.EntryPoint)
+		s.args(item.StoredVersionedContractByHash.Args)
+	case item.StoredVersionedContractByName != nil:
+		s.u8(4)
+		s.string(item.StoredVersionedContractByName.Name)
+		s.version(item.StoredVersionedContractByName.Version)
+		s.string(item.StoredVersionedContractByName.EntryPoint)
+		s.args(item.StoredVersionedContractByName.Args)
+	case item.Transfer != nil:
+		s.u8(5)
+		s.args(item.Transfer.Args)
+	default:
+		s.fail(fmt.Errorf("unknown executable deploy item"))
+	}
+}
+
+func (s *serializer) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -2,8 +2,9 @@
 package deployInfo
 
 import (
+	"casperParser/types/amount"
+	"encoding/json"
 	"fmt"
-	"math/big"
 	"strconv"
 )
 
@@ -33,8 +34,10 @@ func getValue(v interface{}) interface{} {
 		return ""
 	case bool:
 		return strconv.FormatBool(v.(bool))
+	case json.Number:
+		return v.(json.Number).String()
 	case float64:
-		return strconv.Itoa(int(v.(float64)))
+		return strconv.FormatFloat(v.(float64), 'f', -1, 64)
 	case int:
 		return strconv.Itoa(v.(int))
 	case string:
@@ -64,24 +67,24 @@ type JsonBlockHeader struct {
 		EraReport struct {
 			Equivocators []string `json:"equivocators"`
 			Rewards      []struct {
-				Validator string  `json:"validator"`
-				Amount    big.Int `json:"amount"`
+				Validator string        `json:"validator"`
+				Amount    amount.Amount `json:"amount"`
 			} `json:"rewards"`
 			InactiveValidators []string `json:"inactiveValidators"`
 		} `json:"era_report"`
 		NextEraValidatorWeights []struct {
-			Validator string `json:"validator"`
-			Weight    string `json:"weight"`
+			Validator string        `json:"validator"`
+			Weight    amount.Amount `json:"weight"`
 		} `json:"next_era_validator_weights"`
 	} `json:"era_end"`
 }
 
 type DeployInfoStoredValue struct {
 	DeployInfo struct {
-		Deploy    string   `json:"deploy_hash"`
-		Transfers []string `json:"transfers"`
-		From      string   `json:"from"`
-		Source    string   `json:"source"`
-		Gas       string   `json:"gas"`
+		Deploy    string        `json:"deploy_hash"`
+		Transfers []string      `json:"transfers"`
+		From      string        `json:"from"`
+		Source    string        `json:"source"`
+		Gas       amount.Amount `json:"gas"`
 	} `json:"DeployInfo"`
 }
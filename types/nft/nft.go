@@ -0,0 +1,273 @@
+// Package nft follow the CEP-47 and CEP-78 NFT collections. The actions on the tokens of a collection are read from
+// the events it emits, the CEP-47 events written in urefs and the CES events of CEP-78, or from the entrypoint
+// called by the deploy when the collection emits none.
+package nft
+
+import (
+	"casperParser/types/ces"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The standards of the collections
+const (
+	StandardCEP47 = "cep47"
+	StandardCEP78 = "cep78"
+)
+
+// The kinds of action on a token
+const (
+	ActionMint           = "mint"
+	ActionTransfer       = "transfer"
+	ActionBurn           = "burn"
+	ActionApprove        = "approve"
+	ActionMetadataUpdate = "metadata_update"
+)
+
+// cep47KeyFormat of the keys in the CEP-47 events, Key::Account(<hex>) or Key::Hash(<hex>)
+var cep47KeyFormat = regexp.MustCompile(`^Key::(Account|Hash)\(([0-9a-fA-F]{64})\)$`)
+
+// hashFormat of a hash inside a formatted key or hash
+var hashFormat = regexp.MustCompile(`[0-9a-fA-F]{64}`)
+
+// Collection a CEP-47 or CEP-78 contract package, the versions of the package share the tokens
+type Collection struct {
+	PackageHash string
+	// ContractHash of the last version of the package stored
+	ContractHash string
+	Standard     string
+	Name         string
+	Symbol       string
+}
+
+// Action on a token of a collection. The keys are account-hash-<hex> or hash-<hex>.
+type Action struct {
+	PackageHash string
+	TokenID     string
+	Kind        string
+	// From the owner before a transfer or a burn, empty if unknown
+	From string
+	// To the owner after a mint or a transfer
+	To string
+	// Spender approved, empty when the approval is revoked
+	Spender string
+	// Metadata of the token set by a mint or a metadata update, in json. Empty if unknown.
+	Metadata string
+}
+
+// ChangesOwner check if the action is a mint, a transfer or a burn
+func (a Action) ChangesOwner() bool {
+	return a.Kind == ActionMint || a.Kind == ActionTransfer || a.Kind == ActionBurn
+}
+
+// SetsApproved check if the action sets the spender approved for the token, a transfer and a burn revoke it
+func (a Action) SetsApproved() bool {
+	return a.Kind == ActionApprove || a.Kind == ActionTransfer || a.Kind == ActionBurn
+}
+
+// The types of the contracts of the collections, as classified from the contract types of the config
+const (
+	ContractTypeCEP47 = "nftcep47"
+	ContractTypeCEP78 = "nftcep78"
+)
+
+// StandardOf the collection of a contract from the type it is classified with, empty if it isn't a collection.
+// Return the names of the named keys of the name and the symbol of the collection.
+func StandardOf(contractType string) (standard string, nameKey string, symbolKey string) {
+	switch contractType {
+	case ContractTypeCEP78:
+		return StandardCEP78, "collection_name", "collection_symbol"
+	case ContractTypeCEP47:
+		return StandardCEP47, "name", "symbol"
+	}
+	return "", "", ""
+}
+
+// NewCollection of a contract classified as CEP-47 or CEP-78, its name and symbol read from the json values of its
+// named keys by name. False if the contract isn't a collection.
+func NewCollection(contractHash string, packageHash string, contractType string, namedKeyValues map[string]string) (Collection, bool) {
+	standard, nameKey, symbolKey := StandardOf(contractType)
+	if standard == "" {
+		return Collection{}, false
+	}
+	c := Collection{PackageHash: packageHash, ContractHash: contractHash, Standard: standard}
+	_ = json.Unmarshal([]byte(namedKeyValues[nameKey]), &c.Name)
+	_ = json.Unmarshal([]byte(namedKeyValues[symbolKey]), &c.Symbol)
+	return c, true
+}
+
+// FormatKey a key of an event or of an argument in the account-hash-<hex> or hash-<hex> form. The keys decoded by the
+// clvalue package are an object with the formatted key by variant, the CEP-47 events use Key::Account(<hex>).
+// Empty if the value isn't a key.
+func FormatKey(v interface{}) string {
+	switch key := v.(type) {
+	case map[string]interface{}:
+		for _, formatted := range key {
+			s, _ := formatted.(string)
+			return s
+		}
+	case string:
+		if matches := cep47KeyFormat.FindStringSubmatch(key); matches != nil {
+			if matches[1] == "Account" {
+				return "account-hash-" + strings.ToLower(matches[2])
+			}
+			return "hash-" + strings.ToLower(matches[2])
+		}
+		if strings.HasPrefix(key, "account-hash-") || strings.HasPrefix(key, "hash-") {
+			return key
+		}
+	}
+	return ""
+}
+
+// ParseCEP47Event the action of an event of the CEP-47 standard, also emitted by the CEP-78 contracts in the CEP47 events mode.
+// False if the event isn't one of a token.
+func ParseCEP47Event(event map[string]string) (Action, bool) {
+	action := Action{
+		PackageHash: strings.ToLower(hashFormat.FindString(event["contract_package_hash"])),
+		TokenID:     event["token_id"],
+	}
+	switch event["event_type"] {
+	case "cep47_mint_one":
+		action.Kind, action.To = ActionMint, FormatKey(event["recipient"])
+	case "cep47_transfer_token":
+		action.Kind, action.From, action.To = ActionTransfer, FormatKey(event["sender"]), FormatKey(event["recipient"])
+	case "cep47_burn_one":
+		action.Kind, action.From = ActionBurn, FormatKey(event["owner"])
+	case "cep47_approve_token":
+		action.Kind, action.From, action.Spender = ActionApprove, FormatKey(event["owner"]), FormatKey(event["spender"])
+	case "cep47_metadata_update":
+		action.Kind = ActionMetadataUpdate
+	default:
+		return Action{}, false
+	}
+	return action, action.PackageHash != "" && action.TokenID != ""
+}
+
+// ParseCESEvent the action of a CES event of a CEP-78 collection, without the package hash.
+// False if the event isn't one of a token.
+func ParseCESEvent(event ces.Event) (Action, bool) {
+	action := Action{TokenID: tokenID(event.Data["token_id"])}
+	switch event.Name {
+	case "Mint":
+		action.Kind, action.To, action.Metadata = ActionMint, FormatKey(event.Data["recipient"]), metadata(event.Data["data"])
+	case "Transfer":
+		action.Kind, action.From, action.To = ActionTransfer, FormatKey(event.Data["owner"]), FormatKey(event.Data["recipient"])
+	case "Burn":
+		action.Kind, action.From = ActionBurn, FormatKey(event.Data["owner"])
+	case "Approval":
+		action.Kind, action.From, action.Spender = ActionApprove, FormatKey(event.Data["owner"]), FormatKey(event.Data["spender"])
+	case "ApprovalRevoked":
+		action.Kind, action.From = ActionApprove, FormatKey(event.Data["owner"])
+	case "MetadataUpdated":
+		action.Kind, action.Metadata = ActionMetadataUpdate, metadata(event.Data["data"])
+	default:
+		return Action{}, false
+	}
+	return action, action.TokenID != ""
+}
+
+// ParseEntrypoint the actions of a call to an entrypoint of a collection, with the args of the deploy mapped by name.
+// The caller is the key of the account of the deploy. A CEP-78 mint is skipped, the id of the token isn't in its args.
+func ParseEntrypoint(standard string, entrypoint string, args map[string]interface{}, caller string) []Action {
+	var actions []Action
+	add := func(action Action, ids ...string) {
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			action.TokenID = id
+			actions = append(actions, action)
+		}
+	}
+	if standard == StandardCEP47 {
+		switch entrypoint {
+		case "mint":
+			ids, metas := tokenIDs(args["token_ids"]), list(args["token_metas"])
+			for i, id := range ids {
+				action := Action{Kind: ActionMint, To: FormatKey(args["recipient"])}
+				if i < len(metas) {
+					action.Metadata = metadata(metas[i])
+				}
+				add(action, id)
+			}
+		case "mint_copies":
+			add(Action{Kind: ActionMint, To: FormatKey(args["recipient"]), Metadata: metadata(args["token_meta"])}, tokenIDs(args["token_ids"])...)
+		case "transfer":
+			add(Action{Kind: ActionTransfer, From: caller, To: FormatKey(args["recipient"])}, tokenIDs(args["token_ids"])...)
+		case "transfer_from":
+			add(Action{Kind: ActionTransfer, From: FormatKey(args["sender"]), To: FormatKey(args["recipient"])}, tokenIDs(args["token_ids"])...)
+		case "burn":
+			add(Action{Kind: ActionBurn, From: FormatKey(args["owner"])}, tokenIDs(args["token_ids"])...)
+		case "approve":
+			add(Action{Kind: ActionApprove, From: caller, Spender: FormatKey(args["spender"])}, tokenIDs(args["token_ids"])...)
+		case "update_token_meta":
+			add(Action{Kind: ActionMetadataUpdate, Metadata: metadata(args["token_meta"])}, tokenID(args["token_id"]))
+		}
+		return actions
+	}
+	id := tokenID(args["token_id"])
+	if id == "" {
+		id = tokenID(args["token_hash"])
+	}
+	switch entrypoint {
+	case "transfer":
+		add(Action{Kind: ActionTransfer, From: FormatKey(args["source_key"]), To: FormatKey(args["target_key"])}, id)
+	case "burn":
+		add(Action{Kind: ActionBurn, From: caller}, id)
+	case "approve":
+		spender := FormatKey(args["spender"])
+		if spender == "" {
+			spender = FormatKey(args["operator"])
+		}
+		add(Action{Kind: ActionApprove, From: caller, Spender: spender}, id)
+	case "set_token_metadata":
+		add(Action{Kind: ActionMetadataUpdate, Metadata: metadata(args["token_meta_data"])}, id)
+	}
+	return actions
+}
+
+// list of the values of a list argument, nil if it isn't one
+func list(v interface{}) []interface{} {
+	values, _ := v.([]interface{})
+	return values
+}
+
+// tokenIDs of a list of token ids
+func tokenIDs(v interface{}) []string {
+	var ids []string
+	for _, id := range list(v) {
+		ids = append(ids, tokenID(id))
+	}
+	return ids
+}
+
+// tokenID a token id, a number or a string, in a string. Empty if there is none.
+func tokenID(v interface{}) string {
+	switch id := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// metadata in json, a metadata string is kept as is. Empty if there is none.
+func metadata(v interface{}) string {
+	switch meta := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return meta
+	default:
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
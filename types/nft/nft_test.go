@@ -0,0 +1,102 @@
+package nft
+
+import (
+	"casperParser/types/ces"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStandardOf(t *testing.T) {
+	tests := []struct {
+		contractType string
+		standard     string
+		nameKey      string
+	}{
+		{ContractTypeCEP47, StandardCEP47, "name"},
+		{ContractTypeCEP78, StandardCEP78, "collection_name"},
+		{"erc20", "", ""},
+	}
+	for _, test := range tests {
+		if standard, nameKey, _ := StandardOf(test.contractType); standard != test.standard || nameKey != test.nameKey {
+			t.Errorf("Bad standard of %s. Received : %s %s. Expected : %s %s", test.contractType, standard, nameKey, test.standard, test.nameKey)
+		}
+	}
+}
+
+func TestFormatKey(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		name     string
+		key      interface{}
+		expected string
+	}{
+		{"Should format a CEP-47 account key", "Key::Account(" + strings.ToUpper(hash) + ")", "account-hash-" + hash},
+		{"Should format a CEP-47 hash key", "Key::Hash(" + hash + ")", "hash-" + hash},
+		{"Should format a decoded key", map[string]interface{}{"Account": "account-hash-" + hash}, "account-hash-" + hash},
+		{"Should keep a formatted key", "hash-" + hash, "hash-" + hash},
+		{"Should ignore a value that isn't a key", "01" + hash, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if key := FormatKey(test.key); key != test.expected {
+				t.Errorf("Bad key. Received : %s. Expected : %s", key, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseCEP47Event(t *testing.T) {
+	packageHash := strings.Repeat("cd", 32)
+	t.Run("Should parse a transfer", func(t *testing.T) {
+		action, ok := ParseCEP47Event(map[string]string{
+			"event_type":            "cep47_transfer_token",
+			"contract_package_hash": "contract-package-wasm" + packageHash,
+			"sender":                "Key::Account(" + strings.Repeat("01", 32) + ")",
+			"recipient":             "Key::Account(" + strings.Repeat("02", 32) + ")",
+			"token_id":              "7",
+		})
+		expected := Action{PackageHash: packageHash, TokenID: "7", Kind: ActionTransfer, From: "account-hash-" + strings.Repeat("01", 32), To: "account-hash-" + strings.Repeat("02", 32)}
+		if !ok || action != expected {
+			t.Errorf("Bad action. Received : %+v. Expected : %+v", action, expected)
+		}
+	})
+	t.Run("Should ignore the other events", func(t *testing.T) {
+		if _, ok := ParseCEP47Event(map[string]string{"event_type": "auction_bid", "contract_package_hash": packageHash, "token_id": "7"}); ok {
+			t.Errorf("Auction event parsed as an NFT action")
+		}
+	})
+}
+
+func TestParseCESEvent(t *testing.T) {
+	recipient := map[string]interface{}{"Account": "account-hash-" + strings.Repeat("02", 32)}
+	action, ok := ParseCESEvent(ces.Event{Name: "Mint", Data: map[string]interface{}{"recipient": recipient, "token_id": "0", "data": `{"name":"a"}`}})
+	expected := Action{TokenID: "0", Kind: ActionMint, To: "account-hash-" + strings.Repeat("02", 32), Metadata: `{"name":"a"}`}
+	if !ok || action != expected {
+		t.Errorf("Bad action. Received : %+v. Expected : %+v", action, expected)
+	}
+}
+
+func TestParseEntrypoint(t *testing.T) {
+	recipient := map[string]interface{}{"Account": "account-hash-" + strings.Repeat("02", 32)}
+	t.Run("Should parse the tokens of a CEP-47 mint with their metadata", func(t *testing.T) {
+		metas := []interface{}{[]interface{}{map[string]interface{}{"key": "name", "value": "a"}}, []interface{}{map[string]interface{}{"key": "name", "value": "b"}}}
+		actions := ParseEntrypoint(StandardCEP47, "mint", map[string]interface{}{"recipient": recipient, "token_ids": []interface{}{json.Number("1"), json.Number("2")}, "token_metas": metas}, "")
+		if len(actions) != 2 || actions[1].TokenID != "2" || actions[1].To != "account-hash-"+strings.Repeat("02", 32) || actions[1].Metadata != `[{"key":"name","value":"b"}]` {
+			t.Errorf("Bad actions. Received : %+v. Expected : the mints of the tokens 1 and 2", actions)
+		}
+	})
+	t.Run("Should parse a CEP-78 transfer by token hash", func(t *testing.T) {
+		source := map[string]interface{}{"Account": "account-hash-" + strings.Repeat("01", 32)}
+		actions := ParseEntrypoint(StandardCEP78, "transfer", map[string]interface{}{"source_key": source, "target_key": recipient, "token_hash": "abc"}, "")
+		expected := Action{TokenID: "abc", Kind: ActionTransfer, From: "account-hash-" + strings.Repeat("01", 32), To: "account-hash-" + strings.Repeat("02", 32)}
+		if len(actions) != 1 || actions[0] != expected {
+			t.Errorf("Bad actions. Received : %+v. Expected : %+v", actions, expected)
+		}
+	})
+	t.Run("Should skip a CEP-78 mint", func(t *testing.T) {
+		if actions := ParseEntrypoint(StandardCEP78, "mint", map[string]interface{}{"token_owner": recipient, "token_meta_data": "{}"}, ""); len(actions) != 0 {
+			t.Errorf("Bad actions. Received : %+v. Expected : none", actions)
+		}
+	})
+}
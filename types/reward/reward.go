@@ -1,6 +1,8 @@
 // Package reward provide a struct for unmarshalling a json reward response from Casper RPC
 package reward
 
+import "casperParser/types/amount"
+
 type Result struct {
 	EraSummary *struct {
 		BlockHash   string `json:"block_hash"`
@@ -9,13 +11,13 @@ type Result struct {
 			EraInfo struct {
 				SeigniorageAllocations []struct {
 					Delegator *struct {
-						DelegatorPublicKey string `json:"delegator_public_key"`
-						ValidatorPublicKey string `json:"validator_public_key"`
-						Amount             string `json:"amount"`
+						DelegatorPublicKey string        `json:"delegator_public_key"`
+						ValidatorPublicKey string        `json:"validator_public_key"`
+						Amount             amount.Amount `json:"amount"`
 					} `json:"Delegator"`
 					Validator *struct {
-						ValidatorPublicKey string `json:"validator_public_key"`
-						Amount             string `json:"amount"`
+						ValidatorPublicKey string        `json:"validator_public_key"`
+						Amount             amount.Amount `json:"amount"`
 					} `json:"Validator"`
 				} `json:"seigniorage_allocations"`
 			} `json:"EraInfo"`
@@ -0,0 +1,173 @@
+// Package token follow the CEP-18 fungible tokens, the ERC-20 tokens of Casper.
+// A token stores the balance of each holder in the dictionary of its balances named key, under the base64 of the bytes
+// of the holder key, and its supply in the uref of its total_supply named key.
+// The transfers are read from the CES events of the token, or from the entrypoint called by the deploy when it emits none.
+package token
+
+import (
+	"casperParser/types/amount"
+	"casperParser/types/ces"
+	"casperParser/types/clvalue"
+	"casperParser/types/nft"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The named keys of a CEP-18 contract
+const (
+	NameNamedKey        = "name"
+	SymbolNamedKey      = "symbol"
+	DecimalsNamedKey    = "decimals"
+	BalancesNamedKey    = "balances"
+	TotalSupplyNamedKey = "total_supply"
+)
+
+// The kinds of transfer of a token
+const (
+	TransferMint     = "mint"
+	TransferTransfer = "transfer"
+	TransferBurn     = "burn"
+)
+
+// holderPrefixes of the holder keys by tag, an account or a contract package
+var holderPrefixes = map[byte]string{0: "account-hash-", 1: "hash-"}
+
+// Token a CEP-18 contract package, the versions of the package share the balances and the supply
+type Token struct {
+	PackageHash string
+	// ContractHash of the last version of the package stored
+	ContractHash string
+	Name         string
+	Symbol       string
+	Decimals     int
+	TotalSupply  amount.Amount
+	// BalancesURef address of the balances dictionary, in hex without the access rights
+	BalancesURef string
+	// TotalSupplyURef address of the total_supply uref, in hex without the access rights
+	TotalSupplyURef string
+}
+
+// Balance of a holder written by a deploy in the balances dictionary
+type Balance struct {
+	// Holder key, account-hash-<hex> or hash-<hex>. The item key when it isn't the base64 of a key.
+	Holder  string
+	Balance amount.Amount
+}
+
+// Transfer of an amount of a token. The holders are account-hash-<hex> or hash-<hex>, a mint has no sender and a burn no recipient.
+type Transfer struct {
+	PackageHash string
+	Kind        string
+	From        string
+	To          string
+	Amount      amount.Amount
+}
+
+// ParseNamedKeyValues set the metadata and the supply of the token from the json values of its named keys.
+// The values that can't be decoded are left empty, some tokens don't follow the standard.
+func (t *Token) ParseNamedKeyValues(name string, symbol string, decimals string, totalSupply string) {
+	_ = json.Unmarshal([]byte(name), &t.Name)
+	_ = json.Unmarshal([]byte(symbol), &t.Symbol)
+	_ = json.Unmarshal([]byte(decimals), &t.Decimals)
+	_ = json.Unmarshal([]byte(totalSupply), &t.TotalSupply)
+}
+
+// HolderKey the formatted key of the holder of a balances dictionary item
+func HolderKey(itemKey string) string {
+	data, err := base64.StdEncoding.DecodeString(itemKey)
+	if err != nil || len(data) != 33 {
+		return itemKey
+	}
+	prefix, ok := holderPrefixes[data[0]]
+	if !ok {
+		return itemKey
+	}
+	return prefix + hex.EncodeToString(data[1:])
+}
+
+// ParseBalance decode a U256 item of the balances dictionary
+func ParseBalance(value clvalue.DictionaryValue) (Balance, error) {
+	if value.CLType != "U256" {
+		return Balance{}, errors.New("the dictionary value is not a balance")
+	}
+	balance, err := parseU256(value.Bytes)
+	if err != nil {
+		return Balance{}, err
+	}
+	return Balance{Holder: HolderKey(value.ItemKey), Balance: balance}, nil
+}
+
+// ParseTotalSupply decode the U256 value written in the total_supply uref
+func ParseTotalSupply(value clvalue.CLValue) (amount.Amount, error) {
+	if value.ClType != "U256" {
+		return amount.Amount{}, errors.New("the value is not a supply")
+	}
+	data, err := hex.DecodeString(value.Bytes)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return parseU256(data)
+}
+
+// parseU256 decode the bytes of a U256 in an amount
+func parseU256(data []byte) (amount.Amount, error) {
+	value, err := clvalue.Decode("U256", data)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.Parse(value.(string))
+}
+
+// ParseCESEvent the transfer of a CES event of a CEP-18 token, without the package hash.
+// False if the event isn't a mint, a transfer or a burn.
+func ParseCESEvent(event ces.Event) (Transfer, bool) {
+	var transfer Transfer
+	switch event.Name {
+	case "Mint":
+		transfer = Transfer{Kind: TransferMint, To: nft.FormatKey(event.Data["recipient"])}
+	case "Transfer":
+		transfer = Transfer{Kind: TransferTransfer, From: nft.FormatKey(event.Data["sender"]), To: nft.FormatKey(event.Data["recipient"])}
+	case "TransferFrom":
+		transfer = Transfer{Kind: TransferTransfer, From: nft.FormatKey(event.Data["owner"]), To: nft.FormatKey(event.Data["recipient"])}
+	case "Burn":
+		transfer = Transfer{Kind: TransferBurn, From: nft.FormatKey(event.Data["owner"])}
+	default:
+		return Transfer{}, false
+	}
+	return transfer, transfer.setAmount(event.Data["amount"])
+}
+
+// ParseEntrypoint the transfer of a call to an entrypoint of a token, with the args of the deploy mapped by name.
+// The caller is the key of the account of the deploy. False if the entrypoint doesn't move an amount.
+func ParseEntrypoint(entrypoint string, args map[string]interface{}, caller string) (Transfer, bool) {
+	var transfer Transfer
+	switch entrypoint {
+	case "mint":
+		transfer = Transfer{Kind: TransferMint, To: nft.FormatKey(args["owner"])}
+	case "transfer":
+		transfer = Transfer{Kind: TransferTransfer, From: caller, To: nft.FormatKey(args["recipient"])}
+	case "transfer_from":
+		transfer = Transfer{Kind: TransferTransfer, From: nft.FormatKey(args["owner"]), To: nft.FormatKey(args["recipient"])}
+	case "burn":
+		transfer = Transfer{Kind: TransferBurn, From: nft.FormatKey(args["owner"])}
+	default:
+		return Transfer{}, false
+	}
+	return transfer, transfer.setAmount(args["amount"])
+}
+
+// setAmount of the transfer from a U256 value, a string or a number. False if it isn't an amount.
+func (t *Transfer) setAmount(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	value, err := amount.Parse(fmt.Sprint(v))
+	if err != nil {
+		return false
+	}
+	t.Amount = value
+	return true
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"casperParser/types/ces"
+	"casperParser/types/clvalue"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHolderKey(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	data, _ := hex.DecodeString(hash)
+	tests := []struct {
+		name     string
+		itemKey  string
+		expected string
+	}{
+		{"Should format an account holder", base64.StdEncoding.EncodeToString(append([]byte{0}, data...)), "account-hash-" + hash},
+		{"Should format a contract package holder", base64.StdEncoding.EncodeToString(append([]byte{1}, data...)), "hash-" + hash},
+		{"Should keep an item key that isn't a key", hash, hash},
+		{"Should keep a key of another variant", base64.StdEncoding.EncodeToString(append([]byte{2}, data...)), base64.StdEncoding.EncodeToString(append([]byte{2}, data...))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if holder := HolderKey(test.itemKey); holder != test.expected {
+				t.Errorf("Bad holder. Received : %s. Expected : %s", holder, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseBalance(t *testing.T) {
+	t.Run("Should decode the U256 balance of the holder", func(t *testing.T) {
+		itemKey := base64.StdEncoding.EncodeToString(make([]byte, 33))
+		balance, err := ParseBalance(clvalue.DictionaryValue{CLType: "U256", Bytes: []byte{2, 0xe8, 0x03}, ItemKey: itemKey})
+		if err != nil {
+			t.Fatalf("Unable to parse the balance : %s", err)
+		}
+		if balance.Holder != "account-hash-"+strings.Repeat("00", 32) || balance.Balance.String() != "1000" {
+			t.Errorf("Bad balance. Received : %s %s. Expected : %s %s", balance.Holder, balance.Balance.String(), "account-hash-"+strings.Repeat("00", 32), "1000")
+		}
+	})
+	t.Run("Should refuse a value that isn't a U256", func(t *testing.T) {
+		if _, err := ParseBalance(clvalue.DictionaryValue{CLType: "U8", Bytes: []byte{1}}); err == nil {
+			t.Errorf("U8 value parsed as a balance")
+		}
+	})
+}
+
+func TestToken_ParseNamedKeyValues(t *testing.T) {
+	var token Token
+	token.ParseNamedKeyValues(`"Casper Token"`, `"CSPRT"`, `9`, `"1000000000"`)
+	if token.Name != "Casper Token" || token.Symbol != "CSPRT" || token.Decimals != 9 || token.TotalSupply.String() != "1000000000" {
+		t.Errorf("Bad token. Received : %s %s %d %s. Expected : %s %s %d %s", token.Name, token.Symbol, token.Decimals, token.TotalSupply.String(), "Casper Token", "CSPRT", 9, "1000000000")
+	}
+}
+
+func TestParseCESEvent(t *testing.T) {
+	owner := "account-hash-" + strings.Repeat("01", 32)
+	recipient := "account-hash-" + strings.Repeat("02", 32)
+	t.Run("Should parse a transfer from", func(t *testing.T) {
+		transfer, ok := ParseCESEvent(ces.Event{Name: "TransferFrom", Data: map[string]interface{}{
+			"spender":   map[string]interface{}{"Account": "account-hash-" + strings.Repeat("03", 32)},
+			"owner":     map[string]interface{}{"Account": owner},
+			"recipient": map[string]interface{}{"Account": recipient},
+			"amount":    "100",
+		}})
+		if !ok || transfer.Kind != TransferTransfer || transfer.From != owner || transfer.To != recipient || transfer.Amount.String() != "100" {
+			t.Errorf("Bad transfer. Received : %v %v. Expected : a transfer of %s from %s to %s", ok, transfer, "100", owner, recipient)
+		}
+	})
+	t.Run("Should parse a mint without sender", func(t *testing.T) {
+		transfer, ok := ParseCESEvent(ces.Event{Name: "Mint", Data: map[string]interface{}{"recipient": map[string]interface{}{"Account": recipient}, "amount": "5"}})
+		if !ok || transfer.Kind != TransferMint || transfer.From != "" || transfer.To != recipient {
+			t.Errorf("Bad mint. Received : %v %v. Expected : a mint to %s", ok, transfer, recipient)
+		}
+	})
+	t.Run("Should ignore the other events", func(t *testing.T) {
+		if _, ok := ParseCESEvent(ces.Event{Name: "SetAllowance", Data: map[string]interface{}{"amount": "5"}}); ok {
+			t.Errorf("SetAllowance event parsed as a transfer")
+		}
+	})
+}
+
+func TestParseEntrypoint(t *testing.T) {
+	caller := "account-hash-" + strings.Repeat("01", 32)
+	recipient := "account-hash-" + strings.Repeat("02", 32)
+	t.Run("Should send the transfer from the caller", func(t *testing.T) {
+		transfer, ok := ParseEntrypoint("transfer", map[string]interface{}{"recipient": map[string]interface{}{"Account": recipient}, "amount": "42"}, caller)
+		if !ok || transfer.Kind != TransferTransfer || transfer.From != caller || transfer.To != recipient || transfer.Amount.String() != "42" {
+			t.Errorf("Bad transfer. Received : %v %v. Expected : a transfer of %s from %s to %s", ok, transfer, "42", caller, recipient)
+		}
+	})
+	t.Run("Should burn from the owner", func(t *testing.T) {
+		transfer, ok := ParseEntrypoint("burn", map[string]interface{}{"owner": map[string]interface{}{"Account": recipient}, "amount": "7"}, caller)
+		if !ok || transfer.Kind != TransferBurn || transfer.From != recipient || transfer.To != "" {
+			t.Errorf("Bad burn. Received : %v %v. Expected : a burn from %s", ok, transfer, recipient)
+		}
+	})
+	t.Run("Should ignore an entrypoint without amount", func(t *testing.T) {
+		if _, ok := ParseEntrypoint("approve", map[string]interface{}{"amount": "7"}, caller); ok {
+			t.Errorf("approve parsed as a transfer")
+		}
+		if _, ok := ParseEntrypoint("transfer", map[string]interface{}{"recipient": map[string]interface{}{"Account": recipient}}, caller); ok {
+			t.Errorf("transfer without amount parsed as a transfer")
+		}
+	})
+}
@@ -1,11 +1,11 @@
 /*
-	Used to provide useful helpers
+Used to provide useful helpers
 */
 package utils
 
 import (
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"log"
 )
 
 // InitViper Helper Init Viper for the unit tests
@@ -24,6 +24,6 @@ func InitViper() error {
 			return err
 		}
 	}
-	log.Printf("Using config file: %s\n", viper.ConfigFileUsed())
+	log.WithField("config_file", viper.ConfigFileUsed()).Info("using config file")
 	return nil
 }
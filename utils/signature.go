@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// VerifySignature check the signature of the message by the public key, both in hex with their algorithm tag,
+// 01 for ed25519 and 02 for secp256k1. A secp256k1 signature is the 64 bytes r and s ECDSA signature of the sha256 of
+// the message by the compressed public key.
+func VerifySignature(publicKey string, message []byte, signature string) (bool, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) == 0 {
+		return false, fmt.Errorf("invalid public key %s", publicKey)
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) == 0 {
+		return false, fmt.Errorf("invalid signature %s", signature)
+	}
+	if key[0] != sig[0] {
+		return false, nil
+	}
+	switch key[0] {
+	case 1:
+		if len(key) != 1+ed25519.PublicKeySize || len(sig) != 1+ed25519.SignatureSize {
+			return false, nil
+		}
+		return ed25519.Verify(key[1:], message, sig[1:]), nil
+	case 2:
+		if len(key) != 1+secp256k1.PubKeyBytesLenCompressed || len(sig) != 65 {
+			return false, nil
+		}
+		pubKey, err := secp256k1.ParsePubKey(key[1:])
+		if err != nil {
+			return false, nil
+		}
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(sig[1:33]) || s.SetByteSlice(sig[33:]) {
+			return false, nil
+		}
+		digest := sha256.Sum256(message)
+		return ecdsa.NewSignature(&r, &s).Verify(digest[:], pubKey), nil
+	}
+	return false, fmt.Errorf("unknown algorithm tag %d of the public key", key[0])
+}